@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell/v2/terminfo"
+)
+
+// This type keeps the sequences of the terminal description that are written
+// when a screen is initialized and finalized, which are changed to keep the
+// contents of the terminal when 'altscreen' option is disabled.
+type screenSeqs struct {
+	enter string
+	exit  string
+	clear string
+}
+
+// These are the original description of the terminal and the copy of it that
+// is registered in its place while 'altscreen' option is disabled. The shared
+// original description is never changed so that it can be registered again
+// when the option is enabled again.
+var (
+	gOrigTerminfo *terminfo.Terminfo
+	gMainTerminfo *terminfo.Terminfo
+)
+
+// This function returns the sequences to use for the given original sequences
+// of the terminal with the given number of lines. The original sequences are
+// used for the alternate screen. Otherwise, the screen is scrolled with
+// newlines instead of switching to the alternate screen so that the contents
+// of the terminal are kept in the scrollback, and the screen is not cleared so
+// that the contents of the ui is kept after exit. When exiting, the cursor is
+// moved to the last line followed by a newline so that the shell prompt is
+// shown on a new line below the ui.
+func altScreenSeqs(orig screenSeqs, altscreen bool, lines int, move func(col, row int) string) screenSeqs {
+	if altscreen {
+		return orig
+	}
+
+	lines = max(lines, 1)
+
+	return screenSeqs{
+		enter: strings.Repeat("\n", lines),
+		exit:  move(0, lines-1) + "\r\n",
+		clear: "",
+	}
+}
+
+// This function registers the description of the terminal to be used by new
+// screens to enter or not to enter the alternate screen for the terminal with
+// the given number of lines. A copy of the original description is changed
+// and registered instead of the original, and the copy is also changed when
+// the alternate screen is enabled again since the current screen may keep it
+// until it is finalized. An error is returned when the terminal is not known
+// so that the setting can not take effect.
+func setAltScreen(altscreen bool, lines int) error {
+	if altscreen && gOrigTerminfo == nil {
+		return nil
+	}
+
+	if gOrigTerminfo == nil {
+		ti, err := terminfo.LookupTerminfo(os.Getenv("TERM"))
+		if err != nil {
+			return fmt.Errorf("looking up terminfo: %s", err)
+		}
+		main := *ti
+		gOrigTerminfo, gMainTerminfo = ti, &main
+	}
+
+	orig := screenSeqs{gOrigTerminfo.EnterCA, gOrigTerminfo.ExitCA, gOrigTerminfo.Clear}
+	seqs := altScreenSeqs(orig, altscreen, lines, gOrigTerminfo.TGoto)
+	gMainTerminfo.EnterCA, gMainTerminfo.ExitCA, gMainTerminfo.Clear = seqs.enter, seqs.exit, seqs.clear
+
+	if altscreen {
+		terminfo.AddTerminfo(gOrigTerminfo)
+	} else {
+		terminfo.AddTerminfo(gMainTerminfo)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/gdamore/tcell/v2/terminfo"
+)
+
+func TestAltScreenSeqs(t *testing.T) {
+	orig := screenSeqs{"\033[?1049h", "\033[?1049l", "\033[H\033[2J"}
+
+	move := func(col, row int) string {
+		return fmt.Sprintf("\033[%d;%dH", row+1, col+1)
+	}
+
+	tests := []struct {
+		altscreen bool
+		lines     int
+		exp       screenSeqs
+	}{
+		{true, 24, orig},
+		{false, 3, screenSeqs{"\n\n\n", "\033[3;1H\r\n", ""}},
+		{false, 1, screenSeqs{"\n", "\033[1;1H\r\n", ""}},
+		{false, 0, screenSeqs{"\n", "\033[1;1H\r\n", ""}},
+	}
+
+	for _, test := range tests {
+		if got := altScreenSeqs(orig, test.altscreen, test.lines, move); got != test.exp {
+			t.Errorf("at input '%t' with '%d' lines expected '%q' but got '%q'", test.altscreen, test.lines, test.exp, got)
+		}
+	}
+}
+
+func TestSetAltScreen(t *testing.T) {
+	defer func(term string, orig, main *terminfo.Terminfo) {
+		os.Setenv("TERM", term)
+		gOrigTerminfo, gMainTerminfo = orig, main
+	}(os.Getenv("TERM"), gOrigTerminfo, gMainTerminfo)
+	gOrigTerminfo, gMainTerminfo = nil, nil
+
+	os.Setenv("TERM", "lf-test-unknown")
+	if err := setAltScreen(false, 24); err == nil {
+		t.Errorf("expected an error for an unknown terminal")
+	}
+
+	os.Setenv("TERM", "xterm")
+	ti, err := terminfo.LookupTerminfo("xterm")
+	if err != nil {
+		t.Skipf("looking up terminfo: %s", err)
+	}
+	defer terminfo.AddTerminfo(ti)
+	orig := screenSeqs{ti.EnterCA, ti.ExitCA, ti.Clear}
+
+	seqs := func() screenSeqs {
+		curr, err := terminfo.LookupTerminfo("xterm")
+		if err != nil {
+			t.Fatalf("looking up terminfo: %s", err)
+		}
+		return screenSeqs{curr.EnterCA, curr.ExitCA, curr.Clear}
+	}
+
+	tests := []struct {
+		altscreen bool
+		exp       screenSeqs
+	}{
+		{true, orig},
+		{false, altScreenSeqs(orig, false, 24, ti.TGoto)},
+		{false, altScreenSeqs(orig, false, 24, ti.TGoto)},
+		{true, orig},
+	}
+
+	for i, test := range tests {
+		if err := setAltScreen(test.altscreen, 24); err != nil {
+			t.Fatalf("at step '%d' unexpected error: %s", i, err)
+		}
+		if got := seqs(); got != test.exp {
+			t.Errorf("at step '%d' with '%t' expected '%q' but got '%q'", i, test.altscreen, test.exp, got)
+		}
+		if got := (screenSeqs{ti.EnterCA, ti.ExitCA, ti.Clear}); got != orig {
+			t.Errorf("at step '%d' expected the original description to be unchanged but got '%q'", i, got)
+		}
+	}
+
+	if got := (screenSeqs{gMainTerminfo.EnterCA, gMainTerminfo.ExitCA, gMainTerminfo.Clear}); got != orig {
+		t.Errorf("expected the copy kept by screens to be restored '%q' but got '%q'", orig, got)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"log"
@@ -9,6 +10,7 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -21,10 +23,19 @@ type cmdItem struct {
 	value  string
 }
 
+// This interface is implemented by 'time.Timer', which is replaced in tests so
+// that delays can be checked without waiting for them.
+type stopResetter interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
 type app struct {
 	ui            *ui
 	nav           *nav
 	ticker        *time.Ticker
+	searchTimer   stopResetter
+	searchChan    <-chan time.Time
 	quitChan      chan struct{}
 	cmd           *exec.Cmd
 	cmdIn         io.WriteCloser
@@ -32,6 +43,21 @@ type app struct {
 	cmdHistory    []cmdItem
 	cmdHistoryBeg int
 	cmdHistoryInd int
+	dirVisits     []dirVisit
+	pickItems     []string
+	pickCmd       string
+	paletteItems  []paletteItem
+	mountList     []mountEntry
+	trashItems    []trashItem
+	templateName  string
+	promptVar     string
+	promptCmd     string
+	asyncDone     chan struct{}
+	repeatCmd     *callExpr
+	dupScan       *dupScan
+	walkScan      *walkScan
+	workspace     string
+	workspaces    map[string]*workspace
 }
 
 func newApp(screen tcell.Screen) *app {
@@ -40,11 +66,18 @@ func newApp(screen tcell.Screen) *app {
 
 	quitChan := make(chan struct{}, 1)
 
+	searchTimer := time.NewTimer(time.Hour)
+	searchTimer.Stop()
+
 	app := &app{
-		ui:       ui,
-		nav:      nav,
-		ticker:   new(time.Ticker),
-		quitChan: quitChan,
+		ui:          ui,
+		nav:         nav,
+		ticker:      new(time.Ticker),
+		searchTimer: searchTimer,
+		searchChan:  searchTimer.C,
+		quitChan:    quitChan,
+		workspace:   "main",
+		workspaces:  make(map[string]*workspace),
 	}
 
 	sigChan := make(chan os.Signal, 1)
@@ -55,7 +88,9 @@ func newApp(screen tcell.Screen) *app {
 			return
 		case syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM:
 			app.writeHistory()
+			app.writeFrecency()
 			os.Remove(gLogPath)
+			removeResultPath(gResultPath)
 			os.Exit(3)
 			return
 		}
@@ -213,7 +248,7 @@ func (app *app) loop() {
 				continue
 			}
 
-			app.nav.previewChan <- ""
+			app.nav.previewChan <- previewReq{}
 
 			log.Print("bye!")
 
@@ -221,6 +256,10 @@ func (app *app) loop() {
 				log.Printf("writing history file: %s", err)
 			}
 
+			if err := app.writeFrecency(); err != nil {
+				log.Printf("writing frecency file: %s", err)
+			}
+
 			if gLastDirPath != "" {
 				f, err := os.Create(gLastDirPath)
 				if err != nil {
@@ -290,6 +329,11 @@ func (app *app) loop() {
 				d.sel(prev.name(), app.nav.height)
 			}
 
+			// the other pane of split mode starts at its saved file
+			if s := app.nav.split; s != nil && s.dir == d.path && (!ok || len(prev.files) == 0) {
+				d.sel(s.file, app.nav.height)
+			}
+
 			app.nav.dirCache[d.path] = d
 
 			for i := range app.nav.dirs {
@@ -310,6 +354,9 @@ func (app *app) loop() {
 				}
 			}
 
+			app.ui.draw(app.nav)
+		case d := <-app.nav.diskChan:
+			app.nav.checkDisk(d)
 			app.ui.draw(app.nav)
 		case r := <-app.nav.regChan:
 			app.nav.checkReg(r)
@@ -320,6 +367,7 @@ func (app *app) loop() {
 			if err == nil {
 				if r.path == curr.path {
 					app.ui.regPrev = r
+					app.nav.clampPreviewScroll(r)
 				}
 			}
 
@@ -350,6 +398,20 @@ func (app *app) loop() {
 		case e := <-serverChan:
 			e.eval(app, nil)
 			app.ui.draw(app.nav)
+		case focused := <-app.ui.focusChan:
+			if focused == app.ui.focused {
+				continue
+			}
+			app.ui.focused = focused
+			app.setTicker()
+			if focused && gOpts.period != 0 {
+				app.nav.renew()
+				app.ui.loadFile(app.nav, false)
+			}
+			app.ui.draw(app.nav)
+		case <-app.searchChan:
+			incsearch(app)
+			app.ui.draw(app.nav)
 		case <-app.ticker.C:
 			app.nav.renew()
 			app.ui.loadFile(app.nav, false)
@@ -358,6 +420,106 @@ func (app *app) loop() {
 	}
 }
 
+// This function delays incremental searches until there is a pause in typing
+// for 'incsearchdelay' milliseconds. Each call postpones the pending search and
+// it returns true when the search should be run immediately without a delay.
+// Final patterns are always searched when the prompt is accepted.
+func (app *app) debounceSearch() bool {
+	if gOpts.incsearchdelay == 0 {
+		return true
+	}
+
+	if !app.searchTimer.Stop() {
+		select {
+		case <-app.searchChan:
+		default:
+		}
+	}
+	app.searchTimer.Reset(time.Duration(gOpts.incsearchdelay) * time.Millisecond)
+
+	return false
+}
+
+// This function sets an option depending on focus changes using the given
+// function and enables or disables focus reporting of the terminal as needed.
+// The terminal is assumed to be focused when focus reporting is disabled.
+func (app *app) setFocusOpt(set func()) {
+	prev := focusReporting()
+	set()
+	if curr := focusReporting(); curr != prev {
+		setFocusReporting(curr)
+	}
+	if !focusReporting() {
+		app.ui.focused = true
+	}
+}
+
+// This function sets 'altscreen' option and initializes the screen again when
+// the value is changed so that the terminal leaves the mode of the previous
+// value before entering the new one. The option is kept as it is when the new
+// value can not take effect for the terminal.
+func (app *app) setAltScreenOpt(val bool) {
+	if val == gOpts.altscreen {
+		return
+	}
+	_, h := app.ui.screen.Size()
+	app.ui.pause()
+	if err := setAltScreen(val, h); err != nil {
+		app.ui.resume()
+		app.ui.echoerrf("altscreen: %s", err)
+		return
+	}
+	gOpts.altscreen = val
+	app.ui.resume()
+}
+
+// This function evaluates the given expression after the last asynchronous
+// shell command is finished, which is used by 'wait' command to run the rest
+// of a command sequence. The expression is evaluated immediately when there is
+// no such command, and it is discarded when the command is not finished in
+// 'waittimeout' seconds.
+func (app *app) waitAsync(e expr) {
+	done := app.asyncDone
+	if done == nil {
+		e.eval(app, nil)
+		return
+	}
+
+	var timeout <-chan time.Time
+	if gOpts.waittimeout != 0 {
+		timeout = time.After(time.Duration(gOpts.waittimeout) * time.Second)
+	}
+
+	go func() {
+		select {
+		case <-done:
+			app.ui.exprChan <- e
+		case <-timeout:
+			app.ui.exprChan <- &callExpr{"echoerr", []string{"wait: timed out"}, 1}
+		}
+	}()
+}
+
+// This function returns the period of reloading in seconds depending on the
+// focus of the terminal. Longer 'idleperiod' is used instead of 'period' when
+// the terminal is not focused. Reloading is disabled in both cases when
+// 'period' is zero.
+func reloadPeriod(focused bool) int {
+	if gOpts.period == 0 || focused || gOpts.idleperiod == 0 {
+		return gOpts.period
+	}
+	return gOpts.idleperiod
+}
+
+// This function restarts the ticker used for reloading with the current
+// period.
+func (app *app) setTicker() {
+	app.ticker.Stop()
+	if n := reloadPeriod(app.ui.focused); n != 0 {
+		app.ticker = time.NewTicker(time.Duration(n) * time.Second)
+	}
+}
+
 func (app *app) exportFiles() {
 	var currFile string
 	if curr, err := app.nav.currFile(); err == nil {
@@ -369,6 +531,158 @@ func (app *app) exportFiles() {
 	exportFiles(currFile, currSelections)
 }
 
+// This function switches to the workspace with the given name after saving the
+// state of the current workspace. A new workspace is created in the current
+// directory with an empty selection when there is no workspace with the name.
+// It returns whether a new workspace is created.
+func (app *app) switchWorkspace(name string) (bool, error) {
+	if name == app.workspace {
+		return false, fmt.Errorf("already in workspace: %s", name)
+	}
+
+	app.workspaces[app.workspace] = app.nav.saveWorkspace()
+	app.workspace = name
+
+	ws, ok := app.workspaces[name]
+	if !ok {
+		app.nav.selections = make(map[string]int)
+		app.nav.selectionInd = 0
+		app.nav.selSizes.reset()
+		return true, nil
+	}
+
+	return false, app.nav.restoreWorkspace(ws)
+}
+
+// This function closes the current workspace and switches to the workspace
+// after it in sorted order, or the first one when it is the last in order.
+// When the current workspace is the only one, it returns whether lf should
+// quit for 'quitiflast' option or an error when the option is disabled.
+func (app *app) closeWorkspace() (bool, error) {
+	names := app.workspaceNames()
+	if len(names) == 1 {
+		if gOpts.quitiflast {
+			return true, nil
+		}
+		return false, fmt.Errorf("can not close the last workspace: %s", app.workspace)
+	}
+
+	next := names[0]
+	for i, name := range names {
+		if name == app.workspace && i+1 < len(names) {
+			next = names[i+1]
+		}
+	}
+
+	ws := app.workspaces[next]
+	delete(app.workspaces, app.workspace)
+	app.workspace = next
+
+	return false, app.nav.restoreWorkspace(ws)
+}
+
+// This function returns the names of all workspaces in sorted order including
+// the current one.
+func (app *app) workspaceNames() []string {
+	names := []string{app.workspace}
+	for name := range app.workspaces {
+		if name != app.workspace {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// This function returns the tool to compare the given files with 'diff'
+// command. Value of 'difftool' option is used when it is set, otherwise 'diff
+// -u' is used for files and 'diff -ru' is used for directories.
+func diffTool(paths []string) (string, error) {
+	if len(paths) != 2 {
+		return "", fmt.Errorf("requires exactly two selected files but got %d", len(paths))
+	}
+
+	if gOpts.difftool != "" {
+		return gOpts.difftool, nil
+	}
+
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if stat.IsDir() {
+			return "diff -ru", nil
+		}
+	}
+
+	return "diff -u", nil
+}
+
+// This function is used to run a shell command as a filter for 'pipe-rename'
+// command. Names of the given files are written to the standard input of the
+// command one per line and the transformed names are read from its output.
+func (app *app) pipeNames(s string, paths []string) ([]string, error) {
+	var in bytes.Buffer
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if strings.ContainsAny(name, "\r\n") {
+			return nil, fmt.Errorf("file name contains a newline: %q", name)
+		}
+		in.WriteString(name)
+		in.WriteByte('\n')
+	}
+
+	app.exportFiles()
+	exportOpts()
+
+	cmd := shellCommand(s, nil)
+	cmd.Stdin = &in
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNames(string(out)), nil
+}
+
+// This function splits the output of a 'pipe-rename' filter into names. Empty
+// lines are kept so that the number of names can be checked against the
+// number of files.
+func parseNames(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+
+	names := strings.Split(s, "\n")
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, "\r")
+	}
+	return names
+}
+
+// This function is used to run a shell command and read the lines in its
+// output as menu items for 'shell-pick' command.
+func (app *app) readPickItems(s string) ([]string, error) {
+	if gOpts.readonly {
+		return nil, fmt.Errorf("read-only mode")
+	}
+
+	app.exportFiles()
+	exportOpts()
+
+	cmd := shellCommand(s, nil)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseMenuItems(string(out)), nil
+}
+
 func waitKey() error {
 	cmd := pauseCommand()
 
@@ -391,9 +705,18 @@ func waitKey() error {
 //     !       Yes   No     Yes    Yes     Yes     Pause and then resume
 //     &       No    Yes    No     No      No      Do nothing
 func (app *app) runShell(s string, args []string, prefix string) {
+	if gOpts.readonly {
+		app.ui.echoerr("running shell: read-only mode")
+		return
+	}
+
 	app.exportFiles()
 	exportOpts()
 
+	// Results of previous commands are removed so that they are not read
+	// again when the command does not write a new result.
+	os.Remove(gResultPath)
+
 	cmd := shellCommand(s, args)
 
 	var out io.Reader
@@ -404,12 +727,16 @@ func (app *app) runShell(s string, args []string, prefix string) {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 
-		app.nav.previewChan <- ""
+		app.nav.previewChan <- previewReq{}
 		app.ui.pause()
 		defer app.ui.resume()
 		defer app.nav.renew()
 
-		err = cmd.Run()
+		if gOpts.cmdtimeout > 0 {
+			err = runTimeout(cmd, time.Duration(gOpts.cmdtimeout)*time.Second)
+		} else {
+			err = cmd.Run()
+		}
 	case "%":
 		stdin, err := cmd.StdinPipe()
 		if err != nil {
@@ -477,10 +804,18 @@ func (app *app) runShell(s string, args []string, prefix string) {
 			app.ui.exprChan <- &callExpr{"load", nil, 1}
 		}()
 	case "&":
+		if err != nil {
+			return
+		}
+		start := time.Now()
+		done := make(chan struct{})
+		app.asyncDone = done
 		go func() {
 			if err := cmd.Wait(); err != nil {
 				log.Printf("running shell: %s", err)
 			}
+			close(done)
+			app.ui.notify(start, fmt.Sprintf("Finished: %s", s))
 		}()
 	}
 }
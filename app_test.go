@@ -0,0 +1,524 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffTool(t *testing.T) {
+	defer func(difftool string) { gOpts.difftool = difftool }(gOpts.difftool)
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	foo := filepath.Join(dir, "foo")
+	bar := filepath.Join(dir, "bar")
+	baz := filepath.Join(dir, "baz")
+	for _, path := range []string{foo, bar} {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+	if err := os.Mkdir(baz, 0755); err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+
+	tests := []struct {
+		difftool string
+		paths    []string
+		exp      string
+		err      bool
+	}{
+		{"", nil, "", true},
+		{"", []string{foo}, "", true},
+		{"", []string{foo, bar, baz}, "", true},
+		{"", []string{foo, filepath.Join(dir, "qux")}, "", true},
+		{"", []string{foo, bar}, "diff -u", false},
+		{"", []string{foo, baz}, "diff -ru", false},
+		{"", []string{baz, baz}, "diff -ru", false},
+		{"git diff --no-index", []string{foo, bar}, "git diff --no-index", false},
+		{"git diff --no-index", []string{foo, baz}, "git diff --no-index", false},
+	}
+
+	for _, test := range tests {
+		gOpts.difftool = test.difftool
+		got, err := diffTool(test.paths)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%v' expected error '%t' but got '%v'", test.paths, test.err, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%v' with '%s' expected '%s' but got '%s'", test.paths, test.difftool, test.exp, got)
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp []string
+	}{
+		{"", nil},
+		{"\n", nil},
+		{"foo", []string{"foo"}},
+		{"foo\n", []string{"foo"}},
+		{"foo\nbar\n", []string{"foo", "bar"}},
+		{"foo\r\nbar\r\n", []string{"foo", "bar"}},
+		{"foo\n\nbar\n", []string{"foo", "", "bar"}},
+		{"foo bar\n", []string{"foo bar"}},
+	}
+
+	for _, test := range tests {
+		if got := parseNames(test.s); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' expected '%q' but got '%q'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestPipeNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("filters are tested with unix shell commands")
+	}
+
+	defer func(shell string, shellopts []string, ifs string) {
+		gOpts.shell = shell
+		gOpts.shellopts = shellopts
+		gOpts.ifs = ifs
+	}(gOpts.shell, gOpts.shellopts, gOpts.ifs)
+	gOpts.shell = "sh"
+	gOpts.shellopts = nil
+	gOpts.ifs = ""
+
+	app := &app{nav: &nav{dirs: []*dir{{}}, selections: make(map[string]int)}}
+
+	paths := []string{"/foo/a b.txt", "/bar/c.TXT", "/baz/d"}
+
+	names, err := app.pipeNames("tr a-z A-Z", paths)
+	if err != nil {
+		t.Fatalf("running filter: %s", err)
+	}
+	if exp := []string{"A B.TXT", "C.TXT", "D"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, names)
+	}
+
+	renames, err := bulkRenames(paths, names)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	exp := [][2]string{
+		{"/foo/a b.txt", "/foo/A B.TXT"},
+		{"/bar/c.TXT", "/bar/C.TXT"},
+		{"/baz/d", "/baz/D"},
+	}
+	if !reflect.DeepEqual(renames, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, renames)
+	}
+
+	names, err = app.pipeNames("head -n 2", paths)
+	if err != nil {
+		t.Fatalf("running filter: %s", err)
+	}
+	if _, err := bulkRenames(paths, names); err == nil {
+		t.Errorf("expected an error for mismatching number of names")
+	}
+
+	names, err = app.pipeNames("sed 's/.*/x/'", paths)
+	if err != nil {
+		t.Fatalf("running filter: %s", err)
+	}
+	if _, err := bulkRenames(paths[:2], names[:2]); err != nil {
+		t.Errorf("unexpected error for names in different directories: %s", err)
+	}
+	if _, err := bulkRenames([]string{"/foo/a", "/foo/b"}, []string{"x", "x"}); err == nil {
+		t.Errorf("expected an error for files renamed to the same name")
+	}
+
+	for _, name := range []string{"", ".", "..", "foo/bar"} {
+		if _, err := bulkRenames([]string{"/foo/a"}, []string{name}); err == nil {
+			t.Errorf("at name '%s' expected an error", name)
+		}
+	}
+
+	if _, err := app.pipeNames("exit 1", paths); err == nil {
+		t.Errorf("expected an error for failing filter")
+	}
+
+	if _, err := app.pipeNames("cat", []string{"/foo/a\nb"}); err == nil {
+		t.Errorf("expected an error for names with newlines")
+	}
+}
+
+func TestReloadPeriod(t *testing.T) {
+	defer func(period, idleperiod int) {
+		gOpts.period = period
+		gOpts.idleperiod = idleperiod
+	}(gOpts.period, gOpts.idleperiod)
+
+	tests := []struct {
+		period     int
+		idleperiod int
+		focused    bool
+		exp        int
+	}{
+		{0, 0, true, 0},
+		{0, 0, false, 0},
+		{0, 60, true, 0},
+		{0, 60, false, 0},
+		{1, 0, true, 1},
+		{1, 0, false, 1},
+		{1, 60, true, 1},
+		{1, 60, false, 60},
+	}
+
+	for _, test := range tests {
+		gOpts.period = test.period
+		gOpts.idleperiod = test.idleperiod
+		if got := reloadPeriod(test.focused); got != test.exp {
+			t.Errorf("at period '%d', idleperiod '%d', and focused '%t' expected '%d' but got '%d'", test.period, test.idleperiod, test.focused, test.exp, got)
+		}
+	}
+}
+
+// This type is a timer that fires only when told so, which is used to test
+// delays without waiting for them.
+type fakeTimer struct {
+	c      chan time.Time
+	active bool
+	resets []time.Duration
+}
+
+func (t *fakeTimer) Stop() bool {
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	active := t.active
+	t.active = true
+	t.resets = append(t.resets, d)
+	return active
+}
+
+func (t *fakeTimer) fire() {
+	t.active = false
+	t.c <- time.Now()
+}
+
+func TestDebounceSearch(t *testing.T) {
+	defer func(incsearchdelay int) {
+		gOpts.incsearchdelay = incsearchdelay
+	}(gOpts.incsearchdelay)
+
+	timer := &fakeTimer{c: make(chan time.Time, 1)}
+	app := &app{searchTimer: timer, searchChan: timer.c}
+
+	gOpts.incsearchdelay = 0
+	if !app.debounceSearch() {
+		t.Errorf("at incsearchdelay '0' expected immediate search")
+	}
+	if len(timer.resets) != 0 {
+		t.Errorf("at incsearchdelay '0' expected no delayed search but got '%v'", timer.resets)
+	}
+
+	// each keystroke postpones the pending search by the whole delay
+	gOpts.incsearchdelay = 100
+	for i := 0; i < 3; i++ {
+		if app.debounceSearch() {
+			t.Errorf("at incsearchdelay '100' expected delayed search")
+		}
+	}
+	if len(timer.resets) != 3 || timer.resets[2] != 100*time.Millisecond || !timer.active {
+		t.Errorf("expected search to be postponed by '100ms' for each keystroke but got '%v'", timer.resets)
+	}
+
+	// a search fired but not run yet is dropped for the postponed one
+	timer.fire()
+	app.debounceSearch()
+	select {
+	case <-app.searchChan:
+		t.Errorf("expected the fired search to be dropped")
+	default:
+	}
+	if !timer.active {
+		t.Errorf("expected search to be pending after a keystroke")
+	}
+}
+
+func TestWaitAsync(t *testing.T) {
+	defer func(waittimeout int) { gOpts.waittimeout = waittimeout }(gOpts.waittimeout)
+	defer delete(gOpts.cmds, "lf-test-wait")
+	gOpts.waittimeout = 0
+
+	newWaitApp := func() *app {
+		return &app{
+			ui:  &ui{exprChan: make(chan expr, 10)},
+			nav: &nav{dirs: []*dir{{}}, selections: make(map[string]int)},
+		}
+	}
+
+	list := &listExpr{[]expr{
+		&callExpr{"wait", nil, 1},
+		&cmdExpr{"lf-test-wait", &callExpr{"echo", nil, 1}},
+	}, 1}
+
+	a := newWaitApp()
+	list.eval(a, nil)
+	if _, ok := gOpts.cmds["lf-test-wait"]; !ok {
+		t.Errorf("without asynchronous commands expected the rest to be run immediately")
+	}
+	delete(gOpts.cmds, "lf-test-wait")
+
+	a = newWaitApp()
+	done := make(chan struct{})
+	a.asyncDone = done
+	list.eval(a, nil)
+	if _, ok := gOpts.cmds["lf-test-wait"]; ok {
+		t.Errorf("expected the rest to be not run before the command is finished")
+	}
+	select {
+	case e := <-a.ui.exprChan:
+		t.Errorf("expected nothing to be sent before the command is finished but got '%v'", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(done)
+	select {
+	case e := <-a.ui.exprChan:
+		e.eval(a, nil)
+	case <-time.After(time.Second):
+		t.Fatalf("expected the rest to be sent after the command is finished")
+	}
+	if _, ok := gOpts.cmds["lf-test-wait"]; !ok {
+		t.Errorf("expected the rest to be run after the command is finished")
+	}
+	delete(gOpts.cmds, "lf-test-wait")
+
+	gOpts.waittimeout = 1
+	a = newWaitApp()
+	a.asyncDone = make(chan struct{})
+	list.eval(a, nil)
+	select {
+	case e := <-a.ui.exprChan:
+		e.eval(a, nil)
+	case <-time.After(3 * time.Second):
+		t.Fatalf("expected a timeout error")
+	}
+	if _, ok := gOpts.cmds["lf-test-wait"]; ok {
+		t.Errorf("expected the rest to be not run after timeout")
+	}
+	if !strings.Contains(a.ui.msg, "timed out") {
+		t.Errorf("expected a timeout error but got '%s'", a.ui.msg)
+	}
+}
+
+func TestWaitRepeat(t *testing.T) {
+	a := &app{
+		ui:  &ui{exprChan: make(chan expr, 10)},
+		nav: &nav{dirs: []*dir{{}}, selections: make(map[string]int)},
+	}
+	done := make(chan struct{})
+	a.asyncDone = done
+
+	echo := &callExpr{"echo", []string{"foo"}, 1}
+	(&listExpr{[]expr{echo, &callExpr{"wait", nil, 1}, echo}, 2}).eval(a, nil)
+
+	close(done)
+	e := <-a.ui.exprChan
+	list, ok := e.(*listExpr)
+	if !ok {
+		t.Fatalf("expected the rest as a list but got '%v'", e)
+	}
+	if len(list.exprs) != 4 || list.count != 1 {
+		t.Errorf("expected the rest of all repetitions but got '%v'", list)
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting current directory: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	foo := filepath.Join(root, "foo")
+	bar := filepath.Join(root, "bar")
+	for _, dir := range []string{foo, bar} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+		for _, name := range []string{"a", "b"} {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatalf("creating temporary file: %s", err)
+			}
+		}
+	}
+
+	if err := os.Chdir(foo); err != nil {
+		t.Fatalf("changing directory: %s", err)
+	}
+
+	a := &app{nav: newNav(10), workspace: "main", workspaces: make(map[string]*workspace)}
+	defer waitDirs(a.nav)
+	if err := a.nav.sel(filepath.Join(foo, "b")); err != nil {
+		t.Fatalf("selecting file: %s", err)
+	}
+	a.nav.toggleSelection(filepath.Join(foo, "a"))
+
+	if _, err := a.switchWorkspace("main"); err == nil {
+		t.Errorf("expected an error for switching to the current workspace")
+	}
+
+	created, err := a.switchWorkspace("other")
+	if err != nil || !created {
+		t.Fatalf("expected a new workspace but got '%t' (error: %v)", created, err)
+	}
+	if a.nav.currDir().path != foo {
+		t.Errorf("expected the new workspace to start in '%s' but got '%s'", foo, a.nav.currDir().path)
+	}
+	if len(a.nav.selections) != 0 {
+		t.Errorf("expected the new workspace to start with an empty selection but got '%v'", a.nav.selections)
+	}
+
+	if err := a.nav.sel(filepath.Join(bar, "a")); err != nil {
+		t.Fatalf("selecting file: %s", err)
+	}
+	a.nav.toggleSelection(filepath.Join(bar, "b"))
+
+	created, err = a.switchWorkspace("main")
+	if err != nil || created {
+		t.Fatalf("expected an existing workspace but got '%t' (error: %v)", created, err)
+	}
+	if a.nav.currDir().path != foo {
+		t.Errorf("expected the directory '%s' to be restored but got '%s'", foo, a.nav.currDir().path)
+	}
+	if curr, err := a.nav.currFile(); err != nil || curr.Name() != "b" {
+		t.Errorf("expected the current file 'b' to be restored but got '%v' (error: %v)", curr, err)
+	}
+	if exp := map[string]int{filepath.Join(foo, "a"): 0}; !reflect.DeepEqual(a.nav.selections, exp) {
+		t.Errorf("expected the selection '%v' to be restored but got '%v'", exp, a.nav.selections)
+	}
+	if cwd, err := os.Getwd(); err != nil || cwd != foo {
+		t.Errorf("expected the working directory '%s' but got '%s'", foo, cwd)
+	}
+
+	if _, err := a.switchWorkspace("other"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a.nav.currDir().path != bar {
+		t.Errorf("expected the directory '%s' to be restored but got '%s'", bar, a.nav.currDir().path)
+	}
+	if exp := map[string]int{filepath.Join(bar, "b"): 0}; !reflect.DeepEqual(a.nav.selections, exp) {
+		t.Errorf("expected the selection '%v' to be restored but got '%v'", exp, a.nav.selections)
+	}
+
+	waitDirs(a.nav)
+	if err := os.Remove(filepath.Join(foo, "b")); err != nil {
+		t.Fatalf("removing file: %s", err)
+	}
+	if _, err := a.switchWorkspace("main"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if a.nav.currDir().path != foo {
+		t.Errorf("expected the directory '%s' to be restored without the removed file but got '%s'", foo, a.nav.currDir().path)
+	}
+
+	if exp := []string{"main", "other"}; !reflect.DeepEqual(a.workspaceNames(), exp) {
+		t.Errorf("expected workspaces '%v' but got '%v'", exp, a.workspaceNames())
+	}
+}
+
+func TestCloseWorkspace(t *testing.T) {
+	defer func(quitiflast bool) { gOpts.quitiflast = quitiflast }(gOpts.quitiflast)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting current directory: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	foo := filepath.Join(root, "foo")
+	bar := filepath.Join(root, "bar")
+	for _, dir := range []string{foo, bar} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+	}
+
+	if err := os.Chdir(foo); err != nil {
+		t.Fatalf("changing directory: %s", err)
+	}
+
+	a := &app{nav: newNav(10), workspace: "main", workspaces: make(map[string]*workspace)}
+	defer waitDirs(a.nav)
+
+	for _, name := range []string{"other", "third"} {
+		if _, err := a.switchWorkspace(name); err != nil {
+			t.Fatalf("switching workspace: %s", err)
+		}
+	}
+	if err := a.nav.cd(bar); err != nil {
+		t.Fatalf("changing directory: %s", err)
+	}
+	if _, err := a.switchWorkspace("other"); err != nil {
+		t.Fatalf("switching workspace: %s", err)
+	}
+
+	tests := []struct {
+		workspace string
+		names     []string
+	}{
+		{"third", []string{"main", "third"}},
+		{"main", []string{"main"}},
+	}
+
+	for _, test := range tests {
+		quit, err := a.closeWorkspace()
+		if err != nil || quit {
+			t.Fatalf("expected to switch to '%s' but got '%t' (error: %v)", test.workspace, quit, err)
+		}
+		if a.workspace != test.workspace {
+			t.Errorf("expected workspace '%s' but got '%s'", test.workspace, a.workspace)
+		}
+		if names := a.workspaceNames(); !reflect.DeepEqual(names, test.names) {
+			t.Errorf("expected workspaces '%v' but got '%v'", test.names, names)
+		}
+		if test.workspace == "third" && a.nav.currDir().path != bar {
+			t.Errorf("expected the directory '%s' to be restored but got '%s'", bar, a.nav.currDir().path)
+		}
+	}
+
+	gOpts.quitiflast = false
+	if quit, err := a.closeWorkspace(); err == nil || quit {
+		t.Errorf("expected an error for closing the last workspace but got '%t' (error: %v)", quit, err)
+	}
+	if a.workspace != "main" {
+		t.Errorf("expected the last workspace to be kept but got '%s'", a.workspace)
+	}
+
+	gOpts.quitiflast = true
+	if quit, err := a.closeWorkspace(); err != nil || !quit {
+		t.Errorf("expected to quit for closing the last workspace but got '%t' (error: %v)", quit, err)
+	}
+}
@@ -0,0 +1,636 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This is the scheme of virtual directories in archives browsed with
+// 'archivebrowse' option. Paths of these directories consist of the scheme,
+// the path of the archive, and the path of the directory in the archive
+// separated with a double slash (e.g. 'archive:///foo/bar.zip//baz').
+const gArchiveScheme = "archive://"
+
+type archiveFormat byte
+
+const (
+	noArchive archiveFormat = iota
+	zipArchive
+	tarArchive
+	tarGzArchive
+	tarBz2Archive
+)
+
+// These are the extensions of files classified as archives, which are used
+// both for 'archive' type of 'on-type' command and for 'archivebrowse' option.
+// Extensions of formats that can not be browsed are mapped to 'noArchive'.
+var gArchiveExts = map[string]archiveFormat{
+	".7z":      noArchive,
+	".bz2":     noArchive,
+	".gz":      noArchive,
+	".jar":     zipArchive,
+	".lz":      noArchive,
+	".lzma":    noArchive,
+	".rar":     noArchive,
+	".tar":     tarArchive,
+	".tar.bz2": tarBz2Archive,
+	".tar.gz":  tarGzArchive,
+	".tar.xz":  noArchive,
+	".tar.zst": noArchive,
+	".tbz2":    tarBz2Archive,
+	".tgz":     tarGzArchive,
+	".txz":     noArchive,
+	".xz":      noArchive,
+	".zip":     zipArchive,
+	".zst":     noArchive,
+}
+
+// This function returns the archive extension of the given name, preferring
+// compound extensions (e.g. '.tar.gz' instead of '.gz'), or false when the
+// name does not have an archive extension. Leading dots of hidden files are
+// not considered as the start of an extension.
+func archiveExt(name string) (string, bool) {
+	name = strings.ToLower(name)
+	for i := 1; i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if _, ok := gArchiveExts[name[i:]]; ok {
+			return name[i:], true
+		}
+	}
+	return "", false
+}
+
+// This function returns the format of the archive with the given name from its
+// extension or 'noArchive' when the format can not be browsed.
+func archiveFormatOf(name string) archiveFormat {
+	ext, ok := archiveExt(name)
+	if !ok {
+		return noArchive
+	}
+	return gArchiveExts[ext]
+}
+
+func isArchivePath(vpath string) bool { return strings.HasPrefix(vpath, gArchiveScheme) }
+
+// This function returns the path of the virtual directory or file with the
+// given path in the given archive, which is the root of the archive when the
+// inner path is empty.
+func archivePath(archive, inner string) string {
+	if inner == "" {
+		return gArchiveScheme + archive
+	}
+	return gArchiveScheme + archive + "//" + inner
+}
+
+// This function splits the given path of a virtual directory or file into the
+// path of the archive and the path in the archive.
+func splitArchivePath(vpath string) (archive, inner string, ok bool) {
+	if !isArchivePath(vpath) {
+		return "", "", false
+	}
+	s := strings.TrimPrefix(vpath, gArchiveScheme)
+	if i := strings.Index(s, "//"); i >= 0 {
+		return s[:i], s[i+2:], true
+	}
+	return s, "", true
+}
+
+// This function returns the parent directory of the given path, which is the
+// directory containing the archive for the root of an archive.
+func parentDir(vpath string) string {
+	archive, inner, ok := splitArchivePath(vpath)
+	if !ok {
+		return filepath.Dir(vpath)
+	}
+	if inner == "" {
+		return filepath.Dir(archive)
+	}
+	if dir := path.Dir(inner); dir != "." {
+		return archivePath(archive, dir)
+	}
+	return archivePath(archive, "")
+}
+
+// This function returns the last element of the given path, which is the name
+// of the archive for the root of an archive.
+func baseName(vpath string) string {
+	archive, inner, ok := splitArchivePath(vpath)
+	if !ok {
+		return filepath.Base(vpath)
+	}
+	if inner == "" {
+		return filepath.Base(archive)
+	}
+	return path.Base(inner)
+}
+
+// This function returns the real directory for the given path, which is the
+// directory containing the archive for paths in archives.
+func realDir(vpath string) string {
+	if archive, _, ok := splitArchivePath(vpath); ok {
+		return filepath.Dir(archive)
+	}
+	return vpath
+}
+
+// This function decides whether 'open' command should browse the given file
+// as a virtual directory, which is the case for directories in archives and
+// for archives when 'archivebrowse' option is enabled. Archives in archives
+// can not be browsed.
+func browsable(f *file) bool {
+	if isArchivePath(f.path) {
+		return f.IsDir()
+	}
+	return gOpts.archivebrowse && f.Mode().IsRegular() && archiveFormatOf(f.Name()) != noArchive
+}
+
+// This type is the information of an entry in an archive to be used for
+// virtual files.
+type archiveEntry struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	link    string
+}
+
+func (e *archiveEntry) Name() string       { return path.Base(e.name) }
+func (e *archiveEntry) Size() int64        { return e.size }
+func (e *archiveEntry) Mode() os.FileMode  { return e.mode }
+func (e *archiveEntry) ModTime() time.Time { return e.modTime }
+func (e *archiveEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *archiveEntry) Sys() interface{}   { return nil }
+
+// This function cleans the given name of an entry in an archive. Names
+// pointing outside of the archive are rejected since they can not be shown in
+// virtual directories.
+func cleanEntryName(name string) (string, bool) {
+	name = path.Clean("/" + strings.Replace(name, `\`, "/", -1))[1:]
+	if name == "" || name == "." {
+		return "", false
+	}
+	return name, true
+}
+
+func readZipEntries(archive string) ([]*archiveEntry, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []*archiveEntry
+	for _, f := range r.File {
+		name, ok := cleanEntryName(f.Name)
+		if !ok {
+			continue
+		}
+		mode := f.Mode()
+		if strings.HasSuffix(f.Name, "/") {
+			mode |= os.ModeDir
+		}
+		entries = append(entries, &archiveEntry{
+			name:    name,
+			size:    int64(f.UncompressedSize64),
+			mode:    mode,
+			modTime: f.Modified,
+		})
+	}
+
+	return entries, nil
+}
+
+// This function returns a reader for the tar stream in the given file
+// decompressed according to the given format.
+func newTarReader(f io.Reader, format archiveFormat) (*tar.Reader, error) {
+	switch format {
+	case tarGzArchive:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(gz), nil
+	case tarBz2Archive:
+		return tar.NewReader(bzip2.NewReader(f)), nil
+	}
+	return tar.NewReader(f), nil
+}
+
+func readTarEntries(archive string, format archiveFormat) ([]*archiveEntry, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tr, err := newTarReader(f, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*archiveEntry
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name, ok := cleanEntryName(hdr.Name)
+		if !ok {
+			continue
+		}
+		entries = append(entries, &archiveEntry{
+			name:    name,
+			size:    hdr.Size,
+			mode:    hdr.FileInfo().Mode(),
+			modTime: hdr.ModTime,
+			link:    hdr.Linkname,
+		})
+	}
+
+	return entries, nil
+}
+
+// This function returns the entries of the given archive sorted by name.
+// Directories that are not stored in the archive but contain other entries are
+// added so that all entries can be reached by browsing, and only the last one
+// of entries with the same name is kept as when the archive is extracted.
+func readArchiveEntries(archive string) ([]*archiveEntry, error) {
+	var entries []*archiveEntry
+	var err error
+
+	switch format := archiveFormatOf(archive); format {
+	case zipArchive:
+		entries, err = readZipEntries(archive)
+	case tarArchive, tarGzArchive, tarBz2Archive:
+		entries, err = readTarEntries(archive, format)
+	default:
+		return nil, fmt.Errorf("unsupported archive: %s", archive)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*archiveEntry, len(entries))
+	for _, e := range entries {
+		byName[e.name] = e
+	}
+	for _, e := range entries {
+		for dir := path.Dir(e.name); dir != "."; dir = path.Dir(dir) {
+			if _, ok := byName[dir]; ok {
+				break
+			}
+			byName[dir] = &archiveEntry{name: dir, mode: os.ModeDir | 0755, modTime: e.modTime}
+		}
+	}
+
+	list := make([]*archiveEntry, 0, len(byName))
+	for _, e := range byName {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+
+	return list, nil
+}
+
+type archiveCacheEntry struct {
+	modTime time.Time
+	size    int64
+	entries []*archiveEntry
+}
+
+// These are the entries of archives read for virtual directories, which are
+// reused until the archive is changed since reading compressed archives
+// requires decompressing the whole archive.
+var (
+	gArchiveCache = make(map[string]archiveCacheEntry)
+	gArchiveMutex sync.Mutex
+)
+
+func loadArchiveEntries(archive string) ([]*archiveEntry, error) {
+	s, err := os.Stat(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	gArchiveMutex.Lock()
+	c, ok := gArchiveCache[archive]
+	gArchiveMutex.Unlock()
+
+	if ok && c.modTime.Equal(s.ModTime()) && c.size == s.Size() {
+		return c.entries, nil
+	}
+
+	entries, err := readArchiveEntries(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	gArchiveMutex.Lock()
+	gArchiveCache[archive] = archiveCacheEntry{s.ModTime(), s.Size(), entries}
+	gArchiveMutex.Unlock()
+
+	return entries, nil
+}
+
+// This function returns the files in the virtual directory with the given
+// path. Counts of directories are set from the entries since they can not be
+// read from the filesystem.
+func readArchiveDir(vpath string) ([]*file, error) {
+	archive, inner, ok := splitArchivePath(vpath)
+	if !ok {
+		return nil, fmt.Errorf("not in an archive: %s", vpath)
+	}
+
+	entries, err := loadArchiveEntries(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := func(name string) string {
+		if dir := path.Dir(name); dir != "." {
+			return dir
+		}
+		return ""
+	}
+
+	counts := make(map[string]int)
+	for _, e := range entries {
+		counts[parent(e.name)]++
+	}
+
+	if _, ok := counts[inner]; !ok && inner != "" {
+		found := false
+		for _, e := range entries {
+			if e.name == inner && e.IsDir() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no such directory in archive: %s", inner)
+		}
+	}
+
+	var files []*file
+	for _, e := range entries {
+		if parent(e.name) != inner {
+			continue
+		}
+
+		var linkState linkState
+		if e.mode&os.ModeSymlink != 0 {
+			linkState = broken
+		}
+
+		dirCount := -1
+		if e.IsDir() {
+			dirCount = counts[e.name]
+		}
+
+		files = append(files, &file{
+			FileInfo:   e,
+			linkState:  linkState,
+			linkTarget: e.link,
+			path:       archivePath(archive, e.name),
+			dirCount:   dirCount,
+			childCount: dirCount,
+			xattrCount: 0,
+			accessTime: e.modTime,
+			changeTime: e.modTime,
+			ext:        path.Ext(e.name),
+		})
+	}
+
+	return files, nil
+}
+
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
+
+// This function returns the index of the last entry with the given name in
+// the given tar archive, or a negative index when there is no such entry or the
+// last one is not a regular file.
+func lastTarEntry(archive string, format archiveFormat, inner string) (int, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+
+	tr, err := newTarReader(f, format)
+	if err != nil {
+		return -1, err
+	}
+
+	last := -1
+	for i := 0; ; i++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return -1, err
+		}
+		if name, ok := cleanEntryName(hdr.Name); ok && name == inner {
+			if hdr.FileInfo().Mode().IsRegular() {
+				last = i
+			} else {
+				last = -1
+			}
+		}
+	}
+
+	return last, nil
+}
+
+// This function opens the regular file with the given path in an archive for
+// reading its contents. The last entry is used when there are entries with the
+// same name, which is the one shown in the listing.
+func openArchiveFile(vpath string) (io.ReadCloser, error) {
+	archive, inner, ok := splitArchivePath(vpath)
+	if !ok || inner == "" {
+		return nil, fmt.Errorf("not a file in an archive: %s", vpath)
+	}
+
+	notFound := fmt.Errorf("no such file in archive: %s", inner)
+
+	switch format := archiveFormatOf(archive); format {
+	case zipArchive:
+		r, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(r.File) - 1; i >= 0; i-- {
+			f := r.File[i]
+			if name, ok := cleanEntryName(f.Name); !ok || name != inner {
+				continue
+			}
+			if !f.Mode().IsRegular() || strings.HasSuffix(f.Name, "/") {
+				break
+			}
+			rc, err := f.Open()
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+			return readCloser{rc, func() error {
+				rc.Close()
+				return r.Close()
+			}}, nil
+		}
+		r.Close()
+		return nil, notFound
+	case tarArchive, tarGzArchive, tarBz2Archive:
+		// the last entry with the name is used as in the listing, which is only
+		// known after reading the whole archive, so the archive is read again
+		last, err := lastTarEntry(archive, format, inner)
+		if err != nil {
+			return nil, err
+		}
+		if last < 0 {
+			return nil, notFound
+		}
+
+		f, err := os.Open(archive)
+		if err != nil {
+			return nil, err
+		}
+		tr, err := newTarReader(f, format)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		for i := 0; ; i++ {
+			_, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				f.Close()
+				return nil, err
+			}
+			if i == last {
+				return readCloser{tr, f.Close}, nil
+			}
+		}
+		f.Close()
+		return nil, notFound
+	}
+
+	return nil, fmt.Errorf("unsupported archive: %s", archive)
+}
+
+// This function returns the entry of the regular file with the given path in
+// an archive.
+func archiveFileEntry(vpath string) (*archiveEntry, error) {
+	archive, inner, ok := splitArchivePath(vpath)
+	if !ok || inner == "" {
+		return nil, fmt.Errorf("not a file in an archive: %s", vpath)
+	}
+
+	entries, err := loadArchiveEntries(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if e.name != inner {
+			continue
+		}
+		if !e.mode.IsRegular() {
+			return nil, errors.New("only regular files can be extracted")
+		}
+		return e, nil
+	}
+
+	return nil, fmt.Errorf("no such file in archive: %s", inner)
+}
+
+// This function returns the total size of the given files in archives. Files
+// that can not be extracted are skipped since their errors are reported when
+// they are extracted.
+func archiveSize(vpaths []string) int64 {
+	var total int64
+
+	for _, vpath := range vpaths {
+		if entry, err := archiveFileEntry(vpath); err == nil {
+			total += entry.size
+		}
+	}
+
+	return total
+}
+
+// This function extracts the regular file with the given path in an archive
+// to the given directory with the same name and permissions and sends the
+// number of bytes written to the given channel. Existing files are not
+// overwritten, partially extracted files are removed on errors, and it returns
+// the path of the extracted file.
+func extractArchiveFile(vpath, dir string, nums chan int64) (string, error) {
+	entry, err := archiveFileEntry(vpath)
+	if err != nil {
+		return "", err
+	}
+
+	r, err := openArchiveFile(vpath)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	dst := filepath.Join(dir, entry.Name())
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, entry.mode.Perm()|0200)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				f.Close()
+				os.Remove(dst)
+				return "", err
+			}
+			nums <- int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			os.Remove(dst)
+			return "", err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+
+	return dst, nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestArchivePaths(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("archive paths are tested with unix paths")
+	}
+
+	tests := []struct {
+		path   string
+		parent string
+		base   string
+		real   string
+	}{
+		{"/foo/bar.zip", "/foo", "bar.zip", "/foo/bar.zip"},
+		{"archive:///foo/bar.zip", "/foo", "bar.zip", "/foo"},
+		{"archive:///foo/bar.zip//baz", "archive:///foo/bar.zip", "baz", "/foo"},
+		{"archive:///foo/bar.zip//baz/qux", "archive:///foo/bar.zip//baz", "qux", "/foo"},
+	}
+
+	for _, test := range tests {
+		if got := parentDir(test.path); got != test.parent {
+			t.Errorf("at input '%s' expected parent '%s' but got '%s'", test.path, test.parent, got)
+		}
+		if got := baseName(test.path); got != test.base {
+			t.Errorf("at input '%s' expected base '%s' but got '%s'", test.path, test.base, got)
+		}
+		if got := realDir(test.path); got != test.real {
+			t.Errorf("at input '%s' expected real directory '%s' but got '%s'", test.path, test.real, got)
+		}
+	}
+
+	archive, inner, ok := splitArchivePath(archivePath("/foo/bar.tar.gz", "baz/qux"))
+	if !ok || archive != "/foo/bar.tar.gz" || inner != "baz/qux" {
+		t.Errorf("expected '/foo/bar.tar.gz' and 'baz/qux' but got '%s' and '%s'", archive, inner)
+	}
+}
+
+func TestArchiveExt(t *testing.T) {
+	tests := []struct {
+		name   string
+		ext    string
+		format archiveFormat
+	}{
+		{"foo.zip", ".zip", zipArchive},
+		{"FOO.JAR", ".jar", zipArchive},
+		{"foo.tar", ".tar", tarArchive},
+		{"foo.tar.gz", ".tar.gz", tarGzArchive},
+		{"foo.bar.tgz", ".tgz", tarGzArchive},
+		{"foo.tar.bz2", ".tar.bz2", tarBz2Archive},
+		{"foo.tar.xz", ".tar.xz", noArchive},
+		{"foo.txt.gz", ".gz", noArchive},
+		{"foo.7z", ".7z", noArchive},
+		{"foo.txt", "", noArchive},
+		{".zip", "", noArchive},
+		{".foo.zip", ".zip", zipArchive},
+		{"zip", "", noArchive},
+	}
+
+	for _, test := range tests {
+		ext, ok := archiveExt(test.name)
+		if ext != test.ext || ok != (test.ext != "") {
+			t.Errorf("at input '%s' expected extension '%s' but got '%s'", test.name, test.ext, ext)
+		}
+		if got := archiveFormatOf(test.name); got != test.format {
+			t.Errorf("at input '%s' expected format '%d' but got '%d'", test.name, test.format, got)
+		}
+	}
+}
+
+func TestCleanEntryName(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+		ok  bool
+	}{
+		{"foo", "foo", true},
+		{"foo/", "foo", true},
+		{"./foo/bar", "foo/bar", true},
+		{"/foo//bar", "foo/bar", true},
+		{`foo\bar`, "foo/bar", true},
+		{"../../foo", "foo", true},
+		{"./", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := cleanEntryName(test.s)
+		if got != test.exp || ok != test.ok {
+			t.Errorf("at input '%s' expected '%s' and '%t' but got '%s' and '%t'", test.s, test.exp, test.ok, got, ok)
+		}
+	}
+}
+
+// This function returns the names, directory flags, and directory counts of
+// the files in the given virtual directory.
+func listArchiveDir(t *testing.T, path string) ([]string, []bool, []int) {
+	files, err := readArchiveDir(path)
+	if err != nil {
+		t.Fatalf("at input '%s' unexpected error: %s", path, err)
+	}
+
+	var names []string
+	var dirs []bool
+	var counts []int
+	for _, f := range files {
+		names = append(names, f.Name())
+		dirs = append(dirs, f.IsDir())
+		counts = append(counts, f.dirCount)
+		if f.childCount != f.dirCount {
+			t.Errorf("at input '%s' expected entry count '%d' for '%s' but got '%d'", path, f.dirCount, f.Name(), f.childCount)
+		}
+	}
+	return names, dirs, counts
+}
+
+func TestZipArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "foo.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	zw := zip.NewWriter(f)
+	for _, entry := range []struct{ name, s string }{
+		{"a.txt", "foo"},
+		{"empty/", ""},
+		{"sub/b.txt", "bar"},
+		{"sub/deep/c.txt", "baz"},
+	} {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatalf("creating entry: %s", err)
+		}
+		if _, err := w.Write([]byte(entry.s)); err != nil {
+			t.Fatalf("writing entry: %s", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing archive: %s", err)
+	}
+	f.Close()
+
+	tests := []struct {
+		inner  string
+		names  []string
+		dirs   []bool
+		counts []int
+	}{
+		{"", []string{"a.txt", "empty", "sub"}, []bool{false, true, true}, []int{-1, 0, 2}},
+		{"sub", []string{"b.txt", "deep"}, []bool{false, true}, []int{-1, 1}},
+		{"sub/deep", []string{"c.txt"}, []bool{false}, []int{-1}},
+		{"empty", nil, nil, nil},
+	}
+
+	for _, test := range tests {
+		names, dirs, counts := listArchiveDir(t, archivePath(archive, test.inner))
+		if !reflect.DeepEqual(names, test.names) || !reflect.DeepEqual(dirs, test.dirs) || !reflect.DeepEqual(counts, test.counts) {
+			t.Errorf("at input '%s' expected '%v', '%v', and '%v' but got '%v', '%v', and '%v'", test.inner, test.names, test.dirs, test.counts, names, dirs, counts)
+		}
+	}
+
+	for _, inner := range []string{"missing", "a.txt"} {
+		if _, err := readArchiveDir(archivePath(archive, inner)); err == nil {
+			t.Errorf("at input '%s' expected an error for a path that is not a directory", inner)
+		}
+	}
+
+	files, _ := readArchiveDir(archivePath(archive, "sub"))
+	if exp := archivePath(archive, "sub/b.txt"); files[0].path != exp {
+		t.Errorf("expected path '%s' but got '%s'", exp, files[0].path)
+	}
+
+	r, err := openArchiveFile(archivePath(archive, "sub/deep/c.txt"))
+	if err != nil {
+		t.Fatalf("opening file: %s", err)
+	}
+	buf, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(buf) != "baz" {
+		t.Errorf("expected contents 'baz' but got '%s' (%v)", buf, err)
+	}
+
+	nums := make(chan int64, 16)
+	dst, err := extractArchiveFile(archivePath(archive, "sub/b.txt"), dir, nums)
+	if err != nil {
+		t.Fatalf("extracting file: %s", err)
+	}
+	if buf, err := ioutil.ReadFile(dst); err != nil || string(buf) != "bar" || dst != filepath.Join(dir, "b.txt") {
+		t.Errorf("expected 'bar' to be extracted to 'b.txt' but got '%s' in '%s' (%v)", buf, dst, err)
+	}
+	if n := <-nums; n != 3 {
+		t.Errorf("expected '3' bytes to be reported but got '%d'", n)
+	}
+	if _, err := extractArchiveFile(archivePath(archive, "sub/b.txt"), dir, nums); err == nil {
+		t.Errorf("expected an error when extracting over an existing file")
+	}
+	if _, err := extractArchiveFile(archivePath(archive, "sub"), dir, nums); err == nil {
+		t.Errorf("expected an error when extracting a directory")
+	}
+
+	out := filepath.Join(dir, "out")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatalf("creating dir: %s", err)
+	}
+
+	defer func(errorsummary bool) { gOpts.errorsummary = errorsummary }(gOpts.errorsummary)
+	gOpts.errorsummary = false
+
+	srcs := []string{
+		archivePath(archive, "sub"),
+		archivePath(archive, "sub/b.txt"),
+		archivePath(archive, "sub/deep/c.txt"),
+	}
+	if total := archiveSize(srcs); total != 6 {
+		t.Errorf("expected total size '6' but got '%d'", total)
+	}
+
+	nav := &nav{copyBytesChan: make(chan int64, 16), copyTotalChan: make(chan int64, 2)}
+	ui := &ui{exprChan: make(chan expr, 4)}
+	nav.extractAsync(ui, srcs, out)
+
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("expected '%s' to be extracted after an error: %s", name, err)
+		}
+	}
+	if e := (<-ui.exprChan).(*callExpr); e.name != "echoerr" || !strings.Contains(e.args[0], "sub: only regular files") {
+		t.Errorf("expected an error for the directory but got '%v'", e)
+	}
+	if n := <-nav.copyTotalChan + <-nav.copyTotalChan; n != 0 {
+		t.Errorf("expected the progress to be reset but got '%d'", n)
+	}
+}
+
+func TestTarGzArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "foo.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("creating archive: %s", err)
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	// the last one of entries with the same name is used as when extracting
+	for _, e := range []struct {
+		hdr  *tar.Header
+		body string
+	}{
+		{&tar.Header{Name: "./proj/", Typeflag: tar.TypeDir, Mode: 0755}, ""},
+		{&tar.Header{Name: "./proj/main.go", Typeflag: tar.TypeReg, Mode: 0600, Size: 11}, "package foo"},
+		{&tar.Header{Name: "./proj/main.go", Typeflag: tar.TypeReg, Mode: 0644, Size: 12}, "package main"},
+		{&tar.Header{Name: "./proj/link", Typeflag: tar.TypeSymlink, Linkname: "main.go", Mode: 0777}, ""},
+	} {
+		if err := tw.WriteHeader(e.hdr); err != nil {
+			t.Fatalf("writing header: %s", err)
+		}
+		tw.Write([]byte(e.body))
+	}
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	names, dirs, _ := listArchiveDir(t, archivePath(archive, ""))
+	if !reflect.DeepEqual(names, []string{"proj"}) || !reflect.DeepEqual(dirs, []bool{true}) {
+		t.Errorf("expected 'proj' directory but got '%v' and '%v'", names, dirs)
+	}
+
+	files, err := readArchiveDir(archivePath(archive, "proj"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("expected two files but got '%v' (%v)", files, err)
+	}
+	if f := files[0]; f.Name() != "link" || f.linkTarget != "main.go" || f.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected symbolic link 'link' to 'main.go' but got '%s' to '%s'", f.Name(), f.linkTarget)
+	}
+	if f := files[1]; f.Name() != "main.go" || f.Size() != 12 || f.Mode().Perm() != 0644 {
+		t.Errorf("expected 'main.go' with size '12' but got '%s' with size '%d'", f.Name(), f.Size())
+	}
+
+	r, err := openArchiveFile(archivePath(archive, "proj/main.go"))
+	if err != nil {
+		t.Fatalf("opening file: %s", err)
+	}
+	buf, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(buf) != "package main" {
+		t.Errorf("expected contents 'package main' but got '%s' (%v)", buf, err)
+	}
+}
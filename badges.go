@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// These are the glyphs drawn for badges when 'badges' option does not give
+// one explicitly.
+var gBadgeGlyphs = map[string]string{
+	"readonly": "R",
+	"hidden":   "H",
+	"link":     "@",
+	"exec":     "*",
+}
+
+// This function splits a token of 'badges' option into the name of the badge
+// and its glyph. Tokens are either a plain name or a name followed by '=' and
+// the glyph which may contain escape sequences for colors.
+func parseBadge(tok string) (name, glyph string, err error) {
+	name = tok
+	if i := strings.IndexByte(tok, '='); i >= 0 {
+		name, glyph = tok[:i], tok[i+1:]
+		if printLength(glyph) == 0 {
+			return "", "", fmt.Errorf("empty glyph for badge: %s", name)
+		}
+	}
+
+	def, ok := gBadgeGlyphs[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown badge: %s", name)
+	}
+
+	if glyph == "" {
+		glyph = def
+	}
+
+	return name, glyph, nil
+}
+
+// This function reports whether the given file has the attribute of the
+// badge with the given name.
+func hasBadge(f *file, hiddenfiles []string, name string) bool {
+	switch name {
+	case "readonly":
+		return f.linkState != broken && f.Mode().Perm()&0222 == 0
+	case "hidden":
+		return isHidden(f, filepath.Dir(f.path), hiddenfiles)
+	case "link":
+		return f.linkState != notLink
+	case "exec":
+		return !f.IsDir() && f.linkState != broken && isExecutable(f)
+	}
+	return false
+}
+
+// This function returns the badges of the given file for the tokens of
+// 'badges' option. Each badge takes the width of its glyph and it is filled
+// with spaces when the file does not have the attribute so that the column
+// has the same width for all files. Invalid tokens are ignored since they are
+// rejected when the option is set.
+func fileBadges(f *file, hiddenfiles []string, badges []string) []string {
+	var res []string
+
+	for _, tok := range badges {
+		name, glyph, err := parseBadge(tok)
+		if err != nil {
+			continue
+		}
+
+		if hasBadge(f, hiddenfiles, name) {
+			res = append(res, glyph)
+		} else {
+			res = append(res, strings.Repeat(" ", printLength(glyph)))
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseBadge(t *testing.T) {
+	tests := []struct {
+		tok   string
+		name  string
+		glyph string
+		err   bool
+	}{
+		{"readonly", "readonly", "R", false},
+		{"hidden", "hidden", "H", false},
+		{"link=L", "link", "L", false},
+		{"exec=\033[32m*", "exec", "\033[32m*", false},
+		{"exec=", "", "", true},
+		{"exec=\033[32m", "", "", true},
+		{"foo", "", "", true},
+		{"=R", "", "", true},
+	}
+
+	for _, test := range tests {
+		name, glyph, err := parseBadge(test.tok)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%q' expected error '%t' but got '%v'", test.tok, test.err, err)
+			continue
+		}
+		if name != test.name || glyph != test.glyph {
+			t.Errorf("at input '%q' expected '%s' and '%q' but got '%s' and '%q'", test.tok, test.name, test.glyph, name, glyph)
+		}
+	}
+}
+
+func TestFileBadges(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links and permissions are tested on unix")
+	}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, mode := range map[string]os.FileMode{
+		"file":     0644,
+		"readonly": 0444,
+		"exec":     0755,
+		".hidden":  0644,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+		if err := os.Chmod(filepath.Join(dir, name), mode); err != nil {
+			t.Fatalf("changing mode: %s", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatalf("creating dir: %s", err)
+	}
+	if err := os.Symlink("exec", filepath.Join(dir, "link")); err != nil {
+		t.Fatalf("creating link: %s", err)
+	}
+	if err := os.Symlink("missing", filepath.Join(dir, "broken")); err != nil {
+		t.Fatalf("creating link: %s", err)
+	}
+
+	files, err := readdir(dir)
+	if err != nil {
+		t.Fatalf("reading dir: %s", err)
+	}
+
+	byName := make(map[string]*file)
+	for _, f := range files {
+		byName[f.Name()] = f
+	}
+
+	all := []string{"readonly", "hidden", "link", "exec"}
+
+	tests := []struct {
+		name   string
+		badges []string
+		exp    []string
+	}{
+		{"file", all, []string{" ", " ", " ", " "}},
+		{"readonly", all, []string{"R", " ", " ", " "}},
+		{"exec", all, []string{" ", " ", " ", "*"}},
+		{".hidden", all, []string{" ", "H", " ", " "}},
+		{"dir", all, []string{" ", " ", " ", " "}},
+		{"link", all, []string{" ", " ", "@", "*"}},
+		{"broken", all, []string{" ", " ", "@", " "}},
+		{"link", []string{"exec", "link"}, []string{"*", "@"}},
+		{"link", []string{"link=->", "readonly=ro"}, []string{"->", "  "}},
+		{"exec", []string{"exec=\033[32m*"}, []string{"\033[32m*"}},
+		{"file", []string{"exec=\033[32m*"}, []string{" "}},
+		{"file", nil, nil},
+	}
+
+	for _, test := range tests {
+		got := fileBadges(byName[test.name], []string{".*"}, test.badges)
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' with badges '%v' expected '%q' but got '%q'", test.name, test.badges, test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// This is the number of numbered bookmarks which are assigned with numbers
+// from 1 to 9 so that each of them can be chosen with a single key.
+const gBookmarkCount = 9
+
+// This type is the directories of numbered bookmarks where the directory of
+// bookmark 'n' is at index 'n-1' and unassigned bookmarks are empty.
+type bookmarks [gBookmarkCount]string
+
+// This function parses the number of a bookmark from 1 to 9.
+func parseBookmark(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > gBookmarkCount {
+		return 0, fmt.Errorf("bookmark should be a number from 1 to %d: %s", gBookmarkCount, s)
+	}
+	return n, nil
+}
+
+func (bms *bookmarks) set(n int, path string) { bms[n-1] = path }
+
+func (bms *bookmarks) get(n int) (string, error) {
+	if bms[n-1] == "" {
+		return "", fmt.Errorf("no such bookmark: %d", n)
+	}
+	return bms[n-1], nil
+}
+
+// This function reads the bookmarks from the given file with a bookmark on
+// each line as the number and the path separated with a colon as in the marks
+// file. Lines with invalid numbers are ignored.
+func readBookmarks(path string) (bookmarks, error) {
+	var bms bookmarks
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return bms, nil
+	}
+	if err != nil {
+		return bms, fmt.Errorf("opening bookmarks file: %s", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		toks := strings.SplitN(scanner.Text(), ":", 2)
+		if len(toks) != 2 {
+			continue
+		}
+		n, err := parseBookmark(toks[0])
+		if err != nil {
+			continue
+		}
+		bms.set(n, toks[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return bms, fmt.Errorf("reading bookmarks file: %s", err)
+	}
+
+	return bms, nil
+}
+
+// This function writes the assigned bookmarks to the given file.
+func writeBookmarks(path string, bms bookmarks) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("creating data directory: %s", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating bookmarks file: %s", err)
+	}
+	defer f.Close()
+
+	for i, dir := range bms {
+		if dir == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(f, "%d:%s\n", i+1, dir); err != nil {
+			return fmt.Errorf("writing bookmarks file: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func listBookmarks(bms bookmarks) *bytes.Buffer {
+	t := new(tabwriter.Writer)
+	b := new(bytes.Buffer)
+
+	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
+	fmt.Fprintln(t, "bookmark\tpath")
+	for i, dir := range bms {
+		if dir == "" {
+			dir = "-"
+		}
+		fmt.Fprintf(t, "%d\t%s\n", i+1, dir)
+	}
+	t.Flush()
+
+	return b
+}
+
+// This function saves the current directory as the bookmark with the given
+// number and lets other clients read the bookmarks again.
+func (app *app) setBookmark(name, arg string) {
+	n, err := parseBookmark(arg)
+	if err != nil {
+		app.ui.echoerrf("%s: %s", name, err)
+		return
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Printf("getting current directory: %s", err)
+		return
+	}
+
+	app.nav.bookmarks.set(n, wd)
+	if err := writeBookmarks(gBookmarksPath, app.nav.bookmarks); err != nil {
+		app.ui.echoerrf("%s: %s", name, err)
+		return
+	}
+	if err := remote("send sync"); err != nil {
+		app.ui.echoerrf("%s: %s", name, err)
+	}
+}
+
+// This function changes the current directory to the bookmark with the given
+// number. The previous directory is kept in the special mark "'" as in
+// 'mark-load' command.
+func (app *app) jumpBookmark(name, arg string) {
+	n, err := parseBookmark(arg)
+	if err != nil {
+		app.ui.echoerrf("%s: %s", name, err)
+		return
+	}
+
+	path, err := app.nav.bookmarks.get(n)
+	if err != nil {
+		app.ui.echoerrf("%s: %s", name, err)
+		return
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Printf("getting current directory: %s", err)
+	}
+
+	if err := app.nav.cd(path); err != nil {
+		app.ui.echoerrf("%s", err)
+		return
+	}
+	app.ui.loadFile(app.nav, true)
+	app.ui.loadFileInfo(app.nav)
+
+	if wd != path {
+		app.nav.marks["'"] = wd
+		onChdir(app)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseBookmark(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp int
+		err bool
+	}{
+		{"1", 1, false},
+		{"9", 9, false},
+		{"0", 0, true},
+		{"10", 0, true},
+		{"-1", 0, true},
+		{"a", 0, true},
+		{"", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseBookmark(test.s)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' expected error '%t' but got '%v'", test.s, test.err, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' expected '%d' but got '%d'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	var bms bookmarks
+
+	if _, err := bms.get(1); err == nil {
+		t.Errorf("expected an error for an unassigned bookmark")
+	}
+
+	bms.set(1, "/foo")
+	bms.set(9, "/bar")
+	bms.set(1, "/baz")
+
+	tests := []struct {
+		n   int
+		exp string
+		err bool
+	}{
+		{1, "/baz", false},
+		{9, "/bar", false},
+		{5, "", true},
+	}
+
+	for _, test := range tests {
+		got, err := bms.get(test.n)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%d' expected error '%t' but got '%v'", test.n, test.err, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%d' expected '%s' but got '%s'", test.n, test.exp, got)
+		}
+	}
+}
+
+func TestBookmarksFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "lf", "bookmarks")
+
+	bms, err := readBookmarks(path)
+	if err != nil {
+		t.Fatalf("reading missing file: %s", err)
+	}
+	if bms != (bookmarks{}) {
+		t.Errorf("expected no bookmarks without a file but got '%v'", bms)
+	}
+
+	bms.set(2, "/foo bar")
+	bms.set(7, `C:\foo:bar`)
+	if err := writeBookmarks(path, bms); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading file: %s", err)
+	}
+	if exp := "2:/foo bar\n7:C:\\foo:bar\n"; string(buf) != exp {
+		t.Errorf("expected file '%q' but got '%q'", exp, buf)
+	}
+
+	got, err := readBookmarks(path)
+	if err != nil {
+		t.Fatalf("reading file: %s", err)
+	}
+	if got != bms {
+		t.Errorf("expected '%v' but got '%v'", bms, got)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("1:/foo\n0:/zero\n12:/twelve\nfoo\n3:/bar\n"), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+	got, err = readBookmarks(path)
+	if err != nil {
+		t.Fatalf("reading file: %s", err)
+	}
+	if exp := (bookmarks{"/foo", "", "/bar"}); got != exp {
+		t.Errorf("expected invalid lines to be ignored with '%v' but got '%v'", exp, got)
+	}
+}
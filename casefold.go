@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// This function returns the case folded form of the given character which is
+// used to compare characters when case is ignored. The character is mapped to
+// its lowercase form first and then to a single representative of the
+// characters equal to it with Unicode simple case folding (e.g. 's', 'S', and
+// 'ſ' or 'σ', 'Σ', and 'ς'), which is the smallest lowercase character among
+// them. Each character is folded to a single character so that the number of
+// characters in strings does not change, although the number of bytes may
+// change (e.g. 'ſ' to 's'), therefore full case folding with multiple
+// characters (e.g. 'ß' to 'ss') is not done.
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+
+	rep := r
+	lower := unicode.IsLower(r)
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		switch {
+		case unicode.IsLower(f) && (!lower || f < rep):
+			rep, lower = f, true
+		case !lower && f < rep:
+			rep = f
+		}
+	}
+
+	return rep
+}
+
+// This function returns the case folded form of the given string with each
+// character folded as in 'foldRune' function.
+func foldCase(s string) string {
+	return strings.Map(foldRune, s)
+}
@@ -0,0 +1,91 @@
+package main
+
+import "testing"
+
+func TestFoldCase(t *testing.T) {
+	tests := []struct {
+		s1  string
+		s2  string
+		exp bool
+	}{
+		{"foo", "FOO", true},
+		{"foo", "bar", false},
+		{"ſtraße", "STRASSE", false},
+		{"ſtraße", "STRAẞE", true},
+		{"ſ", "s", true},
+		{"ſ", "S", true},
+		{"K", "k", true},
+		{"K", "K", true},
+		{"µ", "μ", true},
+		{"µ", "Μ", true},
+		{"ς", "σ", true},
+		{"ς", "Σ", true},
+		{"ǅ", "ǆ", true},
+		{"ǅ", "Ǆ", true},
+		{"İ", "i", true},
+		{"ı", "i", false},
+		{"ı", "I", false},
+		{"ÇĞÖŞÜ", "çğöşü", true},
+		{"ＡＢＣ", "ａｂｃ", true},
+		{"日本語", "日本語", true},
+	}
+
+	for _, test := range tests {
+		f1, f2 := foldCase(test.s1), foldCase(test.s2)
+		if got := f1 == f2; got != test.exp {
+			t.Errorf("at input '%s' and '%s' expected '%t' but got '%t' with '%s' and '%s'", test.s1, test.s2, test.exp, got, f1, f2)
+		}
+		if len([]rune(f1)) != len([]rune(test.s1)) {
+			t.Errorf("at input '%s' expected the number of characters to be kept but got '%s'", test.s1, f1)
+		}
+	}
+}
+
+func TestFoldSearch(t *testing.T) {
+	defer func(ignorecase, smartcase, ignoredia, smartdia, globsearch, anchorfind bool) {
+		gOpts.ignorecase = ignorecase
+		gOpts.smartcase = smartcase
+		gOpts.ignoredia = ignoredia
+		gOpts.smartdia = smartdia
+		gOpts.globsearch = globsearch
+		gOpts.anchorfind = anchorfind
+	}(gOpts.ignorecase, gOpts.smartcase, gOpts.ignoredia, gOpts.smartdia, gOpts.globsearch, gOpts.anchorfind)
+	gOpts.ignoredia = false
+	gOpts.smartdia = false
+	gOpts.anchorfind = true
+
+	tests := []struct {
+		name       string
+		pattern    string
+		ignorecase bool
+		smartcase  bool
+		globsearch bool
+		exp        bool
+	}{
+		{"STRAẞE.txt", "straße", true, false, false, true},
+		{"STRAẞE.txt", "straße", false, false, false, false},
+		{"STRAẞE.txt", "strasse", true, false, false, false},
+		{"ſtraße.txt", "STRASSE", true, false, false, false},
+		{"ſtraße.txt", "straße", true, true, false, true},
+		{"ſtraße.txt", "Straße", true, true, false, false},
+		{"ΟΔΟΣ.txt", "οδος", true, false, false, true},
+		{"ΟΔΟΣ.txt", "οδο*.txt", true, false, true, true},
+		{"οδός", "ΟΔΌΣ", true, false, false, true},
+		{"Kelvin.txt", "k*", true, false, true, true},
+	}
+
+	for _, test := range tests {
+		gOpts.ignorecase = test.ignorecase
+		gOpts.smartcase = test.smartcase
+		gOpts.globsearch = test.globsearch
+		if got, err := searchMatch(test.name, test.pattern); err != nil || got != test.exp {
+			t.Errorf("at input '%s' with pattern '%s' expected '%t' but got '%t' (error: %v)", test.name, test.pattern, test.exp, got, err)
+		}
+		if test.globsearch {
+			continue
+		}
+		if got := findMatch(test.name, test.pattern); got != test.exp {
+			t.Errorf("at input '%s' with find pattern '%s' expected '%t' but got '%t'", test.name, test.pattern, test.exp, got)
+		}
+	}
+}
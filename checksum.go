@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Files larger than this size show the progress of hashing in the ui.
+const gChecksumProgressSize = 64 << 20
+
+func newHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case "md5":
+		return md5.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	}
+	return nil, fmt.Errorf("unknown algorithm: %s (use 'md5', 'sha1', or 'sha256')", algo)
+}
+
+type progressWriter struct {
+	n        int64
+	progress func(n int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	w.progress(w.n)
+	return len(p), nil
+}
+
+// This function returns the hash of the contents of the given file in
+// hexadecimal. Contents are streamed so large files are not kept in memory.
+// The progress function, when not nil, is called with the number of bytes
+// read so far.
+func fileChecksum(path, algo string, progress func(n int64)) (string, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var w io.Writer = h
+	if progress != nil {
+		w = io.MultiWriter(h, &progressWriter{progress: progress})
+	}
+
+	if _, err := io.Copy(w, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// This function parses the contents of a sidecar file in the format printed
+// by 'sha256sum' and similar tools (i.e. 'hash  name' or 'hash *name' lines)
+// and returns the hash recorded for the given file name. A line with only a
+// hash is also accepted for sidecar files that are written for a single file.
+func parseSidecar(s, name string) (string, bool) {
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1:
+			return strings.ToLower(fields[0]), true
+		case len(fields) >= 2:
+			file := strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+			if file == name || filepath.Base(file) == name {
+				return strings.ToLower(fields[0]), true
+			}
+		}
+	}
+	return "", false
+}
+
+// This function reads the sidecar file of the given file named after the
+// algorithm (e.g. 'foo.iso.sha256') and returns the hash recorded in it. The
+// boolean result is false when there is no sidecar file or the file is not
+// listed in it.
+func readSidecar(path, algo string) (string, bool, error) {
+	buf, err := ioutil.ReadFile(path + "." + algo)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	sum, ok := parseSidecar(string(buf), filepath.Base(path))
+	return sum, ok, nil
+}
+
+func checksumAsync(ui *ui, paths []string, algo string) {
+	var matched, mismatched, failed int
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+
+		var progress func(n int64)
+		if info, err := os.Stat(path); err == nil && info.Size() > gChecksumProgressSize {
+			last := time.Now()
+			total := info.Size()
+			progress = func(n int64) {
+				if time.Since(last) < 500*time.Millisecond {
+					return
+				}
+				last = time.Now()
+				msg := fmt.Sprintf("checksum: %s %d%%", name, n*100/total)
+				ui.exprChan <- &callExpr{"echo", []string{msg}, 1}
+			}
+		}
+
+		sum, err := fileChecksum(path, algo, progress)
+		if err != nil {
+			failed++
+			ui.exprChan <- &callExpr{"echoerr", []string{fmt.Sprintf("checksum: %s", err)}, 1}
+			continue
+		}
+
+		msg := fmt.Sprintf("%s  %s", sum, name)
+
+		expected, ok, err := readSidecar(path, algo)
+		switch {
+		case err != nil:
+			failed++
+			ui.exprChan <- &callExpr{"echoerr", []string{fmt.Sprintf("%s (sidecar: %s)", msg, err)}, 1}
+		case !ok:
+			ui.exprChan <- &callExpr{"echomsg", []string{msg}, 1}
+		case expected == sum:
+			matched++
+			ui.exprChan <- &callExpr{"echomsg", []string{msg + " [match]"}, 1}
+		default:
+			mismatched++
+			ui.exprChan <- &callExpr{"echoerr", []string{msg + " [mismatch]"}, 1}
+		}
+	}
+
+	if len(paths) > 1 {
+		msg := fmt.Sprintf("checksum: %d files, %d matched, %d mismatched, %d failed", len(paths), matched, mismatched, failed)
+		ui.exprChan <- &callExpr{"echomsg", []string{msg}, 1}
+	}
+}
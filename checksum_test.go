@@ -0,0 +1,124 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileChecksum(t *testing.T) {
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	empty := filepath.Join(root, "empty")
+	abc := filepath.Join(root, "abc")
+	if err := ioutil.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+	if err := ioutil.WriteFile(abc, []byte("abc"), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	tests := []struct {
+		path string
+		algo string
+		exp  string
+	}{
+		{empty, "md5", "d41d8cd98f00b204e9800998ecf8427e"},
+		{empty, "sha256", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{abc, "md5", "900150983cd24fb0d6963f7d28e17f72"},
+		{abc, "sha1", "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{abc, "sha256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+
+	for _, test := range tests {
+		if got, err := fileChecksum(test.path, test.algo, nil); err != nil || got != test.exp {
+			t.Errorf("at input '%s' with '%s' expected '%s' but got '%s' (%v)", test.path, test.algo, test.exp, got, err)
+		}
+	}
+
+	var n int64
+	if _, err := fileChecksum(abc, "sha256", func(m int64) { n = m }); err != nil || n != 3 {
+		t.Errorf("progress expected '3' bytes but got '%d' (%v)", n, err)
+	}
+
+	if _, err := fileChecksum(abc, "crc32", nil); err == nil {
+		t.Errorf("unknown algorithm expected an error")
+	}
+
+	if _, err := fileChecksum(root, "sha256", nil); err == nil {
+		t.Errorf("directory expected an error")
+	}
+}
+
+func TestParseSidecar(t *testing.T) {
+	tests := []struct {
+		s    string
+		name string
+		exp  string
+		ok   bool
+	}{
+		{"", "foo", "", false},
+		{"ABC123\n", "foo", "abc123", true},
+		{"abc123  foo\n", "foo", "abc123", true},
+		{"abc123 *foo\n", "foo", "abc123", true},
+		{"abc123  dir/foo\n", "foo", "abc123", true},
+		{"abc123  foo bar\n", "foo bar", "abc123", true},
+		{"abc123  bar\ndef456  foo\n", "foo", "def456", true},
+		{"abc123  bar\n", "foo", "", false},
+	}
+
+	for _, test := range tests {
+		if got, ok := parseSidecar(test.s, test.name); got != test.exp || ok != test.ok {
+			t.Errorf("at input '%q' with name '%s' expected '%s' (%t) but got '%s' (%t)", test.s, test.name, test.exp, test.ok, got, ok)
+		}
+	}
+}
+
+func TestReadSidecar(t *testing.T) {
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	path := filepath.Join(root, "abc")
+	if err := ioutil.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	if _, ok, err := readSidecar(path, "sha256"); ok || err != nil {
+		t.Errorf("missing sidecar expected no hash but got (%t, %v)", ok, err)
+	}
+
+	sum, err := fileChecksum(path, "sha256", nil)
+	if err != nil {
+		t.Fatalf("hashing file: %s", err)
+	}
+
+	tests := []struct {
+		s     string
+		match bool
+	}{
+		{sum + "  abc\n", true},
+		{"0000000000000000000000000000000000000000000000000000000000000000  abc\n", false},
+	}
+
+	for _, test := range tests {
+		if err := ioutil.WriteFile(path+".sha256", []byte(test.s), 0644); err != nil {
+			t.Fatalf("writing sidecar: %s", err)
+		}
+		exp, ok, err := readSidecar(path, "sha256")
+		if !ok || err != nil {
+			t.Errorf("at input '%q' expected a hash but got (%t, %v)", test.s, ok, err)
+			continue
+		}
+		if (exp == sum) != test.match {
+			t.Errorf("at input '%q' expected match '%t' but got '%t'", test.s, test.match, exp == sum)
+		}
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -27,6 +29,7 @@ func run() {
 		panic(err)
 	}
 	defer os.Remove(gLogPath)
+	defer removeResultPath(gResultPath)
 	defer f.Close()
 	log.SetOutput(f)
 
@@ -38,12 +41,25 @@ func run() {
 		app.ui.echoerrf("reading marks file: %s", err)
 	}
 
+	bms, err := readBookmarks(gBookmarksPath)
+	if err != nil {
+		app.ui.echoerrf("%s", err)
+	}
+	app.nav.bookmarks = bms
+
 	if err := app.readHistory(); err != nil {
 		app.ui.echoerrf("reading history file: %s", err)
 	}
 
 	go app.nav.previewLoop(app.ui)
 	app.loop()
+	if focusReporting() {
+		setFocusReporting(false)
+	}
+	_, h := app.ui.screen.Size()
+	if err := setAltScreen(gOpts.altscreen, h); err != nil {
+		log.Printf("setting altscreen: %s", err)
+	}
 	app.ui.screen.Fini()
 }
 
@@ -143,7 +159,78 @@ func loadFiles() (list []string, cp bool, err error) {
 	return
 }
 
+// This function parses the list of paths read for 'select-from-stdin' remote
+// command. Paths are separated with null characters when there is any in the
+// input, otherwise they are separated with newlines. Empty paths are skipped.
+func parsePathList(s string) []string {
+	sep := "\n"
+	if strings.ContainsRune(s, 0) {
+		sep = "\x00"
+	}
+
+	var paths []string
+	for _, path := range strings.Split(s, sep) {
+		if sep == "\n" {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
+
+// This function quotes the given string as a double quoted argument of a
+// command so that it can be sent in a single line to the server.
+func quoteArg(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// This function returns the remote command to send 'select-paths' command with
+// the given paths to the client with the given id or to all clients when the
+// id is empty. Relative paths are resolved with respect to the given directory.
+func selectCommand(id, wd string, paths []string) string {
+	var b strings.Builder
+	b.WriteString("send ")
+	if id != "" {
+		b.WriteString(id + " ")
+	}
+	b.WriteString("select-paths")
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wd, path)
+		}
+		b.WriteString(" " + quoteArg(path))
+	}
+	return b.String()
+}
+
 func remote(cmd string) error {
+	if word, rest := splitWord(cmd); word == "select-from-stdin" {
+		buf, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading paths: %s", err)
+		}
+		paths := parsePathList(string(buf))
+		if len(paths) == 0 {
+			return fmt.Errorf("select-from-stdin: no paths are given")
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting current directory: %s", err)
+		}
+		id, _ := splitWord(rest)
+		cmd = selectCommand(id, wd, paths)
+	} else if word == "batch" && !strings.Contains(cmd, "\n") {
+		buf, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("reading commands: %s", err)
+		}
+		cmd += "\n" + string(buf)
+	}
+
 	c, err := net.Dial(gSocketProt, gSocketPath)
 	if err != nil {
 		return fmt.Errorf("dialing to send server: %s", err)
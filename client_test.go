@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePathList(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp []string
+	}{
+		{"", nil},
+		{"\n", nil},
+		{"foo", []string{"foo"}},
+		{"foo\nbar\n", []string{"foo", "bar"}},
+		{"foo\r\nbar\r\n", []string{"foo", "bar"}},
+		{"foo\n\nbar baz\n", []string{"foo", "bar baz"}},
+		{"foo\x00bar\x00", []string{"foo", "bar"}},
+		{"foo\nbar\x00baz\x00", []string{"foo\nbar", "baz"}},
+		{"\x00foo\x00\x00", []string{"foo"}},
+	}
+
+	for _, test := range tests {
+		if got := parsePathList(test.s); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' expected '%q' but got '%q'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestSelectCommand(t *testing.T) {
+	tests := []struct {
+		id    string
+		wd    string
+		paths []string
+		exp   string
+		args  []string
+	}{
+		{"", "/wd", []string{"/foo"}, `send select-paths "/foo"`, []string{"/foo"}},
+		{"1000", "/wd", []string{"/foo", "bar"}, `send 1000 select-paths "/foo" "/wd/bar"`, []string{"/foo", "/wd/bar"}},
+		{"1", "/wd", []string{`/a "b" \c`}, `send 1 select-paths "/a \"b\" \\c"`, []string{`/a "b" \c`}},
+		{"1", "/wd", []string{"/a\nb", "/c\rd"}, `send 1 select-paths "/a\nb" "/c\rd"`, []string{"/a\nb", "/c\rd"}},
+		{"1", "/wd", []string{"/a $b 'c' ;d"}, `send 1 select-paths "/a $b 'c' ;d"`, []string{"/a $b 'c' ;d"}},
+	}
+
+	for _, test := range tests {
+		got := selectCommand(test.id, test.wd, test.paths)
+		if got != test.exp {
+			t.Errorf("at input '%q' expected '%s' but got '%s'", test.paths, test.exp, got)
+			continue
+		}
+
+		if strings.ContainsAny(got, "\n\r") {
+			t.Errorf("at input '%q' expected a single line but got '%q'", test.paths, got)
+		}
+
+		// the command after 'send' and the id is evaluated by the client
+		cmd := strings.TrimPrefix(got, "send ")
+		if test.id != "" {
+			cmd = strings.TrimPrefix(cmd, test.id+" ")
+		}
+		p := newParser(strings.NewReader(cmd))
+		if !p.parse() {
+			t.Errorf("at input '%q' unable to parse '%s': %v", test.paths, cmd, p.err)
+			continue
+		}
+		e, ok := p.expr.(*callExpr)
+		if !ok || e.name != "select-paths" || !reflect.DeepEqual(e.args, test.args) {
+			t.Errorf("at input '%q' expected 'select-paths' with '%q' but got '%v'", test.paths, test.args, p.expr)
+		}
+	}
+}
+
+func TestQuoteArg(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+	}{
+		{"", `""`},
+		{"/foo/bar", `"/foo/bar"`},
+		{"/foo bar/baz", `"/foo bar/baz"`},
+		{"foo;bar#baz", `"foo;bar#baz"`},
+		{`"foo"`, `"\"foo\""`},
+		{"'foo'", `"'foo'"`},
+		{"$foo", `"$foo"`},
+		{"!foo bar", `"!foo bar"`},
+		{":foo", `":foo"`},
+		{"{{foo}}", `"{{foo}}"`},
+		{`foo\bar`, `"foo\\bar"`},
+		{"foo\nbar", `"foo\nbar"`},
+	}
+
+	for _, test := range tests {
+		got := quoteArg(test.s)
+		if got != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.s, test.exp, got)
+			continue
+		}
+
+		// quoted strings should be parsed back as a single argument
+		p := newParser(strings.NewReader("echo " + got))
+		if !p.parse() || p.err != nil {
+			t.Errorf("at input '%s' unexpected parse error: %v", test.s, p.err)
+			continue
+		}
+		if args := p.expr.(*callExpr).args; len(args) != 1 || args[0] != test.s {
+			t.Errorf("at input '%s' expected '%s' to be parsed as '%s' but got '%v'", test.s, got, test.s, args)
+		}
+	}
+}
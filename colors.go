@@ -1,11 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -104,6 +107,8 @@ func applyAnsiCodes(s string, st tcell.Style) tcell.Style {
 			st = st.Bold(true)
 		case n == 2:
 			st = st.Dim(true)
+		case n == 3:
+			st = st.Italic(true)
 		case n == 4:
 			st = st.Underline(true)
 		case n == 5 || n == 6:
@@ -116,8 +121,22 @@ func applyAnsiCodes(s string, st tcell.Style) tcell.Style {
 			st = st.Foreground(bg)
 		case n == 9:
 			st = st.StrikeThrough(true)
+		case n == 22:
+			st = st.Bold(false).Dim(false)
+		case n == 23:
+			st = st.Italic(false)
+		case n == 24:
+			st = st.Underline(false)
+		case n == 25:
+			st = st.Blink(false)
+		case n == 27:
+			st = st.Reverse(false)
+		case n == 29:
+			st = st.StrikeThrough(false)
 		case n >= 30 && n <= 37:
 			st = st.Foreground(tcell.PaletteColor(n - 30))
+		case n == 39:
+			st = st.Foreground(tcell.ColorDefault)
 		case n == 38:
 			if i+3 <= len(nums) && nums[i+1] == 5 {
 				st = st.Foreground(tcell.PaletteColor(nums[i+2]))
@@ -133,6 +152,8 @@ func applyAnsiCodes(s string, st tcell.Style) tcell.Style {
 			}
 		case n >= 40 && n <= 47:
 			st = st.Background(tcell.PaletteColor(n - 40))
+		case n == 49:
+			st = st.Background(tcell.ColorDefault)
 		case n == 48:
 			if i+3 <= len(nums) && nums[i+1] == 5 {
 				st = st.Background(tcell.PaletteColor(nums[i+2]))
@@ -146,6 +167,10 @@ func applyAnsiCodes(s string, st tcell.Style) tcell.Style {
 			} else {
 				log.Printf("unknown ansi code or incorrect form: %d", n)
 			}
+		case n >= 90 && n <= 97:
+			st = st.Foreground(tcell.PaletteColor(n - 82))
+		case n >= 100 && n <= 107:
+			st = st.Background(tcell.PaletteColor(n - 92))
 		default:
 			log.Printf("unknown ansi code: %d", n)
 		}
@@ -222,48 +247,55 @@ func (sm styleMap) parseBSD(env string) {
 	}
 }
 
-func (sm styleMap) get(f *file) tcell.Style {
-	if val, ok := sm[f.path]; ok {
-		return val
-	}
-
-	if f.IsDir() {
-		if val, ok := sm[f.Name()+"/"]; ok {
-			return val
-		}
-	}
-
-	var key string
-
+// This function returns the key of the type of the given file used in
+// 'LS_COLORS' and 'LF_ICONS' (e.g. 'di' for directories or 'ex' for executable
+// files), or an empty string for other regular files. Working symbolic links
+// are classified by their targets when links is false.
+func fileTypeKey(f *file, links bool) string {
 	switch {
-	case f.linkState == working:
-		key = "ln"
+	case f.linkState == working && links:
+		return "ln"
 	case f.linkState == broken:
-		key = "or"
+		return "or"
 	case f.IsDir() && f.Mode()&os.ModeSticky != 0 && f.Mode()&0002 != 0:
-		key = "tw"
+		return "tw"
 	case f.IsDir() && f.Mode()&0002 != 0:
-		key = "ow"
+		return "ow"
 	case f.IsDir() && f.Mode()&os.ModeSticky != 0:
-		key = "st"
+		return "st"
 	case f.IsDir():
-		key = "di"
+		return "di"
 	case f.Mode()&os.ModeNamedPipe != 0:
-		key = "pi"
+		return "pi"
 	case f.Mode()&os.ModeSocket != 0:
-		key = "so"
+		return "so"
 	case f.Mode()&os.ModeDevice != 0:
-		key = "bd"
+		return "bd"
 	case f.Mode()&os.ModeCharDevice != 0:
-		key = "cd"
+		return "cd"
 	case f.Mode()&os.ModeSetuid != 0:
-		key = "su"
+		return "su"
 	case f.Mode()&os.ModeSetgid != 0:
-		key = "sg"
+		return "sg"
 	case f.Mode()&0111 != 0:
-		key = "ex"
+		return "ex"
+	}
+	return ""
+}
+
+func (sm styleMap) get(f *file) tcell.Style {
+	if val, ok := sm[f.path]; ok {
+		return val
+	}
+
+	if f.IsDir() {
+		if val, ok := sm[f.Name()+"/"]; ok {
+			return val
+		}
 	}
 
+	key := fileTypeKey(f, true)
+
 	if val, ok := sm[key]; ok {
 		return val
 	}
@@ -286,3 +318,110 @@ func (sm styleMap) get(f *file) tcell.Style {
 
 	return tcell.StyleDefault
 }
+
+// This function parses the time window of 'heatmapwindow' option in the
+// syntax of age thresholds without a sign (e.g. '7d' or '12h'). Days are used
+// when there is no unit.
+func parseHeatWindow(s string) (time.Duration, error) {
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		return 0, fmt.Errorf("invalid window: %s", s)
+	}
+	t, err := parseAgeThreshold(s)
+	if err != nil || t.n == 0 {
+		return 0, fmt.Errorf("invalid window: %s", s)
+	}
+	return time.Duration(t.n*t.unit) * time.Second, nil
+}
+
+// This function parses a color given as a color name (e.g. 'orange'), a hex
+// value (e.g. '#ff8700'), or a number of the 256 color palette (e.g. '208').
+func parseHeatColor(s string) (tcell.Color, error) {
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < 256 {
+		return tcell.PaletteColor(n), nil
+	}
+	if c := tcell.GetColor(strings.ToLower(s)); c != tcell.ColorDefault && c.Hex() >= 0 {
+		return c, nil
+	}
+	return tcell.ColorDefault, fmt.Errorf("invalid color: %s", s)
+}
+
+// This function parses the colors of 'heatmapcolors' option given as the
+// color of recent files and the color of old files separated with a colon.
+func parseHeatColors(s string) (recent, old tcell.Color, err error) {
+	toks := strings.Split(s, ":")
+	if len(toks) != 2 {
+		return tcell.ColorDefault, tcell.ColorDefault, fmt.Errorf("should be two colors separated with ':': %s", s)
+	}
+	if recent, err = parseHeatColor(toks[0]); err != nil {
+		return tcell.ColorDefault, tcell.ColorDefault, err
+	}
+	if old, err = parseHeatColor(toks[1]); err != nil {
+		return tcell.ColorDefault, tcell.ColorDefault, err
+	}
+	return recent, old, nil
+}
+
+// This function returns the color for a file with the given age on the
+// gradient from the color of recent files to the color of old files. Files
+// modified in the future are treated as recent and files older than the window
+// get the color of old files.
+func heatColor(age, window time.Duration, recent, old tcell.Color) tcell.Color {
+	switch {
+	case age <= 0:
+		return recent
+	case age >= window:
+		return old
+	}
+
+	r1, g1, b1 := recent.RGB()
+	r2, g2, b2 := old.RGB()
+
+	t := float64(age) / float64(window)
+	mix := func(c1, c2 int32) int32 {
+		return int32(math.Round(float64(c1) + float64(c2-c1)*t))
+	}
+
+	return tcell.NewRGBColor(mix(r1, r2), mix(g1, g2), mix(b1, b2))
+}
+
+// This type is the state of 'heatmap' option used while drawing a directory
+// so that the options are parsed only once for each directory.
+type heatmap struct {
+	combine bool
+	window  time.Duration
+	recent  tcell.Color
+	old     tcell.Color
+	now     time.Time
+}
+
+// This function returns the heatmap for the current options at the given time
+// or nil when 'heatmap' option is disabled.
+func newHeatmap(now time.Time) *heatmap {
+	if gOpts.heatmap == "" {
+		return nil
+	}
+
+	window, err := parseHeatWindow(gOpts.heatmapwindow)
+	if err != nil {
+		return nil
+	}
+	recent, old, err := parseHeatColors(gOpts.heatmapcolors)
+	if err != nil {
+		return nil
+	}
+
+	return &heatmap{gOpts.heatmap == "combine", window, recent, old, now}
+}
+
+// This function returns the given style of a file with the foreground color
+// replaced by the color for the given modification time. Styles with a
+// foreground color are kept as they are when combining with normal colors.
+func (hm *heatmap) style(st tcell.Style, modTime time.Time) tcell.Style {
+	if hm == nil {
+		return st
+	}
+	if fg, _, _ := st.Decompose(); hm.combine && fg != tcell.ColorDefault {
+		return st
+	}
+	return st.Foreground(heatColor(hm.now.Sub(modTime), hm.window, hm.recent, hm.old))
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 )
@@ -68,6 +69,16 @@ func TestApplyAnsiCodes(t *testing.T) {
 		{"38;2;5;102;8", none, none.Foreground(tcell.NewRGBColor(5, 102, 8))},
 		{"48;2;0;48;143", none, none.Background(tcell.NewRGBColor(0, 48, 143))},
 
+		{"3", none, none.Italic(true)},
+		{"1;2;22", none, none},
+		{"4;24", none.Bold(true), none.Bold(true)},
+		{"7;27", none, none},
+		{"31;39", none, none.Foreground(tcell.ColorDefault)},
+		{"41;49", none, none.Background(tcell.ColorDefault)},
+		{"91", none, none.Foreground(tcell.ColorRed)},
+		{"97", none, none.Foreground(tcell.ColorWhite)},
+		{"101", none, none.Background(tcell.ColorRed)},
+
 		// Fixes color construction issue: https://github.com/gokcehan/lf/pull/439#issuecomment-674409446
 		{"38;5;34;1", none, none.Foreground(tcell.Color34).Bold(true)},
 	}
@@ -79,3 +90,84 @@ func TestApplyAnsiCodes(t *testing.T) {
 		}
 	}
 }
+
+func TestHeatColor(t *testing.T) {
+	recent := tcell.NewRGBColor(200, 100, 0)
+	old := tcell.NewRGBColor(0, 0, 100)
+	window := 10 * time.Hour
+
+	tests := []struct {
+		age time.Duration
+		exp tcell.Color
+	}{
+		{0, recent},
+		{-time.Hour, recent},
+		{window, old},
+		{2 * window, old},
+		{window / 2, tcell.NewRGBColor(100, 50, 50)},
+		{window / 4, tcell.NewRGBColor(150, 75, 25)},
+		{window - time.Nanosecond, old},
+	}
+
+	for _, test := range tests {
+		if got := heatColor(test.age, window, recent, old); got != test.exp {
+			t.Errorf("at input '%v' expected '%#x' but got '%#x'", test.age, test.exp.Hex(), got.Hex())
+		}
+	}
+}
+
+func TestParseHeatColors(t *testing.T) {
+	tests := []struct {
+		s      string
+		recent tcell.Color
+		old    tcell.Color
+		err    bool
+	}{
+		{"#ffd75f:#585858", tcell.NewRGBColor(255, 215, 95), tcell.NewRGBColor(88, 88, 88), false},
+		{"yellow:208", tcell.ColorYellow, tcell.PaletteColor(208), false},
+		{"#ffd75f", tcell.ColorDefault, tcell.ColorDefault, true},
+		{"#ffd75f:nocolor", tcell.ColorDefault, tcell.ColorDefault, true},
+		{"default:#585858", tcell.ColorDefault, tcell.ColorDefault, true},
+		{"1:2:3", tcell.ColorDefault, tcell.ColorDefault, true},
+	}
+
+	for _, test := range tests {
+		recent, old, err := parseHeatColors(test.s)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' expected error '%t' but got '%v'", test.s, test.err, err)
+			continue
+		}
+		if recent != test.recent || old != test.old {
+			t.Errorf("at input '%s' expected '%v' and '%v' but got '%v' and '%v'", test.s, test.recent, test.old, recent, old)
+		}
+	}
+}
+
+func TestHeatmapStyle(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := tcell.NewRGBColor(255, 255, 255)
+	old := tcell.NewRGBColor(0, 0, 0)
+	colored := tcell.StyleDefault.Foreground(tcell.ColorRed)
+
+	override := &heatmap{false, time.Hour, recent, old, now}
+	combine := &heatmap{true, time.Hour, recent, old, now}
+
+	tests := []struct {
+		hm  *heatmap
+		st  tcell.Style
+		mod time.Time
+		exp tcell.Style
+	}{
+		{nil, colored, now, colored},
+		{override, colored, now, colored.Foreground(recent)},
+		{override, tcell.StyleDefault, now.Add(-2 * time.Hour), tcell.StyleDefault.Foreground(old)},
+		{combine, colored, now, colored},
+		{combine, tcell.StyleDefault.Bold(true), now, tcell.StyleDefault.Bold(true).Foreground(recent)},
+	}
+
+	for _, test := range tests {
+		if got := test.hm.style(test.st, test.mod); got != test.exp {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.st, test.exp, got)
+		}
+	}
+}
@@ -20,22 +20,62 @@ var (
 		"down",
 		"half-down",
 		"page-down",
+		"preview-scroll-up",
+		"preview-scroll-down",
+		"preview-page-up",
+		"preview-page-down",
 		"updir",
 		"open",
+		"left",
+		"right",
 		"quit",
 		"top",
 		"bottom",
+		"goto-line",
+		"next-ext",
+		"prev-ext",
 		"toggle",
 		"invert",
 		"unselect",
+		"reselect",
+		"mounts",
+		"unmount",
+		"workspace",
+		"workspace-close",
+		"workspace-list",
+		"split",
+		"split-close",
+		"split-focus",
+		"split-copy",
+		"split-move",
+		"toggle-exec",
+		"yank-as",
+		"yank-relative",
+		"checksum",
+		"clean-selection",
+		"list-selection",
 		"copy",
 		"cut",
 		"paste",
+		"follow",
 		"clear",
 		"redraw",
 		"reload",
+		"clear-cache",
+		"sort-next",
+		"move-up",
+		"move-down",
+		"move-to-top",
+		"move-to-bottom",
 		"read",
+		"select-paths",
+		"link-selection-file",
+		"create",
 		"rename",
+		"rename-case",
+		"rename-ext",
+		"rename-seq",
+		"pipe-rename",
 		"shell",
 		"shell-pipe",
 		"shell-wait",
@@ -48,43 +88,113 @@ var (
 		"search-back",
 		"search-next",
 		"search-prev",
+		"search-clear",
 		"mark-save",
 		"mark-remove",
 		"mark-load",
+		"bookmark-set",
+		"bookmark-jump",
+		"bookmark-list",
+		"z",
+		"z-list",
 		"draw",
 		"load",
 		"sync",
 		"echo",
 		"echomsg",
 		"echoerr",
+		"print",
+		"batch",
+		"on-type",
 		"cd",
+		"cd-home",
+		"cd-root",
+		"cd-gitroot",
+		"fast-forward",
 		"select",
+		"reveal",
 		"glob-select",
+		"glob-select-recursive",
+		"select-regex-recursive",
 		"glob-unselect",
+		"select-size",
+		"select-age",
+		"select-duplicates",
 		"source",
+		"shell-pick",
+		"command-palette",
+		"trash-list",
+		"new-from-template",
+		"trash-empty",
+		"prompt",
+		"cmd-timeout",
 		"push",
 		"delete",
+		"diff",
+		"edit",
+		"xattr",
+		"stat",
+		"open-fm",
+		"open-terminal",
+		"archive-extract",
+		"which-key",
+		"wait",
+		"repeat",
+		"cmd-help",
 	}
 
 	gOptWords = []string{
+		"altscreen",
+		"noaltscreen",
+		"altscreen!",
 		"anchorfind",
 		"noanchorfind",
 		"anchorfind!",
+		"archivebrowse",
+		"noarchivebrowse",
+		"archivebrowse!",
+		"breadcrumbs",
+		"nobreadcrumbs",
+		"breadcrumbs!",
+		"compoundext",
+		"nocompoundext",
+		"compoundext!",
 		"dircounts",
 		"nodircounts",
 		"dircounts!",
 		"dirfirst",
 		"nodirfirst",
 		"dirfirst!",
+		"diropener",
+		"nodiropener",
+		"diropener!",
+		"dirsearch",
+		"nodirsearch",
+		"dirsearch!",
+		"diskfree",
+		"nodiskfree",
+		"diskfree!",
 		"drawbox",
 		"nodrawbox",
 		"drawbox!",
+		"errorsummary",
+		"noerrorsummary",
+		"errorsummary!",
+		"fastforward",
+		"nofastforward",
+		"fastforward!",
 		"globsearch",
 		"noglobsearch",
 		"globsearch!",
+		"grid",
+		"nogrid",
+		"grid!",
 		"hidden",
 		"nohidden",
 		"hidden!",
+		"iconalign",
+		"noiconalign",
+		"iconalign!",
 		"icons",
 		"noicons",
 		"icons!",
@@ -97,46 +207,119 @@ var (
 		"incsearch",
 		"noincsearch",
 		"incsearch!",
+		"infocursor",
+		"noinfocursor",
+		"infocursor!",
+		"minimal",
+		"nominimal",
+		"minimal!",
 		"number",
 		"nonumber",
 		"number!",
 		"preview",
 		"nopreview",
 		"preview!",
+		"previewsearch",
+		"nopreviewsearch",
+		"previewsearch!",
+		"previewwrap",
+		"nopreviewwrap",
+		"previewwrap!",
+		"quitiflast",
+		"noquitiflast",
+		"quitiflast!",
+		"readonly",
+		"noreadonly",
+		"readonly!",
 		"relativenumber",
 		"norelativenumber",
 		"relativenumber!",
+		"relativetime",
+		"norelativetime",
+		"relativetime!",
+		"rewritelinks",
+		"norewritelinks",
+		"rewritelinks!",
 		"reverse",
 		"noreverse",
 		"reverse!",
+		"scrollbar",
+		"noscrollbar",
+		"scrollbar!",
+		"selectioninfo",
+		"noselectioninfo",
+		"selectioninfo!",
 		"smartcase",
 		"nosmartcase",
 		"smartcase!",
 		"smartdia",
 		"nosmartdia",
 		"smartdia!",
+		"sortindicator",
+		"nosortindicator",
+		"sortindicator!",
+		"templatesubst",
+		"notemplatesubst",
+		"templatesubst!",
+		"whichkey",
+		"nowhichkey",
+		"whichkey!",
 		"wrapscan",
 		"nowrapscan",
 		"wrapscan!",
 		"wrapscroll",
 		"nowrapscroll",
 		"wrapscroll!",
+		"cmdtimeout",
 		"findlen",
+		"notifytime",
+		"parentwidth",
 		"period",
+		"previewbytes",
+		"previewlines",
+		"previewwidth",
+		"idleperiod",
+		"incsearchdelay",
 		"scrolloff",
 		"tabstop",
+		"waittimeout",
+		"whichkeydelay",
+		"badges",
 		"errorfmt",
+		"filemanager",
 		"filesep",
+		"grouporder",
+		"heatmap",
+		"heatmapcolors",
+		"heatmapwindow",
 		"hiddenfiles",
 		"ifs",
 		"info",
+		"notify",
+		"periodcheck",
+		"permfmt",
+		"previewansi",
 		"previewer",
 		"cleaner",
+		"cursorline",
+		"cursorinactive",
+		"cursorselected",
+		"searchmatch",
+		"boxchars",
+		"boxstyle",
+		"pasteconflict",
+		"selectedline",
+		"difftool",
+		"dirlinkicon",
+		"iconset",
 		"promptfmt",
 		"ratios",
 		"shell",
 		"shellopts",
 		"sortby",
+		"sortcmd",
+		"templatedir",
+		"terminal",
 		"timefmt",
 		"truncatechar",
 	}
@@ -284,6 +467,25 @@ func matchFile(s string) (matches []string, longest string) {
 	return
 }
 
+// This function returns the sorted list of builtin and custom command names
+// without duplicates.
+func cmdWords() []string {
+	words := append([]string{}, gCmdWords...)
+	for c := range gOpts.cmds {
+		words = append(words, c)
+	}
+	sort.Strings(words)
+	j := 0
+	for i := 1; i < len(words); i++ {
+		if words[j] == words[i] {
+			continue
+		}
+		j++
+		words[i], words[j] = words[j], words[i]
+	}
+	return words[:j+1]
+}
+
 func completeCmd(acc []rune) (matches []string, longestAcc []rune) {
 	s := string(acc)
 	f := tokenize(s)
@@ -292,27 +494,16 @@ func completeCmd(acc []rune) (matches []string, longestAcc []rune) {
 
 	switch len(f) {
 	case 1:
-		words := gCmdWords
-		for c := range gOpts.cmds {
-			words = append(words, c)
-		}
-		sort.Strings(words)
-		j := 0
-		for i := 1; i < len(words); i++ {
-			if words[j] == words[i] {
-				continue
-			}
-			j++
-			words[i], words[j] = words[j], words[i]
-		}
-		words = words[:j+1]
-		matches, longest = matchWord(s, words)
+		matches, longest = matchWord(s, cmdWords())
 		longestAcc = []rune(longest)
 	case 2:
 		switch f[0] {
 		case "set":
 			matches, longest = matchWord(f[1], gOptWords)
 			longestAcc = append(acc[:len(acc)-len(f[len(f)-1])], []rune(longest)...)
+		case "cmd-help":
+			matches, longest = matchWord(f[1], cmdWords())
+			longestAcc = append(acc[:len(acc)-len(f[len(f)-1])], []rune(longest)...)
 		case "map", "cmd":
 			longestAcc = acc
 		default:
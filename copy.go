@@ -5,8 +5,46 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// This type is the policy to handle files with conflicting names in the
+// destination while copying and moving files.
+type conflictPolicy int
+
+const (
+	conflictRename conflictPolicy = iota
+	conflictOverwrite
+	conflictSkip
+)
+
+// These are the names of conflict policies for 'pasteconflict' option and the
+// flags of 'paste' command.
+var gConflictPolicies = map[string]conflictPolicy{
+	"rename":    conflictRename,
+	"overwrite": conflictOverwrite,
+	"skip":      conflictSkip,
+}
+
+// This function returns the conflict policy chosen with 'pasteconflict'
+// option. Conflicting files are renamed for unknown policies.
+func currConflictPolicy() conflictPolicy {
+	return gConflictPolicies[gOpts.pasteconflict]
+}
+
+// This function returns the given path with the first numbered suffix (e.g.
+// 'foo.~1~') that does not exist which is used as the new name of a file
+// conflicting with the given path.
+func conflictName(path string) string {
+	var newPath string
+	_, err := os.Lstat(path)
+	for i := 1; !os.IsNotExist(err); i++ {
+		newPath = fmt.Sprintf("%s.~%d~", path, i)
+		_, err = os.Lstat(newPath)
+	}
+	return newPath
+}
+
 func copySize(srcs []string) (int64, error) {
 	var total int64
 
@@ -78,7 +116,12 @@ func copyFile(src, dst string, info os.FileInfo, nums chan int64) error {
 	return nil
 }
 
-func copyAll(srcs []string, dstDir string) (nums chan int64, errs chan error) {
+// This function copies the given files to the given directory in the
+// background. Conflicting files are either renamed, overwritten, or skipped
+// according to the given policy. Existing directories are merged with copied
+// directories when conflicting files are overwritten or skipped, in which case
+// the policy applies to each file inside them recursively.
+func copyAll(srcs []string, dstDir string, policy conflictPolicy) (nums chan int64, errs chan error) {
 	nums = make(chan int64, 1024)
 	errs = make(chan error, 1024)
 
@@ -86,14 +129,15 @@ func copyAll(srcs []string, dstDir string) (nums chan int64, errs chan error) {
 		for _, src := range srcs {
 			dst := filepath.Join(dstDir, filepath.Base(src))
 
-			_, err := os.Lstat(dst)
-			if !os.IsNotExist(err) {
-				var newPath string
-				for i := 1; !os.IsNotExist(err); i++ {
-					newPath = fmt.Sprintf("%s.~%d~", dst, i)
-					_, err = os.Lstat(newPath)
+			if dstStat, err := os.Lstat(dst); err == nil {
+				if policy == conflictRename {
+					dst = conflictName(dst)
+				} else if srcStat, err := os.Lstat(src); err == nil && os.SameFile(srcStat, dstStat) {
+					if policy == conflictOverwrite {
+						errs <- fmt.Errorf("copy: source and destination are the same file: %s", src)
+					}
+					continue
 				}
-				dst = newPath
 			}
 
 			filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
@@ -107,6 +151,24 @@ func copyAll(srcs []string, dstDir string) (nums chan int64, errs chan error) {
 					return nil
 				}
 				newPath := filepath.Join(dst, rel)
+				if stat, err := os.Lstat(newPath); err == nil && policy != conflictRename && !(info.IsDir() && stat.IsDir()) {
+					if policy == conflictSkip {
+						if info.IsDir() {
+							size, _ := copySize([]string{path})
+							nums <- size
+							return filepath.SkipDir
+						}
+						nums <- info.Size()
+						return nil
+					}
+					if err := os.RemoveAll(newPath); err != nil {
+						errs <- fmt.Errorf("remove: %s", err)
+						if info.IsDir() {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+				}
 				if info.IsDir() {
 					if err := os.MkdirAll(newPath, info.Mode()); err != nil {
 						errs <- fmt.Errorf("mkdir: %s", err)
@@ -126,3 +188,161 @@ func copyAll(srcs []string, dstDir string) (nums chan int64, errs chan error) {
 
 	return nums, errs
 }
+
+// This function returns the target of a relative symbolic link moved from the
+// old path to the new path as part of moving the given source, so that it
+// still points to the same location. Links pointing inside the moved source
+// are kept as they are since they move together with their targets, and
+// absolute links are also kept. It returns false when the target is kept.
+func relinkTarget(target, oldPath, newPath, src string) (string, bool) {
+	if filepath.IsAbs(target) {
+		return target, false
+	}
+
+	abs := filepath.Join(filepath.Dir(oldPath), target)
+
+	rel, err := filepath.Rel(src, abs)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return target, false
+	}
+
+	newTarget, err := filepath.Rel(filepath.Dir(newPath), abs)
+	if err != nil || newTarget == target {
+		return target, false
+	}
+
+	return newTarget, true
+}
+
+// This function moves the given source to the given destination and merges
+// directories with existing directories recursively. Conflicting files inside
+// them are either overwritten or skipped according to the given policy.
+// Symbolic links are rewritten relative to the given root which is the source
+// of the whole move. Sources of merged directories are removed when they are
+// left empty.
+func moveMerge(src, dst, root string, policy conflictPolicy) []error {
+	srcStat, err := os.Lstat(src)
+	if err != nil {
+		return []error{err}
+	}
+
+	dstStat, err := os.Lstat(dst)
+	if os.IsNotExist(err) {
+		if err := moveEntry(src, dst, root); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+	if err != nil {
+		return []error{err}
+	}
+
+	if srcStat.IsDir() && dstStat.IsDir() {
+		f, err := os.Open(src)
+		if err != nil {
+			return []error{err}
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			return []error{err}
+		}
+
+		var errs []error
+		for _, name := range names {
+			errs = append(errs, moveMerge(filepath.Join(src, name), filepath.Join(dst, name), root, policy)...)
+		}
+		if len(errs) == 0 {
+			if err := os.Remove(src); err != nil && policy != conflictSkip {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+
+	if policy == conflictSkip {
+		return nil
+	}
+
+	if err := os.RemoveAll(dst); err != nil {
+		return []error{err}
+	}
+	if err := moveEntry(src, dst, root); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+// This function renames the given source to the given destination which does
+// not exist. Files are copied and then removed when they are moved across
+// filesystems.
+func moveEntry(src, dst, root string) error {
+	if err := os.Rename(src, dst); err != nil {
+		if !errCrossDevice(err) {
+			return err
+		}
+
+		nums, errChan := copyAll([]string{src}, filepath.Dir(dst), conflictRename)
+
+		var first error
+	loop:
+		for {
+			select {
+			case <-nums:
+			case err, ok := <-errChan:
+				if !ok {
+					break loop
+				}
+				if first == nil {
+					first = err
+				}
+			}
+		}
+
+		if first != nil {
+			return first
+		}
+		return os.RemoveAll(src)
+	}
+
+	if gOpts.rewritelinks {
+		return relinkAll(src, dst, root)
+	}
+
+	return nil
+}
+
+// This function rewrites the targets of relative symbolic links in the given
+// destination moved from the given source for 'rewritelinks' option. Links
+// pointing inside the given root are kept, which is the source itself unless
+// the source is moved as part of a directory merged into an existing one.
+func relinkAll(src, dst, root string) error {
+	return filepath.Walk(dst, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk: %s", err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return fmt.Errorf("relative: %s", err)
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+
+		newTarget, ok := relinkTarget(target, filepath.Join(src, rel), path, root)
+		if !ok {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		return os.Symlink(newTarget, path)
+	})
+}
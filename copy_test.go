@@ -0,0 +1,338 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestRelinkTarget(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("paths are given in unix form")
+	}
+
+	tests := []struct {
+		target  string
+		oldPath string
+		newPath string
+		src     string
+		exp     string
+		ok      bool
+	}{
+		// absolute links are kept
+		{"/etc/passwd", "/a/src/link", "/b/src/link", "/a/src", "/etc/passwd", false},
+		// links inside the moved directory are kept
+		{"foo", "/a/src/link", "/b/src/link", "/a/src", "foo", false},
+		{"../foo", "/a/src/sub/link", "/b/src/sub/link", "/a/src", "../foo", false},
+		{"..", "/a/src/sub/link", "/b/src/sub/link", "/a/src", "..", false},
+		// links outside the moved directory are rewritten
+		{"../foo", "/a/src/link", "/b/src/link", "/a/src", "../../a/foo", true},
+		{"../../x/foo", "/a/src/sub/link", "/a/b/src/sub/link", "/a/src", "../../../x/foo", true},
+		{"../srcfoo", "/a/src/link", "/b/src/link", "/a/src", "../../a/srcfoo", true},
+		// moved links are rewritten when their targets are not moved
+		{"foo", "/a/link", "/a/b/link", "/a/link", "../foo", true},
+		{"b/foo", "/a/link", "/a/b/link", "/a/link", "foo", true},
+		// links are kept when the relative target is the same
+		{"../foo", "/a/src/link", "/a/dst/link", "/a/src", "../foo", false},
+	}
+
+	for _, test := range tests {
+		got, ok := relinkTarget(test.target, test.oldPath, test.newPath, test.src)
+		if got != test.exp || ok != test.ok {
+			t.Errorf("at input '%s' from '%s' to '%s' expected '%s' (%t) but got '%s' (%t)", test.target, test.oldPath, test.newPath, test.exp, test.ok, got, ok)
+		}
+	}
+}
+
+func TestRelinkAll(t *testing.T) {
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	src := filepath.Join(root, "a", "src")
+	dst := filepath.Join(root, "b", "c", "src")
+	for _, dir := range []string{filepath.Join(src, "sub"), filepath.Dir(dst)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+	}
+	for _, path := range []string{filepath.Join(root, "a", "outside"), filepath.Join(src, "inside")} {
+		if err := ioutil.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	links := []struct {
+		path   string
+		target string
+		exp    string
+	}{
+		{filepath.Join("sub", "in"), filepath.Join("..", "inside"), filepath.Join("..", "inside")},
+		{filepath.Join("sub", "out"), filepath.Join("..", "..", "outside"), filepath.Join("..", "..", "..", "..", "a", "outside")},
+		{"abs", filepath.Join(root, "a", "outside"), filepath.Join(root, "a", "outside")},
+	}
+	for _, link := range links {
+		if err := os.Symlink(link.target, filepath.Join(src, link.path)); err != nil {
+			t.Skipf("creating symbolic link: %s", err)
+		}
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		t.Fatalf("moving directory: %s", err)
+	}
+	if err := relinkAll(src, dst, src); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, link := range links {
+		path := filepath.Join(dst, link.path)
+		target, err := os.Readlink(path)
+		if err != nil || target != link.exp {
+			t.Errorf("at link '%s' expected target '%s' but got '%s' (error: %v)", link.path, link.exp, target, err)
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("at link '%s' expected a working link but got error: %s", link.path, err)
+		}
+	}
+}
+
+// This function creates the given files with their contents under the given
+// directory along with their parent directories.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+}
+
+// This function returns the files under the given directory with their
+// contents.
+func readTree(t *testing.T, root string) map[string]string {
+	files := make(map[string]string)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(buf)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading temporary directory: %s", err)
+	}
+	return files
+}
+
+func TestCopyAllConflict(t *testing.T) {
+	src := map[string]string{
+		"d/a":     "new",
+		"d/c":     "new",
+		"d/sub/b": "new",
+		"f":       "new",
+		"g/h":     "new",
+	}
+	dst := map[string]string{
+		"d/a":     "old",
+		"d/sub/b": "old",
+		"d/x":     "old",
+		"f":       "old",
+		"g":       "old",
+	}
+
+	tests := []struct {
+		name   string
+		policy conflictPolicy
+		exp    map[string]string
+	}{
+		{"rename", conflictRename, map[string]string{
+			"d/a":         "old",
+			"d/sub/b":     "old",
+			"d/x":         "old",
+			"f":           "old",
+			"g":           "old",
+			"d.~1~/a":     "new",
+			"d.~1~/c":     "new",
+			"d.~1~/sub/b": "new",
+			"f.~1~":       "new",
+			"g.~1~/h":     "new",
+		}},
+		{"overwrite", conflictOverwrite, map[string]string{
+			"d/a":     "new",
+			"d/c":     "new",
+			"d/sub/b": "new",
+			"d/x":     "old",
+			"f":       "new",
+			"g/h":     "new",
+		}},
+		{"skip", conflictSkip, map[string]string{
+			"d/a":     "old",
+			"d/c":     "new",
+			"d/sub/b": "old",
+			"d/x":     "old",
+			"f":       "old",
+			"g":       "old",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			srcDir := filepath.Join(root, "src")
+			dstDir := filepath.Join(root, "dst")
+			writeTree(t, srcDir, src)
+			writeTree(t, dstDir, dst)
+
+			var srcs []string
+			for _, name := range []string{"d", "f", "g"} {
+				srcs = append(srcs, filepath.Join(srcDir, name))
+			}
+
+			nums, errs := copyAll(srcs, dstDir, test.policy)
+		loop:
+			for {
+				select {
+				case <-nums:
+				case err, ok := <-errs:
+					if !ok {
+						break loop
+					}
+					t.Errorf("unexpected error: %s", err)
+				}
+			}
+
+			if got := readTree(t, dstDir); !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("expected '%v' but got '%v'", test.exp, got)
+			}
+			if got := readTree(t, srcDir); !reflect.DeepEqual(got, src) {
+				t.Errorf("expected sources to be kept but got '%v'", got)
+			}
+		})
+	}
+}
+
+func TestCopyAllSameFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy conflictPolicy
+		errs   int
+	}{
+		{"overwrite", conflictOverwrite, 1},
+		{"skip", conflictSkip, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTree(t, root, map[string]string{"f": "old"})
+
+			var count int
+			nums, errs := copyAll([]string{filepath.Join(root, "f")}, root, test.policy)
+		loop:
+			for {
+				select {
+				case <-nums:
+				case _, ok := <-errs:
+					if !ok {
+						break loop
+					}
+					count++
+				}
+			}
+
+			if got := readTree(t, root); !reflect.DeepEqual(got, map[string]string{"f": "old"}) {
+				t.Errorf("expected the file to be kept but got '%v'", got)
+			}
+			if count != test.errs {
+				t.Errorf("expected '%d' errors but got '%d'", test.errs, count)
+			}
+		})
+	}
+}
+
+func TestMoveMerge(t *testing.T) {
+	src := map[string]string{
+		"d/a":     "new",
+		"d/c":     "new",
+		"d/sub/b": "new",
+		"f":       "new",
+		"g/h":     "new",
+	}
+	dst := map[string]string{
+		"d/a":     "old",
+		"d/sub/b": "old",
+		"d/x":     "old",
+		"f":       "old",
+		"g":       "old",
+	}
+
+	tests := []struct {
+		name   string
+		policy conflictPolicy
+		exp    map[string]string
+		left   map[string]string
+	}{
+		{"overwrite", conflictOverwrite, map[string]string{
+			"d/a":     "new",
+			"d/c":     "new",
+			"d/sub/b": "new",
+			"d/x":     "old",
+			"f":       "new",
+			"g/h":     "new",
+		}, map[string]string{}},
+		{"skip", conflictSkip, map[string]string{
+			"d/a":     "old",
+			"d/c":     "new",
+			"d/sub/b": "old",
+			"d/x":     "old",
+			"f":       "old",
+			"g":       "old",
+		}, map[string]string{
+			"d/a":     "new",
+			"d/sub/b": "new",
+			"f":       "new",
+			"g/h":     "new",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			srcDir := filepath.Join(root, "src")
+			dstDir := filepath.Join(root, "dst")
+			writeTree(t, srcDir, src)
+			writeTree(t, dstDir, dst)
+
+			for _, name := range []string{"d", "f", "g"} {
+				path := filepath.Join(srcDir, name)
+				for _, err := range moveMerge(path, filepath.Join(dstDir, name), path, test.policy) {
+					t.Errorf("unexpected error: %s", err)
+				}
+			}
+
+			if got := readTree(t, dstDir); !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("expected '%v' but got '%v'", test.exp, got)
+			}
+			if got := readTree(t, srcDir); !reflect.DeepEqual(got, test.left) {
+				t.Errorf("expected sources '%v' to be left but got '%v'", test.left, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+// +build !linux,!darwin,!freebsd,!dragonfly,!windows
+
+package main
+
+import "errors"
+
+var errDiskFreeNotSupported = errors.New("not supported on this platform")
+
+// Free disk space is not supported on this platform so it is never shown.
+func diskFree(path string) (uint64, error) {
+	return 0, errDiskFreeNotSupported
+}
+
+func mountKey(path string) (string, error) {
+	return "", errDiskFreeNotSupported
+}
@@ -0,0 +1,29 @@
+// +build linux darwin freebsd dragonfly
+
+package main
+
+import (
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// This function returns the number of bytes available to unprivileged users on
+// the filesystem containing the given path.
+func diskFree(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// This function returns a key identifying the filesystem containing the given
+// path, which is the device number of the path.
+func mountKey(path string) (string, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(st.Dev), 10), nil
+}
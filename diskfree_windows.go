@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetDiskFreeSpaceEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// This function returns the number of bytes available to the user on the
+// volume containing the given path.
+func diskFree(path string) (uint64, error) {
+	ptr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var free uint64
+	r, _, err := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(ptr)), uintptr(unsafe.Pointer(&free)), 0, 0)
+	if r == 0 {
+		return 0, err
+	}
+
+	return free, nil
+}
+
+// This function returns a key identifying the volume containing the given
+// path, which is the volume name of the path (e.g. 'C:').
+func mountKey(path string) (string, error) {
+	return filepath.VolumeName(path), nil
+}
@@ -23,33 +23,104 @@ The following commands are provided by lf:
     down                     (default 'j' and '<down>')
     half-down                (default '<c-d>')
     page-down                (default '<c-f>' and '<pgdn>')
-    updir                    (default 'h' and '<left>')
-    open                     (default 'l' and '<right>')
+    preview-scroll-up        (default '<a-k>')
+    preview-scroll-down      (default '<a-j>')
+    preview-page-up          (default '<a-u>')
+    preview-page-down        (default '<a-d>')
+    updir                    (default 'h')
+    open                     (default 'l')
+    left                     (default '<left>')
+    right                    (default '<right>')
     top                      (default 'gg' and '<home>')
     bottom                   (default 'G' and '<end>')
+    goto-line
+    next-ext
+    prev-ext
     toggle
     invert                   (default 'v')
     unselect                 (default 'u')
+    reselect
+    toggle-exec
+    yank-as
+    yank-relative
+    checksum
+    clean-selection
+    list-selection (modal)
     glob-select
+    glob-select-recursive
+    select-regex-recursive
     glob-unselect
+    select-size
+    select-age
+    select-duplicates
     copy                     (default 'y')
     cut                      (default 'd')
     paste                    (default 'p')
+    follow
     clear                    (default 'c')
     sync
     draw
     redraw                   (default '<c-l>')
     load
     reload                   (default '<c-r>')
+    clear-cache
+    sort-next
+    move-up
+    move-down
+    move-to-top
+    move-to-bottom
     echo
     echomsg
     echoerr
+    print
+    batch
+    on-type
     cd
+    cd-home
+    cd-root
+    cd-gitroot
+    fast-forward
     select
+    reveal
+    select-paths
+    link-selection-file
+    create
+    new-from-template (modal)
     delete         (modal)
     rename         (modal)   (default 'r')
+    rename-case    (modal)
+    rename-ext     (modal)
+    rename-seq     (modal)
+    pipe-rename    (modal)
     source
+    shell-pick     (modal)
+    command-palette (modal)
+    prompt         (modal)
+    cmd-timeout
     push
+    diff
+    edit
+    xattr
+    stat
+    open-fm
+    open-terminal
+    archive-extract
+    mounts         (modal)
+    unmount        (modal)
+    trash-list     (modal)
+    trash-empty    (modal)
+    workspace
+    workspace-close
+    workspace-list (modal)
+    split
+    split-close
+    split-focus
+    split-copy
+    split-move
+    which-key
+    repeat                   (default '.')
+    wait
+    cmd-help
     read           (modal)   (default ':')
     shell          (modal)   (default '$')
     shell-pipe     (modal)   (default '%')
@@ -63,9 +134,15 @@ The following commands are provided by lf:
     search-back    (modal)   (default '?')
     search-next              (default 'n')
     search-prev              (default 'N')
+    search-clear
     mark-save      (modal)   (default 'm')
     mark-load      (modal)   (default "'")
     mark-remove    (modal)   (default `"`)
+    bookmark-set   (modal)
+    bookmark-jump
+    bookmark-list  (modal)
+    z
+    z-list
 
 The following command line commands are provided by lf:
 
@@ -95,43 +172,102 @@ The following command line commands are provided by lf:
     cmd-capitalize-word      (default '<a-c>')
     cmd-uppercase-word       (default '<a-u>')
     cmd-lowercase-word       (default '<a-l>')
+    cmd-insert-selection
 
 The following options can be used to customize the behavior of lf:
 
+    altscreen      bool      (default on)
     anchorfind     bool      (default on)
+    archivebrowse  bool      (default off)
+    badges         []string  (default '')
+    boxchars       string    (default 'single')
+    boxstyle       string    (default '')
+    breadcrumbs    bool      (default off)
+    cmdtimeout     int       (default 0)
+    compoundext    bool      (default off)
     dircounts      bool      (default off)
     dirfirst       bool      (default on)
+    diropener      bool      (default off)
+    dirsearch      bool      (default off)
+    diskfree       bool      (default off)
     drawbox        bool      (default off)
     errorfmt       string    (default "\033[7;31;47m%s\033[0m")
+    errorsummary   bool      (default off)
+    fastforward    bool      (default off)
+    filemanager    string    (default '')
     filesep        string    (default "\n")
     findlen        int       (default 1)
     globsearch     bool      (default off)
+    grid           bool      (default off)
+    grouporder     []string  (default '')
+    heatmap        string    (default '')
+    heatmapcolors  string    (default '#ffd75f:#585858')
+    heatmapwindow  string    (default '7d')
     hidden         bool      (default off)
     hiddenfiles    []string  (default '.*')
+    iconalign      bool      (default off)
+    iconset        string    (default 'basic')
     icons          bool      (default off)
+    idleperiod     int       (default 0)
     ifs            string    (default '')
     ignorecase     bool      (default on)
     ignoredia      bool      (default on)
     incsearch      bool      (default off)
+    incsearchdelay int       (default 0)
     info           []string  (default '')
+    infocursor     bool      (default off)
+    minimal        bool      (default off)
+    notify         string    (default '')
+    notifytime     int       (default 10)
     number         bool      (default off)
+    parentwidth    int       (default 0)
+    pasteconflict  string    (default 'rename')
     period         int       (default 0)
+    periodcheck    string    (default 'mtime')
+    permfmt        string    (default 'symbolic')
     preview        bool      (default on)
+    previewansi    string    (default 'render')
     previewer      string    (default '')
     cleaner        string    (default '')
+    cursorline     string    (default '')
+    cursorinactive string    (default '')
+    cursorselected string    (default '')
+    difftool       string    (default '')
+    dirlinkicon    string    (default 'link')
+    previewbytes   int       (default 0)
+    previewlines   int       (default 0)
+    previewsearch  bool      (default off)
+    previewwrap    bool      (default off)
+    previewwidth   int       (default 0)
     promptfmt      string    (default "\033[32;1m%u@%h\033[0m:\033[34;1m%d\033[0m\033[1m%f\033[0m")
+    quitiflast     bool      (default off)
     ratios         []int     (default '1:2:3')
+    readonly       bool      (default off)
     relativenumber bool      (default off)
+    relativetime   bool      (default off)
     reverse        bool      (default off)
+    rewritelinks   bool      (default off)
+    scrollbar      bool      (default off)
     scrolloff      int       (default 0)
+    searchmatch    string    (default '1;4')
+    selectedline   string    (default '')
+    selectioninfo  bool      (default off)
     shell          string    (default 'sh' for unix and 'cmd' for windows)
     shellopts      []string  (default '')
     smartcase      bool      (default on)
     smartdia       bool      (default off)
     sortby         string    (default 'natural')
+    sortcmd        string    (default '')
+    sortindicator  bool      (default off)
     tabstop        int       (default 8)
+    templatedir    string    (default '')
+    templatesubst  bool      (default off)
+    terminal       string    (default '')
     timefmt        string    (default 'Mon Jan _2 15:04:05 2006')
     truncatechar   string    (default '~')
+    waittimeout    int       (default 0)
+    whichkey       bool      (default on)
+    whichkeydelay  int       (default 0)
     wrapscan       bool      (default on)
     wrapscroll     bool      (default off)
 
@@ -185,11 +321,26 @@ Marks file should be located at:
     unix     ~/.local/share/lf/marks
     windows  C:\Users\<user>\AppData\Local\lf\marks
 
+Bookmarks file should be located at:
+
+    unix     ~/.local/share/lf/bookmarks
+    windows  C:\Users\<user>\AppData\Local\lf\bookmarks
+
+Frecency file should be located at:
+
+    unix     ~/.local/share/lf/frecency
+    windows  C:\Users\<user>\AppData\Local\lf\frecency
+
 History file should be located at:
 
     unix     ~/.local/share/lf/history
     windows  C:\Users\<user>\AppData\Local\lf\history
 
+Templates directory should be located at:
+
+    unix     ~/.config/lf/templates
+    windows  C:\Users\<user>\AppData\Local\lf\templates
+
 You can configure the default values of following variables to change these
 locations:
 
@@ -219,23 +370,57 @@ Quit lf and return to the shell.
 
 Move the current file selection upwards/downwards by one/half a page/full page.
 
-    updir                    (default 'h' and '<left>')
+    preview-scroll-up        (default '<a-k>')
+    preview-scroll-down      (default '<a-j>')
+    preview-page-up          (default '<a-u>')
+    preview-page-down        (default '<a-d>')
+
+Scroll the preview of the current file upwards/downwards by one line/full page without moving the current file selection.
+Scrolling stops at the end of the preview, which is at most 10000 lines below the top and at the limits of 'previewbytes' and 'previewlines' options.
+The preview is loaded again for each scroll so that lines after the first page are read, and previews of 'previewer' can only be scrolled as far as its output.
+The scroll offset is reset when the current file changes.
+When 'previewsearch' option is enabled, the preview is scrolled from the line of the match.
+
+    updir                    (default 'h')
 
 Change the current working directory to the parent directory.
 
-    open                     (default 'l' and '<right>')
+    open                     (default 'l')
 
 If the current file is a directory, then change the current directory to it, otherwise, execute the 'open' command.
+When 'diropener' option is enabled, directories are also passed to the 'open' command.
 A default 'open' command is provided to call the default system opener asynchronously with the current file as the argument.
 A custom 'open' command can be defined to override this default.
 
 (See also 'OPENER' variable and 'Opening Files' section)
 
+    left                     (default '<left>')
+    right                    (default '<right>')
+
+Move the current file selection to the left/right column in the same row when the current directory is shown in grid layout.
+Otherwise, these commands work the same as 'updir' and 'open' commands respectively.
+
+(See also 'grid' option)
+
     top                      (default 'gg' and '<home>')
     bottom                   (default 'G' and '<end>')
 
 Move the current file selection to the top/bottom of the directory.
 
+    goto-line
+
+Move the current file selection to the line given as the argument.
+The argument is either a line number (e.g. `goto-line 123`) or a percentage of the directory (e.g. `goto-line 50%`).
+Values out of bounds are clamped to the first/last file in the directory.
+You can bind it to a key with a mapping such as `map <c-g> push :goto-line<space>`.
+
+    next-ext
+    prev-ext
+
+Move the current file selection to the next/previous file with the same extension as the current file.
+Extensions are compared ignoring case and files without extensions (e.g. 'Makefile' or '.bashrc') match other files without extensions.
+Search continues from the other end of the directory when 'wrapscroll' option is enabled.
+
     toggle
 
 Toggle the selection of the current file or files given as arguments.
@@ -250,13 +435,99 @@ You can define a new command to select all files in the directory by combining '
 
 Remove the selection of all files in all directories.
 
+    reselect
+
+Restore the last non-empty selection cleared by 'unselect' command or after a file operation such as 'copy', 'cut', or 'delete'.
+Only a single previous selection is kept.
+Current selection, if any, is kept as the previous selection instead so that running the command twice goes back to it.
+
+    toggle-exec
+
+Flip the execute bit of the owner of the current file or selected files, or flip execute bits of the owner, group, and others when 'all' is given as the argument.
+Execute bits are set when the owner can not execute the file and cleared otherwise.
+Directories and other files that are not regular files are skipped and the number of changed and skipped files are shown.
+Execute bits are not supported on windows.
+
+    yank-as
+
+Copy the current file or selected files to the clipboard so that they can be pasted as files in other applications such as file dialogs and other file managers.
+The format of the clipboard payload is given in the argument, which is either 'uri-list' for 'text/uri-list' (default) or 'gnome' for 'x-special/gnome-copied-files' used by gnome and some other file managers.
+Files are given as 'file://' URIs in both formats.
+Either 'wl-copy' on wayland or 'xclip' on X11 is required to set the clipboard.
+
+    yank-relative
+
+Copy the path of the current file or the paths of selected files relative to the base directory given in the argument to the clipboard as text, with a path on each line (e.g. 'yank-relative ~/docs').
+Relative base directories are relative to the current directory and parent directory elements are used when the base directory is not an ancestor of the file (e.g. '../src/main.go').
+The path is shown in the message line after it is copied, or along with the error when the clipboard cannot be set.
+Either 'wl-copy' on wayland or 'xclip' on X11 is required to set the clipboard.
+
+    checksum
+
+Compute the checksum of the current file or selected files and show it in the format of 'sha256sum' and similar tools.
+The algorithm is given in the argument, which is either 'md5', 'sha1', or 'sha256' (default).
+Files are hashed asynchronously and the progress is shown for large files.
+When a sidecar file named after the file and the algorithm exists (e.g. 'foo.iso.sha256'), the recorded hash is compared and the result is reported as a match or a mismatch.
+When multiple files are given, the number of matched, mismatched, and failed files is shown at the end.
+
+    clean-selection
+
+Remove files that no longer exist from the selection and show the number of removed entries.
+Such files are also removed automatically when directories are loaded or reloaded.
+
+    list-selection (modal)
+
+List selected files in all directories in a menu ordered by the time of selection.
+Selections are kept when the current directory is changed, so files from multiple directories can be accumulated before a 'copy', 'cut', or 'delete' operation.
+Entering the number of a file in the menu changes the current directory to its parent and selects it as the current file.
+
     glob-select
 
 Select files that match the given glob.
+Hidden files are also matched when the glob is preceded by '-hidden' flag even if 'hidden' option is disabled, in which case they are selected while remaining hidden (e.g. 'glob-select -hidden .*').
+
+    glob-select-recursive
+
+Select files under the current directory and its subdirectories that match the given glob and show the number of newly selected files.
+The glob is matched against the names of files.
+Hidden files and directories are skipped unless 'hidden' option is enabled or the glob is preceded by '-hidden' flag.
+At most 10000 files are selected at once to avoid accidentally selecting a whole filesystem.
+Files are searched in the background and invoking the command again while the search is running cancels it.
+
+    select-regex-recursive
+
+Select files under the current directory and its subdirectories that match the given regular expression and show the number of newly selected files.
+The expression is matched against the names of files, or against the paths of files relative to the current directory with '/' as the separator when it is preceded by '-path' flag (e.g. 'select-regex-recursive -path ^src/.*_test\.go$').
+Expressions match anywhere in names or paths unless they are anchored with '^' and '$'.
+Hidden files and directories are skipped, the number of files selected at once is limited, and the search can be cancelled as in 'glob-select-recursive' command, and '-hidden' flag can also be given before or after '-path' flag.
 
     glob-unselect
 
 Unselect files that match the given glob.
+Hidden files are also matched when the glob is preceded by '-hidden' flag.
+
+    select-size
+
+Select files in the current directory with sizes above or below the given threshold in the syntax of 'find' (e.g. 'select-size +100M').
+A threshold preceded by '+' matches files larger than the threshold, '-' matches files smaller than the threshold, and a threshold without a sign matches files with the same size when truncated to the unit (e.g. '2M' matches sizes from '2.0M' to '2.9M').
+Units are 'B', 'K', 'M', 'G', and 'T' in powers of 1000 as in sizes shown in the ui, and bytes are used when there is no unit.
+Directories are not selected since their sizes are not the sizes of their contents.
+Files are added to the existing selections and the number of newly selected files is shown.
+
+    select-age
+
+Select files in the current directory modified before or after the given threshold in the syntax of 'find' (e.g. 'select-age +30d').
+A threshold preceded by '+' matches files older than the threshold, '-' matches files newer than the threshold, and a threshold without a sign matches files with the same age when truncated to the unit.
+Units are 's', 'm', 'h', 'd', and 'w' for seconds, minutes, hours, days, and weeks, and days are used when there is no unit.
+Files are added to the existing selections and the number of newly selected files is shown.
+
+    select-duplicates
+
+Select files with identical contents in the current directory, or in the current directory and its subdirectories when '-r' is given as the argument (e.g. 'select-duplicates -r').
+Files are compared by size first and only the files sharing a size with another file are hashed, so that unique files are not read.
+Symbolic links and empty files are ignored.
+The scan runs in the background and invoking the command again while the scan is running cancels it.
+When the scan is finished, the selection is replaced with the duplicate files and the groups of duplicates are listed in a menu.
 
     copy                     (default 'y')
 
@@ -269,6 +540,15 @@ If there are no selections, save the path of the current file to the cut buffer,
     paste                    (default 'p')
 
 Copy/Move files in copy/cut buffer to the current working directory.
+Files with conflicting names are handled according to 'pasteconflict' option by default.
+A different policy can be given for this paste with '-rename', '-overwrite', or '-skip' flag (e.g. 'paste -overwrite').
+
+    follow
+
+Change the current directory to the destination of the last 'paste' command and move the cursor to the pasted file.
+When multiple files are pasted, all of them are selected.
+Pasted files that no longer exist are ignored.
+Renamed destinations of files with conflicting names (e.g. 'foo.~1~') are followed as well.
 
     clear                    (default 'c')
 
@@ -297,6 +577,28 @@ This command is automatically called when required.
 
 Flush the cache and reload all files and directories.
 
+    clear-cache
+
+Flush the caches given as arguments or all caches when no argument is given, so that the cached values are computed again.
+Caches are 'dirs' for directories, which are loaded again, 'previews' for previews of files, 'diskfree' for the free space of filesystems used by 'diskfree' option, 'sortcmd' for the orders of files from 'sortcmd' option, 'archives' for the entries of archives browsed as directories, and 'childcount' for the number of entries in directories used by 'childcount' information.
+This is mainly useful for debugging or after big changes made by external programs.
+
+    clear-cache previews childcount
+
+    sort-next
+
+Change the value of 'sortby' option to the next sort type in the order of 'natural', 'name', 'size', 'time', 'atime', 'ctime', 'ext', and 'manual', and show the new sort type.
+The sort type is changed back to 'natural' after 'manual'.
+
+    move-up
+    move-down
+    move-to-top
+    move-to-bottom
+
+Move the current file up or down by one, or to the top or the bottom of the directory in the manual order used when 'sortby' option is set to 'manual'.
+The order is remembered for each directory during the session, and files without an order such as new files are shown at the end in natural order.
+Note that 'dirfirst', 'grouporder', and 'hidden' options are still applied on top of the manual order.
+
     echo
 
 Print given arguments to the message line at the bottom.
@@ -309,14 +611,116 @@ Print given arguments to the message line at the bottom and also to the log file
 
 Print given arguments to the message line at the bottom in red color and also to the log file.
 
+    print
+
+Print given arguments to the message line at the bottom after expanding placeholders.
+Placeholders '$f', '$fs', and '$fx' are replaced with the current file, selected files, and either selected files or the current file when there is no selection respectively, similar to the environment variables in shell commands.
+Files are separated with 'filesep' option.
+Placeholders in the form '${opt:name}' are replaced with the value of the given option (e.g. '${opt:ratios}' or '${opt:sortby}').
+A literal '$' can be written as '$$' and other words starting with '$' are kept as they are.
+Arguments with placeholders should be quoted since '$' is also used as the prefix of shell commands:
+
+    map P print '$fx (sorted by ${opt:sortby})'
+
+The expanded string can be printed to the standard output from a shell with 'print' remote command.
+
+    batch
+
+Evaluate the commands given as arguments in order and draw the screen only once after all of them are evaluated.
+Errors of failing commands do not stop the rest of the commands and the number of failed commands is shown at the end along with the last error.
+When the first argument is '-e', the remaining commands are skipped after the first failing command instead.
+This command is mainly used by 'batch' remote command to apply multiple commands without intermediate states:
+
+    batch 'set hidden' 'cd /tmp' 'select foo.txt'
+
+    on-type
+
+Run the command of the first branch matching the current file, where branches are given as arguments in the syntax 'pattern=command'.
+Patterns are either one of the type names 'dir' for directories, 'file' for regular files, 'link' for symbolic links, 'exec' for executable files, and 'archive' for files with common archive extensions (e.g. '.zip' or '.tar.gz'), keys of file types used for colors and icons (e.g. 'ln' for symbolic links, 'or' for broken links, or 'ex' for executable files, see 'Colors'), or glob patterns matched with the name of the file (e.g. '*.pdf').
+Symbolic links to directories and files also match 'dir' and 'file' respectively.
+A pattern of '*' can be used at the end to match any file, otherwise an error is shown when no branch matches.
+Commands are evaluated as in the command line, so they need to be quoted when they contain spaces:
+
+    map <enter> on-type dir=open archive=extract '*.pdf=$zathura "$f"' *=open
+
     cd
 
 Change the working directory to the given argument.
+On linux, a leading XDG user directory token (e.g. 'cd $XDG_DOWNLOAD_DIR' or 'cd $XDG_MUSIC_DIR/albums') is replaced with the directory configured in 'user-dirs.dirs' file in the configuration directory (e.g. '~/.config/user-dirs.dirs').
+Defaults of 'xdg-user-dirs' (e.g. '~/Downloads') are used for the directories that are not configured.
+Tokens are also replaced in the paths of bookmarks.
+
+    cd-home
+
+Change the working directory to the home directory.
+
+    cd-root
+
+Change the working directory to the root directory, which is the root of the current drive on windows.
+
+    cd-gitroot
+
+Change the working directory to the root of the git repository of the current directory, which is the nearest directory containing a '.git' entry found by ascending from the current directory.
+An error is shown when the current directory is not in a git repository.
+
+    fast-forward
+
+Change the working directory by descending through the chain of directories that contain only a single subdirectory and nothing else (e.g. 'proj-1.2.3/proj-1.2.3/src' in extracted archives) until a directory with other contents is reached.
+Hidden files also count as contents.
+Symbolic links to directories are followed, but each directory is visited only once so that cycles are not followed.
+See 'fastforward' option to do this automatically when directories are opened.
 
     select
 
 Change the current file selection to the given argument.
 
+    reveal
+
+Move the cursor to the file with the given name in the current directory and scroll the listing if needed without changing the current directory.
+The file is matched by its base name, so a path can also be given as long as its parent is the current directory.
+An error is shown if there is no such file in the listing, including hidden files when 'hidden' option is disabled.
+This command is useful for scripting and integrations (e.g. 'lf -remote "send reveal foo.txt"').
+
+    select-paths
+
+Replace the selection with the files given in the arguments that exist and skip the missing ones.
+The working directory is changed to the common ancestor of the parent directories of the files and the cursor is moved to the first file or its ancestor in that directory.
+This command is used by 'select-from-stdin' remote command.
+
+    link-selection-file
+
+Read the paths written to the result file given in 'LF_RESULT' environment variable by a shell command and use them for navigation or selection.
+Paths are read one per line and relative paths are resolved with respect to the current directory.
+If there is a single directory, the current directory is changed to it as in 'cd' command, otherwise the paths are selected as in 'select-paths' command.
+The result file is removed after it is read and also before each shell command so that a result is only used once.
+Commands should be run synchronously with '$' or '!' prefixes to write the result before this command, for example:
+
+    cmd fzf-pick ${{
+        fzf -m > "$LF_RESULT"
+    }}
+    map <c-p> :fzf-pick; link-selection-file
+
+    cmd zoxide-pick ${{
+        zoxide query -i > "$LF_RESULT"
+    }}
+    map zi :zoxide-pick; link-selection-file
+
+    create
+
+Create a file or a directory with the name given in the argument (e.g. 'create foo.txt' or 'create foo/bar/').
+A trailing slash in the name means a directory, otherwise an empty file is created.
+Intermediate directories are created as needed and the created entry is selected afterwards.
+Nothing is created if the name already exists.
+
+    new-from-template (modal)
+
+List files in the templates directory in a menu and create a file in the current directory from the chosen template by entering its number.
+The name of the new file is then read in the command line with the name of the template as the default, and the created file is selected and opened afterwards.
+A template can also be given in the argument to skip the menu (e.g. 'new-from-template script.sh').
+Intermediate directories are created as needed, the permissions of the template are kept, and nothing is created if the name already exists.
+Placeholders in the contents are replaced when 'templatesubst' option is enabled.
+The templates directory can be changed with 'templatedir' option.
+
     delete         (modal)
 
 Remove the current file or selected file(s).
@@ -324,16 +728,228 @@ Remove the current file or selected file(s).
     rename         (modal)   (default 'r')
 
 Rename the current file using the builtin method.
+The new name is asked in the prompt, or it can be given as an argument to rename without the prompt (e.g. 'rename foo.txt').
 A custom 'rename' command can be defined to override this default.
 
+    rename-case    (modal)
+
+Change the case of the current file or selected files using the method given in the argument.
+Supported methods are 'lower', 'upper', 'title', 'snake' (e.g. 'foo_bar'), and 'kebab' (e.g. 'foo-bar').
+Only the name without the extension is changed and the extension is kept as is.
+Whitespaces, dashes, and underscores in the name are considered as word separators for 'snake' and 'kebab' methods, as well as camel case boundaries.
+A confirmation is asked before renaming and nothing is renamed if a new name collides with an existing file or with the new name of another file.
+
+    rename-ext     (modal)
+
+Change the extension of the current file or selected files to the extension given in the argument with or without a leading dot (e.g. 'rename-ext jpg' to rename 'photo.jpeg' to 'photo.jpg').
+An extension is added to files without one, and extensions are removed when the argument is empty or not given (e.g. "rename-ext ''").
+Only the last extension is changed (e.g. 'foo.tar.gz' to 'foo.tar.zst'), unless 'compoundext' option is enabled in which case the whole compound extension is changed (e.g. 'foo.tar.gz' to 'foo.tgz').
+Names starting with a dot and without any other dots (e.g. '.bashrc') are considered to have no extension.
+A confirmation is asked before renaming and nothing is renamed if a new name collides with an existing file or with the new name of another file.
+
+    rename-seq     (modal)
+
+Rename the current file or selected files with sequential numbers using the template given in the arguments (e.g. 'rename-seq photo_{n:3}' for 'photo_001.jpg', 'photo_002.jpg', and so on).
+The template should contain a single '{n}' placeholder for the number starting from one.
+Numbers are padded with zeros to the width given after a colon in the placeholder (e.g. '{n:3}'), or to the number of digits of the number of files when the width is not given.
+Files are numbered in the order they are shown in their directories with the current sort options.
+Extensions of files are kept as they are.
+A confirmation is asked before renaming and nothing is renamed if a new name collides with an existing file that is not renamed or with the new name of another file.
+Files can be renamed to the old names of other renamed files since they are moved to temporary names first in this case.
+
+    pipe-rename    (modal)
+
+Rename the current file or selected files by filtering their names through the shell command given in the arguments (e.g. 'pipe-rename tr a-z A-Z').
+Names are written to the standard input of the command one per line and new names are read from its standard output in the same order.
+Nothing is renamed if the command fails or the number of new names does not match the number of files.
+A confirmation is asked before renaming and nothing is renamed if a new name collides with an existing file or with the new name of another file.
+
     source
 
 Read the configuration file given in the argument.
 
+    shell-pick     (modal)
+
+Run the shell command given in the second argument and show the lines in its output as a numbered menu.
+The number of an item can then be entered to call the command given in the first argument with the item as its argument.
+Empty lines in the output are ignored and an error is shown when there is nothing to pick.
+The ui waits for the shell command to finish, so it is meant to be used with quick commands.
+For example, you can pick a git branch to checkout as follows:
+
+    cmd checkout %git checkout "$1"
+    cmd pick-branch shell-pick checkout 'git branch --format="%(refname:short)"'
+
+    command-palette (modal)
+
+Show a numbered menu of builtin and user defined commands with their descriptions, which is filtered as you type.
+Typed characters are matched in order but not necessarily adjacent (e.g. 'slsz' matches 'select-size'), and matches at the start of words are shown first.
+Case and diacritics are ignored with respect to 'ignorecase', 'smartcase', 'ignoredia', and 'smartdia' options.
+Commands matching only by their descriptions are shown after commands matching by their names.
+Pressing 'cmd-enter' runs the first command in the menu, or the command with the given number when a number is typed.
+Commands requiring arguments (e.g. 'cd') are instead typed in the command line so that arguments can be added before running them.
+There is no default key for this command, so you need to map it to a key to use it:
+
+    map <a-p> command-palette
+
+    prompt         (modal)
+
+Read a line in the command line with the name of an environment variable given in the first argument as the prompt and the default value given in the second argument as the initial input.
+The input is exported as the environment variable and then the command given in the third argument is evaluated when the input is accepted with 'cmd-enter'.
+The command is not evaluated when the prompt is cancelled with 'cmd-escape' or the input is empty.
+This can be used in custom commands to ask for input before running a shell command (e.g. creating a directory with a name):
+
+    cmd mkdir prompt name "new directory" '$mkdir -- "$name"'
+
+    cmd-timeout
+
+Evaluate the command given in the second argument with 'cmdtimeout' option set to the number of seconds given in the first argument.
+This can be used to override the timeout for a single command (e.g. a command that is known to take long):
+
+    map U cmd-timeout 600 '$sudo apt upgrade'
+
     push
 
 Simulate key pushes given in the argument.
 
+    diff
+
+Compare two selected files or directories and show the output in the pager.
+The tool given in 'difftool' option is used if it is set, otherwise 'diff -u' is used for files and 'diff -ru' is used for directories.
+An error is shown if the number of selected files is not two.
+
+    edit
+
+Open the selected files together in the editor given in 'EDITOR' environment variable as separate arguments (e.g. 'vim file1 file2').
+If there are no selections, the current file is opened instead.
+
+    xattr
+
+Show the names of extended attributes of the current file (e.g. 'com.apple.quarantine' on macos or 'user.xdg.origin.url' on linux).
+Extended attributes are only supported on linux and macos and no attributes are shown on other platforms.
+
+    stat
+
+Show detailed metadata of the current file in a table above the command line, which are the full path, type, size, allocated blocks, inode number, number of hard links, owner and group, mode in symbolic and octal forms, and all available timestamps.
+Symbolic links are not followed and the target of the link is shown instead.
+Blocks, inodes, links, owners, and groups are not shown on windows.
+The table is closed with the next key press.
+
+    open-fm
+
+Open the current directory in the graphical file manager of the system.
+The launcher is 'open' on macos, 'explorer' on windows, and 'xdg-open' on other platforms, which can be changed with 'filemanager' option.
+
+    open-terminal
+
+Spawn a terminal emulator in the current directory.
+The terminal is given by 'terminal' option or 'TERMINAL' environment variable when set.
+Otherwise, 'Terminal' application is used on macos, a new 'cmd' window is used on windows, and the first one found among 'x-terminal-emulator', 'gnome-terminal', 'konsole', 'xfce4-terminal', 'alacritty', 'kitty', and 'xterm' is used on other platforms.
+
+    archive-extract
+
+Extract the current file or selected files while browsing an archive with 'archivebrowse' option.
+Files are extracted to the directory of the archive or to the directory given in the argument with their names, and existing files are not overwritten.
+Only regular files can be extracted.
+Files are extracted in the background with the progress shown as in copying, and errors are shown for each file without stopping the extraction of the remaining files.
+
+    mounts         (modal)
+
+List mounted filesystems of block devices in a menu and change the current directory to the mount point of the chosen entry by entering its number.
+Unmounted removable devices are also listed when 'lsblk' is available, and such devices are mounted with 'udisksctl' when they are chosen.
+This command is only supported on linux.
+
+    unmount        (modal)
+
+List mounted filesystems in a menu and unmount the chosen entry with 'udisksctl' by entering its number.
+The current directory is changed to the parent of the mount point before unmounting if it is under the mount point.
+This command is only supported on linux.
+
+    trash-list     (modal)
+
+List items in the trash with their deletion dates and original paths in a menu, with the most recently deleted items first, and restore the chosen items to their original paths by entering their numbers separated with spaces (e.g. '1 3').
+Trash directories are the ones in the freedesktop.org trash specification, which are '$XDG_DATA_HOME/Trash' and the '.Trash/$uid' and '.Trash-$uid' directories in the mount points of filesystems listed by 'mounts' command.
+Missing parent directories are created when restoring, and items are not restored when there is already a file with the same path.
+Note that moving files to the trash is left to external tools (e.g. 'gio trash' or 'trash-put') which can be used in a custom command.
+
+    trash-empty    (modal)
+
+Remove items in the trash permanently after a confirmation.
+When an age threshold is given in the syntax of 'select-age' command, only the items deleted before the threshold are removed (e.g. 'trash-empty +30d' removes items deleted more than 30 days ago).
+
+    workspace
+
+Switch to the workspace with the name given in the argument.
+Each workspace keeps its own current directory, current file, and selection, which are restored when switching back to the workspace.
+A new workspace is created in the current directory with an empty selection if there is no workspace with the given name.
+The initial workspace is named 'main'.
+Workspaces are not kept after quitting.
+
+    workspace-close
+
+Close the current workspace and switch to the workspace after it in sorted order, or the first one when the current one is the last in order.
+Closing the only workspace quits lf when 'quitiflast' option is enabled, and it is an error otherwise.
+
+    workspace-list (modal)
+
+List workspaces in a menu and switch to the chosen entry by entering its number.
+
+    split
+
+Open a second pane showing the directory given in the argument, or the current directory when there is no argument, for working with two directories side by side.
+The screen is divided into two panes of the same width showing the current directory at the left side and the other directory at the right side, in place of the parent directories and the preview.
+The other pane keeps its own current directory, current file, and selection as in workspaces, and its cursor is drawn with 'cursorinactive' option when it is set.
+Only one other pane can be opened and it is not kept after quitting.
+
+    split-close
+
+Close the other pane and show the parent directories and the preview pane again.
+
+    split-focus
+
+Move the focus to the other pane so that the directory of the other pane becomes the current directory, and the previous current directory is shown in the other pane.
+Panes stay on their sides of the screen and only the focus moves between them.
+
+    split-copy
+    split-move
+
+Copy or move the current file or selected files to the directory of the other pane in the background as in 'paste' command.
+Selection is cleared afterwards.
+These are errors when both panes are in the same directory or when the other pane is in an archive.
+
+    map <tab> split-focus
+    map C split-copy
+    map M split-move
+
+    which-key
+
+Show the menu of keys that can be pressed after the key prefix given in the argument, only if that prefix is currently pending.
+This command is used internally to show the menu of 'whichkey' option after 'whichkeydelay' milliseconds.
+
+    repeat                   (default '.')
+
+Run the last command modifying files again.
+Currently 'create', 'delete', 'paste', 'rename', 'rename-case', and 'rename-ext' commands are repeated, including custom commands overriding them.
+Commands are run again with their original arguments and they operate on the current file or selections at the time of repeating, similar to the dot command in vim.
+Confirmations and prompts of modal commands are shown again, except the builtin 'rename' command which is repeated with the name entered in its prompt.
+
+    wait
+
+Run the rest of the command list after the last asynchronous shell command started with '&' prefix is finished (see also 'waittimeout' option).
+Other commands and keys are still handled in the meantime.
+The rest of the list is run immediately if there is no such command, and this command has no effect outside of lists.
+This can be used to run commands depending on the results of asynchronous commands (e.g. changing the directory to the output of a build):
+
+    cmd build :{{
+        &make
+        wait
+        cd build
+    }}
+
+    cmd-help
+
+Show the definition of the custom command given in the argument, or a short description if it is a builtin command.
+Custom commands are shown instead of builtin commands with the same name since they override them.
+
     read           (modal)   (default ':')
 
 Read a command to evaluate.
@@ -378,6 +994,11 @@ Read a pattern to search for a file name match in the forward/backward direction
 
 (See also 'globsearch', 'incsearch', 'wrapscan', 'ignorecase', 'smartcase', 'ignoredia', and 'smartdia' options and 'Searching Files' section)
 
+    search-clear
+
+Clear the last search pattern so that 'search-next' and 'search-prev' commands do nothing until a new pattern is given.
+When 'dirsearch' option is enabled, the pattern saved for the current directory is also removed.
+
     mark-save      (modal)   (default 'm')
 
 Save the current directory as a bookmark assigned to the given key.
@@ -391,6 +1012,36 @@ A special bookmark "'" holds the previous directory after a 'mark-load', 'cd', o
 
 Remove a bookmark assigned to the given key.
 
+    bookmark-set   (modal)
+    bookmark-jump
+    bookmark-list  (modal)
+
+Numbered bookmarks from 1 to 9 are kept separately from the bookmarks of 'mark-save' command and saved in the bookmarks file.
+'bookmark-set' saves the current directory as the bookmark with the number given in the argument, or the number typed after showing the bookmarks when there is no argument.
+'bookmark-jump' changes the current directory to the bookmark with the number given in the argument.
+'bookmark-list' shows the bookmarks and changes the current directory to the bookmark with the number typed.
+As in 'mark-load' command, the previous directory is kept in the special bookmark "'".
+Since numbers are used as counts for commands, they can be bound with a modifier to jump to bookmarks with a single key:
+
+    map B bookmark-set
+    map b bookmark-list
+    map <a-1> bookmark-jump 1
+    map <a-2> bookmark-jump 2
+    map <a-3> bookmark-jump 3
+
+    z
+    z-list
+
+Visits of directories are counted to rank the directories by frecency, a combination of how often and how recently they are visited, and saved in the frecency file when quitting.
+'z' changes the current directory to the directory with the highest rank matching the keywords given in the arguments, skipping the current directory and directories which no longer exist.
+Keywords should appear in the path of the directory in the given order and the last keyword should appear in the name of the directory.
+Case is ignored according to 'ignorecase' and 'smartcase' options.
+As in 'mark-load' command, the previous directory is kept in the special bookmark "'".
+'z-list' shows the directories with the highest ranks matching the keywords given in the arguments, or all directories when there are no arguments.
+
+    z dot conf     # e.g. ~/projects/dotfiles/config
+    z-list proj
+
 Command Line Commands
 
 This section shows information about command line commands.
@@ -476,15 +1127,81 @@ Delete the next word in forward direction.
 
 Capitalize/uppercase/lowercase the current word and jump to the next word.
 
+    cmd-insert-selection
+
+Insert the path of the current file or the paths of selected files at the cursor separated with spaces.
+Paths are quoted as arguments of commands in the command line (i.e. ':') and as arguments of the shell in shell commands (i.e. '$', '%', '!', and '&').
+Note that environment variables in paths (e.g. '%PATH%') are still expanded in shell commands on windows since percent signs can not be escaped in double quotes.
+When the argument is 'name', the names of files are inserted instead of their full paths.
+This command only works while typing a command or a shell command.
+
+    cmap <c-o> cmd-insert-selection
+    cmap <a-o> cmd-insert-selection name
+
 Options
 
 This section shows information about options to customize the behavior.
 Character ':' is used as the separator for list options '[]int' and '[]string'.
 
+    altscreen      bool      (default on)
+
+Use the alternate screen of the terminal so that the contents of the terminal are restored after exit.
+When this option is disabled, the contents of the terminal are scrolled into the scrollback instead, and the ui is drawn in place and kept in the terminal after exit with the cursor moved below it.
+Outputs of shell commands are also kept in the scrollback in this case.
+The option is not changed and an error is shown when the terminal is not found in the terminfo database.
+This option has no effect on windows.
+
     anchorfind     bool      (default on)
 
 When this option is enabled, find command starts matching patterns from the beginning of file names, otherwise, it can match at an arbitrary position.
 
+    archivebrowse  bool      (default off)
+
+Browse archives as directories with 'open' command instead of passing them to the opener.
+Supported archives are '.zip', '.jar', '.tar', '.tar.gz', '.tgz', '.tar.bz2', and '.tbz2' files.
+Directories in archives have paths starting with 'archive://' followed by the path of the archive and the path in the archive separated with a double slash (e.g. 'archive:///home/user/foo.zip//bar'), which can also be given to 'cd' command.
+Files in archives are previewed with their contents without the previewer and they can be extracted with 'archive-extract' command.
+Archives are read-only, so commands changing files are not allowed in archives, and the working directory of shell commands is the directory of the archive.
+
+    badges         []string  (default '')
+
+List of badges shown in a column before the file names separated with colon.
+Badges are 'readonly' for files without write permissions, 'hidden' for hidden files, 'link' for symbolic links, and 'exec' for executable files that are not directories.
+Each badge is drawn with its default glyph ('R', 'H', '@', and '*' respectively) unless a glyph is given after an equal sign, which may contain escape sequences to change its color.
+Badges that do not apply to a file are left blank so that the column has the same width for all files and names are truncated accordingly.
+Badges are also drawn before the names of files in each column of the grid layout (see 'grid').
+
+    set badges readonly:link
+    set badges "link=\033[36m@:exec=\033[32m*"
+
+    boxchars       string    (default 'single')
+
+Set the characters to draw boxes around panes when 'drawbox' option is enabled.
+Currently supported sets are 'ascii' for '-', '|', and '+' characters for terminals without box drawing characters, 'single' for single lines, 'rounded' for single lines with rounded corners, and 'double' for double lines.
+
+    boxstyle       string    (default '')
+
+Set the style of boxes drawn when 'drawbox' option is enabled as ANSI codes in the same format as 'cursorline' option (e.g. `set boxstyle 34` for blue boxes).
+
+    breadcrumbs    bool      (default off)
+
+Show the path of the current directory as breadcrumbs in a line below the prompt line with components separated by '›' (e.g. '/ › home › user').
+The line is shown in addition to the prompt line so the panes are one line shorter when this option is enabled.
+Components are dropped from the left when the path is longer than the line so that the end of the path is kept visible.
+
+    cmdtimeout     int       (default 0)
+
+Set the timeout in seconds for synchronous shell commands (i.e. '$' and '!' commands).
+Commands that do not finish within the timeout are killed along with the processes started by them and an error is shown.
+Commands started by the shell are not killed on windows.
+Shell commands are not killed when the value of this option is set to zero.
+See 'cmd-timeout' command to override this option for a single command.
+
+    compoundext    bool      (default off)
+
+Consider everything after the first dot in file names as the extension in 'rename-ext' command (e.g. '.tar.gz' instead of '.gz' for 'foo.tar.gz').
+Leading dots of hidden files are not considered as the start of the extension.
+
     dircounts      bool      (default off)
 
 When this option is enabled, directory sizes show the number of items inside instead of the size of directory file.
@@ -497,15 +1214,51 @@ A thousand items are counted per directory at most, and bigger directories are s
     dirfirst       bool      (default on)
 
 Show directories first above regular files.
+This option is ignored when 'grouporder' option is set.
+
+    diropener      bool      (default off)
+
+When this option is enabled, 'open' command passes directories to the 'open' command like regular files instead of changing the working directory to them.
+Directories can still be entered with other commands such as 'cd'.
+
+    dirsearch      bool      (default off)
+
+Remember the last search pattern separately for each directory, similar to the position of the cursor.
+The pattern of a directory is saved when the directory is left and restored when the directory is visited again in the same session, so 'search-next' and 'search-prev' commands continue with the last pattern used in the current directory.
+Directories without a saved pattern start with an empty pattern.
+Patterns are only removed with 'search-clear' command.
+
+    diskfree       bool      (default off)
+
+Show the free space of the filesystem containing the current directory in the status line (e.g. '12G free').
+Free space is refreshed in the background when the current directory is changed so that slow network filesystems do not block the interface, and the last known value of the filesystem is shown in the meantime.
+Nothing is shown when the free space can not be determined (e.g. on unsupported platforms).
 
     drawbox        bool      (default off)
 
 Draw boxes around panes with box drawing characters.
+(See also 'boxchars' and 'boxstyle' options)
 
     errorfmt       string    (default "\033[7;31;47m%s\033[0m")
 
 Format string of error messages shown in the bottom message line.
 
+    errorsummary   bool      (default off)
+
+Coalesce repeated errors of file operations such as 'paste' and 'delete' in the message line.
+Errors with the same reason are shown once followed by the number of files (e.g. 'permission denied (100 files)') instead of showing each error separately.
+Errors with different reasons are all kept in the message separated by semicolons.
+
+    fastforward    bool      (default off)
+
+When a directory is opened, descend automatically through the chain of directories that contain only a single subdirectory and nothing else as in 'fast-forward' command.
+
+    filemanager    string    (default '')
+
+Command used by 'open-fm' command to open the current directory in the file manager of the system.
+The command is split into words by whitespace and the directory is passed as the last argument (e.g. 'nautilus --new-window').
+The default launcher of the platform is used when the value is empty.
+
     filesep        string    (default "\n")
 
 File separator used in environment variables 'fs' and 'fx'.
@@ -521,6 +1274,43 @@ When this option is enabled, search command patterns are considered as globs, ot
 With globbing, '*' matches any sequence, '?' matches any character, and '[...]' or '[^...] matches character sets or ranges.
 Otherwise, these characters are interpreted as they are.
 
+    grid           bool      (default off)
+
+Show the current directory in multiple columns similar to 'ls -x' when file names are short enough to fit.
+Files are placed in rows from left to right and the number of columns is calculated from the width of the longest name in each column.
+In this layout, 'up' and 'down' commands and their variants move by rows, and 'left' and 'right' commands move across columns.
+Line numbers and file information are not shown in grid layout.
+
+    grouporder     []string  (default '')
+
+List of groups of files shown in the given order separated with colon, where files are sorted with 'sortby' within each group.
+Groups are 'dir' for directories, 'link' for symbolic links, 'file' for files that are not directories, and 'hidden' for hidden files.
+A file belongs to the first group in the list it matches (e.g. a symbolic link to a directory is in 'link' group with 'link:dir:file' and in 'dir' group with 'dir:link:file'), and files matching none of the groups are shown last.
+When this option is set, it is used instead of 'dirfirst' option, which is equivalent to 'dir' as the only group.
+Hidden files are only shown as a group when 'hidden' option is enabled.
+
+    set grouporder dir:link:file
+
+    heatmap        string    (default '')
+
+Color file names by their modification times on a gradient from the color of recent files to the color of old files set with 'heatmapcolors' option.
+Files modified within the time window set with 'heatmapwindow' option get a color between the two colors, and older files get the color of old files.
+The value 'override' replaces the foreground colors of all files, and the value 'combine' only colors files without a foreground color from 'LF_COLORS' or 'LS_COLORS' environment variables.
+The heatmap is disabled when the value is empty.
+
+    set heatmap combine
+
+    heatmapcolors  string    (default '#ffd75f:#585858')
+
+Colors of recent and old files for 'heatmap' option separated with colon.
+Colors can be given as names (e.g. 'orange'), hex values (e.g. '#ff8700'), or numbers of the 256 color palette (e.g. '208').
+Colors in between are mixed in the rgb color space, so terminals without true color support may only show an approximation.
+
+    heatmapwindow  string    (default '7d')
+
+Time window for 'heatmap' option as a number followed by an optional unit, which is one of 's' (seconds), 'm' (minutes), 'h' (hours), 'd' (days), or 'w' (weeks).
+Days are used when there is no unit.
+
     hidden         bool      (default off)
 
 Show hidden files.
@@ -534,14 +1324,35 @@ Patterns can be given as relative or absolute paths.
 Globbing supports the usual special characters, '*' to match any sequence, '?' to match any character, and '[...]' or '[^...] to match character sets or ranges.
 In addition, if a pattern starts with '!', then its matches are excluded from hidden files.
 
+    iconalign      bool      (default off)
+
+Pad icons with spaces to the width of the widest icon when 'icons' option is enabled, so that file names start at the same column for all files.
+Without this option, icons of different widths (e.g. wide glyphs mixed with the single space used for files without an icon) shift the names after them.
+
+    iconset        string    (default 'basic')
+
+Set the default icons used when 'icons' option is enabled.
+Currently supported sets are 'basic' to only use icons for file types (e.g. directories and files), and 'extended' to also use icons for dozens of common file extensions and names (e.g. '*.pdf' or 'Makefile').
+Icons of both sets are chosen from the unicode standard so they do not require patched fonts.
+Entries in 'LF_ICONS' environment variable override default icons of both sets.
+
     icons          bool      (default off)
 
 Show icons before each item in the list.
-By default, only two icons, 🗀 (U+1F5C0) and 🗎 (U+1F5CE), are used for directories and files respectively, as they are supported in the unicode standard.
+By default, only two icons, 🗀 (U+1F5C0) and 🗎 (U+1F5CE), are used for directories and files respectively, as they are supported in the unicode standard (see also 'iconset' option).
 Icons can be configured with an environment variable named 'LF_ICONS'.
 The syntax of this variable is similar to 'LS_COLORS'.
 See the wiki page for an example icon configuration.
 
+    idleperiod     int       (default 0)
+
+Set the interval in seconds for periodic checks of directory updates when the terminal is not focused.
+The value of 'period' option is used instead when the terminal is focused, and periodic checks are disabled regardless of this option when 'period' is set to zero.
+A check is done immediately when the terminal is focused again.
+Focus changes are detected using focus reporting escape sequences, which are not supported by some terminals and on windows.
+Separate interval is disabled when the value of this option is set to zero.
+Note that 'alt-[' key is delivered with the following key when this option or 'cursorinactive' option is enabled since it is also the beginning of focus change sequences.
+
     ifs            string    (default '')
 
 Sets 'IFS' variable in shell commands.
@@ -554,6 +1365,9 @@ This option does not have any effect on windows.
     ignorecase     bool      (default on)
 
 Ignore case in sorting and search patterns.
+Characters are compared with their lowercase forms and Unicode simple case folding so that all forms of a letter match each other (e.g. 'ſ' matches 's' and 'S', and 'ς' matches 'σ' and 'Σ').
+Each character is folded to a single character, therefore full case folding to multiple characters is not done (e.g. 'ß' matches 'ẞ' but not 'ss').
+Turkish 'İ' is matched as 'i' and dotless 'ı' only matches itself.
 
     ignoredia      bool      (default on)
 
@@ -563,17 +1377,70 @@ Ignore diacritics in sorting and search patterns.
 
 Jump to the first match after each keystroke during searching.
 
+    incsearchdelay int       (default 0)
+
+Set the delay in milliseconds to wait after the last keystroke before jumping to the first match when 'incsearch' is enabled.
+This can be used to keep typing responsive in huge directories.
+The final pattern is always searched when the prompt is accepted.
+Searches are done after each keystroke when the value of this option is set to zero.
+
     info           []string  (default '')
 
 List of information shown for directory items at the right side of pane.
-Currently supported information types are 'size', 'time', 'atime', and 'ctime'.
+Currently supported information types are 'size', 'childcount', 'time', 'atime', 'ctime', 'perm', and 'xattr'.
+Information type 'childcount' shows the number of immediate entries of directories with a single read of the directory without recursion.
+Entries are counted in the background when the parent directory is loaded.
+Counts are kept until the modification time of the directory changes so that directories are not read again on each reload, which is useful on slow network filesystems.
+Counts are updated after changes in directories when the parent directory is checked for changes (see 'period').
+Information type 'xattr' shows '@' for files with extended attributes similar to 'ls -l@' in macos.
 Information is only shown when the pane width is more than twice the width of information.
 
+    infocursor     bool      (default off)
+
+Show information of 'info' option only on the line of the current file.
+Information is left blank on other lines with the same width reserved so that names do not shift as the cursor moves.
+
+    minimal        bool      (default off)
+
+Draw directory panes in a plain form for maximum redraw speed on slow or remote terminals.
+Enabling this option disables 'icons', 'info', and 'drawbox' options at once, and disabling it restores their previous values.
+Options changed while this option is enabled keep their new values when it is disabled.
+Only the names of files are drawn without line numbers, badges, scrollbars, or search highlights, and the grid layout is not used.
+Colors are not drawn anywhere, including previews, messages, and the status line.
+The current file is shown with the codes of 'cursorline', 'cursorinactive', and 'cursorselected' options (reverse video by default) and the marks before the names are '*' for selected files, '+' for copied files, and '-' for cut files.
+
+    notify         string    (default '')
+
+Method to notify the user when a long running operation is finished.
+Currently supported methods are 'bell' to ring the terminal bell and 'desktop' to send a desktop notification.
+Desktop notifications are sent with 'notify-send' on unix, 'osascript' on macos, and 'powershell' on windows.
+Asynchronous shell commands and copy/move operations started with 'paste' are considered for notifications.
+Notifications are disabled when the value of this option is left empty.
+
+    notifytime     int       (default 10)
+
+Minimum duration in seconds an operation should take to notify the user about its completion.
+This option has no effect when 'notify' is left empty.
+
     number         bool      (default off)
 
 Show the position number for directory items at the left side of pane.
 When 'relativenumber' is enabled, only the current line shows the absolute position and relative positions are shown for the rest.
 
+    parentwidth    int       (default 0)
+
+Minimum width of the terminal in columns to show parent panes.
+Only the current pane and the preview pane are shown when the terminal is narrower.
+Panes are shown again when the terminal is widened.
+This option is disabled when the value is set to zero.
+
+    pasteconflict  string    (default 'rename')
+
+Set the policy for files with conflicting names in the destination when files are copied or moved with 'paste', 'split-copy', and 'split-move' commands.
+Currently supported policies are 'rename' to add a numbered suffix to the new names (e.g. 'foo.~1~'), 'overwrite' to replace existing files, and 'skip' to leave existing files as they are without pasting conflicting files.
+With 'overwrite' and 'skip', pasted directories are merged into existing directories with the same name and the policy applies to each conflicting file inside them recursively.
+This policy can be overridden for a single paste with the flags of 'paste' command.
+
     period         int       (default 0)
 
 Set the interval in seconds for periodic checks of directory updates.
@@ -582,12 +1449,33 @@ Note that directories are already updated automatically in many cases.
 This option can be useful when there is an external process changing the displayed directory and you are not doing anything in lf.
 Periodic checks are disabled when the value of this option is set to zero.
 
+    periodcheck    string    (default 'mtime')
+
+Set how directories are checked for changes by 'load' command, which is also used for periodic checks of 'period' option.
+Directories are only read again when they are changed, so that unchanged directories are not sorted again and the current file stays in place.
+When the value is 'mtime', a directory is changed when its modification time is newer than the time it is loaded, which is the case when files are added, removed, or renamed.
+When the value is 'count', a directory is also changed when the number of its entries is different, which catches changes within the resolution of modification times in some filesystems.
+When the value is 'files', a directory is also changed when the modification time or the size of one of its files is different, which catches edits of file contents that do not change the modification time of the directory.
+Note that 'count' and 'files' read the directory in each check, and 'files' additionally checks each file, so changes are shown later for large directories since entries are compared in the background.
+
+    permfmt        string    (default 'symbolic')
+
+Format of file permissions shown in the status line and in 'perm' information.
+Currently supported formats are 'symbolic' (e.g. 'drwxr-xr-x') and 'octal' (e.g. '0755').
+Setuid, setgid, and sticky bits are shown in the execute fields in symbolic form (e.g. 'rwsr-xr-x' or 'rwxrwxrwt') and in the leading digit in octal form (e.g. '4755').
+
     preview        bool      (default on)
 
 Show previews of files and directories at the right most pane.
 If the file has more lines than the preview pane, rest of the lines are not read.
 Files containing the null character (U+0000) in the read portion are considered binary files and displayed as 'binary'.
 
+    previewansi    string    (default 'render')
+
+Set the handling of ANSI escape sequences in previews.
+Currently supported methods are 'render' to show SGR sequences as colors and attributes, 'strip' to remove all escape sequences, and 'raw' to show escape sequences literally with the escape character displayed as '^['.
+Other escape sequences such as cursor movements are not supported and they are ignored when the method is 'render'.
+
     previewer      string    (default '') (not filtered if empty)
 
 Set the path of a previewer file to filter the content of regular files for previewing.
@@ -604,6 +1492,72 @@ The file should be executable.
 One argument is passed to the file; the path to the file whose preview should be cleaned.
 Preview clearing is disabled when the value of this option is left empty.
 
+    cursorline     string    (default '')
+
+Set the style of the current line as ANSI codes similar to the values of 'LF_COLORS' entries (e.g. `set cursorline "48;5;236"` for a gray background, or `set cursorline 4` for underline).
+Codes are applied on top of the color of the current file and the background spans the full width of the pane including line numbers, or the full width of the column of the current file in the grid layout (see 'grid').
+Foreground color of the file is reset to the default when it is the same as the background color of the current line.
+The current line is shown in reverse video when the value of this option is left empty.
+Note that the value should be quoted when it contains semicolons.
+
+    cursorinactive string    (default '')
+
+Set the style of the current line when the terminal is not focused in the same format as 'cursorline' option (e.g. `set cursorinactive 4` to only underline the current line).
+Focus changes are detected as in 'idleperiod' option.
+The value of 'cursorline' option is used regardless of focus when the value of this option is left empty.
+
+    cursorselected string    (default '')
+
+Set the style of the current line when the current file is selected in the same format as 'cursorline' option (e.g. `set cursorselected "7;35"` for reverse video in magenta).
+This style is used instead of both 'cursorline' and 'selectedline' options so that a selected current file can be told apart from an unselected one.
+When the value of this option is left empty, the style of 'cursorline' option is applied on top of the style of 'selectedline' option.
+
+    difftool       string    (default '')
+
+Set the command used by 'diff' command to compare files.
+Two paths are passed to the command as arguments and the output is shown in the pager.
+Default 'diff' tool is used when the value of this option is left empty, which may not be available on windows (e.g. `set difftool fc` can be used instead).
+
+    dirlinkicon    string    (default 'link')
+
+Set the icon type of symbolic links to directories.
+Currently supported types are 'link' to show the 'ln' icon as other links, 'dir' to show the icon of the target directory (e.g. 'di' or 'tw'), and 'dirlink' to show the 'dl' icon which falls back to the 'di' icon when it is not defined.
+Broken links are always shown with the 'or' icon.
+
+    previewbytes   int       (default 0)
+
+Maximum number of bytes read from a file or the output of the previewer to show the preview.
+Reading stops at the limit and a '...truncated' indicator is shown after the last line when there is more content left.
+This is useful to keep previews of huge files with long lines or without any newlines responsive.
+This option is disabled when the value is set to zero.
+
+    previewlines   int       (default 0)
+
+Maximum number of lines read from a file or the output of the previewer to show the preview.
+Reading stops at the limit and a '...truncated' indicator is shown after the last line when there are more lines left.
+This also limits the number of lines searched for matches when 'previewsearch' is enabled.
+This option is disabled when the value is set to zero.
+
+    previewsearch  bool      (default off)
+
+Scroll previews of files to the first line matching the last search pattern and highlight the match.
+Matching is done as in 'search' command with respect to 'ignorecase', 'smartcase', 'ignoredia', and 'smartdia' options, except that glob patterns are not supported when 'globsearch' is enabled.
+The matching line is shown at the top of the preview with 'scrolloff' lines of context above it.
+Previews are shown from the top when there is no match in the first 10000 lines.
+
+    previewwrap    bool      (default off)
+
+Wrap long lines of previews at the width of the preview pane instead of cutting them at the edge of the pane.
+Lines are wrapped without splitting wide characters and grapheme clusters, and colors of escape codes are kept in the wrapped parts.
+Use 'set previewwrap!' to toggle wrapping (e.g. 'map W set previewwrap!').
+
+    previewwidth   int       (default 0)
+
+Minimum width of the terminal in columns to show the preview pane.
+The right most number in 'ratios' is not used while the preview pane is hidden.
+Panes are shown again when the terminal is widened.
+This option is disabled when the value is set to zero.
+
     promptfmt      string    (default "\033[32;1m%u@%h\033[0m:\033[34;1m%d/\033[0m\033[1m%f\033[0m")
 
 Format string of the prompt shown in the top line.
@@ -611,27 +1565,77 @@ Special expansions are provided, '%u' as the user name, '%h' as the host name, '
 Home folder is shown as '~' in the working directory expansion.
 Directory names are automatically shortened to a single character starting from the left most parent when the prompt does not fit to the screen.
 
+    quitiflast     bool      (default off)
+
+Quit lf when 'workspace-close' command closes the last workspace, similar to closing the last tab in an editor.
+When this option is disabled, closing the last workspace is an error and lf keeps running.
+
     ratios         []int     (default '1:2:3')
 
 List of ratios of pane widths.
 Number of items in the list determines the number of panes in the ui.
 When 'preview' option is enabled, the right most number is used for the width of preview pane.
 
+    readonly       bool      (default off)
+
+Disable commands modifying files, which are 'delete', 'paste', 'rename', 'rename-case', 'rename-ext', 'rename-seq', 'pipe-rename', 'toggle-exec', 'unmount', 'trash-list', 'trash-empty', and 'new-from-template', and all shell commands since they may also modify files.
+An error is shown instead when such a command is run.
+Note that opening files with the default 'open' command is also disabled since it runs a shell command.
+This option can also be enabled with '-readonly' command line flag for demos or browsing sensitive directories.
+Once enabled, it can not be disabled again until lf is restarted.
+
     relativenumber bool      (default off)
 
 Show the position number relative to the current line.
 When 'number' is enabled, current line shows the absolute position, otherwise nothing is shown.
 
+    relativetime   bool      (default off)
+
+Show times in 'time', 'atime', and 'ctime' information relative to the current time (e.g. '3 min ago', '5h ago', 'yesterday', '12 days ago', or '2 mo ago').
+Times are computed again whenever the screen is drawn, so they are kept up to date with reloads.
+Times more than a year in the past or more than a minute in the future are shown in the usual absolute form.
+This option does not affect the time shown in the bottom line, which uses 'timefmt' option.
+
     reverse        bool      (default off)
 
 Reverse the direction of sort.
 
+    rewritelinks   bool      (default off)
+
+Rewrite targets of relative symbolic links when files are moved with 'paste' command so that links still point to the same locations after the move.
+Links pointing inside the moved directory are kept as they are since their targets are moved together with them, while links pointing outside of it are given new relative targets from their new locations.
+Absolute links are not changed.
+This option has no effect when files are moved across filesystems since links are copied as regular files in that case.
+
+    scrollbar      bool      (default off)
+
+Show a vertical scrollbar on the right edge of directory panes when the number of files does not fit in the pane.
+The size and position of the scrollbar reflect the visible part of the list.
+
     scrolloff      int       (default 0)
 
 Minimum number of offset lines shown at all times in the top and the bottom of the screen when scrolling.
 The current line is kept in the middle when this option is set to a large value that is bigger than the half of number of lines.
 A smaller offset can be used when the current file is close to the beginning or end of the list to show the maximum number of items.
 
+    searchmatch    string    (default '1;4')
+
+Set the style of the matches of the search pattern in the names of files in the current directory as ANSI codes in the same format as 'cursorline' option, which is bold and underline by default.
+Codes are applied on top of the style of the line so that the color of the file is kept.
+Matches are found as in 'search' command with respect to 'ignorecase', 'smartcase', 'ignoredia', and 'smartdia' options, and they are not highlighted when 'globsearch' option is enabled or the value of this option is left empty.
+
+    selectedline   string    (default '')
+
+Set the style of the names of selected files as ANSI codes in the same format as 'cursorline' option (e.g. `set selectedline 35` for magenta names).
+Codes are applied on top of the color of the file and the style of the current line is applied on top of them for the current file, unless 'cursorselected' option is set.
+Selected files are only marked in the column before their names when the value of this option is left empty.
+
+    selectioninfo  bool      (default off)
+
+Show the number of selected files and their total size in the status line (e.g. '12 selected, 3.4G').
+The total is updated as files are selected and unselected.
+Sizes of directories are not computed, so a question mark is shown in the total when directories are selected (e.g. '3 selected, 1.2M+?').
+
     shell          string    (default 'sh' for unix and 'cmd' for windows)
 
 Shell executable to use for shell commands.
@@ -656,12 +1660,47 @@ This option has no effect when 'ignoredia' is disabled.
     sortby         string    (default 'natural')
 
 Sort type for directories.
-Currently supported sort types are 'natural', 'name', 'size', 'time', 'ctime', 'atime', and 'ext'.
+Currently supported sort types are 'natural', 'name', 'size', 'time', 'ctime', 'atime', 'ext', 'manual', and 'command'.
+The 'manual' sort type uses the order set by 'move-up', 'move-down', 'move-to-top', and 'move-to-bottom' commands and falls back to natural order for files without an order.
+The 'command' sort type uses the order returned by the command set with 'sortcmd' option.
+
+    sortcmd        string    (default '')
+
+Shell command used to sort files with 'sortby command'.
+The command is run in the directory with the names of all files in natural order given in its standard input, one on each line, and it should print the names or paths of files in the desired order, one on each line.
+Files not printed by the command are kept at the end in natural order, and names printed for files that do not exist or printed more than once are ignored.
+Files are sorted in natural order when the command fails or does not finish within two seconds, in which case the command is killed.
+The order is cached for each directory so the command is only run again when the directory or the command is changed.
+The command is run in the background when directories are loaded, so files are shown in natural order until the order is loaded.
+
+    set sortcmd 'exiftool -q -T -DateTimeOriginal -FileName -@ - | sort | cut -f2'
+    set sortby command
+
+    sortindicator  bool      (default off)
+
+Show the current sort type in the status line followed by an arrow for the direction of sorting, which is '↓' normally and '↑' when 'reverse' option is enabled (e.g. 'name↓' or 'size↑').
 
     tabstop        int       (default 8)
 
 Number of space characters to show for horizontal tabulation (U+0009) character.
 
+    templatedir    string    (default '')
+
+Directory of templates used by 'new-from-template' command.
+The templates directory in the configuration directory is used when the value is empty.
+(See also 'Configuration' section)
+
+    templatesubst  bool      (default off)
+
+Replace placeholders in the contents of templates when creating files with 'new-from-template' command.
+Placeholders are '{{name}}' for the name of the new file, '{{stem}}' for the name without the extension, '{{date}}' for the current date as 'YYYY-MM-DD', and '{{year}}' for the current year.
+
+    terminal       string    (default '')
+
+Command used by 'open-terminal' command to spawn a terminal emulator.
+The command is split into words by whitespace and it is run in the current directory without any additional arguments (e.g. 'alacritty' or 'kitty --single-instance').
+The 'TERMINAL' environment variable and then the default of the platform is used when the value is empty.
+
     timefmt        string    (default 'Mon Jan _2 15:04:05 2006')
 
 Format string of the file modification time shown in the bottom line.
@@ -669,6 +1708,25 @@ Format string of the file modification time shown in the bottom line.
     truncatechar   string    (default '~')
 
 Truncate character shown at the end when the file name does not fit to the pane.
+The value can also be a string with multiple characters as an ellipsis (e.g. '…' or '...').
+Names are truncated at the boundaries of grapheme clusters so that wide characters, characters with combining marks, and emoji sequences are not split, and the remaining space is filled with spaces when a wide character does not fit.
+
+    waittimeout    int       (default 0)
+
+Set the time in seconds to wait for the last asynchronous shell command with 'wait' command.
+The rest of the command list is discarded and an error is shown when the command is not finished in time.
+Commands are waited without a timeout when the value of this option is set to zero.
+
+    whichkey       bool      (default on)
+
+Show a menu of keys that can be pressed next and their commands when a key prefix of a mapping is pressed (e.g. 'g' for 'gg').
+Mappings with longer keys are grouped under their next key and shown as '...'.
+The menu is dismissed when the next key is pressed.
+
+    whichkeydelay  int       (default 0)
+
+Delay in milliseconds before showing the menu of 'whichkey' option.
+The menu is not shown if the mapping is completed before the delay.
 
     wrapscan       bool      (default on)
 
@@ -699,6 +1757,12 @@ Selected file(s) (i.e. 'fs') if there are any selected files, otherwise current
 
 Id of the running client.
 
+    LF_RESULT
+
+Path of a writable result file for custom pickers (e.g. 'fzf' or 'zoxide').
+Shell commands can write paths to this file one per line to be used by 'link-selection-file' command after they exit.
+The file is removed before each shell command and when lf quits.
+
     LF_LEVEL
 
 The value of this variable is set to the current nesting level when you run lf from a shell spawned inside lf.
@@ -754,6 +1818,11 @@ Command 'set' is used to set an option which can be boolean, integer, or string:
     set sortby 'time'  # string value with single quotes (whitespaces)
     set sortby "time"  # string value with double quotes (backslash escapes)
 
+Multiple options can be given to a single 'set' command.
+Boolean options do not take a value, while other options take the next word as their value:
+
+    set hidden noreverse sortby time scrolloff 10
+
 Command 'map' is used to bind a key to a command which can be builtin command, custom command, or shell command:
 
     map gh cd ~        # builtin command
@@ -832,6 +1901,18 @@ On these terminals, keys combined with the alt key are prefixed with 'a' charact
 
     map <a-a> down
 
+Special keys combined with modifier keys are prefixed with 'c' for control, 's' for shift, and 'a' for alt characters, as reported by the terminal:
+
+    map <c-up> down
+    map <s-f-5> down
+    map <a-right> down
+    map <c-s-pgdn> down
+
+Modifiers can be given in any order and they are shown in the order 'a', 'c', and 's' in the names of keys (e.g. '<s-c-up>' is shown as '<c-s-up>').
+Function keys can also be written without a dash (e.g. '<s-f5>').
+Names of special keys that can be combined with all modifiers are 'delete', 'insert', 'up', 'down', 'left', 'right', 'home', 'end', 'upleft', 'upright', 'downleft', 'downright', 'center', 'pgdn', 'pgup', 'clear', 'exit', 'cancel', 'pause', 'print', and function keys from 'f-1' to 'f-64'.
+Regular keys, control keys (e.g. '<c-a>'), and keys sent as control characters ('enter', 'backspace', 'tab', 'backtab', 'esc', and 'backspace2') can only be combined with the alt modifier (e.g. '<a-c-a>' or '<a-enter>') since terminals do not report other modifiers for them.
+
 Please note that, some key combinations are not possible due to the way terminals work (e.g. control and h combination sends a backspace key instead).
 The easiest way to find the name of a key combination is to press the key while lf is running and read the name of the key from the unknown mapping error.
 
@@ -997,19 +2078,38 @@ Getting the file selection is similarly done with 'load' command:
         # do something else with $list
     fi
 
+Files can be selected in clients with 'select-from-stdin' command, which reads paths from the standard input and sends them to the client with the id given in the argument or to all clients when the id is not given.
+Paths are separated by newline characters or null characters when there is any in the input, and relative paths are resolved with respect to the current directory of the command.
+This can be used to drive the selection with external tools:
+
+    fd -e go | lf -remote "select-from-stdin $id"
+    git diff --name-only -z | lf -remote "select-from-stdin $id"
+
+Multiple commands can be sent with 'batch' command, which sends the commands given in the following lines until an empty line to the client with the id given in the argument or to all clients when the id is not given.
+Commands are evaluated together with a single redraw at the end using 'batch' builtin command, so there is no flicker or intermediate states from commands sent separately.
+Commands are read from the standard input when there are no lines after the command, and '-e' can be given before the id to stop at the first failing command:
+
+    lf -remote "$(printf 'batch %s\nset hidden\ncd /tmp\nselect foo.txt\n' $id)"
+    printf 'cd /tmp\nselect foo.txt\n' | lf -remote "batch -e $id"
+
 There is a 'quit' command to close client connections and quit the server:
 
     lf -remote 'quit'
 
-Lastly, there is a 'conn' command to connect the server as a client.
-This should not be needed for users.
+The message of 'print' command can be printed to the standard output with 'print' remote command followed by the id of the client:
+
+    lf -remote "print $id '\$fx'"
+
+Lastly, there is a 'conn' command to connect the server as a client, and 'print-reply' and 'reply' commands used to send the message of 'print' remote command back to the server.
+These should not be needed for users.
 
 File Operations
 
 lf uses its own builtin copy and move operations by default.
 These are implemented as asynchronous operations and progress is shown in the bottom ruler.
-These commands do not overwrite existing files or directories with the same name.
+These commands do not overwrite existing files or directories with the same name by default.
 Instead, a suffix that is compatible with '--backup=numbered' option in GNU cp is added to the new files or directories.
+Existing files can be overwritten or skipped instead with 'pasteconflict' option or the flags of 'paste' command, in which case directories with the same name are merged.
 Only file modes are preserved and all other attributes are ignored including ownership, timestamps, context, links, and xattr.
 Special files such as character and block devices, named pipes, and sockets are skipped and links are followed.
 Moving is performed using the rename operation of the underlying OS.
@@ -1075,12 +2175,13 @@ Some options effect both searching and finding.
 You can disable 'wrapscan' option to prevent searches to wrap around at the end of the file list.
 You can disable 'ignorecase' option to match cases in the pattern and the filename.
 This option is already automatically overridden if the pattern contains upper case characters.
+Cases of non-ascii letters are also ignored with Unicode simple case folding as explained in 'ignorecase' option.
 You can disable 'smartcase' option to disable this behavior.
 Two similar options 'ignoredia' and 'smartdia' are provided to control matching diacritics in latin letters.
 
 Opening Files
 
-You can define a an 'open' command (default 'l' and '<right>') to configure file opening.
+You can define a an 'open' command (default 'l') to configure file opening.
 This command is only called when the current file is not a directory, otherwise the directory is entered instead.
 You can define it just as you would define any other command:
 
@@ -1324,6 +2425,14 @@ Default values are as follows given with their matching order in lf:
     ex  🗎
     fi  🗎
 
+Symbolic links to directories are shown with the 'ln' icon by default.
+An additional 'dl' entry can be used for these links when 'dirlinkicon' option is set to 'dirlink'.
+
+Entries for file types are followed by entries for file names and extensions (e.g. 'Makefile*' and '*.go').
+The 'fi' entry is used for the remaining regular files.
+A literal '*' entry can be used as an explicit catch-all for any file not matched by other entries, including files of other types without a specific entry.
+The 'fi' entry is used for these files when there is no '*' entry.
+
 See the wiki page for an example icons configuration
 https://github.com/gokcehan/lf/wiki/Icons.
 */
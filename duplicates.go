@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+var errDuplicatesCancelled = errors.New("scan cancelled")
+
+// This type keeps the state of a running scan of 'select-duplicates' command
+// so that invoking the command again cancels the scan.
+type dupScan struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newDupScan() *dupScan {
+	return &dupScan{make(chan struct{}), make(chan struct{})}
+}
+
+func (s *dupScan) running() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+type dupFile struct {
+	path string
+	size int64
+}
+
+// This function returns the regular files in the given directory, including
+// the files in subdirectories when recursive is true. Symbolic links are not
+// followed and empty files are skipped since they are trivially identical.
+func dupFiles(dir string, recursive bool, stop <-chan struct{}) ([]dupFile, error) {
+	var files []dupFile
+
+	add := func(path string, info os.FileInfo) {
+		if info.Mode().IsRegular() && info.Size() > 0 {
+			files = append(files, dupFile{path, info.Size()})
+		}
+	}
+
+	if !recursive {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			add(filepath.Join(dir, info.Name()), info)
+		}
+		return files, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		select {
+		case <-stop:
+			return errDuplicatesCancelled
+		default:
+		}
+		if err != nil {
+			log.Printf("select-duplicates: %s", err)
+			return nil
+		}
+		add(path, info)
+		return nil
+	})
+
+	return files, err
+}
+
+// This function groups the given files with identical contents. Files are
+// first grouped by size and only the files sharing a size with another file
+// are hashed with the given function. Groups are sorted by their first path
+// and paths are sorted within each group. Files failing to hash are logged
+// and left out of the groups.
+func duplicateGroups(files []dupFile, hash func(path string) (string, error), stop <-chan struct{}) ([][]string, error) {
+	bySize := make(map[int64][]string)
+	for _, f := range files {
+		bySize[f.size] = append(bySize[f.size], f.path)
+	}
+
+	var groups [][]string
+
+	for _, paths := range bySize {
+		if len(paths) < 2 {
+			continue
+		}
+
+		byHash := make(map[string][]string)
+		for _, path := range paths {
+			select {
+			case <-stop:
+				return nil, errDuplicatesCancelled
+			default:
+			}
+
+			sum, err := hash(path)
+			if err != nil {
+				log.Printf("select-duplicates: %s", err)
+				continue
+			}
+			byHash[sum] = append(byHash[sum], path)
+		}
+
+		for _, group := range byHash {
+			if len(group) < 2 {
+				continue
+			}
+			sort.Strings(group)
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+
+	return groups, nil
+}
+
+// This function scans the given directory for duplicate files and sends the
+// expressions to select them and report each group to the ui. The scan stops
+// early when the stop channel of the scan is closed.
+func duplicatesAsync(ui *ui, dir string, recursive bool, scan *dupScan) {
+	defer close(scan.done)
+
+	files, err := dupFiles(dir, recursive, scan.stop)
+	if err == nil {
+		ui.exprChan <- &callExpr{"echo", []string{fmt.Sprintf("select-duplicates: hashing %d files", len(files))}, 1}
+	}
+
+	var groups [][]string
+	if err == nil {
+		groups, err = duplicateGroups(files, func(path string) (string, error) {
+			return fileChecksum(path, "sha256", nil)
+		}, scan.stop)
+	}
+
+	if err != nil {
+		ui.exprChan <- &callExpr{"echoerr", []string{fmt.Sprintf("select-duplicates: %s", err)}, 1}
+		return
+	}
+
+	if len(groups) == 0 {
+		ui.exprChan <- &callExpr{"echo", []string{"select-duplicates: no duplicates found"}, 1}
+		return
+	}
+
+	ui.exprChan <- &dupResult{dir, groups}
+}
+
+// This type is sent from the scan to the ui so that the duplicates are
+// selected and listed in the main loop.
+type dupResult struct {
+	dir    string
+	groups [][]string
+}
+
+func (e *dupResult) String() string { return "select-duplicates " + e.dir }
+
+func (e *dupResult) eval(app *app, args []string) {
+	app.nav.unselect()
+
+	count := 0
+	for _, group := range e.groups {
+		for _, path := range group {
+			if _, err := os.Lstat(path); err == nil {
+				app.nav.toggleSelection(path)
+				count++
+			}
+		}
+	}
+
+	app.ui.menuBuf = listDuplicates(e.dir, e.groups)
+	app.ui.echof("select-duplicates: %d files selected in %d groups", count, len(e.groups))
+}
+
+// This function returns the table of the given groups of duplicates with
+// paths shown relative to the scanned directory.
+func listDuplicates(dir string, groups [][]string) *bytes.Buffer {
+	t := new(tabwriter.Writer)
+	b := new(bytes.Buffer)
+
+	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
+	fmt.Fprintln(t, "group\tfile")
+	for i, group := range groups {
+		for _, path := range group {
+			if rel, err := filepath.Rel(dir, path); err == nil {
+				path = rel
+			}
+			fmt.Fprintf(t, "%d\t%s\n", i+1, path)
+		}
+	}
+	t.Flush()
+
+	return b
+}
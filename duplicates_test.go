@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDuplicateGroups(t *testing.T) {
+	contents := map[string]string{
+		"a": "foo",
+		"b": "foo",
+		"c": "bar",
+		"d": "foo",
+		"e": "bazz",
+		"f": "quxx",
+		"g": "bazz",
+		"h": "unique size",
+	}
+
+	tests := []struct {
+		paths  []string
+		exp    [][]string
+		hashed []string
+	}{
+		{[]string{"a", "b", "c", "d"}, [][]string{{"a", "b", "d"}}, []string{"a", "b", "c", "d"}},
+		{[]string{"g", "f", "e", "h"}, [][]string{{"e", "g"}}, []string{"e", "f", "g"}},
+		{[]string{"d", "e", "a", "g"}, [][]string{{"a", "d"}, {"e", "g"}}, []string{"a", "d", "e", "g"}},
+		{[]string{"a", "c", "e", "h"}, nil, []string{"a", "c"}},
+		{[]string{"a", "e", "h"}, nil, nil},
+		{nil, nil, nil},
+	}
+
+	for _, test := range tests {
+		var files []dupFile
+		for _, path := range test.paths {
+			files = append(files, dupFile{path, int64(len(contents[path]))})
+		}
+
+		var hashed []string
+		hash := func(path string) (string, error) {
+			hashed = append(hashed, path)
+			return contents[path], nil
+		}
+
+		got, err := duplicateGroups(files, hash, nil)
+		if err != nil {
+			t.Errorf("at input '%v' unexpected error: %s", test.paths, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.paths, test.exp, got)
+		}
+
+		sort.Strings(hashed)
+		if !reflect.DeepEqual(hashed, test.hashed) {
+			t.Errorf("at input '%v' expected '%v' to be hashed but got '%v'", test.paths, test.hashed, hashed)
+		}
+	}
+}
+
+func TestDuplicateGroupsErrors(t *testing.T) {
+	files := []dupFile{{"a", 1}, {"b", 1}, {"c", 1}}
+
+	hash := func(path string) (string, error) {
+		if path == "b" {
+			return "", fmt.Errorf("permission denied")
+		}
+		return "x", nil
+	}
+
+	got, err := duplicateGroups(files, hash, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if exp := [][]string{{"a", "c"}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if _, err := duplicateGroups(files, hash, stop); err != errDuplicatesCancelled {
+		t.Errorf("expected '%v' but got '%v'", errDuplicatesCancelled, err)
+	}
+}
+
+func TestDupFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("creating dir: %s", err)
+	}
+	for name, s := range map[string]string{"foo": "foo", "empty": "", "sub/bar": "bar"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+
+	tests := []struct {
+		recursive bool
+		exp       []dupFile
+	}{
+		{false, []dupFile{{filepath.Join(dir, "foo"), 3}}},
+		{true, []dupFile{{filepath.Join(dir, "foo"), 3}, {filepath.Join(dir, "sub", "bar"), 3}}},
+	}
+
+	for _, test := range tests {
+		got, err := dupFiles(dir, test.recursive, nil)
+		if err != nil {
+			t.Errorf("at input '%t' unexpected error: %s", test.recursive, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%t' expected '%v' but got '%v'", test.recursive, test.exp, got)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"time"
@@ -14,12 +18,42 @@ import (
 
 func (e *setExpr) eval(app *app, args []string) {
 	switch e.opt {
+	case "altscreen":
+		app.setAltScreenOpt(true)
+	case "noaltscreen":
+		app.setAltScreenOpt(false)
+	case "altscreen!":
+		app.setAltScreenOpt(!gOpts.altscreen)
 	case "anchorfind":
 		gOpts.anchorfind = true
 	case "noanchorfind":
 		gOpts.anchorfind = false
 	case "anchorfind!":
 		gOpts.anchorfind = !gOpts.anchorfind
+	case "archivebrowse":
+		gOpts.archivebrowse = true
+	case "noarchivebrowse":
+		gOpts.archivebrowse = false
+	case "archivebrowse!":
+		gOpts.archivebrowse = !gOpts.archivebrowse
+	case "breadcrumbs":
+		gOpts.breadcrumbs = true
+		app.ui.renew()
+		app.nav.height = app.ui.wins[0].h
+	case "nobreadcrumbs":
+		gOpts.breadcrumbs = false
+		app.ui.renew()
+		app.nav.height = app.ui.wins[0].h
+	case "breadcrumbs!":
+		gOpts.breadcrumbs = !gOpts.breadcrumbs
+		app.ui.renew()
+		app.nav.height = app.ui.wins[0].h
+	case "compoundext":
+		gOpts.compoundext = true
+	case "nocompoundext":
+		gOpts.compoundext = false
+	case "compoundext!":
+		gOpts.compoundext = !gOpts.compoundext
 	case "dircounts":
 		gOpts.dircounts = true
 	case "nodircounts":
@@ -38,24 +72,66 @@ func (e *setExpr) eval(app *app, args []string) {
 		gOpts.sortType.option ^= dirfirstSort
 		app.nav.sort()
 		app.ui.sort()
+	case "dirsearch":
+		gOpts.dirsearch = true
+		app.nav.syncSearch()
+	case "nodirsearch":
+		gOpts.dirsearch = false
+		app.nav.syncSearch()
+	case "dirsearch!":
+		gOpts.dirsearch = !gOpts.dirsearch
+		app.nav.syncSearch()
+	case "diropener":
+		gOpts.diropener = true
+	case "nodiropener":
+		gOpts.diropener = false
+	case "diropener!":
+		gOpts.diropener = !gOpts.diropener
+	case "diskfree":
+		gOpts.diskfree = true
+	case "nodiskfree":
+		gOpts.diskfree = false
+	case "diskfree!":
+		gOpts.diskfree = !gOpts.diskfree
 	case "drawbox":
+		minimalChanged("drawbox")
 		gOpts.drawbox = true
 		app.ui.renew()
 		app.nav.height = app.ui.wins[0].h
 	case "nodrawbox":
+		minimalChanged("drawbox")
 		gOpts.drawbox = false
 		app.ui.renew()
 		app.nav.height = app.ui.wins[0].h
 	case "drawbox!":
+		minimalChanged("drawbox")
 		gOpts.drawbox = !gOpts.drawbox
 		app.ui.renew()
 		app.nav.height = app.ui.wins[0].h
+	case "errorsummary":
+		gOpts.errorsummary = true
+	case "noerrorsummary":
+		gOpts.errorsummary = false
+	case "errorsummary!":
+		gOpts.errorsummary = !gOpts.errorsummary
+	case "fastforward":
+		gOpts.fastforward = true
+	case "nofastforward":
+		gOpts.fastforward = false
+	case "fastforward!":
+		gOpts.fastforward = !gOpts.fastforward
 	case "globsearch":
 		gOpts.globsearch = true
 	case "noglobsearch":
 		gOpts.globsearch = false
 	case "globsearch!":
 		gOpts.globsearch = !gOpts.globsearch
+	case "grid":
+		gOpts.grid = true
+	case "nogrid":
+		gOpts.grid = false
+	case "grid!":
+		gOpts.grid = !gOpts.grid
 	case "hidden":
 		gOpts.sortType.option |= hiddenSort
 		app.nav.sort()
@@ -74,11 +150,20 @@ func (e *setExpr) eval(app *app, args []string) {
 		app.nav.position()
 		app.ui.sort()
 		app.ui.loadFile(app.nav, true)
+	case "iconalign":
+		gOpts.iconalign = true
+	case "noiconalign":
+		gOpts.iconalign = false
+	case "iconalign!":
+		gOpts.iconalign = !gOpts.iconalign
 	case "icons":
+		minimalChanged("icons")
 		gOpts.icons = true
 	case "noicons":
+		minimalChanged("icons")
 		gOpts.icons = false
 	case "icons!":
+		minimalChanged("icons")
 		gOpts.icons = !gOpts.icons
 	case "ignorecase":
 		gOpts.ignorecase = true
@@ -110,6 +195,24 @@ func (e *setExpr) eval(app *app, args []string) {
 		gOpts.incsearch = false
 	case "incsearch!":
 		gOpts.incsearch = !gOpts.incsearch
+	case "infocursor":
+		gOpts.infocursor = true
+	case "noinfocursor":
+		gOpts.infocursor = false
+	case "infocursor!":
+		gOpts.infocursor = !gOpts.infocursor
+	case "minimal":
+		setMinimal(true)
+		app.ui.renew()
+		app.nav.height = app.ui.wins[0].h
+	case "nominimal":
+		setMinimal(false)
+		app.ui.renew()
+		app.nav.height = app.ui.wins[0].h
+	case "minimal!":
+		setMinimal(!gOpts.minimal)
+		app.ui.renew()
+		app.nav.height = app.ui.wins[0].h
 	case "number":
 		gOpts.number = true
 	case "nonumber":
@@ -122,20 +225,72 @@ func (e *setExpr) eval(app *app, args []string) {
 			return
 		}
 		gOpts.preview = true
+		app.ui.renew()
 	case "nopreview":
 		gOpts.preview = false
+		app.ui.renew()
 	case "preview!":
 		if len(gOpts.ratios) < 2 {
 			app.ui.echoerr("preview: 'ratios' should consist of at least two numbers before enabling 'preview'")
 			return
 		}
 		gOpts.preview = !gOpts.preview
+		app.ui.renew()
+	case "previewsearch":
+		gOpts.previewsearch = true
+		app.nav.regCache = make(map[string]*reg)
+		app.ui.loadFile(app.nav, true)
+	case "nopreviewsearch":
+		gOpts.previewsearch = false
+		app.nav.regCache = make(map[string]*reg)
+		app.ui.loadFile(app.nav, true)
+	case "previewsearch!":
+		gOpts.previewsearch = !gOpts.previewsearch
+		app.nav.regCache = make(map[string]*reg)
+		app.ui.loadFile(app.nav, true)
+	case "previewwrap":
+		gOpts.previewwrap = true
+	case "nopreviewwrap":
+		gOpts.previewwrap = false
+	case "previewwrap!":
+		gOpts.previewwrap = !gOpts.previewwrap
+	case "quitiflast":
+		gOpts.quitiflast = true
+	case "noquitiflast":
+		gOpts.quitiflast = false
+	case "quitiflast!":
+		gOpts.quitiflast = !gOpts.quitiflast
+	case "readonly":
+		gOpts.readonly = true
+	case "noreadonly":
+		if gOpts.readonly {
+			app.ui.echoerr("noreadonly: can not be disabled in read-only mode")
+			return
+		}
+	case "readonly!":
+		if gOpts.readonly {
+			app.ui.echoerr("readonly!: can not be disabled in read-only mode")
+			return
+		}
+		gOpts.readonly = true
 	case "relativenumber":
 		gOpts.relativenumber = true
 	case "norelativenumber":
 		gOpts.relativenumber = false
 	case "relativenumber!":
 		gOpts.relativenumber = !gOpts.relativenumber
+	case "relativetime":
+		gOpts.relativetime = true
+	case "norelativetime":
+		gOpts.relativetime = false
+	case "relativetime!":
+		gOpts.relativetime = !gOpts.relativetime
+	case "rewritelinks":
+		gOpts.rewritelinks = true
+	case "norewritelinks":
+		gOpts.rewritelinks = false
+	case "rewritelinks!":
+		gOpts.rewritelinks = !gOpts.rewritelinks
 	case "reverse":
 		gOpts.sortType.option |= reverseSort
 		app.nav.sort()
@@ -148,6 +303,21 @@ func (e *setExpr) eval(app *app, args []string) {
 		gOpts.sortType.option ^= reverseSort
 		app.nav.sort()
 		app.ui.sort()
+	case "scrollbar":
+		gOpts.scrollbar = true
+	case "noscrollbar":
+		gOpts.scrollbar = false
+	case "scrollbar!":
+		gOpts.scrollbar = !gOpts.scrollbar
+	case "selectioninfo":
+		gOpts.selectioninfo = true
+		app.nav.countSelections()
+	case "noselectioninfo":
+		gOpts.selectioninfo = false
+		app.nav.countSelections()
+	case "selectioninfo!":
+		gOpts.selectioninfo = !gOpts.selectioninfo
+		app.nav.countSelections()
 	case "smartcase":
 		gOpts.smartcase = true
 	case "nosmartcase":
@@ -160,6 +330,24 @@ func (e *setExpr) eval(app *app, args []string) {
 		gOpts.smartdia = false
 	case "smartdia!":
 		gOpts.smartdia = !gOpts.smartdia
+	case "sortindicator":
+		gOpts.sortindicator = true
+	case "nosortindicator":
+		gOpts.sortindicator = false
+	case "sortindicator!":
+		gOpts.sortindicator = !gOpts.sortindicator
+	case "templatesubst":
+		gOpts.templatesubst = true
+	case "notemplatesubst":
+		gOpts.templatesubst = false
+	case "templatesubst!":
+		gOpts.templatesubst = !gOpts.templatesubst
+	case "whichkey":
+		gOpts.whichkey = true
+	case "nowhichkey":
+		gOpts.whichkey = false
+	case "whichkey!":
+		gOpts.whichkey = !gOpts.whichkey
 	case "wrapscan":
 		gOpts.wrapscan = true
 	case "nowrapscan":
@@ -183,6 +371,80 @@ func (e *setExpr) eval(app *app, args []string) {
 			return
 		}
 		gOpts.findlen = n
+	case "notifytime":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("notifytime: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("notifytime: value should be a non-negative number")
+			return
+		}
+		gOpts.notifytime = n
+	case "cmdtimeout":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("cmdtimeout: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("cmdtimeout: value should be a non-negative number")
+			return
+		}
+		gOpts.cmdtimeout = n
+	case "parentwidth":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("parentwidth: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("parentwidth: value should be a non-negative number")
+			return
+		}
+		gOpts.parentwidth = n
+		app.ui.renew()
+		app.ui.loadFile(app.nav, true)
+	case "previewbytes":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("previewbytes: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("previewbytes: value should be a non-negative number")
+			return
+		}
+		gOpts.previewbytes = n
+		app.nav.regCache = make(map[string]*reg)
+		app.ui.loadFile(app.nav, true)
+	case "previewlines":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("previewlines: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("previewlines: value should be a non-negative number")
+			return
+		}
+		gOpts.previewlines = n
+		app.nav.regCache = make(map[string]*reg)
+		app.ui.loadFile(app.nav, true)
+	case "previewwidth":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("previewwidth: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("previewwidth: value should be a non-negative number")
+			return
+		}
+		gOpts.previewwidth = n
+		app.ui.renew()
+		app.ui.loadFile(app.nav, true)
 	case "period":
 		n, err := strconv.Atoi(e.val)
 		if err != nil {
@@ -194,12 +456,30 @@ func (e *setExpr) eval(app *app, args []string) {
 			return
 		}
 		gOpts.period = n
-		if n == 0 {
-			app.ticker.Stop()
-		} else {
-			app.ticker.Stop()
-			app.ticker = time.NewTicker(time.Duration(gOpts.period) * time.Second)
+		app.setTicker()
+	case "incsearchdelay":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("incsearchdelay: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("incsearchdelay: value should be a non-negative number")
+			return
+		}
+		gOpts.incsearchdelay = n
+	case "idleperiod":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("idleperiod: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("idleperiod: value should be a non-negative number")
+			return
 		}
+		app.setFocusOpt(func() { gOpts.idleperiod = n })
+		app.setTicker()
 	case "scrolloff":
 		n, err := strconv.Atoi(e.val)
 		if err != nil {
@@ -222,10 +502,75 @@ func (e *setExpr) eval(app *app, args []string) {
 			return
 		}
 		gOpts.tabstop = n
+	case "waittimeout":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("waittimeout: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("waittimeout: value should be a non-negative number")
+			return
+		}
+		gOpts.waittimeout = n
+	case "whichkeydelay":
+		n, err := strconv.Atoi(e.val)
+		if err != nil {
+			app.ui.echoerrf("whichkeydelay: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("whichkeydelay: value should be a non-negative number")
+			return
+		}
+		gOpts.whichkeydelay = n
 	case "errorfmt":
 		gOpts.errorfmt = e.val
 	case "filesep":
 		gOpts.filesep = e.val
+	case "badges":
+		var toks []string
+		if e.val != "" {
+			toks = strings.Split(e.val, ":")
+		}
+		seen := make(map[string]bool)
+		for _, s := range toks {
+			name, _, err := parseBadge(s)
+			if err != nil {
+				app.ui.echoerrf("badges: %s", err)
+				return
+			}
+			if seen[name] {
+				app.ui.echoerrf("badges: duplicate badge: %s", name)
+				return
+			}
+			seen[name] = true
+		}
+		gOpts.badges = toks
+	case "grouporder":
+		var toks []string
+		if e.val != "" {
+			toks = strings.Split(e.val, ":")
+		}
+		seen := make(map[string]bool)
+		for _, s := range toks {
+			switch s {
+			case "dir", "link", "file", "hidden":
+			default:
+				app.ui.echoerr("grouporder: should consist of 'dir', 'link', 'file' or 'hidden' separated with colon")
+				return
+			}
+			if seen[s] {
+				app.ui.echoerrf("grouporder: duplicate group: %s", s)
+				return
+			}
+			seen[s] = true
+		}
+		gOpts.grouporder = toks
+		app.nav.sort()
+		app.nav.position()
+		app.ui.sort()
+		app.ui.loadFile(app.nav, true)
 	case "hiddenfiles":
 		toks := strings.Split(e.val, ":")
 		for _, s := range toks {
@@ -248,23 +593,127 @@ func (e *setExpr) eval(app *app, args []string) {
 		gOpts.ifs = e.val
 	case "info":
 		if e.val == "" {
+			minimalChanged("info")
 			gOpts.info = nil
 			return
 		}
 		toks := strings.Split(e.val, ":")
 		for _, s := range toks {
 			switch s {
-			case "size", "time", "atime", "ctime":
+			case "size", "childcount", "time", "atime", "ctime", "perm", "xattr":
 			default:
-				app.ui.echoerr("info: should consist of 'size', 'time', 'atime' or 'ctime' separated with colon")
+				app.ui.echoerr("info: should consist of 'size', 'childcount', 'time', 'atime', 'ctime', 'perm' or 'xattr' separated with colon")
 				return
 			}
 		}
+		minimalChanged("info")
 		gOpts.info = toks
+	case "notify":
+		switch e.val {
+		case "", "bell", "desktop":
+		default:
+			app.ui.echoerr("notify: value should either be 'bell' or 'desktop' or empty")
+			return
+		}
+		gOpts.notify = e.val
+	case "templatedir":
+		gOpts.templatedir = e.val
+	case "periodcheck":
+		switch e.val {
+		case "mtime", "count", "files":
+		default:
+			app.ui.echoerr("periodcheck: value should either be 'mtime', 'count', or 'files'")
+			return
+		}
+		gOpts.periodcheck = e.val
+	case "permfmt":
+		switch e.val {
+		case "symbolic", "octal":
+		default:
+			app.ui.echoerr("permfmt: value should either be 'symbolic' or 'octal'")
+			return
+		}
+		gOpts.permfmt = e.val
+		app.ui.loadFileInfo(app.nav)
+	case "previewansi":
+		switch e.val {
+		case "render", "strip", "raw":
+		default:
+			app.ui.echoerr("previewansi: value should either be 'render', 'strip', or 'raw'")
+			return
+		}
+		gOpts.previewansi = e.val
+		app.nav.regCache = make(map[string]*reg)
+		app.ui.loadFile(app.nav, true)
 	case "previewer":
 		gOpts.previewer = replaceTilde(e.val)
 	case "cleaner":
 		gOpts.cleaner = replaceTilde(e.val)
+	case "cursorline":
+		gOpts.cursorline = e.val
+	case "cursorinactive":
+		app.setFocusOpt(func() { gOpts.cursorinactive = e.val })
+	case "cursorselected":
+		gOpts.cursorselected = e.val
+	case "selectedline":
+		gOpts.selectedline = e.val
+	case "searchmatch":
+		gOpts.searchmatch = e.val
+	case "boxchars":
+		if _, ok := gBoxChars[e.val]; !ok {
+			app.ui.echoerr("boxchars: value should either be 'ascii', 'single', 'rounded', or 'double'")
+			return
+		}
+		gOpts.boxchars = e.val
+	case "boxstyle":
+		gOpts.boxstyle = e.val
+	case "pasteconflict":
+		if _, ok := gConflictPolicies[e.val]; !ok {
+			app.ui.echoerr("pasteconflict: value should either be 'rename', 'overwrite', or 'skip'")
+			return
+		}
+		gOpts.pasteconflict = e.val
+	case "filemanager":
+		gOpts.filemanager = e.val
+	case "heatmap":
+		switch e.val {
+		case "", "override", "combine":
+		default:
+			app.ui.echoerr("heatmap: value should either be empty, 'override', or 'combine'")
+			return
+		}
+		gOpts.heatmap = e.val
+	case "heatmapcolors":
+		if _, _, err := parseHeatColors(e.val); err != nil {
+			app.ui.echoerrf("heatmapcolors: %s", err)
+			return
+		}
+		gOpts.heatmapcolors = e.val
+	case "heatmapwindow":
+		if _, err := parseHeatWindow(e.val); err != nil {
+			app.ui.echoerrf("heatmapwindow: %s", err)
+			return
+		}
+		gOpts.heatmapwindow = e.val
+	case "difftool":
+		gOpts.difftool = e.val
+	case "dirlinkicon":
+		switch e.val {
+		case "link", "dir", "dirlink":
+		default:
+			app.ui.echoerr("dirlinkicon: value should either be 'link', 'dir', or 'dirlink'")
+			return
+		}
+		gOpts.dirlinkicon = e.val
+	case "iconset":
+		switch e.val {
+		case "basic", "extended":
+		default:
+			app.ui.echoerr("iconset: value should either be 'basic' or 'extended'")
+			return
+		}
+		gOpts.iconset = e.val
+		app.ui.icons = parseIcons()
 	case "promptfmt":
 		gOpts.promptfmt = e.val
 	case "ratios":
@@ -309,17 +758,29 @@ func (e *setExpr) eval(app *app, args []string) {
 			gOpts.sortType.method = atimeSort
 		case "ext":
 			gOpts.sortType.method = extSort
+		case "manual":
+			gOpts.sortType.method = manualSort
+		case "command":
+			gOpts.sortType.method = commandSort
 		default:
-			app.ui.echoerr("sortby: value should either be 'natural', 'name', 'size', 'time', 'atime', 'ctime' or 'ext'")
+			app.ui.echoerr("sortby: value should either be 'natural', 'name', 'size', 'time', 'atime', 'ctime', 'ext', 'manual' or 'command'")
 			return
 		}
 		app.nav.sort()
 		app.ui.sort()
+	case "sortcmd":
+		gOpts.sortcmd = e.val
+		if gOpts.sortType.method == commandSort {
+			app.nav.sort()
+			app.ui.sort()
+		}
+	case "terminal":
+		gOpts.terminal = e.val
 	case "timefmt":
 		gOpts.timefmt = e.val
 	case "truncatechar":
-		if runeSliceWidth([]rune(e.val)) != 1 {
-			app.ui.echoerr("truncatechar: value should be a single character")
+		if runeSliceWidth([]rune(e.val)) == 0 {
+			app.ui.echoerr("truncatechar: value should not be empty")
 			return
 		}
 
@@ -332,19 +793,21 @@ func (e *setExpr) eval(app *app, args []string) {
 }
 
 func (e *mapExpr) eval(app *app, args []string) {
+	keys := normalizeKeys(e.keys)
 	if e.expr == nil {
-		delete(gOpts.keys, e.keys)
+		delete(gOpts.keys, keys)
 	} else {
-		gOpts.keys[e.keys] = e.expr
+		gOpts.keys[keys] = e.expr
 	}
 	app.ui.loadFileInfo(app.nav)
 }
 
 func (e *cmapExpr) eval(app *app, args []string) {
+	key := normalizeKeys(e.key)
 	if e.cmd == "" {
-		delete(gOpts.cmdkeys, e.key)
+		delete(gOpts.cmdkeys, key)
 	} else {
-		gOpts.cmdkeys[e.key] = &callExpr{e.cmd, nil, 1}
+		gOpts.cmdkeys[key] = &callExpr{e.cmd, nil, 1}
 	}
 	app.ui.loadFileInfo(app.nav)
 }
@@ -359,11 +822,19 @@ func (e *cmdExpr) eval(app *app, args []string) {
 }
 
 func onChdir(app *app) {
+	app.nav.syncSearch()
+	app.visitDir()
 	if cmd, ok := gOpts.cmds["on-cd"]; ok {
 		cmd.eval(app, nil)
 	}
 }
 
+// This function decides whether 'open' command should change the working
+// directory to the given file instead of passing it to the 'open' command.
+func enterable(f os.FileInfo) bool {
+	return f.IsDir() && !gOpts.diropener
+}
+
 func splitKeys(s string) (keys []string) {
 	for i := 0; i < len(s); {
 		r, w := utf8.DecodeRuneInString(s[i:])
@@ -389,35 +860,58 @@ func update(app *app) {
 	app.ui.menuSelected = -2
 
 	switch {
-	case gOpts.incsearch && app.ui.cmdPrefix == "/":
-		app.nav.search = string(app.ui.cmdAccLeft) + string(app.ui.cmdAccRight)
+	case gOpts.incsearch && (app.ui.cmdPrefix == "/" || app.ui.cmdPrefix == "?"):
+		if app.debounceSearch() {
+			incsearch(app)
+		}
+	case app.ui.cmdPrefix == "command-palette: ":
+		updatePalette(app)
+	case app.ui.cmdPrefix == "trash-list: ":
+		app.ui.menuBuf = listTrashItems(app.trashItems)
+	case app.ui.cmdPrefix == "shell-pick: " || app.ui.cmdPrefix == "list-selection: " || app.ui.cmdPrefix == "mounts: " || app.ui.cmdPrefix == "unmount: " || app.ui.cmdPrefix == "workspace-list: " || app.ui.cmdPrefix == "new-from-template: ":
+		app.ui.menuBuf = listItems(app.pickItems)
+	}
+}
 
-		dir := app.nav.currDir()
-		dir.ind = app.nav.searchInd
-		dir.pos = app.nav.searchPos
+// This function jumps to the match of the pattern typed so far in search
+// prompts when 'incsearch' is enabled. It does nothing when the prompt is
+// already closed since delayed searches can be run afterwards.
+func incsearch(app *app) {
+	if !gOpts.incsearch || (app.ui.cmdPrefix != "/" && app.ui.cmdPrefix != "?") {
+		return
+	}
+
+	app.nav.search = string(app.ui.cmdAccLeft) + string(app.ui.cmdAccRight)
 
+	dir := app.nav.currDir()
+	dir.ind = app.nav.searchInd
+	dir.pos = app.nav.searchPos
+
+	if app.ui.cmdPrefix == "/" {
 		if err := app.nav.searchNext(); err != nil {
 			app.ui.echoerrf("search: %s: %s", err, app.nav.search)
 			return
 		}
-
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case gOpts.incsearch && app.ui.cmdPrefix == "?":
-		app.nav.search = string(app.ui.cmdAccLeft) + string(app.ui.cmdAccRight)
-
-		dir := app.nav.currDir()
-		dir.ind = app.nav.searchInd
-		dir.pos = app.nav.searchPos
-
+	} else {
 		if err := app.nav.searchPrev(); err != nil {
 			app.ui.echoerrf("search: %s: %s", err, app.nav.search)
 			return
 		}
+	}
 
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
+	app.ui.loadFile(app.nav, true)
+	app.ui.loadFileInfo(app.nav)
+}
+
+// This function lists the commands matching the text typed so far in the
+// command palette. The list is kept as it is when a number is typed so that
+// an item can be chosen by its index.
+func updatePalette(app *app) {
+	s := string(app.ui.cmdAccLeft) + string(app.ui.cmdAccRight)
+	if _, err := strconv.Atoi(s); err != nil {
+		app.paletteItems = filterPalette(paletteItems(), s)
 	}
+	app.ui.menuBuf = listPalette(app.paletteItems)
 }
 
 func normal(app *app) {
@@ -428,10 +922,15 @@ func normal(app *app) {
 	app.ui.cmdAccRight = nil
 	app.ui.cmdTmp = nil
 	app.ui.cmdPrefix = ""
+
+	app.promptVar = ""
+	app.promptCmd = ""
 }
 
 func insert(app *app, arg string) {
 	switch {
+	case app.promptVar != "":
+		app.ui.cmdAccLeft = append(app.ui.cmdAccLeft, []rune(arg)...)
 	case gOpts.incsearch && (app.ui.cmdPrefix == "/" || app.ui.cmdPrefix == "?"):
 		app.ui.cmdAccLeft = append(app.ui.cmdAccLeft, []rune(arg)...)
 		update(app)
@@ -509,6 +1008,31 @@ func insert(app *app, arg string) {
 			app.ui.loadFile(app.nav, true)
 			app.ui.loadFileInfo(app.nav)
 		}
+	case strings.HasPrefix(app.ui.cmdPrefix, "trash-empty"):
+		normal(app)
+
+		items := app.trashItems
+		app.trashItems = nil
+		if arg == "y" {
+			go emptyTrashAsync(app.ui, items)
+		}
+	case strings.HasPrefix(app.ui.cmdPrefix, "rename-case") || strings.HasPrefix(app.ui.cmdPrefix, "rename-ext") || strings.HasPrefix(app.ui.cmdPrefix, "rename-seq") || strings.HasPrefix(app.ui.cmdPrefix, "pipe-rename"):
+		name := strings.Fields(app.ui.cmdPrefix)[0]
+
+		normal(app)
+
+		if arg == "y" {
+			if err := app.nav.renameBulk(); err != nil {
+				app.ui.echoerrf("%s: %s", name, err)
+			}
+			app.nav.unselect()
+			if err := remote("send load"); err != nil {
+				app.ui.echoerrf("%s: %s", name, err)
+				return
+			}
+			app.ui.loadFile(app.nav, true)
+			app.ui.loadFileInfo(app.nav)
+		}
 	case strings.HasPrefix(app.ui.cmdPrefix, "replace"):
 		normal(app)
 
@@ -543,6 +1067,9 @@ func insert(app *app, arg string) {
 			app.ui.loadFile(app.nav, true)
 			app.ui.loadFileInfo(app.nav)
 		}
+	case app.ui.cmdPrefix == "shell-pick: " || app.ui.cmdPrefix == "list-selection: " || app.ui.cmdPrefix == "mounts: " || app.ui.cmdPrefix == "unmount: " || app.ui.cmdPrefix == "workspace-list: " || app.ui.cmdPrefix == "command-palette: " || app.ui.cmdPrefix == "trash-list: " || app.ui.cmdPrefix == "new-from-template: ":
+		app.ui.cmdAccLeft = append(app.ui.cmdAccLeft, []rune(arg)...)
+		update(app)
 	case app.ui.cmdPrefix == "mark-save: ":
 		normal(app)
 
@@ -582,6 +1109,12 @@ func insert(app *app, arg string) {
 			app.nav.marks["'"] = wd
 			onChdir(app)
 		}
+	case app.ui.cmdPrefix == "bookmark-set: ":
+		normal(app)
+		app.setBookmark("bookmark-set", arg)
+	case app.ui.cmdPrefix == "bookmark-list: ":
+		normal(app)
+		app.jumpBookmark("bookmark-list", arg)
 	case app.ui.cmdPrefix == "mark-remove: ":
 		normal(app)
 		if err := app.nav.removeMark(arg); err != nil {
@@ -600,50 +1133,608 @@ func insert(app *app, arg string) {
 	}
 }
 
-func (e *callExpr) eval(app *app, args []string) {
-	switch e.name {
-	case "up":
-		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
-			normal(app)
+// This function goes to the newly created file with the given path after
+// reloading the directories. The directory of the file may be different from
+// the current directory when the file is created in a subdirectory.
+func (app *app) selectCreated(path string) error {
+	if err := remote("send load"); err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Printf("getting current directory: %s", err)
+	}
+
+	if err := app.nav.sel(path); err != nil {
+		return err
+	}
+
+	app.ui.loadFile(app.nav, true)
+	app.ui.loadFileInfo(app.nav)
+
+	if dir := filepath.Dir(path); wd != dir {
+		app.nav.marks["'"] = wd
+		onChdir(app)
+	}
+
+	return nil
+}
+
+// This function starts reading the name of the file to create from the given
+// template in the command line with the name of the template as the default.
+func (app *app) readTemplateName(tmpl string) {
+	app.templateName = tmpl
+	app.ui.cmdPrefix = "new file: "
+	app.ui.cmdAccLeft = rawRunes(tmpl)
+}
+
+var reEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// This function exports the input of the pending 'prompt' command as an
+// environment variable and then evaluates its command. The pending state is
+// cleared beforehand so that the command can start another prompt itself.
+func (app *app) acceptPrompt(s string) {
+	name, cmd := app.promptVar, app.promptCmd
+	normal(app)
+
+	log.Printf("prompt: %s=%s -- %s", name, s, cmd)
+	os.Setenv(name, s)
+
+	p := newParser(strings.NewReader(cmd))
+	for p.parse() {
+		p.expr.eval(app, nil)
+	}
+	if p.err != nil {
+		app.ui.echoerrf("prompt: %s", p.err)
+	}
+}
+
+// This function expands the placeholders in the given string for 'print'
+// command. '$f' is replaced with the given current file, '$fs' with the given
+// selected files, and '$fx' with the selected files if there are any or the
+// current file otherwise. Files are separated with 'filesep'. '${opt:name}' is
+// replaced with the value of the given option and '$$' with a single '$'.
+// Other words starting with '$' are kept as they are.
+func expandPrint(s, curr string, sel []string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
 		}
-		app.nav.up(e.count)
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case "half-up":
-		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
-			normal(app)
+
+		rest := s[i+1:]
+
+		if rest[0] == '$' {
+			b.WriteByte('$')
+			i++
+			continue
 		}
-		app.nav.up(e.count * app.nav.height / 2)
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case "page-up":
-		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
-			normal(app)
+
+		if strings.HasPrefix(rest, "{opt:") {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder: %s", s[i:])
+			}
+			name := rest[len("{opt:"):end]
+			val, ok := optValue(name)
+			if !ok {
+				return "", fmt.Errorf("unknown option: %s", name)
+			}
+			b.WriteString(val)
+			i += end + 1
+			continue
 		}
-		app.nav.up(e.count * app.nav.height)
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case "down":
-		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
-			normal(app)
+
+		n := 0
+		for n < len(rest) && (rest[n] == '_' || unicode.IsLetter(rune(rest[n])) || unicode.IsDigit(rune(rest[n]))) {
+			n++
 		}
-		app.nav.down(e.count)
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case "half-down":
-		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
-			normal(app)
+
+		switch rest[:n] {
+		case "f":
+			b.WriteString(curr)
+		case "fs":
+			b.WriteString(strings.Join(sel, gOpts.filesep))
+		case "fx":
+			if len(sel) == 0 {
+				b.WriteString(curr)
+			} else {
+				b.WriteString(strings.Join(sel, gOpts.filesep))
+			}
+		default:
+			b.WriteByte('$')
+			continue
 		}
-		app.nav.down(e.count * app.nav.height / 2)
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case "page-down":
-		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
-			normal(app)
+		i += n
+	}
+
+	return b.String(), nil
+}
+
+// This function expands the given arguments of 'print' command with the
+// current file and the selected files.
+func (app *app) printString(args []string) (string, error) {
+	var curr string
+	if f, err := app.nav.currFile(); err == nil {
+		curr = f.path
+	}
+	return expandPrint(strings.Join(args, " "), curr, app.nav.currSelections())
+}
+
+// This function evaluates the given commands of 'batch' command in order.
+// Failing commands are counted and reported at the end without aborting the
+// rest of the batch, unless errexit is set in which case the batch is stopped
+// at the first failing command. Commands are failed when they show an error.
+func (app *app) runBatch(cmds []string, errexit bool) {
+	var failed int
+	var last string
+
+	for i, cmd := range cmds {
+		app.ui.lastErr = ""
+
+		p := newParser(strings.NewReader(cmd))
+		for p.parse() {
+			p.expr.eval(app, nil)
+		}
+		if p.err != nil {
+			app.ui.echoerrf("%s", p.err)
+		}
+
+		if app.ui.lastErr == "" {
+			continue
+		}
+
+		failed++
+		last = app.ui.lastErr
+
+		if errexit {
+			app.ui.echoerrf("batch: stopped at command %d of %d: %s", i+1, len(cmds), last)
+			return
+		}
+	}
+
+	if failed > 0 {
+		app.ui.echoerrf("batch: %d of %d commands failed: %s", failed, len(cmds), last)
+	}
+}
+
+func bulkRenamePrompt(name string, renames [][2]string) string {
+	if len(renames) == 1 {
+		return name + " '" + filepath.Base(renames[0][0]) + "' to '" + filepath.Base(renames[0][1]) + "' ? [y/N] "
+	}
+	return name + " " + strconv.Itoa(len(renames)) + " items? [y/N] "
+}
+
+// This is a short description of each builtin command shown with 'cmd-help'
+// command. See the documentation for more detailed explanations.
+var gCmdDescs = map[string]string{
+	"set":                    "set an option",
+	"map":                    "bind a key to an expression in normal mode",
+	"cmap":                   "bind a key to a command in command line mode",
+	"cmd":                    "define a custom command",
+	"up":                     "move the current file selection upwards by one",
+	"half-up":                "move the current file selection upwards by half a page",
+	"page-up":                "move the current file selection upwards by a full page",
+	"down":                   "move the current file selection downwards by one",
+	"half-down":              "move the current file selection downwards by half a page",
+	"page-down":              "move the current file selection downwards by a full page",
+	"preview-scroll-up":      "scroll the preview upwards by one line",
+	"preview-scroll-down":    "scroll the preview downwards by one line",
+	"preview-page-up":        "scroll the preview upwards by a full page",
+	"preview-page-down":      "scroll the preview downwards by a full page",
+	"updir":                  "change the current directory to the parent directory",
+	"open":                   "change the current directory to the current file or open it",
+	"left":                   "move to the left column in grid layout or change to the parent directory",
+	"right":                  "move to the right column in grid layout or open the current file",
+	"quit":                   "quit lf",
+	"top":                    "move the current file selection to the top of the directory",
+	"bottom":                 "move the current file selection to the bottom of the directory",
+	"goto-line":              "move the current file selection to a line number or a percentage",
+	"next-ext":               "move the current file selection to the next file with the same extension",
+	"prev-ext":               "move the current file selection to the previous file with the same extension",
+	"toggle":                 "toggle the selection of the current file or given files",
+	"invert":                 "reverse the selection of all files in the current directory",
+	"unselect":               "remove the selection of all files in all directories",
+	"reselect":               "restore the last selection cleared by unselect",
+	"mounts":                 "list filesystems and removable devices to change directory to",
+	"unmount":                "list mounted filesystems to unmount",
+	"workspace":              "switch to the workspace with the given name or create it",
+	"workspace-close":        "close the current workspace and switch to the next one",
+	"workspace-list":         "list workspaces in a menu to switch to",
+	"split":                  "open a second pane in the given directory",
+	"split-close":            "close the second pane",
+	"split-focus":            "move the focus to the other pane",
+	"split-copy":             "copy the current file or selected files to the other pane",
+	"split-move":             "move the current file or selected files to the other pane",
+	"wait":                   "run the rest of the command list after the last asynchronous shell command",
+	"sort-next":              "change the sort method to the next one",
+	"move-up":                "move the current file up in the manual order",
+	"move-down":              "move the current file down in the manual order",
+	"move-to-top":            "move the current file to the top of the manual order",
+	"move-to-bottom":         "move the current file to the bottom of the manual order",
+	"toggle-exec":            "flip the execute bits of the current file or selected files",
+	"yank-as":                "copy the current file or selected files to the clipboard for other applications",
+	"yank-relative":          "copy the paths of the current file or selected files relative to the given directory",
+	"checksum":               "compute the checksum of the current file or selected files",
+	"clean-selection":        "remove files that no longer exist from the selection",
+	"list-selection":         "list selected files in all directories in a menu",
+	"copy":                   "save the current file or selected files to the copy buffer",
+	"cut":                    "save the current file or selected files to the cut buffer",
+	"paste":                  "copy or move files in the buffer to the current directory",
+	"follow":                 "go to the files pasted last and select them",
+	"clear":                  "clear the file buffer",
+	"select-paths":           "replace the selection with the given files and go to their common directory",
+	"link-selection-file":    "change the directory or select the files written to 'LF_RESULT' by a shell command",
+	"create":                 "create a file or a directory with the given name",
+	"delete":                 "remove the current file or selected files",
+	"rename":                 "rename the current file",
+	"rename-case":            "change the case of the current file or selected files",
+	"rename-ext":             "change the extension of the current file or selected files",
+	"rename-seq":             "rename the current file or selected files with sequential numbers",
+	"pipe-rename":            "rename the current file or selected files with the output of a filter",
+	"draw":                   "draw the screen",
+	"redraw":                 "synchronize the terminal and redraw the screen",
+	"load":                   "load modified files and directories",
+	"reload":                 "flush the cache and reload all files and directories",
+	"clear-cache":            "flush the given caches or all caches",
+	"sync":                   "synchronize the file buffer and marks with the server",
+	"read":                   "read a command to evaluate",
+	"shell":                  "read a shell command to execute",
+	"shell-pipe":             "read a shell command to execute piping its output to the ui",
+	"shell-wait":             "read a shell command to execute and wait for a key press",
+	"shell-async":            "read a shell command to execute asynchronously",
+	"shell-pick":             "pick a line from the output of a shell command in a menu",
+	"find":                   "read a character to find the next file starting with it",
+	"find-back":              "read a character to find the previous file starting with it",
+	"find-next":              "find the next file with the last find character",
+	"find-prev":              "find the previous file with the last find character",
+	"search":                 "read a pattern to search the next file matching it",
+	"search-back":            "read a pattern to search the previous file matching it",
+	"search-next":            "search the next file with the last search pattern",
+	"search-prev":            "search the previous file with the last search pattern",
+	"search-clear":           "clear the last search pattern",
+	"mark-save":              "save the current directory as a bookmark",
+	"mark-load":              "change the current directory to a bookmark",
+	"mark-remove":            "remove a bookmark",
+	"bookmark-set":           "save the current directory as the numbered bookmark",
+	"bookmark-jump":          "change the current directory to the numbered bookmark",
+	"bookmark-list":          "show the numbered bookmarks and change to the chosen one",
+	"z":                      "change to the most frecent directory matching the keywords",
+	"z-list":                 "show the most frecent directories matching the keywords",
+	"echo":                   "print the given arguments to the message line",
+	"echomsg":                "print the given arguments to the message line and the log file",
+	"echoerr":                "print the given arguments as an error to the message line and the log file",
+	"on-type":                "run the command of the first branch matching the current file",
+	"print":                  "print the given arguments with placeholders expanded to the message line",
+	"print-reply":            "send the message of 'print' with the given reply id to the server",
+	"batch":                  "evaluate the given commands in order with a single redraw",
+	"cd":                     "change the current directory to the given argument",
+	"cd-home":                "change the current directory to the home directory",
+	"cd-root":                "change the current directory to the root directory",
+	"cd-gitroot":             "change the current directory to the root of the git repository",
+	"fast-forward":           "descend through directories containing only a single subdirectory",
+	"select":                 "change the current file selection to the given argument",
+	"reveal":                 "move the cursor to the given file in the current directory",
+	"glob-select":            "select files that match the given glob (-hidden to include hidden files)",
+	"glob-select-recursive":  "select files in subdirectories that match the given glob",
+	"select-regex-recursive": "select files in subdirectories that match the given regular expression",
+	"glob-unselect":          "unselect files that match the given glob",
+	"select-size":            "select files with sizes above or below the given threshold",
+	"select-age":             "select files modified before or after the given threshold",
+	"select-duplicates":      "select files with identical contents in the current directory",
+	"command-palette":        "choose a command to run from a list filtered as you type",
+	"new-from-template":      "create a file from a template in a menu and open it",
+	"trash-list":             "list items in the trash in a menu to restore",
+	"trash-empty":            "remove items in the trash permanently or the ones older than the given age",
+	"source":                 "read the given configuration file",
+	"prompt":                 "read a line into an environment variable and then run a command",
+	"cmd-timeout":            "run a command with the given timeout for synchronous shell commands",
+	"push":                   "simulate key pushes given in the argument",
+	"diff":                   "compare two selected files or directories",
+	"edit":                   "open the current file or selected files together in the editor",
+	"xattr":                  "show the names of extended attributes of the current file",
+	"open-fm":                "open the current directory in the file manager of the system",
+	"open-terminal":          "spawn a terminal in the current directory",
+	"archive-extract":        "extract the current file or selected files in an archive to the directory of the archive",
+	"stat":                   "show detailed metadata of the current file",
+	"which-key":              "show the keys that can be pressed after a pending key prefix",
+	"repeat":                 "run the last command modifying files again",
+	"cmd-help":               "show the definition or the description of a command",
+	"cmd-insert":             "insert the given characters to the command line",
+	"cmd-insert-selection":   "insert the quoted paths of the current file or selected files to the command line",
+	"cmd-escape":             "quit the command line mode",
+	"cmd-complete":           "autocomplete the current word",
+	"cmd-menu-complete":      "autocomplete the current word, then select the next match",
+	"cmd-menu-complete-back": "autocomplete the current word, then select the previous match",
+	"cmd-enter":              "execute the current line",
+	"cmd-interrupt":          "interrupt the current shell-pipe command",
+	"cmd-history-next":       "go to the next item in the history",
+	"cmd-history-prev":       "go to the previous item in the history",
+	"cmd-left":               "move the cursor to the left",
+	"cmd-right":              "move the cursor to the right",
+	"cmd-home":               "move the cursor to the beginning of the line",
+	"cmd-end":                "move the cursor to the end of the line",
+	"cmd-delete":             "delete the next character",
+	"cmd-delete-back":        "delete the previous character",
+	"cmd-delete-home":        "delete everything up to the beginning of the line",
+	"cmd-delete-end":         "delete everything up to the end of the line",
+	"cmd-delete-unix-word":   "delete the previous unix word",
+	"cmd-yank":               "paste the buffer content containing the last deleted item",
+	"cmd-transpose":          "transpose the positions of last two characters",
+	"cmd-transpose-word":     "transpose the positions of last two words",
+	"cmd-word":               "move the cursor by one word forward",
+	"cmd-word-back":          "move the cursor by one word backward",
+	"cmd-delete-word":        "delete the next word forward",
+	"cmd-capitalize-word":    "capitalize the current word",
+	"cmd-uppercase-word":     "convert the current word to uppercase",
+	"cmd-lowercase-word":     "convert the current word to lowercase",
+}
+
+// This function returns the definition of the given command if it is a custom
+// command, or the description of it if it is a builtin command. Custom
+// commands are checked first since they can override builtin commands.
+func cmdHelp(name string) (string, error) {
+	if e, ok := gOpts.cmds[name]; ok {
+		if e, ok := e.(*execExpr); ok {
+			var lines []string
+			for _, line := range strings.Split(e.value, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					lines = append(lines, line)
+				}
+			}
+			return fmt.Sprintf("cmd %s %s{{ %s }}", name, e.prefix, strings.Join(lines, "; ")), nil
+		}
+		return fmt.Sprintf("cmd %s %s", name, e), nil
+	}
+
+	if desc, ok := gCmdDescs[name]; ok {
+		return fmt.Sprintf("%s: builtin: %s", name, desc), nil
+	}
+
+	return "", fmt.Errorf("command not found: %s", name)
+}
+
+// This function returns the pattern of glob selection commands and whether
+// hidden files should be matched as well when the pattern is preceded by
+// '-hidden' flag. It returns false when there is not exactly one pattern.
+func globArgs(args []string) (pattern string, hidden bool, ok bool) {
+	if len(args) == 2 && args[0] == "-hidden" {
+		return args[1], true, true
+	}
+	if len(args) != 1 {
+		return "", false, false
+	}
+	return args[0], false, true
+}
+
+// This function returns the pattern of 'select-regex-recursive' command and
+// whether hidden files should be matched and whether the pattern should be
+// matched against relative paths when the pattern is preceded by '-hidden' and
+// '-path' flags in any order. It returns false when there is not exactly one
+// pattern.
+func regexArgs(args []string) (pattern string, hidden, byPath, ok bool) {
+	for len(args) > 1 {
+		switch args[0] {
+		case "-hidden":
+			hidden = true
+		case "-path":
+			byPath = true
+		default:
+			return "", false, false, false
+		}
+		args = args[1:]
+	}
+	if len(args) != 1 {
+		return "", false, false, false
+	}
+	return args[0], hidden, byPath, true
+}
+
+// This function returns the conflict policy given with a flag to 'paste'
+// command, or the default policy of 'pasteconflict' option without a flag.
+func pasteArgs(args []string) (policy conflictPolicy, ok bool) {
+	switch {
+	case len(args) == 0:
+		return currConflictPolicy(), true
+	case len(args) == 1 && strings.HasPrefix(args[0], "-"):
+		policy, ok = gConflictPolicies[args[0][1:]]
+		return policy, ok
+	default:
+		return conflictRename, false
+	}
+}
+
+// These are the commands modifying files which are recorded to be run again
+// with 'repeat' command. Commands are run again as they are so they operate on
+// the current file or selections at the time of repeating.
+var gRepeatable = map[string]bool{
+	"create":      true,
+	"delete":      true,
+	"paste":       true,
+	"rename":      true,
+	"rename-case": true,
+	"rename-ext":  true,
+}
+
+// These are the builtin commands modifying files which are disabled when
+// 'readonly' option is enabled. Shell commands are disabled separately since
+// they may also modify files.
+var gMutating = map[string]bool{
+	"create":      true,
+	"delete":      true,
+	"paste":       true,
+	"rename":      true,
+	"rename-case": true,
+	"rename-ext":  true,
+	"rename-seq":  true,
+	"pipe-rename": true,
+	"toggle-exec": true,
+	"unmount":     true,
+	"shell":       true,
+	"shell-pipe":  true,
+	"shell-wait":  true,
+	"shell-async": true,
+	"shell-pick":  true,
+	"trash-list":  true,
+	"trash-empty": true,
+
+	"new-from-template": true,
+	"archive-extract":   true,
+	"split-copy":        true,
+	"split-move":        true,
+}
+
+// This function renames the current file to the given name, which is relative
+// to the current directory unless it is absolute. Confirmation prompts are
+// opened instead when the parent directory of the new name is missing or there
+// is already another file with the new name.
+func (app *app) renameCurr(s string) {
+	curr, err := app.nav.currFile()
+	if err != nil {
+		app.ui.echoerrf("rename: %s", err)
+		return
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Printf("getting current directory: %s", err)
+		return
+	}
+
+	oldPath := filepath.Join(wd, curr.Name())
+
+	newPath := filepath.Clean(s)
+	if !filepath.IsAbs(newPath) {
+		newPath = filepath.Join(wd, newPath)
+	}
+
+	if oldPath == newPath {
+		return
+	}
+
+	app.nav.renameOldPath = oldPath
+	app.nav.renameNewPath = newPath
+
+	newDir := filepath.Dir(newPath)
+	if _, err := os.Stat(newDir); os.IsNotExist(err) {
+		app.ui.cmdPrefix = "create '" + newDir + "' ? [y/N] "
+		return
+	}
+
+	oldStat, err := os.Stat(oldPath)
+	if err != nil {
+		app.ui.echoerrf("rename: %s", err)
+		return
+	}
+
+	if newStat, err := os.Stat(newPath); !os.IsNotExist(err) && !os.SameFile(oldStat, newStat) {
+		app.ui.cmdPrefix = "replace '" + newPath + "' ? [y/N] "
+		return
+	}
+
+	if err := app.nav.rename(); err != nil {
+		app.ui.echoerrf("rename: %s", err)
+		return
+	}
+
+	if err := remote("send load"); err != nil {
+		app.ui.echoerrf("rename: %s", err)
+		return
+	}
+
+	app.ui.loadFile(app.nav, true)
+	app.ui.loadFileInfo(app.nav)
+}
+
+// This function records the given command to be run again with 'repeat'
+// command. The builtin 'rename' command without arguments only opens the
+// prompt, so it is recorded with the entered name when the prompt is
+// submitted instead.
+func (app *app) recordRepeat(e *callExpr) {
+	if e.name == "rename" && len(e.args) == 0 {
+		if _, ok := gOpts.cmds["rename"]; !ok {
+			return
+		}
+	}
+	if gRepeatable[e.name] {
+		app.repeatCmd = e
+	}
+}
+
+func (e *callExpr) eval(app *app, args []string) {
+	if gOpts.readonly && gMutating[e.name] {
+		app.ui.echoerrf("%s: read-only mode", e.name)
+		return
+	}
+
+	// files in archives can only be extracted since archives are browsed
+	// without extracting them
+	if gMutating[e.name] && e.name != "archive-extract" && isArchivePath(app.nav.currDir().path) {
+		app.ui.echoerrf("%s: archives are read-only", e.name)
+		return
+	}
+
+	app.recordRepeat(e)
+
+	switch e.name {
+	case "up":
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		app.nav.up(e.count * app.nav.currDir().columns())
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "half-up":
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		app.nav.up(e.count * app.nav.height / 2 * app.nav.currDir().columns())
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "page-up":
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		app.nav.up(e.count * app.nav.height * app.nav.currDir().columns())
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "down":
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
 		}
-		app.nav.down(e.count * app.nav.height)
+		app.nav.down(e.count * app.nav.currDir().columns())
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
+	case "half-down":
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		app.nav.down(e.count * app.nav.height / 2 * app.nav.currDir().columns())
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "page-down":
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		app.nav.down(e.count * app.nav.height * app.nav.currDir().columns())
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "preview-scroll-up", "preview-scroll-down", "preview-page-up", "preview-page-down":
+		if !gOpts.preview {
+			return
+		}
+		n := e.count
+		if strings.HasPrefix(e.name, "preview-page-") {
+			n *= app.ui.wins[len(app.ui.wins)-1].h
+		}
+		if strings.HasSuffix(e.name, "-up") {
+			n = -n
+		}
+		if app.nav.scrollPreview(n, app.ui.regPrev) {
+			app.ui.loadFile(app.nav, false)
+		}
 	case "updir":
 		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
 			normal(app)
@@ -667,7 +1758,7 @@ func (e *callExpr) eval(app *app, args []string) {
 			return
 		}
 
-		if curr.IsDir() {
+		if enterable(curr) || browsable(curr) {
 			err := app.nav.open()
 			if err != nil {
 				app.ui.echoerrf("opening directory: %s", err)
@@ -678,65 +1769,407 @@ func (e *callExpr) eval(app *app, args []string) {
 			onChdir(app)
 			return
 		}
-
-		if gSelectionPath != "" {
-			out, err := os.Create(gSelectionPath)
+
+		if isArchivePath(curr.path) {
+			app.ui.echoerr("opening: files in archives should be extracted with 'archive-extract' first")
+			return
+		}
+
+		if gSelectionPath != "" {
+			out, err := os.Create(gSelectionPath)
+			if err != nil {
+				log.Printf("opening selection file: %s", err)
+				return
+			}
+			defer out.Close()
+
+			var path string
+			if list, err := app.nav.currFileOrSelections(); err == nil {
+				path = strings.Join(list, "\n")
+			} else {
+				return
+			}
+
+			_, err = out.WriteString(path)
+			if err != nil {
+				log.Printf("writing selection file: %s", err)
+			}
+
+			app.quitChan <- struct{}{}
+
+			return
+		}
+
+		if cmd, ok := gOpts.cmds["open"]; ok {
+			cmd.eval(app, e.args)
+		}
+	case "left":
+		if app.nav.currDir().columns() == 1 {
+			(&callExpr{"updir", nil, e.count}).eval(app, nil)
+			return
+		}
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		app.nav.left(e.count)
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "right":
+		if app.nav.currDir().columns() == 1 {
+			(&callExpr{"open", nil, e.count}).eval(app, nil)
+			return
+		}
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		app.nav.right(e.count)
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "next-ext", "prev-ext":
+		if app.ui.cmdPrefix != "" && app.ui.cmdPrefix != ">" {
+			normal(app)
+		}
+		for i := 0; i < e.count; i++ {
+			if !app.nav.jumpExt(e.name == "prev-ext") {
+				app.ui.echoerrf("%s: no other file with the same extension", e.name)
+				break
+			}
+		}
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "quit":
+		app.quitChan <- struct{}{}
+	case "top":
+		app.nav.top()
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "bottom":
+		app.nav.bottom()
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "goto-line":
+		if len(e.args) != 1 {
+			app.ui.echoerr("goto-line: requires an argument")
+			return
+		}
+		ind, err := parseLine(e.args[0], len(app.nav.currDir().files))
+		if err != nil {
+			app.ui.echoerrf("goto-line: %s", err)
+			return
+		}
+		app.nav.gotoLine(ind)
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "toggle":
+		if len(e.args) == 0 {
+			app.nav.toggle()
+		} else {
+			dir := app.nav.currDir()
+			for _, path := range e.args {
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir.path, path)
+				}
+				if _, err := os.Lstat(path); !os.IsNotExist(err) {
+					app.nav.toggleSelection(path)
+				} else {
+					app.ui.echoerrf("toggle: %s", err)
+				}
+			}
+		}
+	case "invert":
+		app.nav.invert()
+	case "unselect":
+		app.nav.unselect()
+	case "reselect":
+		if err := app.nav.reselect(); err != nil {
+			app.ui.echoerrf("reselect: %s", err)
+			return
+		}
+	case "list-selection":
+		if len(app.nav.selections) == 0 {
+			app.ui.echoerr("list-selection: no files selected")
+			return
+		}
+		app.pickItems = app.nav.currSelections()
+		app.ui.menuBuf = listItems(app.pickItems)
+		app.ui.cmdPrefix = "list-selection: "
+	case "mounts", "unmount":
+		list, err := listMounts()
+		if err != nil {
+			app.ui.echoerrf("%s: %s", e.name, err)
+			return
+		}
+		app.mountList = nil
+		for _, m := range list {
+			if e.name == "mounts" || m.dir != "" {
+				app.mountList = append(app.mountList, m)
+			}
+		}
+		if len(app.mountList) == 0 {
+			app.ui.echoerrf("%s: no filesystems found", e.name)
+			return
+		}
+		app.pickItems = nil
+		for _, m := range app.mountList {
+			app.pickItems = append(app.pickItems, m.String())
+		}
+		app.ui.menuBuf = listItems(app.pickItems)
+		app.ui.cmdPrefix = e.name + ": "
+	case "workspace":
+		if len(e.args) != 1 {
+			app.ui.echoerr("workspace: requires a name")
+			return
+		}
+
+		wd, err := os.Getwd()
+		if err != nil {
+			log.Printf("getting current directory: %s", err)
+		}
+
+		created, err := app.switchWorkspace(e.args[0])
+		if err != nil {
+			app.ui.echoerrf("workspace: %s", err)
+			return
+		}
+
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+
+		if dir := app.nav.currDir().path; wd != dir {
+			app.nav.marks["'"] = wd
+			onChdir(app)
+		}
+
+		if created {
+			app.ui.echof("workspace: %s (new)", app.workspace)
+		} else {
+			app.ui.echof("workspace: %s", app.workspace)
+		}
+	case "workspace-close":
+		wd, err := os.Getwd()
+		if err != nil {
+			log.Printf("getting current directory: %s", err)
+		}
+
+		quit, err := app.closeWorkspace()
+		if err != nil {
+			app.ui.echoerrf("workspace-close: %s", err)
+			return
+		}
+
+		if quit {
+			app.quitChan <- struct{}{}
+			return
+		}
+
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+
+		if dir := app.nav.currDir().path; wd != dir {
+			app.nav.marks["'"] = wd
+			onChdir(app)
+		}
+
+		app.ui.echof("workspace: %s", app.workspace)
+	case "workspace-list":
+		app.pickItems = app.workspaceNames()
+		app.ui.menuBuf = listItems(app.pickItems)
+		app.ui.cmdPrefix = "workspace-list: "
+	case "wait":
+		// commands are only sequenced within command lists
+	case "sort-next":
+		next := gSortMethods[(int(gOpts.sortType.method)+1)%len(gSortMethods)]
+		(&setExpr{"sortby", next}).eval(app, nil)
+		app.ui.echof("sort-next: %s", gOpts.sortType.indicator())
+	case "move-up", "move-down", "move-to-top", "move-to-bottom":
+		dir := app.nav.currDir()
+		to := 0
+		switch e.name {
+		case "move-up":
+			to = dir.ind - 1
+		case "move-down":
+			to = dir.ind + 1
+		case "move-to-bottom":
+			to = len(dir.files) - 1
+		}
+		if err := app.nav.moveManual(to); err != nil {
+			app.ui.echoerrf("%s: %s", e.name, err)
+			return
+		}
+		app.ui.loadFile(app.nav, true)
+	case "toggle-exec":
+		if len(e.args) > 1 || (len(e.args) == 1 && e.args[0] != "all") {
+			app.ui.echoerr("toggle-exec: argument should either be empty or 'all'")
+			return
+		}
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("toggle-exec: %s", err)
+			return
+		}
+		changed, skipped, err := app.nav.toggleExec(list, len(e.args) == 1)
+		app.nav.renew()
+		app.ui.loadFile(app.nav, true)
+		if err != nil {
+			app.ui.echoerrf("toggle-exec: %s", err)
+			return
+		}
+		if skipped > 0 {
+			app.ui.echof("toggle-exec: %d files changed, %d non-regular files skipped", changed, skipped)
+		} else {
+			app.ui.echof("toggle-exec: %d files changed", changed)
+		}
+	case "select-paths":
+		if len(e.args) == 0 {
+			app.ui.echoerr("select-paths: requires at least one argument")
+			return
+		}
+
+		list := app.nav.selectPaths(e.args)
+		if len(list) == 0 {
+			app.ui.echoerr("select-paths: no such files")
+			return
+		}
+
+		wd, err := os.Getwd()
+		if err != nil {
+			log.Printf("getting current directory: %s", err)
+		}
+
+		dir, name := selectTarget(list)
+		if err := app.nav.sel(filepath.Join(dir, name)); err != nil {
+			app.ui.echoerrf("select-paths: %s", err)
+			return
+		}
+
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+
+		if wd != dir {
+			app.nav.marks["'"] = wd
+			onChdir(app)
+		}
+
+		if skipped := len(e.args) - len(list); skipped > 0 {
+			app.ui.echof("select-paths: %d files selected, %d missing files skipped", len(list), skipped)
+		} else {
+			app.ui.echof("select-paths: %d files selected", len(list))
+		}
+	case "link-selection-file":
+		wd, err := os.Getwd()
+		if err != nil {
+			log.Printf("getting current directory: %s", err)
+		}
+
+		paths, err := readResult(gResultPath, wd)
+		if err != nil {
+			app.ui.echoerrf("link-selection-file: %s", err)
+			return
+		}
+
+		resultExpr(paths).eval(app, nil)
+	case "create":
+		if len(e.args) != 1 {
+			app.ui.echoerr("create: requires an argument")
+			return
+		}
+
+		path, err := app.nav.create(e.args[0])
+		if err != nil {
+			app.ui.echoerrf("create: %s", err)
+			return
+		}
+
+		if err := app.selectCreated(path); err != nil {
+			app.ui.echoerrf("create: %s", err)
+		}
+	case "yank-as":
+		if len(e.args) > 1 {
+			app.ui.echoerr("yank-as: requires at most one argument")
+			return
+		}
+		format := "uri-list"
+		if len(e.args) == 1 {
+			format = e.args[0]
+		}
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("yank-as: %s", err)
+			return
+		}
+		mime, data, err := clipboardPayload(format, list)
+		if err != nil {
+			app.ui.echoerrf("yank-as: %s", err)
+			return
+		}
+		cmd, err := clipboardCommand(mime)
+		if err != nil {
+			app.ui.echoerrf("yank-as: %s", err)
+			return
+		}
+		cmd.Stdin = strings.NewReader(data)
+		if err := cmd.Run(); err != nil {
+			app.ui.echoerrf("yank-as: %s", err)
+			return
+		}
+		app.ui.echof("yank-as: %d files copied to the clipboard", len(list))
+	case "yank-relative":
+		if len(e.args) != 1 {
+			app.ui.echoerr("yank-relative: requires a base directory")
+			return
+		}
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("yank-relative: %s", err)
+			return
+		}
+		var rels []string
+		for _, path := range list {
+			rel, err := relativePath(e.args[0], path, app.nav.currDir().path)
 			if err != nil {
-				log.Printf("opening selection file: %s", err)
-				return
-			}
-			defer out.Close()
-
-			var path string
-			if list, err := app.nav.currFileOrSelections(); err == nil {
-				path = strings.Join(list, "\n")
-			} else {
+				app.ui.echoerrf("yank-relative: %s", err)
 				return
 			}
-
-			_, err = out.WriteString(path)
-			if err != nil {
-				log.Printf("writing selection file: %s", err)
-			}
-
-			app.quitChan <- struct{}{}
-
+			rels = append(rels, rel)
+		}
+		s := strings.Join(rels, "\n")
+		cmd, err := clipboardCommand("text/plain;charset=utf-8")
+		if err != nil {
+			app.ui.echoerrf("yank-relative: %s: %s", err, s)
 			return
 		}
-
-		if cmd, ok := gOpts.cmds["open"]; ok {
-			cmd.eval(app, e.args)
+		cmd.Stdin = strings.NewReader(s)
+		if err := cmd.Run(); err != nil {
+			app.ui.echoerrf("yank-relative: %s: %s", err, s)
+			return
 		}
-	case "quit":
-		app.quitChan <- struct{}{}
-	case "top":
-		app.nav.top()
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case "bottom":
-		app.nav.bottom()
-		app.ui.loadFile(app.nav, true)
-		app.ui.loadFileInfo(app.nav)
-	case "toggle":
-		if len(e.args) == 0 {
-			app.nav.toggle()
+		if len(rels) == 1 {
+			app.ui.echof("yank-relative: %s", s)
 		} else {
-			dir := app.nav.currDir()
-			for _, path := range e.args {
-				if !filepath.IsAbs(path) {
-					path = filepath.Join(dir.path, path)
-				}
-				if _, err := os.Lstat(path); !os.IsNotExist(err) {
-					app.nav.toggleSelection(path)
-				} else {
-					app.ui.echoerrf("toggle: %s", err)
-				}
-			}
+			app.ui.echof("yank-relative: %d paths copied to the clipboard", len(rels))
 		}
-	case "invert":
-		app.nav.invert()
-	case "unselect":
-		app.nav.unselect()
+	case "checksum":
+		if len(e.args) > 1 {
+			app.ui.echoerr("checksum: requires at most one argument")
+			return
+		}
+		algo := "sha256"
+		if len(e.args) == 1 {
+			algo = e.args[0]
+		}
+		if _, err := newHash(algo); err != nil {
+			app.ui.echoerrf("checksum: %s", err)
+			return
+		}
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("checksum: %s", err)
+			return
+		}
+		go checksumAsync(app.ui, list, algo)
+	case "clean-selection":
+		n := app.nav.pruneSelections()
+		app.ui.echof("clean-selection: %d stale entries removed", n)
 	case "copy":
 		if err := app.nav.save(true); err != nil {
 			app.ui.echoerrf("copy: %s", err)
@@ -762,9 +2195,16 @@ func (e *callExpr) eval(app *app, args []string) {
 	case "paste":
 		if cmd, ok := gOpts.cmds["paste"]; ok {
 			cmd.eval(app, e.args)
-		} else if err := app.nav.paste(app.ui); err != nil {
-			app.ui.echoerrf("paste: %s", err)
-			return
+		} else {
+			policy, ok := pasteArgs(e.args)
+			if !ok {
+				app.ui.echoerr("paste: flag should either be '-rename', '-overwrite', or '-skip'")
+				return
+			}
+			if err := app.nav.paste(app.ui, policy); err != nil {
+				app.ui.echoerrf("paste: %s", err)
+				return
+			}
 		}
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
@@ -791,6 +2231,117 @@ func (e *callExpr) eval(app *app, args []string) {
 		}
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
+	case "rename-case":
+		if len(e.args) != 1 {
+			app.ui.echoerr("rename-case: requires an argument")
+			return
+		}
+
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("rename-case: %s", err)
+			return
+		}
+
+		renames, err := caseRenames(list, e.args[0])
+		if err != nil {
+			app.ui.echoerrf("rename-case: %s", err)
+			return
+		}
+
+		if len(renames) == 0 {
+			app.ui.echo("rename-case: nothing to rename")
+			return
+		}
+
+		app.nav.renameList = renames
+		app.ui.cmdPrefix = bulkRenamePrompt("rename-case", renames)
+	case "rename-ext":
+		if len(e.args) > 1 {
+			app.ui.echoerr("rename-ext: requires at most one argument")
+			return
+		}
+
+		var ext string
+		if len(e.args) == 1 {
+			ext = e.args[0]
+		}
+
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("rename-ext: %s", err)
+			return
+		}
+
+		renames, err := extRenames(list, ext)
+		if err != nil {
+			app.ui.echoerrf("rename-ext: %s", err)
+			return
+		}
+
+		if len(renames) == 0 {
+			app.ui.echo("rename-ext: nothing to rename")
+			return
+		}
+
+		app.nav.renameList = renames
+		app.ui.cmdPrefix = bulkRenamePrompt("rename-ext", renames)
+	case "rename-seq":
+		if len(e.args) == 0 {
+			app.ui.echoerr("rename-seq: requires a template")
+			return
+		}
+
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("rename-seq: %s", err)
+			return
+		}
+
+		renames, err := seqRenames(app.nav.sortPaths(list), strings.Join(e.args, " "))
+		if err != nil {
+			app.ui.echoerrf("rename-seq: %s", err)
+			return
+		}
+
+		if len(renames) == 0 {
+			app.ui.echo("rename-seq: nothing to rename")
+			return
+		}
+
+		app.nav.renameList = renames
+		app.ui.cmdPrefix = bulkRenamePrompt("rename-seq", renames)
+	case "pipe-rename":
+		if len(e.args) == 0 {
+			app.ui.echoerr("pipe-rename: requires a shell command")
+			return
+		}
+
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("pipe-rename: %s", err)
+			return
+		}
+
+		names, err := app.pipeNames(strings.Join(e.args, " "), list)
+		if err != nil {
+			app.ui.echoerrf("pipe-rename: %s", err)
+			return
+		}
+
+		renames, err := bulkRenames(list, names)
+		if err != nil {
+			app.ui.echoerrf("pipe-rename: %s", err)
+			return
+		}
+
+		if len(renames) == 0 {
+			app.ui.echo("pipe-rename: nothing to rename")
+			return
+		}
+
+		app.nav.renameList = renames
+		app.ui.cmdPrefix = bulkRenamePrompt("pipe-rename", renames)
 	case "clear":
 		if err := saveFiles(nil, false); err != nil {
 			app.ui.echoerrf("clear: %s", err)
@@ -816,6 +2367,13 @@ func (e *callExpr) eval(app *app, args []string) {
 		}
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
+	case "clear-cache":
+		if err := app.nav.clearCaches(e.args); err != nil {
+			app.ui.echoerrf("clear-cache: %s", err)
+			return
+		}
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
 	case "read":
 		app.ui.cmdPrefix = ":"
 		app.ui.loadFileInfo(app.nav)
@@ -905,6 +2463,56 @@ func (e *callExpr) eval(app *app, args []string) {
 		}
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
+	case "open-fm":
+		dir := realDir(app.nav.currDir().path)
+		args := fileManagerArgs(runtime.GOOS, gOpts.filemanager, dir)
+		if err := startDetached(args, dir); err != nil {
+			app.ui.echoerrf("open-fm: %s", err)
+		}
+	case "open-terminal":
+		launcher := gOpts.terminal
+		if launcher == "" {
+			launcher = os.Getenv("TERMINAL")
+		}
+		dir := realDir(app.nav.currDir().path)
+		args, err := terminalArgs(runtime.GOOS, launcher, dir, exec.LookPath)
+		if err != nil {
+			app.ui.echoerrf("open-terminal: %s", err)
+			return
+		}
+		if err := startDetached(args, dir); err != nil {
+			app.ui.echoerrf("open-terminal: %s", err)
+		}
+	case "archive-extract":
+		if len(e.args) > 1 {
+			app.ui.echoerr("archive-extract: requires at most one argument")
+			return
+		}
+
+		dir := realDir(app.nav.currDir().path)
+		if len(e.args) == 1 {
+			dir = replaceTilde(e.args[0])
+			if !filepath.IsAbs(dir) {
+				wd, err := os.Getwd()
+				if err != nil {
+					app.ui.echoerrf("archive-extract: %s", err)
+					return
+				}
+				dir = filepath.Join(wd, dir)
+			}
+		}
+
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("archive-extract: %s", err)
+			return
+		}
+
+		app.nav.unselect()
+		go app.nav.extractAsync(app.ui, list, dir)
+	case "search-clear":
+		app.nav.clearSearch()
+		app.ui.loadFile(app.nav, true)
 	case "mark-save":
 		app.ui.cmdPrefix = "mark-save: "
 	case "mark-load":
@@ -913,6 +2521,47 @@ func (e *callExpr) eval(app *app, args []string) {
 	case "mark-remove":
 		app.ui.menuBuf = listMarks(app.nav.marks)
 		app.ui.cmdPrefix = "mark-remove: "
+	case "bookmark-set":
+		switch len(e.args) {
+		case 0:
+			app.ui.menuBuf = listBookmarks(app.nav.bookmarks)
+			app.ui.cmdPrefix = "bookmark-set: "
+		case 1:
+			app.setBookmark("bookmark-set", e.args[0])
+		default:
+			app.ui.echoerr("bookmark-set: requires at most one argument")
+		}
+	case "bookmark-jump":
+		if len(e.args) != 1 {
+			app.ui.echoerr("bookmark-jump: requires a bookmark number")
+			return
+		}
+		app.jumpBookmark("bookmark-jump", e.args[0])
+	case "bookmark-list":
+		app.ui.menuBuf = listBookmarks(app.nav.bookmarks)
+		app.ui.cmdPrefix = "bookmark-list: "
+	case "z":
+		if len(e.args) == 0 {
+			app.ui.echoerr("z: requires at least one keyword")
+			return
+		}
+		app.jumpFrecency(e.args)
+	case "z-list":
+		fr, err := app.readFrecency()
+		if err != nil {
+			app.ui.echoerrf("z-list: %s", err)
+			return
+		}
+		now := time.Now()
+		paths := fr.matches(e.args, now)
+		if len(paths) == 0 {
+			app.ui.echoerr("z-list: no matching directory")
+			return
+		}
+		if len(paths) > gFrecencyListLimit {
+			paths = paths[:gFrecencyListLimit]
+		}
+		app.ui.menuBuf = listFrecency(fr, paths, now)
 	case "rename":
 		if cmd, ok := gOpts.cmds["rename"]; ok {
 			cmd.eval(app, e.args)
@@ -920,6 +2569,9 @@ func (e *callExpr) eval(app *app, args []string) {
 				app.ui.echoerrf("rename: %s", err)
 				return
 			}
+		} else if len(e.args) == 1 {
+			app.renameCurr(e.args[0])
+			return
 		} else {
 			curr, err := app.nav.currFile()
 			if err != nil {
@@ -927,7 +2579,7 @@ func (e *callExpr) eval(app *app, args []string) {
 				return
 			}
 			app.ui.cmdPrefix = "rename: "
-			app.ui.cmdAccLeft = append(app.ui.cmdAccLeft, []rune(curr.Name())...)
+			app.ui.cmdAccLeft = append(app.ui.cmdAccLeft, rawRunes(curr.Name())...)
 		}
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
@@ -941,6 +2593,54 @@ func (e *callExpr) eval(app *app, args []string) {
 		app.ui.echomsg(strings.Join(e.args, " "))
 	case "echoerr":
 		app.ui.echoerr(strings.Join(e.args, " "))
+	case "batch":
+		errexit := len(e.args) > 0 && e.args[0] == "-e"
+		if errexit {
+			e.args = e.args[1:]
+		}
+		app.runBatch(e.args, errexit)
+	case "on-type":
+		branches, err := parseTypeBranches(e.args)
+		if err != nil {
+			app.ui.echoerrf("on-type: %s", err)
+			return
+		}
+		curr, err := app.nav.currFile()
+		if err != nil {
+			app.ui.echoerrf("on-type: %s", err)
+			return
+		}
+		cmd, ok := typeCommand(curr, branches)
+		if !ok {
+			app.ui.echoerrf("on-type: no branch for '%s'", curr.Name())
+			return
+		}
+		p := newParser(strings.NewReader(cmd))
+		for p.parse() {
+			p.expr.eval(app, nil)
+		}
+		if p.err != nil {
+			app.ui.echoerrf("on-type: %s", p.err)
+		}
+	case "print":
+		s, err := app.printString(e.args)
+		if err != nil {
+			app.ui.echoerrf("print: %s", err)
+			return
+		}
+		app.ui.echo(s)
+	case "print-reply":
+		if len(e.args) == 0 {
+			app.ui.echoerr("print-reply: requires a reply id")
+			return
+		}
+		s, err := app.printString(e.args[1:])
+		if err != nil {
+			s = "print: " + err.Error()
+		}
+		if err := remote(fmt.Sprintf("reply %s %s", e.args[0], strconv.Quote(s))); err != nil {
+			app.ui.echoerrf("print-reply: %s", err)
+		}
 	case "cd":
 		path := "~"
 		if len(e.args) > 0 {
@@ -952,7 +2652,108 @@ func (e *callExpr) eval(app *app, args []string) {
 			log.Printf("getting current directory: %s", err)
 		}
 
-		if err := app.nav.cd(path); err != nil {
+		if err := app.nav.cd(path); err != nil {
+			app.ui.echoerrf("%s", err)
+			return
+		}
+
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(wd, path)
+		} else {
+			path = filepath.Clean(path)
+		}
+
+		if wd != path {
+			app.nav.marks["'"] = wd
+			onChdir(app)
+		}
+	case "split":
+		if len(e.args) > 1 {
+			app.ui.echoerr("split: requires at most one argument")
+			return
+		}
+		path := app.nav.currDir().path
+		if len(e.args) == 1 {
+			path = replaceUserDir(e.args[0])
+			if !isArchivePath(path) {
+				abs, err := filepath.Abs(path)
+				if err != nil {
+					app.ui.echoerrf("split: %s", err)
+					return
+				}
+				path = abs
+			}
+		}
+		if err := app.nav.openSplit(path); err != nil {
+			app.ui.echoerrf("split: %s", err)
+		}
+	case "split-close":
+		if err := app.nav.closeSplit(); err != nil {
+			app.ui.echoerrf("split-close: %s", err)
+		}
+	case "split-focus":
+		if err := app.nav.focusSplit(); err != nil {
+			app.ui.echoerrf("split-focus: %s", err)
+			return
+		}
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+		onChdir(app)
+	case "split-copy", "split-move":
+		if err := app.nav.splitPaste(app.ui, e.name == "split-copy"); err != nil {
+			app.ui.echoerrf("%s: %s", e.name, err)
+			return
+		}
+		app.nav.unselect()
+		app.ui.loadFileInfo(app.nav)
+	case "cd-home":
+		cmd := &callExpr{"cd", []string{gUser.HomeDir}, 1}
+		cmd.eval(app, nil)
+	case "cd-root":
+		wd := app.nav.currDir().path
+		cmd := &callExpr{"cd", []string{filepath.VolumeName(wd) + string(filepath.Separator)}, 1}
+		cmd.eval(app, nil)
+	case "cd-gitroot":
+		root, ok := gitRoot(app.nav.currDir().path)
+		if !ok {
+			app.ui.echoerr("cd-gitroot: no git repository found")
+			return
+		}
+		cmd := &callExpr{"cd", []string{root}, 1}
+		cmd.eval(app, nil)
+	case "fast-forward":
+		wd := app.nav.currDir().path
+		target := fastForward(wd)
+		if target == wd {
+			app.ui.echoerr("fast-forward: no single subdirectory chain")
+			return
+		}
+
+		if err := app.nav.cd(target); err != nil {
+			app.ui.echoerrf("fast-forward: %s", err)
+			return
+		}
+
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+
+		app.nav.marks["'"] = wd
+		onChdir(app)
+	case "select":
+		if len(e.args) != 1 {
+			app.ui.echoerr("select: requires an argument")
+			return
+		}
+
+		wd, err := os.Getwd()
+		if err != nil {
+			log.Printf("getting current directory: %s", err)
+		}
+
+		if err := app.nav.sel(e.args[0]); err != nil {
 			app.ui.echoerrf("%s", err)
 			return
 		}
@@ -960,6 +2761,7 @@ func (e *callExpr) eval(app *app, args []string) {
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
 
+		path := filepath.Dir(e.args[0])
 		if !filepath.IsAbs(path) {
 			path = filepath.Join(wd, path)
 		} else {
@@ -970,9 +2772,21 @@ func (e *callExpr) eval(app *app, args []string) {
 			app.nav.marks["'"] = wd
 			onChdir(app)
 		}
-	case "select":
+	case "reveal":
 		if len(e.args) != 1 {
-			app.ui.echoerr("select: requires an argument")
+			app.ui.echoerr("reveal: requires an argument")
+			return
+		}
+		if err := app.nav.reveal(e.args[0]); err != nil {
+			app.ui.echoerrf("reveal: %s", err)
+			return
+		}
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+	case "follow":
+		paths, err := app.nav.followPaths()
+		if err != nil {
+			app.ui.echoerrf("follow: %s", err)
 			return
 		}
 
@@ -981,43 +2795,289 @@ func (e *callExpr) eval(app *app, args []string) {
 			log.Printf("getting current directory: %s", err)
 		}
 
-		if err := app.nav.sel(e.args[0]); err != nil {
-			app.ui.echoerrf("%s", err)
+		if err := app.nav.sel(paths[0]); err != nil {
+			app.ui.echoerrf("follow: %s", err)
 			return
 		}
 
+		if len(paths) > 1 {
+			app.nav.unselect()
+			for _, path := range paths {
+				app.nav.toggleSelection(path)
+			}
+		}
+
 		app.ui.loadFile(app.nav, true)
 		app.ui.loadFileInfo(app.nav)
 
-		path := filepath.Dir(e.args[0])
-		if !filepath.IsAbs(path) {
-			path = filepath.Join(wd, path)
-		} else {
-			path = filepath.Clean(path)
-		}
-
-		if wd != path {
+		if path := filepath.Dir(paths[0]); wd != path {
 			app.nav.marks["'"] = wd
 			onChdir(app)
 		}
 	case "glob-select":
-		if len(e.args) != 1 {
+		pattern, hidden, ok := globArgs(e.args)
+		if !ok {
 			app.ui.echoerr("glob-select: requires a pattern to match")
 			return
 		}
-		if err := app.nav.globSel(e.args[0], false); err != nil {
+		if err := app.nav.globSel(pattern, false, hidden); err != nil {
 			app.ui.echoerrf("%s", err)
 			return
 		}
+	case "glob-select-recursive":
+		pattern, hidden, ok := globArgs(e.args)
+		if !ok {
+			app.ui.echoerr("glob-select-recursive: requires a pattern to match")
+			return
+		}
+		if app.walkScan != nil && app.walkScan.running() {
+			close(app.walkScan.stop)
+			app.walkScan = nil
+			return
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			app.ui.echoerrf("glob-select-recursive: %s", err)
+			return
+		}
+		root := app.nav.currDir().path
+		hidden = hidden || gOpts.sortType.option&hiddenSort != 0
+		hiddenfiles := gOpts.hiddenfiles
+		app.walkScan = newWalkScan()
+		app.ui.echo("glob-select-recursive: searching...")
+		go walkSelAsync(app.ui, "glob-select-recursive", pattern, app.walkScan, func(stop <-chan struct{}) ([]string, bool, error) {
+			return globWalk(root, pattern, gGlobSelectLimit, hidden, hiddenfiles, stop)
+		})
+	case "select-regex-recursive":
+		pattern, hidden, byPath, ok := regexArgs(e.args)
+		if !ok {
+			app.ui.echoerr("select-regex-recursive: requires a pattern to match")
+			return
+		}
+		if app.walkScan != nil && app.walkScan.running() {
+			close(app.walkScan.stop)
+			app.walkScan = nil
+			return
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			app.ui.echoerrf("select-regex-recursive: %s", err)
+			return
+		}
+		root := app.nav.currDir().path
+		hidden = hidden || gOpts.sortType.option&hiddenSort != 0
+		hiddenfiles := gOpts.hiddenfiles
+		app.walkScan = newWalkScan()
+		app.ui.echo("select-regex-recursive: searching...")
+		go walkSelAsync(app.ui, "select-regex-recursive", pattern, app.walkScan, func(stop <-chan struct{}) ([]string, bool, error) {
+			return regexWalk(root, re, byPath, gGlobSelectLimit, hidden, hiddenfiles, stop)
+		})
 	case "glob-unselect":
-		if len(e.args) != 1 {
+		pattern, hidden, ok := globArgs(e.args)
+		if !ok {
 			app.ui.echoerr("glob-unselect: requires a pattern to match")
 			return
 		}
-		if err := app.nav.globSel(e.args[0], true); err != nil {
+		if err := app.nav.globSel(pattern, true, hidden); err != nil {
 			app.ui.echoerrf("%s", err)
 			return
 		}
+	case "select-size":
+		if len(e.args) != 1 {
+			app.ui.echoerr("select-size: requires a threshold (e.g. '+100M')")
+			return
+		}
+		t, err := parseSizeThreshold(e.args[0])
+		if err != nil {
+			app.ui.echoerrf("select-size: %s", err)
+			return
+		}
+		count := app.nav.selectMatching(func(f *file) bool {
+			return !f.IsDir() && t.match(f.Size())
+		})
+		app.ui.echof("select-size: %d files selected", count)
+	case "select-age":
+		if len(e.args) != 1 {
+			app.ui.echoerr("select-age: requires a threshold (e.g. '+30d')")
+			return
+		}
+		t, err := parseAgeThreshold(e.args[0])
+		if err != nil {
+			app.ui.echoerrf("select-age: %s", err)
+			return
+		}
+		now := time.Now()
+		count := app.nav.selectMatching(func(f *file) bool {
+			return t.match(int64(now.Sub(f.ModTime()) / time.Second))
+		})
+		app.ui.echof("select-age: %d files selected", count)
+	case "select-duplicates":
+		recursive := false
+		switch {
+		case len(e.args) == 1 && e.args[0] == "-r":
+			recursive = true
+		case len(e.args) != 0:
+			app.ui.echoerr("select-duplicates: requires at most '-r' argument")
+			return
+		}
+		if app.dupScan != nil && app.dupScan.running() {
+			close(app.dupScan.stop)
+			app.dupScan = nil
+			return
+		}
+		app.dupScan = newDupScan()
+		app.ui.echo("select-duplicates: scanning...")
+		go duplicatesAsync(app.ui, app.nav.currDir().path, recursive, app.dupScan)
+	case "xattr":
+		curr, err := app.nav.currFile()
+		if err != nil {
+			app.ui.echoerrf("xattr: %s", err)
+			return
+		}
+		names, err := listXattrs(curr.path)
+		if err != nil {
+			app.ui.echoerrf("xattr: %s", err)
+			return
+		}
+		if len(names) == 0 {
+			app.ui.echo("xattr: no extended attributes")
+			return
+		}
+		app.ui.echof("xattr: %s", strings.Join(names, ", "))
+	case "stat":
+		curr, err := app.nav.currFile()
+		if err != nil {
+			app.ui.echoerrf("stat: %s", err)
+			return
+		}
+		fields, err := statFields(curr.path)
+		if err != nil {
+			app.ui.echoerrf("stat: %s", err)
+			return
+		}
+		app.ui.menuBuf = listStat(fields)
+	case "which-key":
+		if len(e.args) != 1 {
+			app.ui.echoerr("which-key: requires a key prefix")
+			return
+		}
+		// delayed popups are only shown if the prefix is still pending
+		if app.ui.cmdPrefix != "" || string(app.ui.keyAcc) != e.args[0] {
+			return
+		}
+		app.ui.menuBuf = listBinds(continuations(gOpts.keys, e.args[0]))
+	case "diff":
+		tool, err := diffTool(app.nav.currSelections())
+		if err != nil {
+			app.ui.echoerrf("diff: %s", err)
+			return
+		}
+		prefix, s, args := diffShell(tool, app.nav.currSelections())
+		log.Printf("diff: %s -- %s", s, args)
+		app.runShell(s, args, prefix)
+	case "edit":
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("edit: %s", err)
+			return
+		}
+		prefix, s, args := editShell(list)
+		log.Printf("edit: %s -- %s", s, args)
+		app.runShell(s, args, prefix)
+	case "repeat":
+		if app.repeatCmd == nil {
+			app.ui.echoerr("repeat: no command to repeat")
+			return
+		}
+		log.Printf("repeat: %s", app.repeatCmd)
+		app.repeatCmd.eval(app, nil)
+	case "cmd-help":
+		if len(e.args) != 1 {
+			app.ui.echoerr("cmd-help: requires a command name")
+			return
+		}
+		s, err := cmdHelp(e.args[0])
+		if err != nil {
+			app.ui.echoerrf("cmd-help: %s", err)
+			return
+		}
+		app.ui.echo(s)
+	case "new-from-template":
+		if len(e.args) > 1 {
+			app.ui.echoerr("new-from-template: requires at most one argument")
+			return
+		}
+		if len(e.args) == 1 {
+			if _, err := templatePath(templateDir(), e.args[0]); err != nil {
+				app.ui.echoerrf("new-from-template: %s", err)
+				return
+			}
+			app.readTemplateName(e.args[0])
+			return
+		}
+		dir := templateDir()
+		list, err := listTemplates(dir)
+		if err != nil {
+			app.ui.echoerrf("new-from-template: %s", err)
+			return
+		}
+		if len(list) == 0 {
+			app.ui.echoerrf("new-from-template: no templates in %s", dir)
+			return
+		}
+		app.pickItems = list
+		app.ui.menuBuf = listItems(list)
+		app.ui.cmdPrefix = "new-from-template: "
+	case "trash-list":
+		items := listTrash()
+		if len(items) == 0 {
+			app.ui.echoerr("trash-list: trash is empty")
+			return
+		}
+		app.trashItems = items
+		app.ui.menuBuf = listTrashItems(items)
+		app.ui.cmdPrefix = "trash-list: "
+	case "trash-empty":
+		if len(e.args) > 1 {
+			app.ui.echoerr("trash-empty: requires at most one argument")
+			return
+		}
+		items := listTrash()
+		if len(e.args) == 1 {
+			t, err := parseAgeThreshold(e.args[0])
+			if err != nil {
+				app.ui.echoerrf("trash-empty: %s", err)
+				return
+			}
+			items = expiredTrash(items, t, time.Now())
+		}
+		if len(items) == 0 {
+			app.ui.echo("trash-empty: nothing to remove")
+			return
+		}
+		app.trashItems = items
+		app.ui.cmdPrefix = "trash-empty " + strconv.Itoa(len(items)) + " items? [y/N] "
+	case "command-palette":
+		app.paletteItems = paletteItems()
+		app.ui.menuBuf = listPalette(app.paletteItems)
+		app.ui.cmdPrefix = "command-palette: "
+	case "shell-pick":
+		if len(e.args) != 2 {
+			app.ui.echoerr("shell-pick: requires a command name and a shell command")
+			return
+		}
+		items, err := app.readPickItems(e.args[1])
+		if err != nil {
+			app.ui.echoerrf("shell-pick: %s", err)
+			return
+		}
+		if len(items) == 0 {
+			app.ui.echoerr("shell-pick: no items to pick")
+			return
+		}
+		app.pickItems = items
+		app.pickCmd = e.args[0]
+		app.ui.menuBuf = listItems(items)
+		app.ui.cmdPrefix = "shell-pick: "
 	case "source":
 		if len(e.args) != 1 {
 			app.ui.echoerr("source: requires an argument")
@@ -1025,6 +3085,43 @@ func (e *callExpr) eval(app *app, args []string) {
 		}
 		app.readFile(replaceTilde(e.args[0]))
 		app.ui.loadFileInfo(app.nav)
+	case "prompt":
+		if len(e.args) != 3 {
+			app.ui.echoerr("prompt: requires a variable name, a default value, and a command")
+			return
+		}
+		if !reEnvName.MatchString(e.args[0]) {
+			app.ui.echoerrf("prompt: invalid variable name: %s", e.args[0])
+			return
+		}
+		normal(app)
+		app.promptVar = e.args[0]
+		app.promptCmd = e.args[2]
+		app.ui.cmdPrefix = e.args[0] + ": "
+		app.ui.cmdAccLeft = rawRunes(e.args[1])
+	case "cmd-timeout":
+		if len(e.args) != 2 {
+			app.ui.echoerr("cmd-timeout: requires a timeout and a command")
+			return
+		}
+		n, err := strconv.Atoi(e.args[0])
+		if err != nil {
+			app.ui.echoerrf("cmd-timeout: %s", err)
+			return
+		}
+		if n < 0 {
+			app.ui.echoerr("cmd-timeout: timeout should be a non-negative number")
+			return
+		}
+		defer func(cmdtimeout int) { gOpts.cmdtimeout = cmdtimeout }(gOpts.cmdtimeout)
+		gOpts.cmdtimeout = n
+		p := newParser(strings.NewReader(e.args[1]))
+		for p.parse() {
+			p.expr.eval(app, nil)
+		}
+		if p.err != nil {
+			app.ui.echoerrf("cmd-timeout: %s", p.err)
+		}
 	case "push":
 		if len(e.args) != 1 {
 			app.ui.echoerr("push: requires an argument")
@@ -1047,6 +3144,55 @@ func (e *callExpr) eval(app *app, args []string) {
 		}
 
 		insert(app, e.args[0])
+	case "cmd-insert-selection":
+		if len(e.args) > 1 {
+			app.ui.echoerr("cmd-insert-selection: requires at most one argument")
+			return
+		}
+
+		name := false
+		if len(e.args) == 1 {
+			switch e.args[0] {
+			case "path":
+			case "name":
+				name = true
+			default:
+				app.ui.echoerr("cmd-insert-selection: argument should either be 'path' or 'name'")
+				return
+			}
+		}
+
+		var quote func(string) string
+		switch app.ui.cmdPrefix {
+		case ":":
+			quote = quoteArg
+		case "$", "%", "!", "&":
+			quote = func(s string) string { return shellQuote(s, runtime.GOOS) }
+		default:
+			app.ui.echoerr("cmd-insert-selection: only works in command and shell lines")
+			return
+		}
+
+		list, err := app.nav.currFileOrSelections()
+		if err != nil {
+			app.ui.echoerrf("cmd-insert-selection: %s", err)
+			return
+		}
+
+		args := make([]string, len(list))
+		for i, path := range list {
+			if name {
+				path = filepath.Base(path)
+			}
+			args[i] = quote(path)
+		}
+
+		if app.ui.menuBuf != nil {
+			app.ui.menuBuf = nil
+			app.ui.menuSelected = -2
+		}
+
+		insert(app, strings.Join(args, " "))
 	case "cmd-escape":
 		if app.ui.cmdPrefix == ">" {
 			return
@@ -1174,8 +3320,8 @@ func (e *callExpr) eval(app *app, args []string) {
 			app.ui.menuSelected = -2
 		}
 	case "cmd-enter":
-		s := string(append(app.ui.cmdAccLeft, app.ui.cmdAccRight...))
-		if len(s) == 0 {
+		s := rawString(append(app.ui.cmdAccLeft, app.ui.cmdAccRight...))
+		if len(s) == 0 && app.ui.cmdPrefix != "command-palette: " {
 			return
 		}
 
@@ -1186,6 +3332,11 @@ func (e *callExpr) eval(app *app, args []string) {
 		app.ui.cmdAccRight = nil
 		app.ui.cmdTmp = nil
 
+		if app.promptVar != "" {
+			app.acceptPrompt(s)
+			return
+		}
+
 		switch app.ui.cmdPrefix {
 		case ":":
 			log.Printf("command: %s", s)
@@ -1250,61 +3401,151 @@ func (e *callExpr) eval(app *app, args []string) {
 				app.ui.loadFile(app.nav, true)
 				app.ui.loadFileInfo(app.nav)
 			}
-		case "rename: ":
+		case "shell-pick: ":
 			app.ui.cmdPrefix = ""
-			if curr, err := app.nav.currFile(); err != nil {
-				app.ui.echoerrf("rename: %s", err)
-			} else {
-				wd, err := os.Getwd()
-				if err != nil {
-					log.Printf("getting current directory: %s", err)
-					return
-				}
-
-				oldPath := filepath.Join(wd, curr.Name())
-
-				newPath := filepath.Clean(s)
-				if !filepath.IsAbs(newPath) {
-					newPath = filepath.Join(wd, newPath)
-				}
-
-				if oldPath == newPath {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > len(app.pickItems) {
+				app.ui.echoerrf("shell-pick: invalid choice: %s", s)
+				return
+			}
+			log.Printf("shell-pick: %s -- %s", app.pickCmd, app.pickItems[n-1])
+			cmd := &callExpr{app.pickCmd, []string{app.pickItems[n-1]}, 1}
+			cmd.eval(app, nil)
+		case "list-selection: ":
+			app.ui.cmdPrefix = ""
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > len(app.pickItems) {
+				app.ui.echoerrf("list-selection: invalid choice: %s", s)
+				return
+			}
+			cmd := &callExpr{"select", []string{app.pickItems[n-1]}, 1}
+			cmd.eval(app, nil)
+		case "command-palette: ":
+			app.ui.cmdPrefix = ""
+			items := app.paletteItems
+			app.paletteItems = nil
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				if len(items) == 0 {
+					app.ui.echoerrf("command-palette: no matching command: %s", s)
 					return
 				}
-
-				app.nav.renameOldPath = oldPath
-				app.nav.renameNewPath = newPath
-
-				newDir := filepath.Dir(newPath)
-				if _, err := os.Stat(newDir); os.IsNotExist(err) {
-					app.ui.cmdPrefix = "create '" + newDir + "' ? [y/N] "
-					return
+				n = 1
+			}
+			if n < 1 || n > len(items) {
+				app.ui.echoerrf("command-palette: invalid choice: %s", s)
+				return
+			}
+			name := items[n-1].name
+			if gCmdArgs[name] {
+				app.ui.cmdPrefix = ":"
+				app.ui.cmdAccLeft = []rune(name + " ")
+				return
+			}
+			log.Printf("command-palette: %s", name)
+			cmd := &callExpr{name, nil, 1}
+			cmd.eval(app, nil)
+		case "new-from-template: ":
+			app.ui.cmdPrefix = ""
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > len(app.pickItems) {
+				app.ui.echoerrf("new-from-template: invalid choice: %s", s)
+				return
+			}
+			app.readTemplateName(app.pickItems[n-1])
+		case "new file: ":
+			app.ui.cmdPrefix = ""
+			tmpl := app.templateName
+			app.templateName = ""
+			path, err := app.nav.createFromTemplate(tmpl, s)
+			if err != nil {
+				app.ui.echoerrf("new-from-template: %s", err)
+				return
+			}
+			if err := app.selectCreated(path); err != nil {
+				app.ui.echoerrf("new-from-template: %s", err)
+				return
+			}
+			open := &callExpr{"open", nil, 1}
+			open.eval(app, nil)
+		case "trash-list: ":
+			app.ui.cmdPrefix = ""
+			items := app.trashItems
+			app.trashItems = nil
+			inds, err := parseChoices(s, len(items))
+			if err != nil {
+				app.ui.echoerrf("trash-list: %s", err)
+				return
+			}
+			restored := 0
+			for _, i := range inds {
+				if err := restoreTrash(items[i]); err != nil {
+					app.ui.echoerrf("trash-list: %s", err)
+					continue
 				}
-
-				oldStat, err := os.Stat(oldPath)
+				restored++
+			}
+			if restored == 0 {
+				return
+			}
+			if err := remote("send load"); err != nil {
+				app.ui.echoerrf("trash-list: %s", err)
+				return
+			}
+			app.ui.loadFile(app.nav, true)
+			app.ui.loadFileInfo(app.nav)
+			app.ui.echof("trash-list: %d items restored", restored)
+		case "workspace-list: ":
+			app.ui.cmdPrefix = ""
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > len(app.pickItems) {
+				app.ui.echoerrf("workspace-list: invalid choice: %s", s)
+				return
+			}
+			if app.pickItems[n-1] == app.workspace {
+				return
+			}
+			cmd := &callExpr{"workspace", []string{app.pickItems[n-1]}, 1}
+			cmd.eval(app, nil)
+		case "mounts: ":
+			app.ui.cmdPrefix = ""
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > len(app.mountList) {
+				app.ui.echoerrf("mounts: invalid choice: %s", s)
+				return
+			}
+			m := app.mountList[n-1]
+			if m.dir == "" {
+				dir, err := mountDevice(m.dev)
 				if err != nil {
-					app.ui.echoerrf("rename: %s", err)
-					return
-				}
-
-				if newStat, err := os.Stat(newPath); !os.IsNotExist(err) && !os.SameFile(oldStat, newStat) {
-					app.ui.cmdPrefix = "replace '" + newPath + "' ? [y/N] "
-					return
-				}
-
-				if err := app.nav.rename(); err != nil {
-					app.ui.echoerrf("rename: %s", err)
-					return
-				}
-
-				if err := remote("send load"); err != nil {
-					app.ui.echoerrf("rename: %s", err)
+					app.ui.echoerrf("mounts: %s", err)
 					return
 				}
-
-				app.ui.loadFile(app.nav, true)
-				app.ui.loadFileInfo(app.nav)
+				m.dir = dir
+			}
+			cmd := &callExpr{"cd", []string{m.dir}, 1}
+			cmd.eval(app, nil)
+		case "unmount: ":
+			app.ui.cmdPrefix = ""
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > len(app.mountList) {
+				app.ui.echoerrf("unmount: invalid choice: %s", s)
+				return
 			}
+			m := app.mountList[n-1]
+			if wd, err := os.Getwd(); err == nil && isSubdir(m.dir, wd) {
+				cmd := &callExpr{"cd", []string{filepath.Dir(m.dir)}, 1}
+				cmd.eval(app, nil)
+			}
+			if err := unmountDevice(m.dev); err != nil {
+				app.ui.echoerrf("unmount: %s", err)
+				return
+			}
+			app.ui.echof("unmount: %s unmounted from %s", m.dev, m.dir)
+		case "rename: ":
+			app.ui.cmdPrefix = ""
+			app.repeatCmd = &callExpr{"rename", []string{s}, 1}
+			app.renameCurr(s)
 		default:
 			log.Printf("entering unknown execution prefix: %q", app.ui.cmdPrefix)
 		}
@@ -1555,8 +3796,16 @@ func (e *execExpr) eval(app *app, args []string) {
 
 func (e *listExpr) eval(app *app, args []string) {
 	for i := 0; i < e.count; i++ {
-		for _, expr := range e.exprs {
-			expr.eval(app, nil)
+		for j, ex := range e.exprs {
+			if call, ok := ex.(*callExpr); ok && call.name == "wait" {
+				rest := append([]expr{}, e.exprs[j+1:]...)
+				for k := i + 1; k < e.count; k++ {
+					rest = append(rest, e.exprs...)
+				}
+				app.waitAsync(&listExpr{rest, 1})
+				return
+			}
+			ex.eval(app, nil)
 		}
 	}
 }
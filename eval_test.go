@@ -1,9 +1,13 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 var gEvalTests = []struct {
@@ -131,6 +135,24 @@ var gEvalTests = []struct {
 		[]expr{&setExpr{"hidden", ""}, &setExpr{"preview", ""}},
 	},
 
+	{
+		"set hidden preview reverse",
+		[]string{"set", "hidden", "preview", "reverse", "\n"},
+		[]expr{&listExpr{[]expr{&setExpr{"hidden", ""}, &setExpr{"preview", ""}, &setExpr{"reverse", ""}}, 1}},
+	},
+
+	{
+		"set nohidden ratios 1:2:3 preview! sortby time",
+		[]string{"set", "nohidden", "ratios", "1:2:3", "preview!", "sortby", "time", "\n"},
+		[]expr{&listExpr{[]expr{&setExpr{"nohidden", ""}, &setExpr{"ratios", "1:2:3"}, &setExpr{"preview!", ""}, &setExpr{"sortby", "time"}}, 1}},
+	},
+
+	{
+		"set info size:time hidden; set preview",
+		[]string{"set", "info", "size:time", "hidden", ";", "set", "preview", "\n"},
+		[]expr{&listExpr{[]expr{&setExpr{"info", "size:time"}, &setExpr{"hidden", ""}}, 1}, &setExpr{"preview", ""}},
+	},
+
 	{
 		`set ifs ""`,
 		[]string{"set", "ifs", "", "\n"},
@@ -459,3 +481,480 @@ func TestSplitKeys(t *testing.T) {
 		}
 	}
 }
+
+func TestEnterable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("creating temporary file: %s", err)
+	}
+
+	dstat, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("getting directory information: %s", err)
+	}
+
+	fstat, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("getting file information: %s", err)
+	}
+
+	defer func(old bool) { gOpts.diropener = old }(gOpts.diropener)
+
+	tests := []struct {
+		diropener bool
+		f         os.FileInfo
+		exp       bool
+	}{
+		{false, dstat, true},
+		{false, fstat, false},
+		{true, dstat, false},
+		{true, fstat, false},
+	}
+
+	for _, test := range tests {
+		gOpts.diropener = test.diropener
+		if got := enterable(test.f); got != test.exp {
+			t.Errorf("at input '%s' with diropener '%t' expected '%t' but got '%t'", test.f.Name(), test.diropener, test.exp, got)
+		}
+	}
+}
+
+func TestCmdHelp(t *testing.T) {
+	defer func(cmds map[string]expr) { gOpts.cmds = cmds }(gOpts.cmds)
+	gOpts.cmds = map[string]expr{
+		"foo":  &execExpr{"$", "echo foo"},
+		"bar":  &execExpr{"%", "\n\techo bar\n\techo baz\n"},
+		"baz":  &listExpr{[]expr{&callExpr{"up", nil, 1}}, 1},
+		"open": &execExpr{"&", "xdg-open \"$f\""},
+	}
+
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{"foo", "cmd foo ${{ echo foo }}"},
+		{"bar", "cmd bar %{{ echo bar; echo baz }}"},
+		{"baz", "cmd baz :{{ up -- []; }}"},
+		{"open", "cmd open &{{ xdg-open \"$f\" }}"},
+		{"up", "up: builtin: " + gCmdDescs["up"]},
+		{"cmd-help", "cmd-help: builtin: " + gCmdDescs["cmd-help"]},
+	}
+
+	for _, test := range tests {
+		got, err := cmdHelp(test.name)
+		if err != nil {
+			t.Errorf("at input '%s' unexpected error: %s", test.name, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.name, test.exp, got)
+		}
+	}
+
+	if _, err := cmdHelp("qux"); err == nil {
+		t.Errorf("at input 'qux' expected an error")
+	}
+
+	for _, name := range gCmdWords {
+		if _, ok := gCmdDescs[name]; !ok {
+			t.Errorf("builtin command '%s' does not have a description", name)
+		}
+	}
+}
+
+func TestParseSetError(t *testing.T) {
+	tests := []struct {
+		inp string
+		err string
+	}{
+		{"set", "expected identifier: \n"},
+		{"set hidden :up", "expected identifier after 'hidden': :"},
+	}
+
+	for _, test := range tests {
+		p := newParser(strings.NewReader(test.inp))
+		if p.parse(); p.err == nil || p.err.Error() != test.err {
+			t.Errorf("at input '%s' expected error '%s' but got '%v'", test.inp, test.err, p.err)
+		}
+	}
+}
+
+func TestRecordRepeat(t *testing.T) {
+	app := &app{}
+
+	rename := &callExpr{"rename", []string{"foo"}, 1}
+	del := &callExpr{"delete", nil, 1}
+	renameCase := &callExpr{"rename-case", []string{"lower"}, 1}
+
+	tests := []struct {
+		e   *callExpr
+		exp *callExpr
+	}{
+		{&callExpr{"up", nil, 1}, nil},
+		{&callExpr{"rename", nil, 1}, nil},
+		{rename, rename},
+		{&callExpr{"down", nil, 1}, rename},
+		{&callExpr{"repeat", nil, 1}, rename},
+		{del, del},
+		{&callExpr{"toggle", nil, 1}, del},
+		{renameCase, renameCase},
+		{&callExpr{"cmd-enter", nil, 1}, renameCase},
+	}
+
+	for _, test := range tests {
+		app.recordRepeat(test.e)
+		if app.repeatCmd != test.exp {
+			t.Errorf("at input '%s' expected '%v' but got '%v'", test.e, test.exp, app.repeatCmd)
+		}
+	}
+}
+
+func TestRepeatRename(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting current directory: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	defer func(preview bool) {
+		gOpts.preview = preview
+	}(gOpts.preview)
+	gOpts.preview = false
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	foo := filepath.Join(root, "foo")
+	bar := filepath.Join(root, "bar")
+	for _, path := range []string{filepath.Join(foo, "a"), filepath.Join(bar, "c")} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	loadTime := time.Now().Add(-time.Hour)
+	dirs := map[string]*dir{
+		foo: {path: foo, loadTime: loadTime, files: []*file{{FileInfo: fakeFileInfo("a"), path: filepath.Join(foo, "a")}}},
+		bar: {path: bar, loadTime: loadTime, files: []*file{{FileInfo: fakeFileInfo("c"), path: filepath.Join(bar, "c")}}},
+	}
+
+	app := &app{
+		ui:  &ui{},
+		nav: &nav{dirs: []*dir{dirs[foo]}, dirCache: dirs, dirChan: make(chan *dir, 10)},
+	}
+	defer waitDirs(app.nav)
+
+	if err := os.Chdir(foo); err != nil {
+		t.Fatalf("changing directory: %s", err)
+	}
+
+	(&callExpr{"rename", []string{"b"}, 1}).eval(app, nil)
+
+	if exp := (&callExpr{"rename", []string{"b"}, 1}); !reflect.DeepEqual(app.repeatCmd, exp) {
+		t.Errorf("expected recorded command '%s' but got '%v'", exp, app.repeatCmd)
+	}
+	if _, err := os.Stat(filepath.Join(foo, "b")); err != nil {
+		t.Errorf("expected the file to be renamed: %s", err)
+	}
+
+	app.nav.dirs = []*dir{dirs[bar]}
+	if err := os.Chdir(bar); err != nil {
+		t.Fatalf("changing directory: %s", err)
+	}
+
+	(&callExpr{"repeat", nil, 1}).eval(app, nil)
+
+	if _, err := os.Stat(filepath.Join(bar, "b")); err != nil {
+		t.Errorf("expected the current file to be renamed again: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(bar, "c")); !os.IsNotExist(err) {
+		t.Errorf("expected the current file to be moved but got error: %v", err)
+	}
+}
+
+func TestGlobArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		pattern string
+		hidden  bool
+		ok      bool
+	}{
+		{[]string{}, "", false, false},
+		{[]string{"*.txt"}, "*.txt", false, true},
+		{[]string{"-hidden", "*.txt"}, "*.txt", true, true},
+		{[]string{"-hidden"}, "-hidden", false, true},
+		{[]string{"*.txt", "*.go"}, "", false, false},
+		{[]string{"-hidden", "*.txt", "*.go"}, "", false, false},
+	}
+
+	for _, test := range tests {
+		pattern, hidden, ok := globArgs(test.args)
+		if pattern != test.pattern || hidden != test.hidden || ok != test.ok {
+			t.Errorf("at input '%v' expected '%s', '%t', and '%t' but got '%s', '%t', and '%t'", test.args, test.pattern, test.hidden, test.ok, pattern, hidden, ok)
+		}
+	}
+}
+
+func TestRegexArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		pattern string
+		hidden  bool
+		byPath  bool
+		ok      bool
+	}{
+		{[]string{}, "", false, false, false},
+		{[]string{`\.go$`}, `\.go$`, false, false, true},
+		{[]string{"-path", "^src/"}, "^src/", false, true, true},
+		{[]string{"-hidden", "-path", "^src/"}, "^src/", true, true, true},
+		{[]string{"-path", "-hidden", "^src/"}, "^src/", true, true, true},
+		{[]string{"-path"}, "-path", false, false, true},
+		{[]string{"foo", "bar"}, "", false, false, false},
+		{[]string{"-path", "foo", "bar"}, "", false, false, false},
+	}
+
+	for _, test := range tests {
+		pattern, hidden, byPath, ok := regexArgs(test.args)
+		if pattern != test.pattern || hidden != test.hidden || byPath != test.byPath || ok != test.ok {
+			t.Errorf("at input '%v' expected '%s', '%t', '%t', and '%t' but got '%s', '%t', '%t', and '%t'", test.args, test.pattern, test.hidden, test.byPath, test.ok, pattern, hidden, byPath, ok)
+		}
+	}
+}
+
+func TestPasteArgs(t *testing.T) {
+	defer func(pasteconflict string) {
+		gOpts.pasteconflict = pasteconflict
+	}(gOpts.pasteconflict)
+	gOpts.pasteconflict = "skip"
+
+	tests := []struct {
+		args   []string
+		policy conflictPolicy
+		ok     bool
+	}{
+		{nil, conflictSkip, true},
+		{[]string{"-rename"}, conflictRename, true},
+		{[]string{"-overwrite"}, conflictOverwrite, true},
+		{[]string{"-skip"}, conflictSkip, true},
+		{[]string{"-foo"}, conflictRename, false},
+		{[]string{"overwrite"}, conflictRename, false},
+		{[]string{"-overwrite", "-skip"}, conflictRename, false},
+	}
+
+	for _, test := range tests {
+		policy, ok := pasteArgs(test.args)
+		if policy != test.policy || ok != test.ok {
+			t.Errorf("at input '%v' expected '%d' and '%t' but got '%d' and '%t'", test.args, test.policy, test.ok, policy, ok)
+		}
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	defer os.Setenv("LF_TEST_PROMPT", os.Getenv("LF_TEST_PROMPT"))
+	defer delete(gOpts.cmds, "lf-test-prompt")
+
+	valid := []string{"LF_TEST_PROMPT", "foo", "cmd lf-test-prompt echo"}
+
+	tests := []struct {
+		args   []string
+		keys   []expr
+		prefix string
+		acc    string
+		val    string
+		set    bool
+	}{
+		{valid, nil, "LF_TEST_PROMPT: ", "foo", "", false},
+		{valid, []expr{&callExpr{"cmd-escape", nil, 1}}, "", "", "", false},
+		{valid, []expr{&callExpr{"cmd-insert", []string{" bar"}, 1}, &callExpr{"cmd-enter", nil, 1}}, "", "", "foo bar", true},
+		{[]string{"1-invalid", "", "echo"}, nil, "", "", "", false},
+		{[]string{"LF_TEST_PROMPT", "foo"}, nil, "", "", "", false},
+	}
+
+	for _, test := range tests {
+		os.Unsetenv("LF_TEST_PROMPT")
+		delete(gOpts.cmds, "lf-test-prompt")
+
+		a := &app{ui: &ui{}, nav: &nav{dirs: []*dir{{}}, selections: make(map[string]int)}}
+		(&callExpr{"prompt", test.args, 1}).eval(a, nil)
+		for _, e := range test.keys {
+			e.eval(a, nil)
+		}
+
+		if a.ui.cmdPrefix != test.prefix || string(a.ui.cmdAccLeft) != test.acc {
+			t.Errorf("at input '%v' with keys '%v' expected prompt '%s' with '%s' but got '%s' with '%s'",
+				test.args, test.keys, test.prefix, test.acc, a.ui.cmdPrefix, string(a.ui.cmdAccLeft))
+		}
+		if test.prefix == "" && (a.promptVar != "" || a.promptCmd != "") {
+			t.Errorf("at input '%v' with keys '%v' expected pending prompt to be cleared", test.args, test.keys)
+		}
+		if val, ok := os.LookupEnv("LF_TEST_PROMPT"); val != test.val || ok != test.set {
+			t.Errorf("at input '%v' with keys '%v' expected variable '%s' but got '%s'", test.args, test.keys, test.val, val)
+		}
+		if _, ok := gOpts.cmds["lf-test-prompt"]; ok != test.set {
+			t.Errorf("at input '%v' with keys '%v' expected command run to be '%t' but got '%t'", test.args, test.keys, test.set, ok)
+		}
+	}
+}
+
+func TestReadonly(t *testing.T) {
+	defer func(readonly, preview bool) {
+		gOpts.readonly = readonly
+		gOpts.preview = preview
+	}(gOpts.readonly, gOpts.preview)
+	gOpts.readonly = true
+	gOpts.preview = false
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	var files []*file
+	for _, name := range []string{"a", "b", "c"} {
+		path := filepath.Join(root, name)
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			t.Fatalf("getting file information: %s", err)
+		}
+		files = append(files, &file{FileInfo: info, path: path})
+	}
+
+	d := &dir{path: root, files: files}
+	a := &app{ui: &ui{}, nav: &nav{dirs: []*dir{d}, height: 10, selections: make(map[string]int), saves: make(map[string]bool)}}
+
+	for _, name := range []string{"create", "delete", "paste", "rename", "rename-case", "toggle-exec", "shell", "shell-pick"} {
+		a.ui.msg = ""
+		(&callExpr{name, []string{"lower"}, 1}).eval(a, nil)
+		if !strings.Contains(a.ui.msg, "read-only mode") {
+			t.Errorf("at command '%s' expected read-only mode error but got '%s'", name, a.ui.msg)
+		}
+		if a.ui.cmdPrefix != "" {
+			t.Errorf("at command '%s' expected no prompt but got '%s'", name, a.ui.cmdPrefix)
+		}
+	}
+
+	a.ui.msg = ""
+	(&execExpr{"$", "touch new"}).eval(a, nil)
+	if !strings.Contains(a.ui.msg, "read-only mode") {
+		t.Errorf("at shell command expected read-only mode error but got '%s'", a.ui.msg)
+	}
+
+	(&callExpr{"down", nil, 2}).eval(a, nil)
+	if d.ind != 2 {
+		t.Errorf("expected navigation to work but index is '%d'", d.ind)
+	}
+	(&callExpr{"top", nil, 1}).eval(a, nil)
+	if d.ind != 0 {
+		t.Errorf("expected navigation to work but index is '%d'", d.ind)
+	}
+
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("expected file '%s' to be kept: %s", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "new")); err == nil {
+		t.Errorf("expected shell command to be not run")
+	}
+	if _, err := os.Stat(filepath.Join(root, "lower")); err == nil {
+		t.Errorf("expected file to be not created")
+	}
+
+	(&setExpr{"noreadonly", ""}).eval(a, nil)
+	if !gOpts.readonly {
+		t.Errorf("expected read-only mode to be kept")
+	}
+}
+
+func TestExpandPrint(t *testing.T) {
+	defer func(filesep string, ratios []int, sortType sortType) {
+		gOpts.filesep = filesep
+		gOpts.ratios = ratios
+		gOpts.sortType = sortType
+	}(gOpts.filesep, gOpts.ratios, gOpts.sortType)
+	gOpts.filesep = "\n"
+	gOpts.ratios = []int{1, 2, 3}
+	gOpts.sortType = sortType{nameSort, reverseSort}
+
+	tests := []struct {
+		s    string
+		curr string
+		sel  []string
+		exp  string
+	}{
+		{"", "/foo", nil, ""},
+		{"hello world", "/foo", nil, "hello world"},
+		{"$f", "/foo", nil, "/foo"},
+		{"file: $f.", "/foo", []string{"/bar"}, "file: /foo."},
+		{"$fx", "/foo", nil, "/foo"},
+		{"$fx", "/foo", []string{"/bar", "/baz"}, "/bar\n/baz"},
+		{"$fs", "/foo", nil, ""},
+		{"$fs", "/foo", []string{"/bar", "/baz"}, "/bar\n/baz"},
+		{"[$f][$fs][$fx]", "/foo", []string{"/bar"}, "[/foo][/bar][/bar]"},
+		{"$foo $HOME $ $", "/foo", nil, "$foo $HOME $ $"},
+		{"$$f costs $$5", "/foo", nil, "$f costs $5"},
+		{"${opt:ratios}", "/foo", nil, "1:2:3"},
+		{"${opt:filesep}", "/foo", nil, "\n"},
+		{"${opt:sortby} ${opt:reverse} ${opt:hidden}", "/foo", nil, "name true false"},
+		{"$f in ${opt:ratios}!", "/foo", nil, "/foo in 1:2:3!"},
+	}
+
+	for _, test := range tests {
+		got, err := expandPrint(test.s, test.curr, test.sel)
+		if err != nil {
+			t.Errorf("at input '%s' unexpected error: %s", test.s, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' expected '%q' but got '%q'", test.s, test.exp, got)
+		}
+	}
+
+	for _, s := range []string{"${opt:nonexistent}", "${opt:keys}", "${opt:ratios"} {
+		if _, err := expandPrint(s, "/foo", nil); err == nil {
+			t.Errorf("at input '%s' expected an error", s)
+		}
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	tests := []struct {
+		cmds    []string
+		errexit bool
+		msg     string
+		lastErr string
+	}{
+		{[]string{"echo foo", "echo bar"}, false, "bar", ""},
+		{[]string{"echo foo", "foo", "echo bar"}, false, "", "batch: 1 of 3 commands failed: command not found: foo"},
+		{[]string{"foo", "bar", "echo baz"}, false, "", "batch: 2 of 3 commands failed: command not found: bar"},
+		{[]string{"echo foo", "foo", "echo bar"}, true, "", "batch: stopped at command 2 of 3: command not found: foo"},
+		{[]string{"echo foo; foo", "echo bar"}, true, "", "batch: stopped at command 1 of 2: command not found: foo"},
+	}
+
+	for _, test := range tests {
+		a := &app{ui: &ui{}}
+		a.runBatch(test.cmds, test.errexit)
+		if test.lastErr != a.ui.lastErr {
+			t.Errorf("at input '%q' expected error '%s' but got '%s'", test.cmds, test.lastErr, a.ui.lastErr)
+		}
+		if test.msg != "" && test.msg != a.ui.msg {
+			t.Errorf("at input '%q' expected message '%s' but got '%s'", test.cmds, test.msg, a.ui.msg)
+		}
+	}
+}
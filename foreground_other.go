@@ -0,0 +1,9 @@
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!windows
+
+package main
+
+// Moving process groups to the foreground of the terminal is not supported on
+// this platform so commands with a timeout are left in the background.
+func takeForeground(pgid int) (restore func()) {
+	return func() {}
+}
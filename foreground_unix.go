@@ -0,0 +1,28 @@
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// This function sets the foreground process group of the terminal. SIGTTOU is
+// ignored during the call since it is sent when the caller is not in the
+// foreground process group anymore. Errors are ignored since the standard
+// input may not be a terminal.
+func setForeground(pgid int) {
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	syscall.Syscall(syscall.SYS_IOCTL, os.Stdin.Fd(), syscall.TIOCSPGRP, uintptr(unsafe.Pointer(&pgid)))
+}
+
+// This function moves the given process group to the foreground of the
+// terminal and returns a function to move the process group of the caller
+// back to the foreground.
+func takeForeground(pgid int) (restore func()) {
+	setForeground(pgid)
+	return func() { setForeground(syscall.Getpgrp()) }
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+// This is the total rank of the directories in the frecency file above which
+// the ranks are aged so that directories not visited for a long time are
+// eventually forgotten.
+const gFrecencyMaxRank = 10000
+
+// This is the number of directories shown with 'z-list' command.
+const gFrecencyListLimit = 20
+
+type frecencyEntry struct {
+	rank float64
+	last time.Time
+}
+
+// This type is the frecency database which keeps the rank and the time of the
+// last visit for each visited directory.
+type frecency map[string]*frecencyEntry
+
+type dirVisit struct {
+	path string
+	time time.Time
+}
+
+// This function returns the score of a directory with the given rank and time
+// of the last visit. Recently visited directories are favored with a higher
+// weight so that the score combines both the frequency and the recency.
+func frecencyScore(rank float64, last, now time.Time) float64 {
+	switch dt := now.Sub(last); {
+	case dt < time.Hour:
+		return rank * 4
+	case dt < 24*time.Hour:
+		return rank * 2
+	case dt < 7*24*time.Hour:
+		return rank / 2
+	default:
+		return rank / 4
+	}
+}
+
+func (fr frecency) visit(path string, t time.Time) {
+	e, ok := fr[path]
+	if !ok {
+		e = &frecencyEntry{}
+		fr[path] = e
+	}
+	e.rank++
+	if t.After(e.last) {
+		e.last = t
+	}
+}
+
+// This function scales down the ranks of all directories when the total rank
+// exceeds the given limit and removes directories with ranks falling below
+// one.
+func (fr frecency) age(limit float64) {
+	var total float64
+	for _, e := range fr {
+		total += e.rank
+	}
+
+	if total <= limit {
+		return
+	}
+
+	factor := 0.9 * limit / total
+	for path, e := range fr {
+		e.rank *= factor
+		if e.rank < 1 {
+			delete(fr, path)
+		}
+	}
+}
+
+// This function reports whether the given path matches the given keywords.
+// Keywords should appear in the path in the given order and the last keyword
+// should appear in the last component of the path. Case is ignored according
+// to 'ignorecase' and 'smartcase' options as in 'searchMatch' function.
+func frecencyMatch(path string, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+
+	norm := func(s, keyword string) (string, string) {
+		if gOpts.ignorecase {
+			lkeyword := strings.ToLower(keyword)
+			if !gOpts.smartcase || lkeyword == keyword {
+				return foldCase(s), foldCase(keyword)
+			}
+		}
+		return s, keyword
+	}
+
+	s := path
+	for _, keyword := range keywords {
+		str, kw := norm(s, keyword)
+		ind := strings.Index(str, kw)
+		if ind == -1 {
+			return false
+		}
+
+		// folded characters may have a different length in bytes than the
+		// original ones, so the rest is found by the number of characters
+		for n := utf8.RuneCountInString(str[:ind+len(kw)]); n > 0; n-- {
+			_, size := utf8.DecodeRuneInString(s)
+			s = s[size:]
+		}
+	}
+
+	base, last := norm(filepath.Base(path), keywords[len(keywords)-1])
+	return strings.Contains(base, last)
+}
+
+// This function returns the directories matching the given keywords sorted by
+// their scores from the highest to the lowest.
+func (fr frecency) matches(keywords []string, now time.Time) []string {
+	var paths []string
+	for path := range fr {
+		if frecencyMatch(path, keywords) {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+	sort.SliceStable(paths, func(i, j int) bool {
+		ei, ej := fr[paths[i]], fr[paths[j]]
+		return frecencyScore(ei.rank, ei.last, now) > frecencyScore(ej.rank, ej.last, now)
+	})
+
+	return paths
+}
+
+// This function reads the frecency database from the given file with a
+// directory on each line as the rank, the time of the last visit in seconds,
+// and the path separated with colons. Invalid lines are ignored.
+func readFrecency(path string) (frecency, error) {
+	fr := make(frecency)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return fr, nil
+	}
+	if err != nil {
+		return fr, fmt.Errorf("opening frecency file: %s", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		toks := strings.SplitN(scanner.Text(), ":", 3)
+		if len(toks) != 3 || toks[2] == "" {
+			continue
+		}
+		rank, err := strconv.ParseFloat(toks[0], 64)
+		if err != nil || rank <= 0 {
+			continue
+		}
+		last, err := strconv.ParseInt(toks[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		fr[toks[2]] = &frecencyEntry{rank, time.Unix(last, 0)}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fr, fmt.Errorf("reading frecency file: %s", err)
+	}
+
+	return fr, nil
+}
+
+// This function writes the frecency database to the given file sorted by the
+// paths of directories.
+func writeFrecency(path string, fr frecency) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("creating data directory: %s", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating frecency file: %s", err)
+	}
+	defer f.Close()
+
+	var paths []string
+	for dir := range fr {
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths)
+
+	for _, dir := range paths {
+		e := fr[dir]
+		rank := strconv.FormatFloat(e.rank, 'f', -1, 64)
+		if _, err := fmt.Fprintf(f, "%s:%d:%s\n", rank, e.last.Unix(), dir); err != nil {
+			return fmt.Errorf("writing frecency file: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func listFrecency(fr frecency, paths []string, now time.Time) *bytes.Buffer {
+	t := new(tabwriter.Writer)
+	b := new(bytes.Buffer)
+
+	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
+	fmt.Fprintln(t, "score\tpath")
+	for _, path := range paths {
+		e := fr[path]
+		fmt.Fprintf(t, "%.1f\t%s\n", frecencyScore(e.rank, e.last, now), path)
+	}
+	t.Flush()
+
+	return b
+}
+
+// This function keeps the current directory as a visit to be added to the
+// frecency database.
+func (app *app) visitDir() {
+	path := app.nav.currDir().path
+	if isArchivePath(path) {
+		return
+	}
+	app.dirVisits = append(app.dirVisits, dirVisit{path, time.Now()})
+}
+
+// This function reads the frecency database and adds the visits of this
+// client which are not yet written to the file.
+func (app *app) readFrecency() (frecency, error) {
+	fr, err := readFrecency(gFrecencyPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range app.dirVisits {
+		fr.visit(v.path, v.time)
+	}
+	return fr, nil
+}
+
+// This function writes the visits of this client to the frecency file. The
+// file is read again before writing so that visits of other clients are kept
+// as in 'writeHistory' function.
+func (app *app) writeFrecency() error {
+	if len(app.dirVisits) == 0 {
+		return nil
+	}
+
+	fr, err := app.readFrecency()
+	if err != nil {
+		return err
+	}
+	app.dirVisits = nil
+
+	fr.age(gFrecencyMaxRank)
+
+	return writeFrecency(gFrecencyPath, fr)
+}
+
+// This function changes the current directory to the directory with the
+// highest score matching the given keywords. The current directory and
+// directories which no longer exist are skipped.
+func (app *app) jumpFrecency(keywords []string) {
+	fr, err := app.readFrecency()
+	if err != nil {
+		app.ui.echoerrf("z: %s", err)
+		return
+	}
+
+	wd := app.nav.currDir().path
+
+	for _, path := range fr.matches(keywords, time.Now()) {
+		if path == wd {
+			continue
+		}
+		if s, err := os.Stat(path); err != nil || !s.IsDir() {
+			continue
+		}
+
+		if err := app.nav.cd(path); err != nil {
+			app.ui.echoerrf("%s", err)
+			return
+		}
+		app.ui.loadFile(app.nav, true)
+		app.ui.loadFileInfo(app.nav)
+
+		app.nav.marks["'"] = wd
+		onChdir(app)
+		return
+	}
+
+	app.ui.echoerrf("z: no matching directory: %s", strings.Join(keywords, " "))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFrecencyScore(t *testing.T) {
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rank float64
+		last time.Time
+		exp  float64
+	}{
+		{10, now, 40},
+		{10, now.Add(-59 * time.Minute), 40},
+		{10, now.Add(-time.Hour), 20},
+		{10, now.Add(-23 * time.Hour), 20},
+		{10, now.Add(-24 * time.Hour), 5},
+		{10, now.Add(-6 * 24 * time.Hour), 5},
+		{10, now.Add(-7 * 24 * time.Hour), 2.5},
+		{10, now.Add(-365 * 24 * time.Hour), 2.5},
+	}
+
+	for _, test := range tests {
+		if got := frecencyScore(test.rank, test.last, now); got != test.exp {
+			t.Errorf("at input '%g' and '%s' expected '%g' but got '%g'", test.rank, test.last, test.exp, got)
+		}
+	}
+}
+
+func TestFrecencyMatch(t *testing.T) {
+	defer func(ignorecase, smartcase bool) {
+		gOpts.ignorecase = ignorecase
+		gOpts.smartcase = smartcase
+	}(gOpts.ignorecase, gOpts.smartcase)
+	gOpts.ignorecase = true
+	gOpts.smartcase = true
+
+	tests := []struct {
+		path     string
+		keywords []string
+		exp      bool
+	}{
+		{"/home/user/projects/lf", []string{"lf"}, true},
+		{"/home/user/projects/lf", []string{"proj"}, false},
+		{"/home/user/projects/lf", []string{"proj", "lf"}, true},
+		{"/home/user/projects/lf", []string{"lf", "proj"}, false},
+		{"/home/user/projects/lf", []string{"LF"}, false},
+		{"/home/user/Projects/lf", []string{"Projects", "lf"}, true},
+		{"/home/user/Projects/lf", []string{"projects", "lf"}, true},
+		{"/home/user/dotfiles/config", []string{"dots"}, false},
+		{"/home/user/dotfiles/config", []string{"dot", "conf"}, true},
+		{"/home/user/dotfiles/config", []string{"conf", "conf"}, false},
+		{"/conf/conf", []string{"conf", "conf"}, true},
+		{"/home/user", nil, true},
+		{"/ȺȺȺȺ/x", []string{"x"}, true},
+		{"/ȺȺȺȺ/x", []string{"ⱥⱥ", "x"}, true},
+		{"/ſſſſ/ab", []string{"ab", "ab"}, false},
+		{"/ſſſſ/ab", []string{"ss", "ss", "ab"}, true},
+		{"/\u212a/ab", []string{"ab", "b"}, false},
+		{"/\u212a/kb", []string{"k", "kb"}, true},
+	}
+
+	for _, test := range tests {
+		if got := frecencyMatch(test.path, test.keywords); got != test.exp {
+			t.Errorf("at input '%s' and '%v' expected '%t' but got '%t'", test.path, test.keywords, test.exp, got)
+		}
+	}
+}
+
+func TestFrecencyMatches(t *testing.T) {
+	now := time.Date(2020, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	fr := make(frecency)
+	for i := 0; i < 10; i++ {
+		fr.visit("/old/src", now.Add(-30*24*time.Hour))
+	}
+	for i := 0; i < 3; i++ {
+		fr.visit("/recent/src", now.Add(-time.Minute))
+	}
+	for i := 0; i < 4; i++ {
+		fr.visit("/today/src", now.Add(-2*time.Hour))
+	}
+	fr.visit("/tie/b/src", now.Add(-2*time.Hour))
+	fr.visit("/tie/a/src", now.Add(-2*time.Hour))
+	fr.visit("/src/other", now)
+
+	if e := fr["/old/src"]; e.rank != 10 || !e.last.Equal(now.Add(-30*24*time.Hour)) {
+		t.Errorf("expected rank '10' but got '%g' with last visit '%s'", e.rank, e.last)
+	}
+
+	tests := []struct {
+		keywords []string
+		exp      []string
+	}{
+		{[]string{"src"}, []string{"/recent/src", "/today/src", "/old/src", "/tie/a/src", "/tie/b/src"}},
+		{[]string{"tie", "src"}, []string{"/tie/a/src", "/tie/b/src"}},
+		{[]string{"other"}, []string{"/src/other"}},
+		{[]string{"missing"}, nil},
+	}
+
+	for _, test := range tests {
+		if got := fr.matches(test.keywords, now); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.keywords, test.exp, got)
+		}
+	}
+}
+
+func TestFrecencyAge(t *testing.T) {
+	now := time.Now()
+
+	fr := frecency{
+		"/foo": {60, now},
+		"/bar": {39, now},
+		"/baz": {1, now},
+	}
+
+	fr.age(200)
+	if len(fr) != 3 || fr["/foo"].rank != 60 {
+		t.Errorf("expected ranks to be kept below the limit but got '%v'", fr)
+	}
+
+	fr.age(50)
+	if len(fr) != 2 {
+		t.Errorf("expected low ranks to be removed but got '%v'", fr)
+	}
+	if got := fr["/foo"].rank + fr["/bar"].rank; got > 50 {
+		t.Errorf("expected total rank below '50' but got '%g'", got)
+	}
+	if fr["/foo"].rank <= fr["/bar"].rank {
+		t.Errorf("expected order of ranks to be kept but got '%g' and '%g'", fr["/foo"].rank, fr["/bar"].rank)
+	}
+}
+
+func TestFrecencyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "lf", "frecency")
+
+	fr, err := readFrecency(path)
+	if err != nil {
+		t.Fatalf("reading missing file: %s", err)
+	}
+	if len(fr) != 0 {
+		t.Errorf("expected no directories without a file but got '%v'", fr)
+	}
+
+	fr.visit("/foo bar", time.Unix(100, 0))
+	fr.visit("/foo bar", time.Unix(50, 0))
+	fr.visit(`C:\foo:bar`, time.Unix(200, 0))
+	fr[`C:\foo:bar`].rank = 1.5
+	if err := writeFrecency(path, fr); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading file: %s", err)
+	}
+	if exp := "2:100:/foo bar\n1.5:200:C:\\foo:bar\n"; string(buf) != exp {
+		t.Errorf("expected file '%q' but got '%q'", exp, buf)
+	}
+
+	got, err := readFrecency(path)
+	if err != nil {
+		t.Fatalf("reading file: %s", err)
+	}
+	if !reflect.DeepEqual(got, fr) {
+		t.Errorf("expected '%v' but got '%v'", fr, got)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("1:10:/foo\nx:10:/bar\n1:x:/baz\n0:10:/qux\n1:10:\nfoo\n2:20:/quux\n"), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+	got, err = readFrecency(path)
+	if err != nil {
+		t.Fatalf("reading file: %s", err)
+	}
+	exp := frecency{
+		"/foo":  {1, time.Unix(10, 0)},
+		"/quux": {2, time.Unix(20, 0)},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected invalid lines to be ignored with '%v' but got '%v'", exp, got)
+	}
+}
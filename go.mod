@@ -5,5 +5,6 @@ go 1.12
 require (
 	github.com/gdamore/tcell/v2 v2.0.0
 	github.com/mattn/go-runewidth v0.0.9
+	golang.org/x/sys v0.0.0-20190626150813-e07cf5db2756
 	gopkg.in/djherbis/times.v1 v1.2.0
 )
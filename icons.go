@@ -31,6 +31,10 @@ func parseIcons() iconMap {
 
 	im.parseEnv(strings.Join(defaultIcons, ":"))
 
+	if gOpts.iconset == "extended" {
+		im.parseEnv(strings.Join(gExtendedIcons, ":"))
+	}
+
 	if env := os.Getenv("LF_ICONS"); env != "" {
 		im.parseEnv(env)
 	}
@@ -63,6 +67,30 @@ func (im iconMap) parseEnv(env string) {
 	}
 }
 
+// This function returns the display width of the given icon.
+func iconWidth(icon string) int {
+	return runeSliceWidth([]rune(stripEscapes(icon)))
+}
+
+// This function returns the display width of the widest icon in the map. The
+// fallback icon is a single space so the width is at least one.
+func (im iconMap) width() int {
+	w := 1
+	for _, val := range im {
+		w = max(w, iconWidth(val))
+	}
+	return w
+}
+
+// This function returns the given icon padded with spaces to the given width
+// so that names after icons of different widths start at the same column.
+func padIcon(icon string, width int) string {
+	if w := iconWidth(icon); w < width {
+		return icon + strings.Repeat(" ", width-w)
+	}
+	return icon
+}
+
 func (im iconMap) get(f *file) string {
 	if val, ok := im[f.path]; ok {
 		return val
@@ -77,32 +105,15 @@ func (im iconMap) get(f *file) string {
 	var key string
 
 	switch {
-	case f.linkState == working:
-		key = "ln"
-	case f.linkState == broken:
-		key = "or"
-	case f.IsDir() && f.Mode()&os.ModeSticky != 0 && f.Mode()&0002 != 0:
-		key = "tw"
-	case f.IsDir() && f.Mode()&0002 != 0:
-		key = "ow"
-	case f.IsDir() && f.Mode()&os.ModeSticky != 0:
-		key = "st"
-	case f.IsDir():
+	case f.linkState == working && f.IsDir() && gOpts.dirlinkicon == "dirlink":
+		key = "dl"
+	default:
+		key = fileTypeKey(f, !f.IsDir() || gOpts.dirlinkicon == "link")
+	}
+
+	// directory links fall back to directory icon when they are not defined
+	if _, ok := im[key]; !ok && key == "dl" {
 		key = "di"
-	case f.Mode()&os.ModeNamedPipe != 0:
-		key = "pi"
-	case f.Mode()&os.ModeSocket != 0:
-		key = "so"
-	case f.Mode()&os.ModeDevice != 0:
-		key = "bd"
-	case f.Mode()&os.ModeCharDevice != 0:
-		key = "cd"
-	case f.Mode()&os.ModeSetuid != 0:
-		key = "su"
-	case f.Mode()&os.ModeSetgid != 0:
-		key = "sg"
-	case f.Mode()&0111 != 0:
-		key = "ex"
 	}
 
 	if val, ok := im[key]; ok {
@@ -117,7 +128,20 @@ func (im iconMap) get(f *file) string {
 		return val
 	}
 
-	if val, ok := im["*"+f.ext]; ok {
+	if f.ext != "" {
+		if val, ok := im["*"+f.ext]; ok {
+			return val
+		}
+	}
+
+	if f.Mode().IsRegular() {
+		if val, ok := im["fi"]; ok {
+			return val
+		}
+	}
+
+	// explicit catch-all for files not matched by any other entry
+	if val, ok := im["*"]; ok {
 		return val
 	}
 
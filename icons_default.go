@@ -0,0 +1,107 @@
+package main
+
+// These icons are added on top of the basic icons when 'iconset' option is
+// set to 'extended'. Entries use the '$LF_ICONS' format so that user entries
+// can override them.
+var gExtendedIcons = []string{
+	"*.txt=📄",
+	"*.md=📄",
+	"*.rst=📄",
+	"*.org=📄",
+	"*.log=📄",
+
+	"*.pdf=📕",
+	"*.epub=📕",
+	"*.djvu=📕",
+	"*.doc=📝",
+	"*.docx=📝",
+	"*.odt=📝",
+	"*.rtf=📝",
+	"*.tex=📝",
+	"*.xls=📊",
+	"*.xlsx=📊",
+	"*.ods=📊",
+	"*.csv=📊",
+	"*.ppt=📽",
+	"*.pptx=📽",
+	"*.odp=📽",
+
+	"*.png=🖼",
+	"*.jpg=🖼",
+	"*.jpeg=🖼",
+	"*.gif=🖼",
+	"*.bmp=🖼",
+	"*.svg=🖼",
+	"*.webp=🖼",
+	"*.ico=🖼",
+	"*.tif=🖼",
+	"*.tiff=🖼",
+	"*.xcf=🖼",
+
+	"*.mp3=🎵",
+	"*.flac=🎵",
+	"*.ogg=🎵",
+	"*.opus=🎵",
+	"*.wav=🎵",
+	"*.m4a=🎵",
+	"*.aac=🎵",
+
+	"*.mp4=🎞",
+	"*.mkv=🎞",
+	"*.avi=🎞",
+	"*.mov=🎞",
+	"*.webm=🎞",
+	"*.flv=🎞",
+	"*.wmv=🎞",
+
+	"*.zip=📦",
+	"*.tar=📦",
+	"*.gz=📦",
+	"*.tgz=📦",
+	"*.bz2=📦",
+	"*.xz=📦",
+	"*.zst=📦",
+	"*.7z=📦",
+	"*.rar=📦",
+	"*.deb=📦",
+	"*.rpm=📦",
+
+	"*.iso=💿",
+	"*.img=💿",
+
+	"*.c=💻",
+	"*.h=💻",
+	"*.cpp=💻",
+	"*.hpp=💻",
+	"*.go=💻",
+	"*.rs=💻",
+	"*.py=💻",
+	"*.js=💻",
+	"*.ts=💻",
+	"*.java=💻",
+	"*.rb=💻",
+	"*.lua=💻",
+	"*.html=💻",
+	"*.css=💻",
+	"*.sh=🐚",
+	"*.bash=🐚",
+	"*.zsh=🐚",
+	"*.fish=🐚",
+
+	"*.json=⚙",
+	"*.yaml=⚙",
+	"*.yml=⚙",
+	"*.toml=⚙",
+	"*.ini=⚙",
+	"*.conf=⚙",
+	"*.xml=⚙",
+
+	"*.pem=🔑",
+	"*.key=🔑",
+	"*.gpg=🔑",
+	"*.asc=🔑",
+
+	"Makefile*=🔨",
+	"Dockerfile*=🐳",
+	"LICENSE*=⚖",
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// This function creates a temporary directory with the given entries and
+// returns the directory along with the files read from it by name. Names ending
+// with '/' are created as directories and names of the form 'link@target' are
+// created as symbolic links to the target in the same directory.
+func newIconFiles(t *testing.T, names ...string) (string, map[string]*file) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+
+	for _, name := range names {
+		if strings.HasSuffix(name, "/") {
+			err = os.Mkdir(filepath.Join(dir, name), 0755)
+		} else if ind := strings.IndexByte(name, '@'); ind >= 0 {
+			err = os.Symlink(filepath.Join(dir, name[ind+1:]), filepath.Join(dir, name[:ind]))
+		} else {
+			err = ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			t.Fatalf("creating temporary entry: %s", err)
+		}
+	}
+
+	files, err := readdir(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("reading directory: %s", err)
+	}
+
+	byName := make(map[string]*file)
+	for _, f := range files {
+		byName[f.Name()] = f
+	}
+
+	return dir, byName
+}
+
+func TestDirLinkIcon(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on windows")
+	}
+
+	defer func(dirlinkicon string) { gOpts.dirlinkicon = dirlinkicon }(gOpts.dirlinkicon)
+
+	dir, byName := newIconFiles(t, "dir/", "file", "dir-link@dir", "file-link@file", "missing-link@missing")
+	defer os.RemoveAll(dir)
+
+	icons := iconMap{"di": "D", "ln": "L", "or": "O", "fi": "F"}
+	withdl := iconMap{"di": "D", "ln": "L", "or": "O", "fi": "F", "dl": "X"}
+
+	tests := []struct {
+		dirlinkicon string
+		icons       iconMap
+		name        string
+		exp         string
+	}{
+		{"link", icons, "dir", "D"},
+		{"link", icons, "dir-link", "L"},
+		{"link", icons, "file-link", "L"},
+		{"link", icons, "missing-link", "O"},
+		{"link", withdl, "dir-link", "L"},
+		{"dir", icons, "dir-link", "D"},
+		{"dir", icons, "file-link", "L"},
+		{"dir", icons, "missing-link", "O"},
+		{"dirlink", icons, "dir-link", "D"},
+		{"dirlink", withdl, "dir-link", "X"},
+		{"dirlink", withdl, "dir", "D"},
+		{"dirlink", withdl, "file-link", "L"},
+		{"dirlink", withdl, "missing-link", "O"},
+	}
+
+	for _, test := range tests {
+		gOpts.dirlinkicon = test.dirlinkicon
+		f, ok := byName[test.name]
+		if !ok {
+			t.Fatalf("file not found: %s", test.name)
+		}
+		if got := test.icons.get(f); got != test.exp {
+			t.Errorf("at input '%s' with '%s' expected '%s' but got '%s'", test.name, test.dirlinkicon, test.exp, got)
+		}
+	}
+}
+
+func TestIconCatchAll(t *testing.T) {
+	dir, byName := newIconFiles(t, "dir/", "foo.txt", "foo.go", "noext", "Makefile")
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		icons iconMap
+		name  string
+		exp   string
+	}{
+		{iconMap{"*": "A", "fi": "F", "di": "D", "*.go": "G"}, "foo.go", "G"},
+		{iconMap{"*": "A", "fi": "F", "di": "D", "*.go": "G"}, "foo.txt", "F"},
+		{iconMap{"*": "A", "fi": "F", "di": "D", "*.go": "G"}, "noext", "F"},
+		{iconMap{"*": "A", "fi": "F", "di": "D", "*.go": "G"}, "dir", "D"},
+		{iconMap{"*": "A", "fi": "F", "Makefile*": "M"}, "Makefile", "M"},
+		{iconMap{"*": "A", "fi": "F"}, "dir", "A"},
+		{iconMap{"*": "A"}, "foo.txt", "A"},
+		{iconMap{"*": "A"}, "noext", "A"},
+		{iconMap{"*": "A"}, "dir", "A"},
+		{iconMap{"fi": "F"}, "dir", "F"},
+		{iconMap{"fi": "F"}, "foo.txt", "F"},
+		{iconMap{}, "foo.txt", " "},
+		{iconMap{}, "dir", " "},
+	}
+
+	for _, test := range tests {
+		f, ok := byName[test.name]
+		if !ok {
+			t.Fatalf("file not found: %s", test.name)
+		}
+		if got := test.icons.get(f); got != test.exp {
+			t.Errorf("at input '%s' with '%v' expected '%s' but got '%s'", test.name, test.icons, test.exp, got)
+		}
+	}
+}
+
+func TestIconSet(t *testing.T) {
+	defer func(iconset string) { gOpts.iconset = iconset }(gOpts.iconset)
+	defer os.Setenv("LF_ICONS", os.Getenv("LF_ICONS"))
+
+	dir, byName := newIconFiles(t, "a.pdf", "b.go", "c.unknown", "Makefile")
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		iconset string
+		env     string
+		name    string
+		exp     string
+	}{
+		{"basic", "", "a.pdf", "🗎"},
+		{"basic", "", "b.go", "🗎"},
+		{"basic", "*.go=G", "b.go", "G"},
+		{"extended", "", "a.pdf", "📕"},
+		{"extended", "", "b.go", "💻"},
+		{"extended", "", "c.unknown", "🗎"},
+		{"extended", "", "Makefile", "🔨"},
+		{"extended", "*.go=G", "b.go", "G"},
+		{"extended", "*.go=G", "a.pdf", "📕"},
+		{"extended", "fi=F", "c.unknown", "F"},
+		{"extended", "fi=F", "a.pdf", "📕"},
+	}
+
+	for _, test := range tests {
+		gOpts.iconset = test.iconset
+		os.Setenv("LF_ICONS", test.env)
+		if got := parseIcons().get(byName[test.name]); got != test.exp {
+			t.Errorf("at input '%s' with iconset '%s' and LF_ICONS '%s' expected '%s' but got '%s'", test.name, test.iconset, test.env, test.exp, got)
+		}
+	}
+}
+
+func TestIconAlign(t *testing.T) {
+	dir, files := newIconFiles(t, "dir/", "foo.go", "foo.pdf", "noext")
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		icons iconMap
+		width int
+	}{
+		{iconMap{}, 1},
+		{iconMap{"di": "D", "*.go": "G"}, 1},
+		{iconMap{"di": "📁", "*.go": "G"}, 2},
+		{iconMap{"di": "\033[34m📁\033[0m", "*.pdf": "[p]"}, 3},
+	}
+
+	for _, test := range tests {
+		if got := test.icons.width(); got != test.width {
+			t.Errorf("at input '%v' expected width '%d' but got '%d'", test.icons, test.width, got)
+		}
+
+		// names start after the icon and a separating space
+		for _, f := range files {
+			icon := padIcon(test.icons.get(f), test.icons.width())
+			if got := iconWidth(icon) + 1; got != test.width+1 {
+				t.Errorf("at input '%v' expected name of '%s' to start at column '%d' but got '%d'",
+					test.icons, f.Name(), test.width+1, got)
+			}
+		}
+	}
+
+	if got := padIcon("[p]", 1); got != "[p]" {
+		t.Errorf("expected icons wider than the width to be kept but got '%s'", got)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// These are the terminal emulators tried in order on platforms other than
+// darwin and windows when neither 'terminal' option nor '$TERMINAL' is set.
+var gTerminals = []string{
+	"x-terminal-emulator",
+	"gnome-terminal",
+	"konsole",
+	"xfce4-terminal",
+	"alacritty",
+	"kitty",
+	"xterm",
+}
+
+// This function returns the command line to open the given directory in the
+// file manager of the given platform. A non-empty launcher from 'filemanager'
+// option replaces the platform default and gets the directory as its last
+// argument.
+func fileManagerArgs(goos, launcher, dir string) []string {
+	if args := strings.Fields(launcher); len(args) != 0 {
+		return append(args, dir)
+	}
+
+	switch goos {
+	case "darwin":
+		return []string{"open", dir}
+	case "windows":
+		return []string{"explorer", dir}
+	}
+
+	return []string{"xdg-open", dir}
+}
+
+// This function returns the command line to spawn a terminal in the given
+// directory on the given platform. A non-empty launcher is returned as is
+// since it is run in the directory rather than given it. The lookPath function
+// is used to find an installed terminal emulator when there is no default for
+// the platform.
+func terminalArgs(goos, launcher, dir string, lookPath func(string) (string, error)) ([]string, error) {
+	if args := strings.Fields(launcher); len(args) != 0 {
+		return args, nil
+	}
+
+	switch goos {
+	case "darwin":
+		return []string{"open", "-a", "Terminal", dir}, nil
+	case "windows":
+		return []string{"cmd", "/c", "start", "cmd"}, nil
+	}
+
+	for _, term := range gTerminals {
+		if _, err := lookPath(term); err == nil {
+			return []string{term}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no terminal emulator found (set 'terminal' option)")
+}
+
+// This function starts the given command line in the given directory detached
+// from lf so that it keeps running after lf quits.
+func startDetached(args []string, dir string) error {
+	cmd := detachedCommand(args[0], args[1:]...)
+	cmd.Dir = dir
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
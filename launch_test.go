@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFileManagerArgs(t *testing.T) {
+	tests := []struct {
+		goos     string
+		launcher string
+		dir      string
+		exp      []string
+	}{
+		{"linux", "", "/foo bar", []string{"xdg-open", "/foo bar"}},
+		{"freebsd", "", "/foo", []string{"xdg-open", "/foo"}},
+		{"darwin", "", "/foo", []string{"open", "/foo"}},
+		{"windows", "", `C:\foo`, []string{"explorer", `C:\foo`}},
+		{"linux", "nautilus --new-window", "/foo", []string{"nautilus", "--new-window", "/foo"}},
+		{"darwin", "  open -R  ", "/foo", []string{"open", "-R", "/foo"}},
+		{"windows", "explorer.exe", `C:\foo`, []string{"explorer.exe", `C:\foo`}},
+	}
+
+	for _, test := range tests {
+		if got := fileManagerArgs(test.goos, test.launcher, test.dir); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' with launcher '%s' and dir '%s' expected '%q' but got '%q'",
+				test.goos, test.launcher, test.dir, test.exp, got)
+		}
+	}
+}
+
+func TestTerminalArgs(t *testing.T) {
+	tests := []struct {
+		goos      string
+		launcher  string
+		installed []string
+		exp       []string
+		err       bool
+	}{
+		{"linux", "", []string{"xterm", "kitty"}, []string{"kitty"}, false},
+		{"linux", "", []string{"xterm", "x-terminal-emulator"}, []string{"x-terminal-emulator"}, false},
+		{"openbsd", "", []string{"xterm"}, []string{"xterm"}, false},
+		{"linux", "", nil, nil, true},
+		{"linux", "alacritty -e tmux", nil, []string{"alacritty", "-e", "tmux"}, false},
+		{"darwin", "", nil, []string{"open", "-a", "Terminal", "/foo"}, false},
+		{"darwin", "open -a iTerm .", nil, []string{"open", "-a", "iTerm", "."}, false},
+		{"windows", "", nil, []string{"cmd", "/c", "start", "cmd"}, false},
+		{"windows", "wt", nil, []string{"wt"}, false},
+	}
+
+	for _, test := range tests {
+		lookPath := func(name string) (string, error) {
+			for _, s := range test.installed {
+				if s == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+
+		got, err := terminalArgs(test.goos, test.launcher, "/foo", lookPath)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' with launcher '%s' expected error '%t' but got '%v'",
+				test.goos, test.launcher, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' with launcher '%s' and installed '%v' expected '%q' but got '%q'",
+				test.goos, test.launcher, test.installed, test.exp, got)
+		}
+	}
+}
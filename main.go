@@ -29,6 +29,7 @@ var (
 	gSocketProt    string
 	gSocketPath    string
 	gLogPath       string
+	gResultPath    string
 	gServerLogPath string
 	gSelect        string
 	gCommands      arrayFlag
@@ -58,6 +59,7 @@ func init() {
 
 func exportEnvVars() {
 	os.Setenv("id", strconv.Itoa(gClientID))
+	os.Setenv("LF_RESULT", gResultPath)
 
 	os.Setenv("OPENER", envOpener)
 	os.Setenv("EDITOR", envEditor)
@@ -101,6 +103,31 @@ func fieldToString(field reflect.Value) string {
 	return value
 }
 
+// This function returns the value of the option with the given name in the
+// same format used for exporting options to shell commands. Options of sort
+// type are given with their own names (e.g. 'sortby' and 'reverse').
+func optValue(name string) (string, bool) {
+	switch name {
+	case "sortby":
+		return gSortMethods[gOpts.sortType.method], true
+	case "reverse":
+		return strconv.FormatBool(gOpts.sortType.option&reverseSort != 0), true
+	case "hidden":
+		return strconv.FormatBool(gOpts.sortType.option&hiddenSort != 0), true
+	case "dirfirst":
+		return strconv.FormatBool(gOpts.sortType.option&dirfirstSort != 0), true
+	case "keys", "cmdkeys", "cmds", "sortType":
+		return "", false
+	}
+
+	field := reflect.ValueOf(&gOpts).Elem().FieldByName(name)
+	if !field.IsValid() {
+		return "", false
+	}
+
+	return fieldToString(field), true
+}
+
 func exportOpts() {
 	e := reflect.ValueOf(&gOpts).Elem()
 
@@ -116,35 +143,10 @@ func exportOpts() {
 
 		// Get string representation of the value
 		if name == "lf_sortType" {
-			var sortby string
-
-			switch gOpts.sortType.method {
-			case naturalSort:
-				sortby = "natural"
-			case nameSort:
-				sortby = "name"
-			case sizeSort:
-				sortby = "size"
-			case timeSort:
-				sortby = "time"
-			case ctimeSort:
-				sortby = "ctime"
-			case atimeSort:
-				sortby = "atime"
-			case extSort:
-				sortby = "ext"
+			for _, opt := range []string{"sortby", "reverse", "hidden", "dirfirst"} {
+				value, _ := optValue(opt)
+				os.Setenv("lf_"+opt, value)
 			}
-
-			os.Setenv("lf_sortby", sortby)
-
-			reverse := strconv.FormatBool(gOpts.sortType.option&reverseSort != 0)
-			os.Setenv("lf_reverse", reverse)
-
-			hidden := strconv.FormatBool(gOpts.sortType.option&hiddenSort != 0)
-			os.Setenv("lf_hidden", hidden)
-
-			dirfirst := strconv.FormatBool(gOpts.sortType.option&dirfirstSort != 0)
-			os.Setenv("lf_dirfirst", dirfirst)
 		} else {
 			field := e.Field(i)
 			value := fieldToString(field)
@@ -217,6 +219,11 @@ func main() {
 		"",
 		"path to the file to write selected files on open (to use as open file dialog)")
 
+	flag.BoolVar(&gOpts.readonly,
+		"readonly",
+		false,
+		"disable commands modifying files")
+
 	flag.Var(&gCommands,
 		"command",
 		"command to execute on client initialization")
@@ -255,6 +262,11 @@ func main() {
 
 		gClientID = os.Getpid()
 		gLogPath = filepath.Join(os.TempDir(), fmt.Sprintf("lf.%s.%d.log", gUser.Username, gClientID))
+		if path, err := newResultPath(); err != nil {
+			log.Printf("creating result directory: %s", err)
+		} else {
+			gResultPath = path
+		}
 		switch flag.NArg() {
 		case 0:
 			_, err := os.Getwd()
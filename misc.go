@@ -1,12 +1,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
 )
@@ -22,8 +26,10 @@ func replaceTilde(s string) string {
 
 func runeSliceWidth(rs []rune) int {
 	w := 0
-	for _, r := range rs {
-		w += runewidth.RuneWidth(r)
+	for i := 0; i < len(rs); {
+		n := graphemeLen(rs[i:])
+		w += graphemeWidth(rs[i : i+n])
+		i += n
 	}
 	return w
 }
@@ -31,32 +37,203 @@ func runeSliceWidth(rs []rune) int {
 func runeSliceWidthRange(rs []rune, beg, end int) []rune {
 	curr := 0
 	b := 0
-	for i, r := range rs {
-		w := runewidth.RuneWidth(r)
+	prev := 0
+	for i := 0; i < len(rs); {
+		n := graphemeLen(rs[i:])
+		w := graphemeWidth(rs[i : i+n])
 		switch {
 		case curr == beg:
 			b = i
 		case curr < beg && curr+w > beg:
-			b = i + 1
+			b = i + n
 		case curr == end:
 			return rs[b:i]
 		case curr > end:
-			return rs[b : i-1]
+			return rs[b:max(b, prev)]
 		}
 		curr += w
+		prev = i
+		i += n
 	}
 	return nil
 }
 
+func isRegionalIndicator(r rune) bool { return 0x1F1E6 <= r && r <= 0x1F1FF }
+
+// This function returns whether the given rune extends the grapheme cluster
+// of the previous rune instead of starting a new one. This is a simplified
+// version of the rules in unicode text segmentation (UAX #29) which handles
+// combining marks, variation selectors, emoji modifiers and tags, and zero
+// width joiner sequences. Regional indicators are paired separately.
+func isGraphemeExtend(prev, r rune) bool {
+	switch {
+	case prev == '\u200d':
+		return !unicode.IsControl(r)
+	case r == '\u200d':
+		return true
+	case 0x1F3FB <= r && r <= 0x1F3FF: // emoji modifiers
+		return true
+	case 0xE0020 <= r && r <= 0xE007F: // tags
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
+
+// This function returns whether the given rune continues the given grapheme
+// cluster. Regional indicators are paired to form flags.
+func graphemeContinues(g []rune, r rune) bool {
+	if isRegionalIndicator(g[0]) {
+		return len(g) == 1 && isRegionalIndicator(r)
+	}
+	return isGraphemeExtend(g[len(g)-1], r)
+}
+
+// This function returns the number of runes in the first grapheme cluster of
+// the given runes.
+func graphemeLen(rs []rune) int {
+	if len(rs) == 0 {
+		return 0
+	}
+
+	n := 1
+	for n < len(rs) && graphemeContinues(rs[:n], rs[n]) {
+		n++
+	}
+	return n
+}
+
+// This function decodes the first grapheme cluster of the given string and
+// returns its runes and its size in bytes.
+func nextGrapheme(s string) ([]rune, int) {
+	r, n := utf8.DecodeRuneInString(s)
+	g := []rune{r}
+	for n < len(s) {
+		r, w := utf8.DecodeRuneInString(s[n:])
+		if !graphemeContinues(g, r) {
+			break
+		}
+		g = append(g, r)
+		n += w
+	}
+	return g, n
+}
+
+// This function returns the display width of the given grapheme cluster. The
+// width of the cluster is the width of its first rune, except emoji
+// presentation selector (U+FE0F) and flags made of regional indicators are
+// shown in two columns.
+func graphemeWidth(g []rune) int {
+	r := g[0]
+	if isRawRune(r) {
+		r = utf8.RuneError
+	}
+
+	w := runewidth.RuneWidth(r)
+
+	if isRegionalIndicator(r) && len(g) == 2 {
+		return 2
+	}
+
+	for _, r := range g[1:] {
+		if r == '\ufe0f' && w == 1 {
+			return 2
+		}
+	}
+
+	return w
+}
+
+// This function truncates the given runes to the given display width and
+// shows the given ellipsis at the end when the runes do not fit. Grapheme
+// clusters are never split and the result is padded with spaces when a wide
+// cluster does not fit, so the result is always exactly the given width when
+// the runes are truncated.
+func truncateRunes(rs []rune, width int, ellipsis string) []rune {
+	if width <= 0 {
+		return nil
+	}
+
+	if runeSliceWidth(rs) <= width {
+		return rs
+	}
+
+	es := []rune(ellipsis)
+	limit := width - runeSliceWidth(es)
+	if limit < 0 {
+		return truncateRunes(es, width, "")
+	}
+
+	var res []rune
+	w := 0
+	for i := 0; i < len(rs); {
+		n := graphemeLen(rs[i:])
+		gw := graphemeWidth(rs[i : i+n])
+		if w+gw > limit {
+			break
+		}
+		res = append(res, rs[i:i+n]...)
+		w += gw
+		i += n
+	}
+
+	for ; w < limit; w++ {
+		res = append(res, ' ')
+	}
+
+	return append(res, es...)
+}
+
+// File names are arbitrary bytes on unix and they are not necessarily valid
+// utf-8 strings. Converting such names to rune slices (e.g. to edit them in the
+// command line) replaces invalid bytes with the replacement character (U+FFFD)
+// and the original name is lost. Instead, the following functions map each
+// invalid byte to a surrogate code point in the range U+DC80 to U+DCFF, which
+// can not appear in a valid utf-8 string, and map them back to the original
+// bytes afterwards. This is similar to the 'surrogateescape' error handler in
+// python. Such runes are still displayed as the replacement character.
+const (
+	gRawRuneBeg = 0xDC80
+	gRawRuneEnd = 0xDCFF
+)
+
+func isRawRune(r rune) bool { return gRawRuneBeg <= r && r <= gRawRuneEnd }
+
+func rawRunes(s string) []rune {
+	rs := make([]rune, 0, len(s))
+	for i := 0; i < len(s); {
+		r, w := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && w == 1 && s[i] >= 0x80 {
+			r = gRawRuneBeg - 0x80 + rune(s[i])
+		}
+		rs = append(rs, r)
+		i += w
+	}
+	return rs
+}
+
+func rawString(rs []rune) string {
+	buf := make([]byte, 0, len(rs))
+	for _, r := range rs {
+		if isRawRune(r) {
+			buf = append(buf, byte(r-gRawRuneBeg+0x80))
+			continue
+		}
+		buf = append(buf, string(r)...)
+	}
+	return string(buf)
+}
+
 // This function is used to escape whitespaces and special characters with
 // backlashes in a given string.
 func escape(s string) string {
-	buf := make([]rune, 0, len(s))
-	for _, r := range s {
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); {
+		r, w := utf8.DecodeRuneInString(s[i:])
 		if unicode.IsSpace(r) || r == '\\' || r == ';' || r == '#' {
 			buf = append(buf, '\\')
 		}
-		buf = append(buf, r)
+		buf = append(buf, s[i:i+w]...)
+		i += w
 	}
 	return string(buf)
 }
@@ -65,22 +242,26 @@ func escape(s string) string {
 // whitespaces and special characters in a given string.
 func unescape(s string) string {
 	esc := false
-	buf := make([]rune, 0, len(s))
-	for _, r := range s {
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); {
+		r, w := utf8.DecodeRuneInString(s[i:])
 		if esc {
 			if !unicode.IsSpace(r) && r != '\\' && r != ';' && r != '#' {
 				buf = append(buf, '\\')
 			}
-			buf = append(buf, r)
+			buf = append(buf, s[i:i+w]...)
 			esc = false
+			i += w
 			continue
 		}
 		if r == '\\' {
 			esc = true
+			i += w
 			continue
 		}
 		esc = false
-		buf = append(buf, r)
+		buf = append(buf, s[i:i+w]...)
+		i += w
 	}
 	if esc {
 		buf = append(buf, '\\')
@@ -88,6 +269,30 @@ func unescape(s string) string {
 	return string(buf)
 }
 
+// This function quotes the given string as a single argument of a shell
+// command on the given platform. Strings with characters other than letters,
+// digits, and a few punctuation characters are quoted in single quotes for
+// posix shells, and in double quotes on windows where double quotes are not
+// allowed in file names. Note that percent signs can not be escaped in double
+// quotes on windows, so environment variables (e.g. '%PATH%') are still
+// expanded there.
+func shellQuote(s, goos string) string {
+	safe := s != ""
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("@%+=:,./-_", r) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	if goos == "windows" {
+		return `"` + s + `"`
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
 // This function splits the given string by whitespaces. It is aware of escaped
 // whitespaces so that they are not splitted unintentionally.
 func tokenize(s string) []string {
@@ -133,6 +338,234 @@ func splitWord(s string) (word, rest string) {
 	return
 }
 
+// This function splits the stem of a file name into words for case
+// conversions. Whitespaces, dashes, and underscores are considered as
+// separators and a lowercase letter or digit followed by an uppercase letter
+// is considered as a word boundary (e.g. 'fooBar' is split as 'foo' and 'Bar').
+func caseWords(s string) []string {
+	var words []string
+	var word []rune
+	var prev rune
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = []rune{r}
+		default:
+			word = append(word, r)
+		}
+		prev = r
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// This function returns the extension of the given file name. Names starting
+// with a dot and without any other dots (e.g. '.bashrc') are considered to
+// have no extension.
+func nameExt(name string) string {
+	ext := filepath.Ext(name)
+	if ext == name {
+		return ""
+	}
+	return ext
+}
+
+// This function returns the compound extension of the given file name, which
+// starts from the first dot after any leading dots (e.g. '.tar.gz' for
+// 'foo.tar.gz' or '.conf.bak' for '.foo.conf.bak').
+func nameExtAll(name string) string {
+	stem := strings.TrimLeft(name, ".")
+	if ind := strings.IndexByte(stem, '.'); ind >= 0 {
+		return stem[ind:]
+	}
+	return ""
+}
+
+// This function replaces the extension of the given file name with the given
+// extension, which can be given with or without a leading dot. An extension is
+// added when the name has none and it is removed when the given extension is
+// empty. Only the last extension is replaced unless 'all' is set, in which case
+// the compound extension (e.g. '.tar.gz') is replaced as a whole.
+func changeExt(name, ext string, all bool) string {
+	old := nameExt(name)
+	if all {
+		old = nameExtAll(name)
+	}
+
+	ext = strings.TrimPrefix(ext, ".")
+	if ext != "" {
+		ext = "." + ext
+	}
+
+	return strings.TrimSuffix(name, old) + ext
+}
+
+// This function converts the case of the given file name using one of the
+// methods 'lower', 'upper', 'title', 'snake', or 'kebab'. Only the stem of the
+// name is converted and the extension returned by 'nameExt' is kept as is.
+func changeCase(name, method string) (string, error) {
+	ext := nameExt(name)
+	stem := strings.TrimSuffix(name, ext)
+
+	switch method {
+	case "lower":
+		stem = strings.ToLower(stem)
+	case "upper":
+		stem = strings.ToUpper(stem)
+	case "title":
+		rs := []rune(stem)
+		for i, r := range rs {
+			if i == 0 || !unicode.IsLetter(rs[i-1]) && !unicode.IsDigit(rs[i-1]) {
+				rs[i] = unicode.ToTitle(r)
+			} else {
+				rs[i] = unicode.ToLower(r)
+			}
+		}
+		stem = string(rs)
+	case "snake", "kebab":
+		sep := "_"
+		if method == "kebab" {
+			sep = "-"
+		}
+		stem = strings.ToLower(strings.Join(caseWords(stem), sep))
+	default:
+		return "", fmt.Errorf("unknown method: %s", method)
+	}
+
+	if stem == "" {
+		return name, nil
+	}
+
+	return stem + ext, nil
+}
+
+var reSeqNum = regexp.MustCompile(`\{n(:[^}]*)?\}`)
+
+// This function expands the '{n}' placeholder in the given template with the
+// given number to make a sequential name. Numbers are padded with zeros to the
+// width given in the placeholder (e.g. '{n:3}' for '001') or to the number of
+// digits of the total count otherwise (e.g. '01' to '12' for 12 files).
+func seqName(template string, n, total int) (string, error) {
+	loc := reSeqNum.FindStringSubmatchIndex(template)
+	if loc == nil {
+		return "", errors.New("template should contain '{n}' placeholder")
+	}
+
+	width := len(strconv.Itoa(total))
+	if loc[2] != -1 {
+		w, err := strconv.Atoi(template[loc[2]+1 : loc[3]])
+		if err != nil || w < 0 {
+			return "", fmt.Errorf("invalid placeholder: %s", template[loc[0]:loc[1]])
+		}
+		width = w
+	}
+
+	if reSeqNum.MatchString(template[loc[1]:]) {
+		return "", errors.New("template should contain a single '{n}' placeholder")
+	}
+
+	return template[:loc[0]] + fmt.Sprintf("%0*d", width, n) + template[loc[1]:], nil
+}
+
+// This type is used to represent a mounted filesystem or an unmounted device
+// listed in the menu of 'mounts' command.
+type mountEntry struct {
+	dev       string
+	dir       string
+	fstype    string
+	removable bool
+}
+
+// This function returns the menu item of the entry with columns separated by
+// tabs to be aligned in the menu.
+func (m mountEntry) String() string {
+	dir := m.dir
+	if dir == "" {
+		dir = "(not mounted)"
+	}
+	s := m.dev + "\t" + dir + "\t" + m.fstype
+	if m.removable {
+		s += "\tremovable"
+	}
+	return s
+}
+
+// This function returns the given mode with the execute bit of owner flipped,
+// or with execute bits of owner, group, and others all flipped depending on
+// the execute bit of owner when 'all' is set.
+func toggleExec(mode os.FileMode, all bool) os.FileMode {
+	bits := os.FileMode(0100)
+	if all {
+		bits = 0111
+	}
+	if mode&0100 != 0 {
+		return mode &^ bits
+	}
+	return mode | bits
+}
+
+// This function returns the 'file://' URI of the given absolute path with
+// special characters percent-encoded (e.g. 'file:///foo%20bar').
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u := url.URL{Scheme: "file", Path: path}
+	return u.String()
+}
+
+// This function returns the MIME type and the clipboard payload of the given
+// files in the given format. Format 'uri-list' is the 'text/uri-list' type
+// with a URI on each line terminated by CRLF as in RFC 2483. Format 'gnome' is
+// the 'x-special/gnome-copied-files' type used by gnome and other file
+// managers, which starts with the 'copy' action followed by the URIs.
+func clipboardPayload(format string, paths []string) (mime, data string, err error) {
+	var uris []string
+	for _, path := range paths {
+		uris = append(uris, fileURI(path))
+	}
+
+	switch format {
+	case "uri-list":
+		return "text/uri-list", strings.Join(uris, "\r\n") + "\r\n", nil
+	case "gnome":
+		return "x-special/gnome-copied-files", "copy\n" + strings.Join(uris, "\n"), nil
+	default:
+		return "", "", fmt.Errorf("unknown format: %s (should either be 'uri-list' or 'gnome')", format)
+	}
+}
+
+// This function returns the given path relative to the given base directory,
+// which is used by 'yank-relative' command. A leading tilde in the base is
+// replaced with the home directory and relative bases are resolved against the
+// given working directory. Bases that are not ancestors of the path result in
+// parent directory elements (e.g. '../foo/bar').
+func relativePath(base, path, wd string) (string, error) {
+	base = replaceTilde(base)
+	if !filepath.IsAbs(base) {
+		base = filepath.Join(wd, base)
+	}
+	return filepath.Rel(base, path)
+}
+
+// This function returns whether the given path is the same as the given
+// directory or it is under the directory.
+func isSubdir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // This function converts a size in bytes to a human readable form using metric
 // suffixes (e.g. 1K = 1000). For values less than 10 the first significant
 // digit is shown, otherwise it is hidden. Numbers are always rounded down.
@@ -166,6 +599,71 @@ func humanize(size int64) string {
 	return ""
 }
 
+// This type is a threshold given in the syntax of 'find' with an optional
+// sign where '+' matches values greater than the threshold, '-' matches values
+// less than the threshold, and no sign matches values that are the same when
+// truncated to the unit of the threshold.
+type threshold struct {
+	sign int
+	n    int64
+	unit int64
+}
+
+func (t threshold) match(v int64) bool {
+	switch t.sign {
+	case 1:
+		return v > t.n*t.unit
+	case -1:
+		return v < t.n*t.unit
+	}
+	return v >= 0 && v/t.unit == t.n
+}
+
+// This function parses a threshold with the given unit suffixes. The first
+// unit is used when there is no suffix and the rest of the units correspond to
+// the suffixes in order. Suffixes are case insensitive.
+func parseThreshold(s string, suffixes string, units []int64) (threshold, error) {
+	var t threshold
+
+	num := s
+	switch {
+	case strings.HasPrefix(num, "+"):
+		t.sign = 1
+		num = num[1:]
+	case strings.HasPrefix(num, "-"):
+		t.sign = -1
+		num = num[1:]
+	}
+
+	t.unit = units[0]
+	if len(num) > 0 {
+		if i := strings.IndexByte(suffixes, byte(unicode.ToLower(rune(num[len(num)-1])))); i >= 0 {
+			t.unit = units[i+1]
+			num = num[:len(num)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return threshold{}, fmt.Errorf("invalid threshold: %s", s)
+	}
+	t.n = n
+
+	return t, nil
+}
+
+// This function parses a size threshold (e.g. '+100M') in bytes. Units are
+// the same as the ones shown by 'humanize' which are powers of 1000.
+func parseSizeThreshold(s string) (threshold, error) {
+	return parseThreshold(s, "bkmgt", []int64{1, 1, 1e3, 1e6, 1e9, 1e12})
+}
+
+// This function parses an age threshold (e.g. '+30d') in seconds. Days are
+// used when there is no unit.
+func parseAgeThreshold(s string) (threshold, error) {
+	return parseThreshold(s, "smhdw", []int64{86400, 1, 60, 3600, 86400, 7 * 86400})
+}
+
 // This function compares two strings for natural sorting which takes into
 // account values of numbers in strings. For example, '2' is less than '10',
 // and similarly 'foo2bar' is less than 'foo10bar', but 'bar2bar' is greater
@@ -207,7 +705,7 @@ func naturalLess(s1, s2 string) bool {
 	}
 }
 
-var reAltKey = regexp.MustCompile(`<a-(.)>`)
+var reFuncKey = regexp.MustCompile(`^<f([0-9]+)>$`)
 
 var reWord = regexp.MustCompile(`(\pL|\pN)+`)
 var reWordBeg = regexp.MustCompile(`([^\pL\pN]|^)(\pL|\pN)`)
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"testing"
 )
 
@@ -36,6 +40,11 @@ func TestRuneSliceWidth(t *testing.T) {
 		{[]rune{'ı', 'ş'}, 2},
 		{[]rune{'世', '界'}, 4},
 		{[]rune{'世', 'a', '界', 'ı'}, 6},
+		{[]rune("e\u0301a"), 2},
+		{[]rune("\U0001F468\u200d\U0001F469\u200d\U0001F467"), 2},
+		{[]rune("\U0001F44D\U0001F3FD"), 2},
+		{[]rune("\u2764\ufe0f"), 2},
+		{[]rune("\U0001F1F9\U0001F1F7\U0001F1FA"), 3},
 	}
 
 	for _, test := range tests {
@@ -62,6 +71,9 @@ func TestRuneSliceWidthRange(t *testing.T) {
 		{[]rune{'世', 'a', '界', 'ı'}, 2, 4, []rune{'a'}},
 		{[]rune{'世', 'a', '界', 'ı'}, 3, 5, []rune{'界'}},
 		{[]rune{'世', 'a', '界', 'ı'}, 3, 4, []rune{}},
+		{[]rune("ae\u0301b"), 1, 2, []rune("e\u0301")},
+		{[]rune("a\u2764\ufe0fb"), 0, 2, []rune("a")},
+		{[]rune("a\u2764\ufe0fb"), 0, 3, []rune("a\u2764\ufe0f")},
 	}
 
 	for _, test := range tests {
@@ -71,6 +83,76 @@ func TestRuneSliceWidthRange(t *testing.T) {
 	}
 }
 
+func TestGraphemeLen(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp int
+	}{
+		{"", 0},
+		{"ab", 1},
+		{"e\u0301\u0302b", 3},
+		{"\U0001F468\u200d\U0001F469\u200d\U0001F467a", 5},
+		{"\U0001F44D\U0001F3FDa", 2},
+		{"\u2764\ufe0fa", 2},
+		{"\U0001F3F4\U000E0067\U000E0062\U000E007F", 4},
+		{"\U0001F1F9\U0001F1F7\U0001F1FA\U0001F1F8", 2},
+		{"\U0001F1F9a", 1},
+		{"\u200d\n", 1},
+	}
+
+	for _, test := range tests {
+		if got := graphemeLen([]rune(test.s)); got != test.exp {
+			t.Errorf("at input '%q' expected '%d' but got '%d'", test.s, test.exp, got)
+		}
+		g, n := nextGrapheme(test.s)
+		if test.s == "" {
+			continue
+		}
+		if len(g) != test.exp || string(g) != test.s[:n] {
+			t.Errorf("at input '%q' expected grapheme with '%d' runes but got '%q'", test.s, test.exp, string(g))
+		}
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s        string
+		width    int
+		ellipsis string
+		exp      string
+	}{
+		{"abc", 3, "~", "abc"},
+		{"abcd", 3, "~", "ab~"},
+		{"abcd", 3, "…", "ab…"},
+		{"abcdef", 5, "...", "ab..."},
+		{"abcd", 2, "...", ".."},
+		{"世界世界", 5, "~", "世界~"},
+		{"世界世界", 4, "~", "世 ~"},
+		{"a世界", 3, "~", "a ~"},
+		{"ae\u0301cd", 3, "~", "ae\u0301~"},
+		{"abe\u0301d", 3, "~", "ab~"},
+		{"ab\U0001F468\u200d\U0001F469\u200d\U0001F467", 4, "~", "ab\U0001F468\u200d\U0001F469\u200d\U0001F467"},
+		{"ab\U0001F468\u200d\U0001F469\u200d\U0001F467c", 4, "~", "ab ~"},
+		{"a\U0001F468\u200d\U0001F469\u200d\U0001F467bc", 4, "~", "a\U0001F468\u200d\U0001F469\u200d\U0001F467~"},
+		{"a\U0001F1F9\U0001F1F7bc", 3, "~", "a ~"},
+		{"a\u2764\ufe0fbc", 4, "…", "a\u2764\ufe0f…"},
+		{"abc", 0, "~", ""},
+		{"abc", -1, "~", ""},
+		{"abc", -1, "", ""},
+		{"", -1, "~", ""},
+	}
+
+	for _, test := range tests {
+		got := string(truncateRunes([]rune(test.s), test.width, test.ellipsis))
+		if got != test.exp {
+			t.Errorf("at input '%q' with width '%d' and ellipsis '%s' expected '%q' but got '%q'", test.s, test.width, test.ellipsis, test.exp, got)
+		}
+		if w := runeSliceWidth([]rune(got)); w > 0 && w > test.width {
+			t.Errorf("at input '%q' expected width at most '%d' but got '%d'", test.s, test.width, w)
+		}
+	}
+}
+
 func TestEscape(t *testing.T) {
 	tests := []struct {
 		s   string
@@ -96,6 +178,31 @@ func TestEscape(t *testing.T) {
 	}
 }
 
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		s    string
+		goos string
+		exp  string
+	}{
+		{"", "linux", "''"},
+		{"/foo/bar.txt", "linux", "/foo/bar.txt"},
+		{"/foo bar/baz", "linux", "'/foo bar/baz'"},
+		{"foo'bar", "linux", `'foo'\''bar'`},
+		{"$HOME;rm *", "linux", "'$HOME;rm *'"},
+		{"foo\nbar", "linux", "'foo\nbar'"},
+		{"çalışma", "linux", "çalışma"},
+		{`C:\foo bar`, "windows", `"C:\foo bar"`},
+		{"foo&bar", "windows", `"foo&bar"`},
+		{"foo", "windows", "foo"},
+	}
+
+	for _, test := range tests {
+		if got := shellQuote(test.s, test.goos); got != test.exp {
+			t.Errorf("at input '%s' on '%s' expected '%s' but got '%s'", test.s, test.goos, test.exp, got)
+		}
+	}
+}
+
 func TestUnescape(t *testing.T) {
 	tests := []struct {
 		s   string
@@ -218,3 +325,348 @@ func TestNaturalLess(t *testing.T) {
 		}
 	}
 }
+
+func TestRawRunes(t *testing.T) {
+	tests := []string{
+		"",
+		"foo",
+		"föö",
+		"foo\xffbar",
+		"\x80\xfe",
+		"f\xc3",
+		"日本\xe6\x97",
+	}
+
+	for _, test := range tests {
+		if got := rawString(rawRunes(test)); got != test {
+			t.Errorf("at input '%q' expected '%q' but got '%q'", test, test, got)
+		}
+	}
+
+	if w := runeSliceWidth(rawRunes("foo\xffbar")); w != 7 {
+		t.Errorf("at input '%q' expected '%d' but got '%d'", "foo\xffbar", 7, w)
+	}
+}
+
+func TestRawRename(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := "foo\xffbar"
+	if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+		t.Skipf("creating file with invalid utf-8 name: %s", err)
+	}
+
+	old := filepath.Join(dir, name)
+	if _, err := os.Lstat(filepath.Join(dir, rawString(rawRunes(name)))); err != nil {
+		t.Errorf("unedited name does not target '%q': %s", old, err)
+	}
+
+	acc := append(rawRunes(name), []rune(".txt")...)
+	if err := os.Rename(old, filepath.Join(dir, rawString(acc))); err != nil {
+		t.Errorf("renaming '%q': %s", old, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, name+".txt")); err != nil {
+		t.Errorf("renamed file not found: %s", err)
+	}
+}
+
+func TestChangeCase(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		exp    string
+	}{
+		{"Foo Bar.TXT", "lower", "foo bar.TXT"},
+		{"foo bar.txt", "upper", "FOO BAR.txt"},
+		{"hello wORLD-foo.md", "title", "Hello World-Foo.md"},
+		{"Hello World.tar.gz", "snake", "hello_world.tar.gz"},
+		{"  Hello -- World  .txt", "snake", "hello_world.txt"},
+		{"fooBar baz_qux.go", "snake", "foo_bar_baz_qux.go"},
+		{"Hello World_Foo.txt", "kebab", "hello-world-foo.txt"},
+		{"fooBar", "kebab", "foo-bar"},
+		{".Bashrc", "lower", ".bashrc"},
+		{".Hidden File.TXT", "snake", ".hidden_file.TXT"},
+		{"ÇAĞRI.txt", "lower", "çağri.txt"},
+		{"ǆemal.txt", "title", "ǅemal.txt"},
+		{"élan vital", "title", "Élan Vital"},
+		{"Ünïcode Wörds.txt", "kebab", "ünïcode-wörds.txt"},
+		{"---.txt", "snake", "---.txt"},
+	}
+
+	for _, test := range tests {
+		got, err := changeCase(test.name, test.method)
+		if err != nil {
+			t.Errorf("at input '%s' with method '%s' unexpected error: %s", test.name, test.method, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' with method '%s' expected '%s' but got '%s'", test.name, test.method, test.exp, got)
+		}
+	}
+
+	if _, err := changeCase("foo", "camel"); err == nil {
+		t.Errorf("at method 'camel' expected an error")
+	}
+}
+
+func TestChangeExt(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		all  bool
+		exp  string
+	}{
+		{"photo.jpeg", "jpg", false, "photo.jpg"},
+		{"photo.jpeg", ".jpg", false, "photo.jpg"},
+		{"photo", "jpg", false, "photo.jpg"},
+		{"photo.jpeg", "", false, "photo"},
+		{"photo.jpeg", ".", false, "photo"},
+		{"photo", "", false, "photo"},
+		{"foo.tar.gz", "zst", false, "foo.tar.zst"},
+		{"foo.tar.gz", "tgz", true, "foo.tgz"},
+		{"foo.tar.gz", "", true, "foo"},
+		{"foo.tar.gz", "", false, "foo.tar"},
+		{"foo", "txt", true, "foo.txt"},
+		{".bashrc", "bak", false, ".bashrc.bak"},
+		{".bashrc", "bak", true, ".bashrc.bak"},
+		{".bashrc", "", false, ".bashrc"},
+		{".foo.conf.bak", "", true, ".foo"},
+		{".foo.conf.bak", "old", false, ".foo.conf.old"},
+		{"foo.", "txt", false, "foo.txt"},
+	}
+
+	for _, test := range tests {
+		if got := changeExt(test.name, test.ext, test.all); got != test.exp {
+			t.Errorf("at input '%s' with extension '%s' and all '%t' expected '%s' but got '%s'", test.name, test.ext, test.all, test.exp, got)
+		}
+	}
+}
+
+func TestSeqName(t *testing.T) {
+	tests := []struct {
+		template string
+		n        int
+		total    int
+		exp      string
+		err      bool
+	}{
+		{"photo_{n}", 1, 5, "photo_1", false},
+		{"photo_{n}", 1, 12, "photo_01", false},
+		{"photo_{n}", 12, 12, "photo_12", false},
+		{"photo_{n}", 7, 100, "photo_007", false},
+		{"photo_{n:3}", 1, 5, "photo_001", false},
+		{"photo_{n:3}", 1234, 2000, "photo_1234", false},
+		{"photo_{n:0}", 5, 100, "photo_5", false},
+		{"{n:2} - song", 3, 5, "03 - song", false},
+		{"a{n}b", 4, 9, "a4b", false},
+		{"photo", 1, 5, "", true},
+		{"photo_{n:x}", 1, 5, "", true},
+		{"photo_{n:-1}", 1, 5, "", true},
+		{"{n}_{n}", 1, 5, "", true},
+		{"{name}", 1, 5, "", true},
+	}
+
+	for _, test := range tests {
+		got, err := seqName(test.template, test.n, test.total)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' with '%d/%d' expected error '%t' but got '%v'", test.template, test.n, test.total, test.err, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' with '%d/%d' expected '%s' but got '%s'", test.template, test.n, test.total, test.exp, got)
+		}
+	}
+}
+
+func TestIsSubdir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		path string
+		exp  bool
+	}{
+		{"/media/usb", "/media/usb", true},
+		{"/media/usb", "/media/usb/foo/bar", true},
+		{"/media/usb", "/media/usb2", false},
+		{"/media/usb", "/media", false},
+		{"/media/usb", "/home/user", false},
+		{"/", "/home/user", true},
+	}
+
+	for _, test := range tests {
+		if got := isSubdir(test.dir, test.path); got != test.exp {
+			t.Errorf("at input '%s' and '%s' expected '%t' but got '%t'", test.dir, test.path, test.exp, got)
+		}
+	}
+}
+
+func TestToggleExec(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		all  bool
+		exp  os.FileMode
+	}{
+		{0644, false, 0744},
+		{0744, false, 0644},
+		{0755, false, 0655},
+		{0655, false, 0755},
+		{0600, false, 0700},
+		{0644, true, 0755},
+		{0755, true, 0644},
+		{0655, true, 0755},
+		{0711, true, 0600},
+		{0700, true, 0600},
+		{0744 | os.ModeSetuid, false, 0644 | os.ModeSetuid},
+	}
+
+	for _, test := range tests {
+		if got := toggleExec(test.mode, test.all); got != test.exp {
+			t.Errorf("at input '%v' with all '%t' expected '%v' but got '%v'", test.mode, test.all, test.exp, got)
+		}
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("relative paths are tested with unix paths")
+	}
+
+	defer func(home string) { gUser.HomeDir = home }(gUser.HomeDir)
+	gUser.HomeDir = "/home/user"
+
+	tests := []struct {
+		base string
+		path string
+		wd   string
+		exp  string
+	}{
+		{"/home/user", "/home/user/docs/a.md", "/", "docs/a.md"},
+		{"/home/user/docs", "/home/user/docs/a.md", "/", "a.md"},
+		{"/home/user/docs/", "/home/user/docs/a.md", "/", "a.md"},
+		{"/home/user/docs/a.md", "/home/user/docs/a.md", "/", "."},
+		{"/home/user/src", "/home/user/docs/a.md", "/", "../docs/a.md"},
+		{"/home/user/src/lf/cmd", "/home/user/docs/a.md", "/", "../../../docs/a.md"},
+		{"/home/user/docs/sub", "/home/user/docs", "/", ".."},
+		{"/opt", "/home/user/docs/a.md", "/", "../home/user/docs/a.md"},
+		{"/", "/home/user/docs/a.md", "/", "home/user/docs/a.md"},
+		{"~", "/home/user/docs/a.md", "/", "docs/a.md"},
+		{"~/src", "/home/user/docs/a.md", "/", "../docs/a.md"},
+		{"docs", "/home/user/docs/a.md", "/home/user", "a.md"},
+		{"..", "/home/user/docs/a.md", "/home/user/docs", "docs/a.md"},
+		{"../src", "/home/user/docs/a.md", "/home/user/docs", "../docs/a.md"},
+	}
+
+	for _, test := range tests {
+		got, err := relativePath(test.base, test.path, test.wd)
+		if err != nil {
+			t.Errorf("at input '%s' and '%s' unexpected error: %s", test.base, test.path, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' and '%s' expected '%s' but got '%s'", test.base, test.path, test.exp, got)
+		}
+	}
+}
+
+func TestClipboardPayload(t *testing.T) {
+	tests := []struct {
+		format  string
+		paths   []string
+		expMime string
+		expData string
+	}{
+		{"uri-list", []string{"/foo"}, "text/uri-list", "file:///foo\r\n"},
+		{"uri-list", []string{"/foo", "/bar/baz"}, "text/uri-list", "file:///foo\r\nfile:///bar/baz\r\n"},
+		{"uri-list", []string{"/foo bar/100%.txt"}, "text/uri-list", "file:///foo%20bar/100%25.txt\r\n"},
+		{"uri-list", []string{"/föö/#qux?"}, "text/uri-list", "file:///f%C3%B6%C3%B6/%23qux%3F\r\n"},
+		{"gnome", []string{"/foo"}, "x-special/gnome-copied-files", "copy\nfile:///foo"},
+		{"gnome", []string{"/foo", "/bar baz"}, "x-special/gnome-copied-files", "copy\nfile:///foo\nfile:///bar%20baz"},
+	}
+
+	for _, test := range tests {
+		mime, data, err := clipboardPayload(test.format, test.paths)
+		if err != nil {
+			t.Errorf("at input '%v' with format '%s' unexpected error: %s", test.paths, test.format, err)
+			continue
+		}
+		if mime != test.expMime || data != test.expData {
+			t.Errorf("at input '%v' with format '%s' expected '%s' and '%q' but got '%s' and '%q'", test.paths, test.format, test.expMime, test.expData, mime, data)
+		}
+	}
+
+	if _, _, err := clipboardPayload("kde", []string{"/foo"}); err == nil {
+		t.Errorf("expected an error for unknown format")
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		s     string
+		parse func(string) (threshold, error)
+		exp   threshold
+		err   bool
+	}{
+		{"100", parseSizeThreshold, threshold{0, 100, 1}, false},
+		{"+100M", parseSizeThreshold, threshold{1, 100, 1e6}, false},
+		{"-5k", parseSizeThreshold, threshold{-1, 5, 1e3}, false},
+		{"+2G", parseSizeThreshold, threshold{1, 2, 1e9}, false},
+		{"1T", parseSizeThreshold, threshold{0, 1, 1e12}, false},
+		{"+10b", parseSizeThreshold, threshold{1, 10, 1}, false},
+		{"+30", parseAgeThreshold, threshold{1, 30, 86400}, false},
+		{"+30d", parseAgeThreshold, threshold{1, 30, 86400}, false},
+		{"-2h", parseAgeThreshold, threshold{-1, 2, 3600}, false},
+		{"10m", parseAgeThreshold, threshold{0, 10, 60}, false},
+		{"+1W", parseAgeThreshold, threshold{1, 1, 7 * 86400}, false},
+		{"-90s", parseAgeThreshold, threshold{-1, 90, 1}, false},
+		{"", parseSizeThreshold, threshold{}, true},
+		{"+", parseSizeThreshold, threshold{}, true},
+		{"M", parseSizeThreshold, threshold{}, true},
+		{"+-5", parseSizeThreshold, threshold{}, true},
+		{"5X", parseSizeThreshold, threshold{}, true},
+		{"1.5M", parseSizeThreshold, threshold{}, true},
+		{"+30y", parseAgeThreshold, threshold{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := test.parse(test.s)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' expected error '%t' but got '%v'", test.s, test.err, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' expected '%v' but got '%v'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestThresholdMatch(t *testing.T) {
+	tests := []struct {
+		t   threshold
+		v   int64
+		exp bool
+	}{
+		{threshold{1, 100, 1e6}, 100e6 + 1, true},
+		{threshold{1, 100, 1e6}, 100e6, false},
+		{threshold{1, 100, 1e6}, 5, false},
+		{threshold{-1, 5, 1e3}, 4999, true},
+		{threshold{-1, 5, 1e3}, 5000, false},
+		{threshold{-1, 5, 1e3}, 0, true},
+		{threshold{0, 2, 1e6}, 2e6, true},
+		{threshold{0, 2, 1e6}, 2999999, true},
+		{threshold{0, 2, 1e6}, 3e6, false},
+		{threshold{0, 2, 1e6}, 1999999, false},
+		{threshold{0, 0, 86400}, 3600, true},
+		{threshold{0, 0, 86400}, -3600, false},
+		{threshold{1, 30, 86400}, 31 * 86400, true},
+		{threshold{1, 30, 86400}, 29 * 86400, false},
+	}
+
+	for _, test := range tests {
+		if got := test.t.match(test.v); got != test.exp {
+			t.Errorf("at input '%v' with value '%d' expected '%t' but got '%t'", test.t, test.v, test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// This function replaces octal escapes (e.g. '\040' for space) used in
+// '/proc/mounts' entries with the characters they represent.
+func unescapeMount(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// This function parses the contents of '/proc/mounts' and returns the entries
+// of filesystems mounted from block devices. Pseudo filesystems (e.g. 'proc'
+// or 'tmpfs') are skipped since they are not interesting to navigate.
+func parseMounts(s string) []mountEntry {
+	var mounts []mountEntry
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		mounts = append(mounts, mountEntry{
+			dev:    unescapeMount(fields[0]),
+			dir:    unescapeMount(fields[1]),
+			fstype: fields[2],
+		})
+	}
+	return mounts
+}
+
+// This function parses the output of 'lsblk -P' with 'NAME', 'RM', 'TYPE',
+// 'FSTYPE', and 'MOUNTPOINT' columns and returns the entries of devices with
+// filesystems. Values are unquoted as go string literals so that hexadecimal
+// escapes written by lsblk (e.g. '\x20' for space) are decoded as well.
+func parseLsblk(s string) []mountEntry {
+	var devices []mountEntry
+	for _, line := range strings.Split(s, "\n") {
+		vals := make(map[string]string)
+		for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+			eq := strings.Index(line, `="`)
+			if eq < 0 {
+				break
+			}
+			end := strings.IndexByte(line[eq+2:], '"')
+			if end < 0 {
+				break
+			}
+			key, val := line[:eq], line[eq+2:eq+2+end]
+			if v, err := strconv.Unquote(`"` + val + `"`); err == nil {
+				val = v
+			}
+			vals[key] = val
+			line = line[eq+2+end+1:]
+		}
+		if vals["NAME"] == "" || vals["FSTYPE"] == "" {
+			continue
+		}
+		devices = append(devices, mountEntry{
+			dev:       vals["NAME"],
+			dir:       vals["MOUNTPOINT"],
+			fstype:    vals["FSTYPE"],
+			removable: vals["RM"] == "1",
+		})
+	}
+	return devices
+}
+
+// This function merges the mounted filesystems with the devices listed by
+// 'lsblk'. Mounted filesystems are kept first in their original order and
+// unmounted removable devices are appended so that they can be mounted.
+func mergeMounts(mounts, devices []mountEntry) []mountEntry {
+	removable := make(map[string]bool)
+	for _, d := range devices {
+		removable[d.dev] = d.removable
+	}
+
+	mounted := make(map[string]bool)
+	var list []mountEntry
+	for _, m := range mounts {
+		m.removable = removable[m.dev]
+		mounted[m.dev] = true
+		list = append(list, m)
+	}
+
+	for _, d := range devices {
+		if d.removable && d.dir == "" && !mounted[d.dev] {
+			list = append(list, d)
+		}
+	}
+
+	return list
+}
+
+// This function returns the list of mounted filesystems and unmounted
+// removable devices. Unmounted devices are only listed when 'lsblk' is
+// available.
+func listMounts() ([]mountEntry, error) {
+	buf, err := ioutil.ReadFile("/proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := parseMounts(string(buf))
+
+	if _, err := exec.LookPath("lsblk"); err != nil {
+		return mounts, nil
+	}
+
+	out, err := exec.Command("lsblk", "-Ppo", "NAME,RM,TYPE,FSTYPE,MOUNTPOINT").Output()
+	if err != nil {
+		return mounts, nil
+	}
+
+	return mergeMounts(mounts, parseLsblk(string(out))), nil
+}
+
+// This function parses the output of 'udisksctl mount' and returns the mount
+// point of the device (e.g. 'Mounted /dev/sdb1 at /media/user/disk').
+func parseUdisksMount(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	ind := strings.Index(s, " at ")
+	if !strings.HasPrefix(s, "Mounted ") || ind < 0 {
+		return "", fmt.Errorf("unexpected output: %s", s)
+	}
+	return strings.TrimSuffix(s[ind+len(" at "):], "."), nil
+}
+
+func udisksctl(args ...string) (string, error) {
+	if _, err := exec.LookPath("udisksctl"); err != nil {
+		return "", fmt.Errorf("udisksctl is required to mount and unmount devices")
+	}
+
+	out, err := exec.Command("udisksctl", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// This function mounts the given device using 'udisksctl' and returns the
+// mount point.
+func mountDevice(dev string) (string, error) {
+	out, err := udisksctl("mount", "--no-user-interaction", "-b", dev)
+	if err != nil {
+		return "", err
+	}
+	return parseUdisksMount(out)
+}
+
+// This function unmounts the given device using 'udisksctl'.
+func unmountDevice(dev string) error {
+	_, err := udisksctl("unmount", "--no-user-interaction", "-b", dev)
+	return err
+}
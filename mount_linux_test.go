@@ -0,0 +1,117 @@
+// +build linux
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMounts(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp []mountEntry
+	}{
+		{
+			`sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/nvme0n1p2 / ext4 rw,relatime 0 0
+tmpfs /run tmpfs rw,nosuid,nodev 0 0
+/dev/sdb1 /media/user/My\040Disk vfat rw,nosuid,nodev 0 0
+/dev/sdc1 /mnt/back\134slash ntfs3 rw 0 0
+`,
+			[]mountEntry{
+				{dev: "/dev/nvme0n1p2", dir: "/", fstype: "ext4"},
+				{dev: "/dev/sdb1", dir: "/media/user/My Disk", fstype: "vfat"},
+				{dev: "/dev/sdc1", dir: `/mnt/back\slash`, fstype: "ntfs3"},
+			},
+		},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		if got := parseMounts(test.s); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' expected '%v' but got '%v'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestParseLsblk(t *testing.T) {
+	s := `NAME="/dev/sda" RM="0" TYPE="disk" FSTYPE="" MOUNTPOINT=""
+NAME="/dev/sda1" RM="0" TYPE="part" FSTYPE="ext4" MOUNTPOINT="/"
+NAME="/dev/sdb" RM="1" TYPE="disk" FSTYPE="" MOUNTPOINT=""
+NAME="/dev/sdb1" RM="1" TYPE="part" FSTYPE="vfat" MOUNTPOINT="/media/user/My\x20Disk"
+NAME="/dev/sdb2" RM="1" TYPE="part" FSTYPE="exfat" MOUNTPOINT=""
+NAME="/dev/sr0" RM="1" TYPE="rom" FSTYPE="iso9660" MOUNTPOINT="/media/user/\x22cd\x22"
+`
+
+	exp := []mountEntry{
+		{dev: "/dev/sda1", dir: "/", fstype: "ext4"},
+		{dev: "/dev/sdb1", dir: "/media/user/My Disk", fstype: "vfat", removable: true},
+		{dev: "/dev/sdb2", fstype: "exfat", removable: true},
+		{dev: "/dev/sr0", dir: `/media/user/"cd"`, fstype: "iso9660", removable: true},
+	}
+
+	if got := parseLsblk(s); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestMergeMounts(t *testing.T) {
+	mounts := []mountEntry{
+		{dev: "/dev/sda1", dir: "/", fstype: "ext4"},
+		{dev: "/dev/sdb1", dir: "/media/usb", fstype: "vfat"},
+	}
+
+	devices := []mountEntry{
+		{dev: "/dev/sda1", dir: "/", fstype: "ext4"},
+		{dev: "/dev/sda2", fstype: "ext4"},
+		{dev: "/dev/sdb1", dir: "/media/usb", fstype: "vfat", removable: true},
+		{dev: "/dev/sdb2", fstype: "exfat", removable: true},
+	}
+
+	exp := []mountEntry{
+		{dev: "/dev/sda1", dir: "/", fstype: "ext4"},
+		{dev: "/dev/sdb1", dir: "/media/usb", fstype: "vfat", removable: true},
+		{dev: "/dev/sdb2", fstype: "exfat", removable: true},
+	}
+
+	if got := mergeMounts(mounts, devices); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+
+	if got := mergeMounts(mounts, nil); !reflect.DeepEqual(got, mounts) {
+		t.Errorf("without devices expected '%v' but got '%v'", mounts, got)
+	}
+
+	items := []string{
+		"/dev/sda1\t/\text4",
+		"/dev/sdb1\t/media/usb\tvfat\tremovable",
+		"/dev/sdb2\t(not mounted)\texfat\tremovable",
+	}
+	for i, m := range exp {
+		if got := m.String(); got != items[i] {
+			t.Errorf("at entry '%v' expected menu item '%q' but got '%q'", m, items[i], got)
+		}
+	}
+}
+
+func TestParseUdisksMount(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+		ok  bool
+	}{
+		{"Mounted /dev/sdb1 at /media/user/disk\n", "/media/user/disk", true},
+		{"Mounted /dev/sdb1 at /run/media/user/My Disk.\n", "/run/media/user/My Disk", true},
+		{"Error mounting /dev/sdb1: GDBus.Error", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		got, err := parseUdisksMount(test.s)
+		if got != test.exp || (err == nil) != test.ok {
+			t.Errorf("at input '%q' expected '%s' but got '%s' (error: %v)", test.s, test.exp, got, err)
+		}
+	}
+}
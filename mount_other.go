@@ -0,0 +1,20 @@
+// +build !linux
+
+package main
+
+import "errors"
+
+var errMountNotSupported = errors.New("not supported on this platform")
+
+// Listing mounted filesystems is not supported on this platform.
+func listMounts() ([]mountEntry, error) {
+	return nil, errMountNotSupported
+}
+
+func mountDevice(dev string) (string, error) {
+	return "", errMountNotSupported
+}
+
+func unmountDevice(dev string) error {
+	return errMountNotSupported
+}
@@ -10,10 +10,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	times "gopkg.in/djherbis/times.v1"
 )
@@ -32,6 +35,8 @@ type file struct {
 	linkTarget string
 	path       string
 	dirCount   int
+	childCount int
+	xattrCount int
 	accessTime time.Time
 	changeTime time.Time
 	ext        string
@@ -95,6 +100,8 @@ func readdir(path string) ([]*file, error) {
 			linkTarget: linkTarget,
 			path:       fpath,
 			dirCount:   -1,
+			childCount: -1,
+			xattrCount: -1,
 			accessTime: at,
 			changeTime: ct,
 			ext:        ext,
@@ -108,38 +115,161 @@ type dir struct {
 	loading     bool      // directory is loading from disk
 	loadTime    time.Time // current loading or last load time
 	ind         int       // index of current entry in files
-	pos         int       // position of current entry in ui
+	pos         int       // position of current entry in ui (row of current entry in grid layout)
+	cols        int       // number of columns in grid layout from last draw
 	path        string    // full path of directory
 	files       []*file   // displayed files in directory including or excluding hidden ones
 	allFiles    []*file   // all files in directory including hidden ones (same array as files)
 	sortType    sortType  // sort method and options from last sort
 	hiddenfiles []string  // hiddenfiles value from last sort
+	grouporder  []string  // grouporder value from last sort
 	ignorecase  bool      // ignorecase value from last sort
 	ignoredia   bool      // ignoredia value from last sort
+	sortcmd     string    // sortcmd value of the order loaded in last sort
+	childcount  bool      // whether entries of directories are counted in last load
 	noPerm      bool      // whether lf has no permission to open the directory
 }
 
 func newDir(path string) *dir {
 	time := time.Now()
+	childcount := hasInfo("childcount")
 
-	files, err := readdir(path)
+	var files []*file
+	var err error
+	if isArchivePath(path) {
+		files, err = readArchiveDir(path)
+	} else {
+		files, err = readdir(path)
+		if childcount {
+			countChildren(files)
+		}
+		if gOpts.sortType.method == commandSort {
+			loadSortCmd(path, files)
+		}
+	}
 	if err != nil {
 		log.Printf("reading directory: %s", err)
 	}
 
 	return &dir{
-		loadTime: time,
-		path:     path,
-		files:    files,
-		allFiles: files,
-		noPerm:   os.IsPermission(err),
+		loadTime:   time,
+		path:       path,
+		files:      files,
+		allFiles:   files,
+		childcount: childcount,
+		noPerm:     os.IsPermission(err),
+	}
+}
+
+type childCount struct {
+	modTime time.Time
+	count   int
+}
+
+// This is the cache of the number of entries in directories used for
+// 'childcount' information, which is kept as long as the modification time
+// of the directory is the same so that directories are not read again for
+// each reload. Directories are loaded in the background so the cache is
+// guarded by a mutex.
+var (
+	gChildCounts     = make(map[string]childCount)
+	gChildCountMutex sync.Mutex
+)
+
+// This function returns the modification time of the given directory, which
+// is read again for symbolic links to get the time of the target directory.
+func childModTime(f *file) (time.Time, error) {
+	if f.linkState == notLink {
+		return f.ModTime(), nil
+	}
+	s, err := os.Stat(f.path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.ModTime(), nil
+}
+
+// This function returns the number of immediate entries in the given directory
+// with a single read of the directory without recursion. The count is read
+// from the cache when the given modification time matches the cached one.
+// Directories that can not be read are cached with a negative count.
+func loadChildCount(path string, modTime time.Time) int {
+	gChildCountMutex.Lock()
+	c, ok := gChildCounts[path]
+	gChildCountMutex.Unlock()
+	if ok && c.modTime.Equal(modTime) {
+		return c.count
+	}
+
+	count := -1
+	if d, err := os.Open(path); err != nil {
+		log.Printf("counting directory entries: %s", err)
+	} else {
+		names, err := d.Readdirnames(-1)
+		d.Close()
+		if err != nil {
+			log.Printf("counting directory entries: %s", err)
+		} else {
+			count = len(names)
+		}
+	}
+
+	gChildCountMutex.Lock()
+	gChildCounts[path] = childCount{modTime, count}
+	gChildCountMutex.Unlock()
+
+	return count
+}
+
+// This function counts the immediate entries of the directories among the
+// given files for 'childcount' information. Directories that can not be read
+// are left with a negative count to be shown as unknown.
+func countChildren(files []*file) {
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+
+		modTime, err := childModTime(f)
+		if err != nil {
+			log.Printf("counting directory entries: %s", err)
+			continue
+		}
+
+		f.childCount = loadChildCount(f.path, modTime)
+	}
+}
+
+// This function reports whether the modification time of any of the
+// directories among the given files is different from the cached one for
+// 'childcount' information, which means that the counts need to be loaded
+// again.
+func childrenChanged(files []*file) bool {
+	for _, f := range files {
+		if !f.IsDir() {
+			continue
+		}
+
+		s, err := os.Stat(f.path)
+		if err != nil {
+			continue
+		}
+
+		gChildCountMutex.Lock()
+		c, ok := gChildCounts[f.path]
+		gChildCountMutex.Unlock()
+		if !ok || !c.modTime.Equal(s.ModTime()) {
+			return true
+		}
 	}
+
+	return false
 }
 
 func normalize(s1, s2 string, ignorecase, ignoredia bool) (string, string) {
 	if gOpts.ignorecase {
-		s1 = strings.ToLower(s1)
-		s2 = strings.ToLower(s2)
+		s1 = foldCase(s1)
+		s2 = foldCase(s2)
 	}
 	if gOpts.ignoredia {
 		s1 = removeDiacritics(s1)
@@ -148,16 +278,33 @@ func normalize(s1, s2 string, ignorecase, ignoredia bool) (string, string) {
 	return s1, s2
 }
 
+// This function returns the rank of the given file for 'grouporder' option,
+// which is the index of the first group in the order that the file belongs
+// to. Files that do not belong to any of the groups are ranked last.
+func groupRank(f *file, path string, order, hiddenfiles []string) int {
+	for i, group := range order {
+		switch {
+		case group == "dir" && f.IsDir(),
+			group == "link" && f.linkState != notLink,
+			group == "file" && !f.IsDir(),
+			group == "hidden" && isHidden(f, path, hiddenfiles):
+			return i
+		}
+	}
+	return len(order)
+}
+
 func (dir *dir) sort() {
 	dir.sortType = gOpts.sortType
 	dir.hiddenfiles = gOpts.hiddenfiles
+	dir.grouporder = gOpts.grouporder
 	dir.ignorecase = gOpts.ignorecase
 	dir.ignoredia = gOpts.ignoredia
 
 	dir.files = dir.allFiles
 
 	switch dir.sortType.method {
-	case naturalSort:
+	case naturalSort, manualSort, commandSort:
 		sort.SliceStable(dir.files, func(i, j int) bool {
 			s1, s2 := normalize(dir.files[i].Name(), dir.files[j].Name(), dir.ignorecase, dir.ignoredia)
 			return naturalLess(s1, s2)
@@ -204,13 +351,39 @@ func (dir *dir) sort() {
 		})
 	}
 
+	switch dir.sortType.method {
+	case manualSort:
+		gManualMutex.Lock()
+		order, ok := gManualOrders[dir.path]
+		gManualMutex.Unlock()
+		if ok {
+			orderFiles(dir.files, order)
+		}
+	case commandSort:
+		order, ok := sortCmdOrder(dir.path)
+		if ok {
+			dir.sortcmd = gOpts.sortcmd
+		} else {
+			dir.sortcmd = ""
+		}
+		orderFiles(dir.files, order)
+	}
+
 	if dir.sortType.option&reverseSort != 0 {
 		for i, j := 0, len(dir.files)-1; i < j; i, j = i+1, j-1 {
 			dir.files[i], dir.files[j] = dir.files[j], dir.files[i]
 		}
 	}
 
-	if dir.sortType.option&dirfirstSort != 0 {
+	if len(dir.grouporder) != 0 {
+		ranks := make(map[*file]int, len(dir.files))
+		for _, f := range dir.files {
+			ranks[f] = groupRank(f, dir.path, dir.grouporder, dir.hiddenfiles)
+		}
+		sort.SliceStable(dir.files, func(i, j int) bool {
+			return ranks[dir.files[i]] < ranks[dir.files[j]]
+		})
+	} else if dir.sortType.option&dirfirstSort != 0 {
 		sort.SliceStable(dir.files, func(i, j int) bool {
 			if dir.files[i].IsDir() == dir.files[j].IsDir() {
 				return i < j
@@ -239,6 +412,108 @@ func (dir *dir) sort() {
 	}
 }
 
+// This function sorts the given files in the given order of names. Files
+// missing in the order are kept at the end in their current order so that new
+// files are appended after the ordered ones, and names without a file or
+// repeated in the order are ignored.
+func orderFiles(files []*file, order []string) {
+	if order == nil {
+		return
+	}
+
+	ranks := make(map[string]int, len(order))
+	for i, name := range order {
+		if _, ok := ranks[name]; !ok {
+			ranks[name] = i
+		}
+	}
+
+	rank := func(f *file) int {
+		if r, ok := ranks[f.Name()]; ok {
+			return r
+		}
+		return len(order)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		return rank(files[i]) < rank(files[j])
+	})
+}
+
+// These are the orders of files set by hand for each directory to be used with
+// 'sortby manual', which are kept for the session. Orders are replaced instead
+// of modified in place since they are read while sorting in the background.
+var (
+	gManualOrders = make(map[string][]string)
+	gManualMutex  sync.Mutex
+)
+
+// This function returns the given list of names with the given name moved to
+// the given index, which is clamped to the bounds of the list.
+func moveName(names []string, name string, to int) []string {
+	var res []string
+	for _, n := range names {
+		if n != name {
+			res = append(res, n)
+		}
+	}
+
+	to = max(0, min(to, len(res)))
+
+	res = append(res, "")
+	copy(res[to+1:], res[to:])
+	res[to] = name
+
+	return res
+}
+
+// This function moves the current file in the manual order of the current
+// directory to the given index in the list of files shown. The order is saved
+// before reversing so that it is not affected by 'reverse' option, and the
+// order of files not shown such as hidden files is kept after them.
+func (nav *nav) moveManual(to int) error {
+	dir := nav.currDir()
+
+	if dir.sortType.method != manualSort {
+		return errors.New("sort type is not 'manual'")
+	}
+	if len(dir.files) == 0 {
+		return errors.New("empty directory")
+	}
+
+	names := make([]string, len(dir.files))
+	for i, f := range dir.files {
+		names[i] = f.Name()
+	}
+
+	name := names[dir.ind]
+	names = moveName(names, name, to)
+
+	if dir.sortType.option&reverseSort != 0 {
+		for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+			names[i], names[j] = names[j], names[i]
+		}
+	}
+
+	shown := make(map[string]bool, len(names))
+	for _, n := range names {
+		shown[n] = true
+	}
+	gManualMutex.Lock()
+	for _, n := range gManualOrders[dir.path] {
+		if !shown[n] {
+			names = append(names, n)
+		}
+	}
+	gManualOrders[dir.path] = names
+	gManualMutex.Unlock()
+
+	dir.sort()
+	dir.sel(name, nav.height)
+
+	return nil
+}
+
 func (dir *dir) name() string {
 	if len(dir.files) == 0 {
 		return ""
@@ -264,8 +539,35 @@ func (dir *dir) sel(name string, height int) {
 		}
 	}
 
-	edge := min(min(height/2, gOpts.scrolloff), len(dir.files)-dir.ind-1)
-	dir.pos = min(dir.ind, height-edge-1)
+	dir.setPos(height)
+}
+
+// This function returns the number of columns of the directory in grid
+// layout, which is one when the directory is drawn as a list.
+func (dir *dir) columns() int {
+	return max(dir.cols, 1)
+}
+
+// This function calculates the position of the current entry in ui from its
+// index. Positions are rows instead of entries in grid layout.
+func (dir *dir) setPos(height int) {
+	cols := dir.columns()
+	row := dir.ind / cols
+	rows := (len(dir.files) + cols - 1) / cols
+
+	edge := min(min(height/2, gOpts.scrolloff), rows-row-1)
+	dir.pos = max(min(row, height-edge-1), 0)
+}
+
+// This function sets the number of columns of the directory in grid layout and
+// updates the position of the current entry when it is changed.
+func (dir *dir) setCols(cols, height int) {
+	if max(cols, 1) == dir.columns() {
+		return
+	}
+
+	dir.cols = cols
+	dir.setPos(height)
 }
 
 type nav struct {
@@ -285,17 +587,31 @@ type nav struct {
 	moveTotalChan   chan int
 	deleteCountChan chan int
 	deleteTotalChan chan int
-	previewChan     chan string
+	previewChan     chan previewReq
 	dirChan         chan *dir
 	regChan         chan *reg
+	diskChan        chan *disk
 	dirCache        map[string]*dir
 	regCache        map[string]*reg
+	diskPaths       map[string]string
+	diskCache       map[string]*disk
+	diskPath        string
 	saves           map[string]bool
 	marks           map[string]string
+	bookmarks       bookmarks
 	renameOldPath   string
 	renameNewPath   string
+	renameList      [][2]string
+	pastePaths      []string
 	selections      map[string]int
 	selectionInd    int
+	selSizes        selTally
+	prevSelections  map[string]int
+	prevSelInd      int
+	previewPath     string
+	previewScroll   int
+	split           *workspace
+	splitRight      bool
 	height          int
 	find            string
 	findBack        bool
@@ -303,6 +619,8 @@ type nav struct {
 	searchBack      bool
 	searchInd       int
 	searchPos       int
+	searches        map[string]string
+	searchDir       string
 	volatilePreview bool
 }
 
@@ -315,8 +633,11 @@ func (nav *nav) loadDir(path string) *dir {
 			path:        path,
 			sortType:    gOpts.sortType,
 			hiddenfiles: gOpts.hiddenfiles,
+			grouporder:  gOpts.grouporder,
 			ignorecase:  gOpts.ignorecase,
 			ignoredia:   gOpts.ignoredia,
+			sortcmd:     gOpts.sortcmd,
+			childcount:  hasInfo("childcount"),
 		}
 		nav.dirCache[path] = d
 		go func() {
@@ -333,23 +654,115 @@ func (nav *nav) loadDir(path string) *dir {
 	return d
 }
 
+// This function reports whether the entries of the given directory are
+// different from the given loaded files, which catches changes that keep the
+// modification time of the directory. Depending on 'periodcheck' option, the
+// number of entries is compared with 'count', which catches changes within the
+// resolution of modification times, and the modification times and sizes of
+// files are also compared with 'files', which catches edits of file contents.
+func entriesChanged(path string, files []*file, check string) bool {
+	if check == "mtime" {
+		return false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return false
+	}
+
+	if len(names) != len(files) {
+		return true
+	}
+
+	if check == "count" {
+		return false
+	}
+
+	for _, f := range files {
+		s, err := os.Stat(f.path)
+		if err != nil {
+			if f.linkState == broken {
+				continue
+			}
+			return true
+		}
+		if !s.ModTime().Equal(f.ModTime()) || s.Size() != f.Size() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// These are the directories with entries being compared in the background for
+// 'periodcheck' option, so that a directory is not checked again before the
+// previous check is finished on slow filesystems.
+var (
+	gDirChecks     = make(map[string]bool)
+	gDirCheckMutex sync.Mutex
+)
+
+// This function compares the entries of the given directory with the loaded
+// ones in the background and loads the directory again when they are different.
+// Directories among the entries are also checked for changes of their counts
+// when 'childcount' information is shown. Loaded files are passed to the
+// goroutine beforehand so that the directory is not accessed while it is
+// changed in the main goroutine.
+func (nav *nav) checkEntries(dir *dir) {
+	gDirCheckMutex.Lock()
+	if gDirChecks[dir.path] {
+		gDirCheckMutex.Unlock()
+		return
+	}
+	gDirChecks[dir.path] = true
+	gDirCheckMutex.Unlock()
+
+	path, files, check, childcount := dir.path, dir.allFiles, gOpts.periodcheck, dir.childcount
+	go func() {
+		changed := entriesChanged(path, files, check) || childcount && childrenChanged(files)
+
+		gDirCheckMutex.Lock()
+		delete(gDirChecks, path)
+		gDirCheckMutex.Unlock()
+
+		if changed {
+			nd := newDir(path)
+			nd.sort()
+			nav.dirChan <- nd
+		}
+	}()
+}
+
 func (nav *nav) checkDir(dir *dir) {
-	s, err := os.Stat(dir.path)
+	// virtual directories are read again when the archive is changed
+	path := dir.path
+	if archive, _, ok := splitArchivePath(path); ok {
+		path = archive
+	}
+
+	s, err := os.Stat(path)
 	if err != nil {
 		log.Printf("getting directory info: %s", err)
 		return
 	}
 
-	switch {
-	case s.ModTime().After(dir.loadTime):
-		now := time.Now()
+	now := time.Now()
 
-		// XXX: Linux builtin exFAT drivers are able to predict modifications in the future
-		// https://bugs.launchpad.net/ubuntu/+source/ubuntu-meta/+bug/1872504
-		if s.ModTime().After(now) {
-			return
-		}
+	// XXX: Linux builtin exFAT drivers are able to predict modifications in the future
+	// https://bugs.launchpad.net/ubuntu/+source/ubuntu-meta/+bug/1872504
+	if s.ModTime().After(now) {
+		return
+	}
 
+	// the modification time only changes when entries are added, removed, or
+	// renamed, so entries are also compared in the background for other changes
+	switch {
+	case s.ModTime().After(dir.loadTime) || dir.childcount != hasInfo("childcount"):
 		dir.loading = true
 		dir.loadTime = now
 		go func() {
@@ -359,21 +772,28 @@ func (nav *nav) checkDir(dir *dir) {
 		}()
 	case dir.sortType != gOpts.sortType ||
 		!reflect.DeepEqual(dir.hiddenfiles, gOpts.hiddenfiles) ||
+		!reflect.DeepEqual(dir.grouporder, gOpts.grouporder) ||
 		dir.ignorecase != gOpts.ignorecase ||
-		dir.ignoredia != gOpts.ignoredia:
+		dir.ignoredia != gOpts.ignoredia ||
+		dir.sortType.method == commandSort && dir.sortcmd != gOpts.sortcmd:
 		dir.loading = true
 		go func() {
+			if dir.sortType.method == commandSort {
+				loadSortCmd(dir.path, dir.allFiles)
+			}
 			dir.sort()
 			dir.loading = false
 			nav.dirChan <- dir
 		}()
+	case (gOpts.periodcheck != "mtime" || dir.childcount) && !dir.loading:
+		nav.checkEntries(dir)
 	}
 }
 
 func (nav *nav) getDirs(wd string) {
 	var dirs []*dir
 
-	for curr, base := wd, ""; !isRoot(base); curr, base = filepath.Dir(curr), filepath.Base(curr) {
+	for curr, base := wd, ""; !isRoot(base); curr, base = parentDir(curr), baseName(curr) {
 		dir := nav.loadDir(curr)
 		dir.sel(base, nav.height)
 		dirs = append(dirs, dir)
@@ -386,6 +806,66 @@ func (nav *nav) getDirs(wd string) {
 	nav.dirs = dirs
 }
 
+// This type is used to store the free space of a filesystem containing a
+// directory for 'diskfree' option.
+type disk struct {
+	path string
+	key  string
+	free uint64
+	err  error
+}
+
+// This function returns the text of the free space shown in the status line,
+// which is empty when the free space is not known.
+func (d *disk) String() string {
+	if d == nil || d.err != nil {
+		return ""
+	}
+	return humanize(int64(d.free)) + " free"
+}
+
+// This function sends the free space of the filesystem containing the given
+// directory to the disk channel. It is run asynchronously since the call may
+// take a long time on network filesystems.
+func loadDisk(path string, ch chan<- *disk) {
+	d := &disk{path: path}
+	if d.key, d.err = mountKey(path); d.err == nil {
+		d.free, d.err = diskFree(path)
+	}
+	ch <- d
+}
+
+// This function returns the cached free space of the filesystem containing the
+// given directory. Free space is refreshed in the background whenever the
+// directory is different from the last one. Directories are mapped to their
+// filesystems only after they are loaded once, so the cached value of the
+// filesystem is shown for directories visited before and nothing is shown for
+// a new directory until it is loaded, even in the same filesystem.
+func (nav *nav) diskFree(path string) *disk {
+	if path != nav.diskPath {
+		nav.diskPath = path
+		go loadDisk(path, nav.diskChan)
+	}
+
+	if key, ok := nav.diskPaths[path]; ok {
+		return nav.diskCache[key]
+	}
+
+	return nil
+}
+
+// This function stores the free space loaded in the background in the cache of
+// its filesystem. Failed calls are logged and the free space is not shown.
+func (nav *nav) checkDisk(d *disk) {
+	if d.err != nil {
+		log.Printf("getting free disk space: %s", d.err)
+		delete(nav.diskPaths, d.path)
+		return
+	}
+	nav.diskPaths[d.path] = d.key
+	nav.diskCache[d.key] = d
+}
+
 func newNav(height int) *nav {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -399,15 +879,19 @@ func newNav(height int) *nav {
 		moveTotalChan:   make(chan int, 1024),
 		deleteCountChan: make(chan int, 1024),
 		deleteTotalChan: make(chan int, 1024),
-		previewChan:     make(chan string, 1024),
+		previewChan:     make(chan previewReq, 1024),
 		dirChan:         make(chan *dir),
 		regChan:         make(chan *reg),
+		diskChan:        make(chan *disk, 1024),
 		dirCache:        make(map[string]*dir),
 		regCache:        make(map[string]*reg),
+		diskPaths:       make(map[string]string),
+		diskCache:       make(map[string]*disk),
 		saves:           make(map[string]bool),
 		marks:           make(map[string]string),
 		selections:      make(map[string]int),
 		selectionInd:    0,
+		searches:        make(map[string]string),
 		height:          height,
 	}
 
@@ -421,21 +905,68 @@ func (nav *nav) renew() {
 		nav.checkDir(d)
 	}
 
+	if nav.split != nil {
+		if d, ok := nav.dirCache[nav.split.dir]; ok {
+			nav.checkDir(d)
+		}
+	}
+
+	nav.pruneSelections()
+}
+
+// This function flips the execute bits of the given files and returns the
+// number of changed files. Files other than regular files (e.g. directories)
+// are skipped and counted separately.
+func (nav *nav) toggleExec(paths []string, all bool) (changed, skipped int, err error) {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return changed, skipped, err
+		}
+		if !info.Mode().IsRegular() {
+			skipped++
+			continue
+		}
+		if err := os.Chmod(path, toggleExec(info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky), all)); err != nil {
+			return changed, skipped, err
+		}
+		changed++
+	}
+	return changed, skipped, nil
+}
+
+// This function removes selected files that no longer exist from the
+// selection set and returns the number of removed entries.
+func (nav *nav) pruneSelections() int {
+	count := 0
 	for m := range nav.selections {
 		if _, err := os.Lstat(m); os.IsNotExist(err) {
 			delete(nav.selections, m)
+			nav.selSizes.remove(m)
+			count++
 		}
 	}
 
 	if len(nav.selections) == 0 {
 		nav.selectionInd = 0
 	}
+
+	return count
 }
 
 func (nav *nav) reload() error {
-	nav.dirCache = make(map[string]*dir)
 	nav.regCache = make(map[string]*reg)
 
+	return nav.reloadDirs()
+}
+
+// This function flushes the cache of directories and loads the current
+// directories again keeping the current file.
+func (nav *nav) reloadDirs() error {
+	nav.dirCache = make(map[string]*dir)
+
+	nav.pruneSelections()
+
 	wd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("getting current directory: %s", err)
@@ -448,6 +979,68 @@ func (nav *nav) reload() error {
 		last.files = append(last.files, curr)
 	}
 
+	nav.loadSplit()
+
+	return nil
+}
+
+// These are the caches flushed by 'clear-cache' command with their names. Each
+// function drops the cached values so that they are computed again when they
+// are needed next time.
+var gCaches = map[string]func(nav *nav) error{
+	"dirs": (*nav).reloadDirs,
+	"previews": func(nav *nav) error {
+		nav.regCache = make(map[string]*reg)
+		return nil
+	},
+	"diskfree": func(nav *nav) error {
+		nav.diskPaths = make(map[string]string)
+		nav.diskCache = make(map[string]*disk)
+		nav.diskPath = ""
+		return nil
+	},
+	"sortcmd": func(nav *nav) error {
+		gSortCmdMutex.Lock()
+		gSortCmdCache = make(map[string]sortCmdEntry)
+		gSortCmdMutex.Unlock()
+		return nil
+	},
+	"archives": func(nav *nav) error {
+		gArchiveMutex.Lock()
+		gArchiveCache = make(map[string]archiveCacheEntry)
+		gArchiveMutex.Unlock()
+		return nil
+	},
+	"childcount": func(nav *nav) error {
+		gChildCountMutex.Lock()
+		gChildCounts = make(map[string]childCount)
+		gChildCountMutex.Unlock()
+		return nil
+	},
+}
+
+// This function flushes the caches with the given names or all caches when no
+// name is given. Names are checked before any of the caches is flushed.
+func (nav *nav) clearCaches(names []string) error {
+	if len(names) == 0 {
+		for name := range gCaches {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		if _, ok := gCaches[name]; !ok {
+			return fmt.Errorf("unknown cache: %s", name)
+		}
+	}
+
+	for _, name := range names {
+		if err := gCaches[name](nav); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+
 	return nil
 }
 
@@ -459,17 +1052,27 @@ func (nav *nav) position() {
 	}
 }
 
+// This type is a request to load the preview of a file sent to the preview
+// loop. The search pattern and the scroll offset are copied into the request
+// so that the loop does not read the state of the main goroutine. An empty
+// path is a request to run the cleaner of the last preview instead.
+type previewReq struct {
+	path   string
+	search string
+	scroll int
+}
+
 func (nav *nav) previewLoop(ui *ui) {
 	var path string
 	for {
-		p, ok := <-nav.previewChan
+		req, ok := <-nav.previewChan
 		if !ok {
 			return
 		}
-		if len(p) != 0 {
+		if len(req.path) != 0 {
 			win := ui.wins[len(ui.wins)-1]
-			nav.preview(p, win)
-			path = p
+			nav.preview(req, win)
+			path = req.path
 		} else if len(gOpts.previewer) != 0 && len(gOpts.cleaner) != 0 && nav.volatilePreview {
 			cmd := exec.Command(gOpts.cleaner, path)
 			if err := cmd.Run(); err != nil {
@@ -480,34 +1083,173 @@ func (nav *nav) previewLoop(ui *ui) {
 	}
 }
 
-func (nav *nav) preview(path string, win *win) {
-	reg := &reg{loadTime: time.Now(), path: path}
-	defer func() { nav.regChan <- reg }()
+// This function prepares a line of a preview according to 'previewansi'
+// option. Lines are kept as they are for 'render' method since escape
+// sequences are handled while printing.
+func previewLine(s string) string {
+	switch gOpts.previewansi {
+	case "strip":
+		return stripEscapes(s)
+	case "raw":
+		return strings.Replace(s, "\033", "^[", -1)
+	}
+	return s
+}
 
-	var reader io.Reader
+// This is the maximum number of lines read by previews to find a match when
+// 'previewsearch' option is enabled.
+const gPreviewSearchLimit = 10000
 
-	if len(gOpts.previewer) != 0 {
-		exportOpts()
-		cmd := exec.Command(gOpts.previewer, path,
-			strconv.Itoa(win.w),
-			strconv.Itoa(win.h),
-			strconv.Itoa(win.x),
-			strconv.Itoa(win.y))
+// This function finds the first match of the search pattern in the given line
+// of a preview and returns its beginning and end offsets in the line or -1 if
+// there is no match. Escape sequences in the line are skipped while matching.
+// Case and diacritics are ignored according to 'ignorecase', 'smartcase',
+// 'ignoredia', and 'smartdia' options as in 'search' command. Glob patterns
+// are not supported and always return -1.
+func previewMatch(line, pattern string) (beg, end int) {
+	if pattern == "" || gOpts.globsearch {
+		return -1, -1
+	}
 
-		out, err := cmd.StdoutPipe()
-		if err != nil {
-			log.Printf("previewing file: %s", err)
-			return
-		}
+	norm := searchNorm(pattern)
 
-		if err := cmd.Start(); err != nil {
-			log.Printf("previewing file: %s", err)
-			out.Close()
-			return
+	var pat []rune
+	for _, r := range pattern {
+		pat = append(pat, norm(r))
+	}
+
+	var runes []rune
+	var offs []int
+	for i := 0; i < len(line); {
+		if line[i] == gEscapeCode {
+			n, _, _ := readEscape(line[i:])
+			i += n
+			continue
+		}
+		r, w := utf8.DecodeRuneInString(line[i:])
+		runes = append(runes, norm(r))
+		offs = append(offs, i)
+		i += w
+	}
+	offs = append(offs, len(line))
+
+outer:
+	for i := 0; i+len(pat) <= len(runes); i++ {
+		for j, r := range pat {
+			if runes[i+j] != r {
+				continue outer
+			}
 		}
+		_, w := utf8.DecodeRuneInString(line[offs[i+len(pat)-1]:])
+		return offs[i], offs[i+len(pat)-1] + w
+	}
 
-		defer func() {
-			if err := cmd.Wait(); err != nil {
+	return -1, -1
+}
+
+// This function returns the index of the first line to show in a preview of
+// the given height so that the matching line is shown at the top with
+// 'scrolloff' lines of context above it. The offset is clamped so that the
+// end of the file does not leave empty lines at the bottom. It returns zero
+// when there is no match.
+func previewOffset(match, total, height int) int {
+	if match < 0 {
+		return 0
+	}
+
+	off := match - min(gOpts.scrolloff, (height-1)/2)
+	off = min(off, total-height)
+
+	return max(off, 0)
+}
+
+// This is the maximum number of lines the preview can be scrolled down with
+// 'preview-scroll-down' and 'preview-page-down' commands.
+const gPreviewScrollLimit = 10000
+
+// This function resets the scroll offset of the preview when the previewed
+// file changes to the given file.
+func (nav *nav) syncPreviewScroll(path string) {
+	if path != nav.previewPath {
+		nav.previewPath = path
+		nav.previewScroll = 0
+	}
+}
+
+// This function scrolls the preview of the current file by the given number of
+// lines, which is negative to scroll up. Scrolling down stops when the end of
+// the preview is already shown in the given preview. It reports whether the
+// offset is changed so that the preview should be loaded again.
+func (nav *nav) scrollPreview(n int, reg *reg) bool {
+	off := min(max(nav.previewScroll+n, 0), gPreviewScrollLimit)
+
+	if off > nav.previewScroll && reg != nil && reg.path == nav.previewPath && !reg.loading && reg.last {
+		return false
+	}
+
+	if off == nav.previewScroll {
+		return false
+	}
+
+	nav.previewScroll = off
+	return true
+}
+
+// This function lowers the scroll offset to the offset of the given loaded
+// preview when the preview is scrolled past its end, so that further scrolling
+// starts from the last lines shown.
+func (nav *nav) clampPreviewScroll(reg *reg) {
+	if reg.path == nav.previewPath && reg.last && reg.scroll < nav.previewScroll {
+		nav.previewScroll = reg.scroll
+	}
+}
+
+func (nav *nav) preview(req previewReq, win *win) {
+	path := req.path
+	reg := &reg{loadTime: time.Now(), path: path}
+	defer func() { nav.regChan <- reg }()
+
+	var search string
+	if gOpts.previewsearch {
+		search = req.search
+		reg.search = search
+	}
+
+	scroll := req.scroll
+
+	var reader io.Reader
+
+	if isArchivePath(path) {
+		f, err := openArchiveFile(path)
+		if err != nil {
+			log.Printf("opening file: %s", err)
+			return
+		}
+
+		defer f.Close()
+		reader = f
+	} else if len(gOpts.previewer) != 0 {
+		exportOpts()
+		cmd := exec.Command(gOpts.previewer, path,
+			strconv.Itoa(win.w),
+			strconv.Itoa(win.h),
+			strconv.Itoa(win.x),
+			strconv.Itoa(win.y))
+
+		out, err := cmd.StdoutPipe()
+		if err != nil {
+			log.Printf("previewing file: %s", err)
+			return
+		}
+
+		if err := cmd.Start(); err != nil {
+			log.Printf("previewing file: %s", err)
+			out.Close()
+			return
+		}
+
+		defer func() {
+			if err := cmd.Wait(); err != nil {
 				if e, ok := err.(*exec.ExitError); ok {
 					if e.ExitCode() != 0 {
 						reg.volatile = true
@@ -531,29 +1273,114 @@ func (nav *nav) preview(path string, win *win) {
 		reader = f
 	}
 
+	reg.lines, reg.scroll, reg.last = previewLines(reader, win.h, scroll, search)
+}
+
+// This indicator is shown at the end of previews cut by 'previewbytes' or
+// 'previewlines' options.
+const gPreviewTruncated = "\033[7m...truncated\033[0m"
+
+// This type is a reader returning at most the given number of bytes for
+// 'previewbytes' option. It records whether there are more bytes left after
+// the limit so that the preview can be marked as truncated.
+type limitReader struct {
+	r         io.Reader
+	n         int64
+	truncated bool
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		var b [1]byte
+		if n, _ := l.r.Read(b[:]); n > 0 {
+			l.truncated = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// This function reads the lines of a preview with the given height from the
+// given reader. Reading stops as soon as enough lines are read, or at the
+// limits of 'previewbytes' and 'previewlines' options in which case the
+// truncated indicator is shown after the last line. Lines are scrolled by the
+// given offset, which is clamped so that the end of the preview does not leave
+// empty lines at the bottom. It returns the lines to show, the offset used,
+// and whether the last line of the preview is shown.
+func previewLines(reader io.Reader, h, scroll int, search string) (lines []string, off int, last bool) {
+	var lr *limitReader
+	if gOpts.previewbytes > 0 {
+		lr = &limitReader{r: reader, n: int64(gOpts.previewbytes)}
+		reader = lr
+	}
+
 	buf := bufio.NewScanner(reader)
 
-	for i := 0; i < win.h && buf.Scan(); i++ {
+	// Lines are read until a match is found so that the preview can be
+	// scrolled to the match, up to a limit to avoid reading huge files.
+	match, beg, end := -1, -1, -1
+	limit := h + scroll
+	if search != "" {
+		limit = gPreviewSearchLimit
+	}
+
+	capped := gOpts.previewlines > 0 && gOpts.previewlines < limit
+	if capped {
+		limit = gOpts.previewlines
+	}
+
+	for i := 0; i < limit && buf.Scan(); i++ {
 		for _, r := range buf.Text() {
 			if r == 0 {
-				reg.lines = []string{"\033[7mbinary\033[0m"}
-				return
+				return []string{"\033[7mbinary\033[0m"}, 0, true
+			}
+		}
+		line := previewLine(buf.Text())
+		if search != "" && match < 0 {
+			if beg, end = previewMatch(line, search); beg >= 0 {
+				match = i
+				limit = min(limit, i+h+scroll)
 			}
 		}
-		reg.lines = append(reg.lines, buf.Text())
+		lines = append(lines, line)
 	}
 
+	truncated := capped && len(lines) == gOpts.previewlines && buf.Scan()
+	more := !capped && len(lines) == limit && buf.Scan()
+
 	if buf.Err() != nil {
 		log.Printf("loading file: %s", buf.Err())
 	}
+
+	if lr != nil && lr.truncated {
+		truncated = true
+	}
+
+	if match >= 0 {
+		lines[match] = lines[match][:beg] + "\033[7m" + lines[match][beg:end] + "\033[27m" + lines[match][end:]
+	}
+
+	if truncated {
+		lines = append(lines, gPreviewTruncated)
+	}
+
+	off = previewOffset(match, len(lines), h)
+	first := min(off+scroll, max(len(lines)-h, 0))
+	last = !more && first+h >= len(lines)
+	return lines[first:min(first+h, len(lines))], first - off, last
 }
 
 func (nav *nav) loadReg(path string, volatile bool) *reg {
 	r, ok := nav.regCache[path]
-	if !ok || (volatile && r.volatile) {
-		r := &reg{loading: true, loadTime: time.Now(), path: path, volatile: true}
+	if !ok || (volatile && r.volatile) || (gOpts.previewsearch && r.search != nav.search) || r.scroll != nav.previewScroll {
+		r := &reg{loading: true, loadTime: time.Now(), path: path, volatile: true, search: nav.search, scroll: nav.previewScroll}
 		nav.regCache[path] = r
-		nav.previewChan <- path
+		nav.previewChan <- previewReq{path: path, search: nav.search, scroll: nav.previewScroll}
 		return r
 	}
 
@@ -578,7 +1405,7 @@ func (nav *nav) checkReg(reg *reg) {
 
 	if s.ModTime().After(reg.loadTime) {
 		reg.loadTime = now
-		nav.previewChan <- reg.path
+		nav.previewChan <- previewReq{path: reg.path, search: reg.search, scroll: reg.scroll}
 	}
 }
 
@@ -587,6 +1414,11 @@ func (nav *nav) sort() {
 		name := d.name()
 		d.sort()
 		d.sel(name, nav.height)
+
+		// orders of 'sortcmd' option are loaded in the background
+		if d.sortType.method == commandSort && d.sortcmd != gOpts.sortcmd && !d.loading {
+			nav.checkDir(d)
+		}
 	}
 }
 
@@ -600,11 +1432,14 @@ func (nav *nav) up(dist int) {
 		return
 	}
 
+	cols := dir.columns()
+	row := dir.ind / cols
+
 	dir.ind -= dist
 	dir.ind = max(0, dir.ind)
 
-	dir.pos -= dist
-	edge := min(min(nav.height/2, gOpts.scrolloff), dir.ind)
+	dir.pos -= row - dir.ind/cols
+	edge := min(min(nav.height/2, gOpts.scrolloff), dir.ind/cols)
 	dir.pos = max(dir.pos, edge)
 }
 
@@ -620,18 +1455,78 @@ func (nav *nav) down(dist int) {
 		return
 	}
 
+	cols := dir.columns()
+	row := dir.ind / cols
+	maxrow := maxind / cols
+
 	dir.ind += dist
 	dir.ind = min(maxind, dir.ind)
 
-	dir.pos += dist
-	edge := min(min(nav.height/2, gOpts.scrolloff), maxind-dir.ind)
+	dir.pos += dir.ind/cols - row
+	edge := min(min(nav.height/2, gOpts.scrolloff), maxrow-dir.ind/cols)
 
 	// use a smaller value when the height is even and scrolloff is maxed
 	// in order to stay at the same row as much as possible while up/down
 	edge = min(edge, nav.height/2+nav.height%2-1)
 
 	dir.pos = min(dir.pos, nav.height-edge-1)
-	dir.pos = min(dir.pos, maxind)
+	dir.pos = min(dir.pos, maxrow)
+}
+
+// This function moves the current entry to the left in the same row in grid
+// layout.
+func (nav *nav) left(dist int) {
+	dir := nav.currDir()
+
+	dir.ind -= min(dist, dir.ind%dir.columns())
+}
+
+// This function moves the current entry to the right in the same row in grid
+// layout.
+func (nav *nav) right(dist int) {
+	dir := nav.currDir()
+
+	cols := dir.columns()
+	dist = min(dist, cols-1-dir.ind%cols)
+	dir.ind = min(dir.ind+dist, len(dir.files)-1)
+}
+
+// This function moves the cursor to the next or previous file with the same
+// extension as the current file, ignoring case. Files without extensions
+// match other files without extensions. The search continues from the other
+// end when 'wrapscroll' is enabled. It returns false when no file is found.
+func (nav *nav) jumpExt(back bool) bool {
+	dir := nav.currDir()
+
+	if len(dir.files) == 0 {
+		return false
+	}
+
+	ext := nameExt(dir.files[dir.ind].Name())
+
+	n := len(dir.files)
+	for k := 1; k < n; k++ {
+		i := dir.ind + k
+		if back {
+			i = dir.ind - k
+		}
+		if i < 0 || i >= n {
+			if !gOpts.wrapscroll {
+				return false
+			}
+			i = (i + n) % n
+		}
+		if strings.EqualFold(nameExt(dir.files[i].Name()), ext) {
+			if i > dir.ind {
+				nav.down(i - dir.ind)
+			} else {
+				nav.up(dir.ind - i)
+			}
+			return true
+		}
+	}
+
+	return false
 }
 
 func (nav *nav) updir() error {
@@ -643,7 +1538,7 @@ func (nav *nav) updir() error {
 
 	nav.dirs = nav.dirs[:len(nav.dirs)-1]
 
-	if err := os.Chdir(filepath.Dir(dir.path)); err != nil {
+	if err := os.Chdir(realDir(parentDir(dir.path))); err != nil {
 		return fmt.Errorf("updir: %s", err)
 	}
 
@@ -658,6 +1553,22 @@ func (nav *nav) open() error {
 
 	path := curr.path
 
+	// the working directory is kept as the directory of the archive in
+	// virtual directories
+	if browsable(curr) {
+		if !isArchivePath(path) {
+			path = archivePath(path, "")
+		}
+		nav.dirs = append(nav.dirs, nav.loadDir(path))
+		return nil
+	}
+
+	if gOpts.fastforward {
+		if target := fastForward(path); target != path {
+			return nav.cd(target)
+		}
+	}
+
 	dir := nav.loadDir(path)
 
 	nav.dirs = append(nav.dirs, dir)
@@ -669,6 +1580,63 @@ func (nav *nav) open() error {
 	return nil
 }
 
+// This function descends from the given directory through the chain of
+// directories that only contain a single subdirectory and nothing else (e.g.
+// 'proj-1.2.3/proj-1.2.3/') and returns the first directory with other
+// contents. Hidden files also count as contents. Symbolic links to
+// directories are followed but a directory is never visited twice so that
+// cycles are not followed forever.
+func fastForward(path string) string {
+	visited := make(map[string]bool)
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil || visited[real] {
+			return path
+		}
+		visited[real] = true
+
+		f, err := os.Open(path)
+		if err != nil {
+			return path
+		}
+		names, err := f.Readdirnames(2)
+		f.Close()
+		if err != nil || len(names) != 1 {
+			return path
+		}
+
+		next := filepath.Join(path, names[0])
+		if stat, err := os.Stat(next); err != nil || !stat.IsDir() {
+			return path
+		}
+		if real, err := filepath.EvalSymlinks(next); err != nil || visited[real] {
+			return path
+		}
+
+		path = next
+	}
+}
+
+// This function ascends from the given directory to find the nearest directory
+// containing a '.git' entry, which is the root of a git repository. Both
+// directories and files are accepted as '.git' entries since worktrees and
+// submodules use files instead. It returns false when there is no such
+// directory up to the root of the filesystem.
+func gitRoot(path string) (string, bool) {
+	path = filepath.Clean(path)
+	for {
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+			return path, true
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", false
+		}
+		path = parent
+	}
+}
+
 func (nav *nav) top() {
 	dir := nav.currDir()
 
@@ -680,18 +1648,142 @@ func (nav *nav) bottom() {
 	dir := nav.currDir()
 
 	dir.ind = len(dir.files) - 1
-	dir.pos = min(dir.ind, nav.height-1)
+	dir.pos = min(dir.ind/dir.columns(), nav.height-1)
+}
+
+// This function parses the argument of 'goto-line' command which is either a
+// line number (e.g. '123') or a percentage of the list (e.g. '50%') similar to
+// vim and returns the corresponding index clamped to the given length.
+func parseLine(s string, length int) (int, error) {
+	percent := strings.HasSuffix(s, "%")
+	n, err := strconv.Atoi(strings.TrimSuffix(s, "%"))
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("negative line: %s", s)
+	}
+	if percent {
+		n = (n*length + 99) / 100
+	}
+	return max(0, min(n, length)-1), nil
+}
+
+func (nav *nav) gotoLine(ind int) {
+	dir := nav.currDir()
+
+	if len(dir.files) == 0 {
+		return
+	}
+
+	ind = max(0, min(ind, len(dir.files)-1))
+
+	switch {
+	case ind > dir.ind:
+		nav.down(ind - dir.ind)
+	case ind < dir.ind:
+		nav.up(dir.ind - ind)
+	}
+}
+
+// This type keeps the running total of the sizes of selected files shown with
+// 'selectioninfo' option. Sizes are saved for each path so that the same size
+// is subtracted when the file is unselected even if the file changes in the
+// meantime. Directories and files that cannot be read have unknown sizes and
+// they are only counted.
+type selTally struct {
+	sizes   map[string]int64
+	total   int64
+	unknown int
+}
+
+// This function adds the given path with the given size to the total, or as
+// unknown if the size is negative. Paths that are already added are replaced.
+func (t *selTally) add(path string, size int64) {
+	if t.sizes == nil {
+		t.sizes = make(map[string]int64)
+	}
+	t.remove(path)
+	t.sizes[path] = size
+	if size < 0 {
+		t.unknown++
+	} else {
+		t.total += size
+	}
+}
+
+func (t *selTally) remove(path string) {
+	size, ok := t.sizes[path]
+	if !ok {
+		return
+	}
+	delete(t.sizes, path)
+	if size < 0 {
+		t.unknown--
+	} else {
+		t.total -= size
+	}
+}
+
+func (t *selTally) reset() {
+	t.sizes = nil
+	t.total = 0
+	t.unknown = 0
+}
+
+// This function returns the text shown in the status line for the given number
+// of selected files (e.g. '12 selected, 3.4G'). A question mark is shown for
+// the sizes of directories which are not computed.
+func (t *selTally) String() string {
+	var size string
+	switch {
+	case t.unknown == len(t.sizes):
+		size = "?"
+	case t.unknown > 0:
+		size = humanize(t.total) + "+?"
+	default:
+		size = humanize(t.total)
+	}
+	return fmt.Sprintf("%d selected, %s", len(t.sizes), size)
+}
+
+// This function returns the size of the given path to be added to the total
+// of the selection, which is negative for directories and paths that cannot
+// be read. Symbolic links are followed.
+func selectionSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return -1
+	}
+	return info.Size()
+}
+
+// This function recomputes the total of the sizes of selected files after the
+// whole selection is replaced. The total is only kept while 'selectioninfo'
+// option is enabled so that files are not read otherwise.
+func (nav *nav) countSelections() {
+	nav.selSizes.reset()
+	if !gOpts.selectioninfo {
+		return
+	}
+	for path := range nav.selections {
+		nav.selSizes.add(path, selectionSize(path))
+	}
 }
 
 func (nav *nav) toggleSelection(path string) {
 	if _, ok := nav.selections[path]; ok {
 		delete(nav.selections, path)
+		nav.selSizes.remove(path)
 		if len(nav.selections) == 0 {
 			nav.selectionInd = 0
 		}
 	} else {
 		nav.selections[path] = nav.selectionInd
 		nav.selectionInd++
+		if gOpts.selectioninfo {
+			nav.selSizes.add(path, selectionSize(path))
+		}
 	}
 }
 
@@ -713,8 +1805,128 @@ func (nav *nav) invert() {
 }
 
 func (nav *nav) unselect() {
+	if len(nav.selections) != 0 {
+		nav.prevSelections = nav.selections
+		nav.prevSelInd = nav.selectionInd
+	}
 	nav.selections = make(map[string]int)
 	nav.selectionInd = 0
+	nav.selSizes.reset()
+}
+
+// This function replaces the selection with the given paths that exist and
+// returns the list of selected paths. Paths that do not exist are skipped. The
+// previous selection is kept to be restored with 'reselect'.
+func (nav *nav) selectPaths(paths []string) []string {
+	var list []string
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		list = append(list, path)
+	}
+
+	if len(list) == 0 {
+		return nil
+	}
+
+	nav.unselect()
+	for _, path := range list {
+		if _, ok := nav.selections[path]; !ok {
+			nav.toggleSelection(path)
+		}
+	}
+
+	return list
+}
+
+// This function returns the directory to navigate to for showing the given
+// paths, which is the common ancestor of their parent directories, and the name
+// of the file to move the cursor to in that directory, which is either the
+// first path itself or its ancestor in the directory.
+func selectTarget(paths []string) (dir, name string) {
+	dir = filepath.Dir(paths[0])
+	for _, path := range paths[1:] {
+		for !isSubdir(dir, filepath.Dir(path)) {
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	name = paths[0]
+	for filepath.Dir(name) != dir {
+		name = filepath.Dir(name)
+	}
+
+	return dir, filepath.Base(name)
+}
+
+// This type is the state of navigation kept for a workspace, which consists of
+// the current directory, the current file, and the selection.
+type workspace struct {
+	dir          string
+	file         string
+	selections   map[string]int
+	selectionInd int
+}
+
+// This function returns the current state of navigation to be restored when
+// switching back to the workspace.
+func (nav *nav) saveWorkspace() *workspace {
+	ws := &workspace{
+		dir:          nav.currDir().path,
+		selections:   make(map[string]int, len(nav.selections)),
+		selectionInd: nav.selectionInd,
+	}
+
+	if curr, err := nav.currFile(); err == nil {
+		ws.file = curr.Name()
+	}
+
+	for path, ind := range nav.selections {
+		ws.selections[path] = ind
+	}
+
+	return ws
+}
+
+// This function restores the given state of navigation. The cursor is moved to
+// the saved file if it still exists, otherwise only the directory is changed.
+func (nav *nav) restoreWorkspace(ws *workspace) error {
+	nav.selections = make(map[string]int, len(ws.selections))
+	for path, ind := range ws.selections {
+		nav.selections[path] = ind
+	}
+	nav.selectionInd = ws.selectionInd
+	nav.countSelections()
+
+	if ws.file != "" {
+		if err := nav.sel(filepath.Join(ws.dir, ws.file)); err == nil {
+			return nil
+		}
+	}
+
+	return nav.cd(ws.dir)
+}
+
+// This function restores the last selection cleared by 'unselect'. Current
+// selection, if any, is kept as the previous selection instead so that
+// restoring twice goes back to the current selection.
+func (nav *nav) reselect() error {
+	if len(nav.prevSelections) == 0 {
+		return errors.New("no previous selection")
+	}
+
+	prev, prevInd := nav.prevSelections, nav.prevSelInd
+	if len(nav.selections) != 0 {
+		nav.prevSelections, nav.prevSelInd = nav.selections, nav.selectionInd
+	} else {
+		nav.prevSelections, nav.prevSelInd = nil, 0
+	}
+	nav.selections, nav.selectionInd = prev, prevInd
+	nav.countSelections()
+
+	return nil
 }
 
 func (nav *nav) save(cp bool) error {
@@ -735,8 +1947,72 @@ func (nav *nav) save(cp bool) error {
 	return nil
 }
 
-func (nav *nav) copyAsync(ui *ui, srcs []string, dstDir string) {
+// This type is used to show the errors of a file operation in the message
+// line. Errors are numbered in the order they occur and repeated errors with
+// the same reason are coalesced into a summary (e.g. 'permission denied (100
+// files)') when 'errorsummary' option is enabled.
+type errSummary struct {
+	count   int
+	reasons []string
+	firsts  map[string]string
+	counts  map[string]int
+}
+
+// This function returns the reason of the given error without the operation
+// and the path (e.g. 'permission denied' for 'open /foo: permission denied').
+func errReason(err error) string {
+	switch e := err.(type) {
+	case *os.PathError:
+		return e.Err.Error()
+	case *os.LinkError:
+		return e.Err.Error()
+	case *os.SyscallError:
+		return e.Err.Error()
+	}
+
+	msg := err.Error()
+	if ind := strings.LastIndex(msg, ": "); ind >= 0 {
+		return msg[ind+2:]
+	}
+	return msg
+}
+
+// This function adds the given error and returns the message to show. Reasons
+// that occur only once are shown with their full errors.
+func (s *errSummary) add(err error) string {
+	s.count++
+
+	if !gOpts.errorsummary {
+		return fmt.Sprintf("[%d] %s", s.count, err)
+	}
+
+	if s.counts == nil {
+		s.firsts = make(map[string]string)
+		s.counts = make(map[string]int)
+	}
+
+	reason := errReason(err)
+	if s.counts[reason] == 0 {
+		s.reasons = append(s.reasons, reason)
+		s.firsts[reason] = err.Error()
+	}
+	s.counts[reason]++
+
+	var msgs []string
+	for _, r := range s.reasons {
+		if s.counts[r] == 1 {
+			msgs = append(msgs, s.firsts[r])
+		} else {
+			msgs = append(msgs, fmt.Sprintf("%s (%d files)", r, s.counts[r]))
+		}
+	}
+
+	return fmt.Sprintf("[%d] %s", s.count, strings.Join(msgs, "; "))
+}
+
+func (nav *nav) copyAsync(ui *ui, srcs []string, dstDir string, policy conflictPolicy) {
 	echo := &callExpr{"echoerr", []string{""}, 1}
+	start := time.Now()
 
 	_, err := os.Stat(dstDir)
 	if os.IsNotExist(err) {
@@ -754,20 +2030,19 @@ func (nav *nav) copyAsync(ui *ui, srcs []string, dstDir string) {
 
 	nav.copyTotalChan <- total
 
-	nums, errs := copyAll(srcs, dstDir)
+	nums, errChan := copyAll(srcs, dstDir, policy)
 
-	errCount := 0
+	var errs errSummary
 loop:
 	for {
 		select {
 		case n := <-nums:
 			nav.copyBytesChan <- n
-		case err, ok := <-errs:
+		case err, ok := <-errChan:
 			if !ok {
 				break loop
 			}
-			errCount++
-			echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+			echo.args[0] = errs.add(err)
 			ui.exprChan <- echo
 		}
 	}
@@ -775,18 +2050,21 @@ loop:
 	nav.copyTotalChan <- -total
 
 	if err := remote("send load"); err != nil {
-		errCount++
-		echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+		echo.args[0] = errs.add(err)
 		ui.exprChan <- echo
 	}
 
-	if errCount == 0 {
+	if errs.count == 0 {
 		ui.exprChan <- &callExpr{"echo", []string{"\033[0;32mCopied successfully\033[0m"}, 1}
+		ui.notify(start, "Copied successfully")
+	} else {
+		ui.notify(start, fmt.Sprintf("Copy finished with %d error(s)", errs.count))
 	}
 }
 
-func (nav *nav) moveAsync(ui *ui, srcs []string, dstDir string) {
+func (nav *nav) moveAsync(ui *ui, srcs []string, dstDir string, policy conflictPolicy) {
 	echo := &callExpr{"echoerr", []string{""}, 1}
+	start := time.Now()
 
 	_, err := os.Stat(dstDir)
 	if os.IsNotExist(err) {
@@ -797,14 +2075,13 @@ func (nav *nav) moveAsync(ui *ui, srcs []string, dstDir string) {
 
 	nav.moveTotalChan <- len(srcs)
 
-	errCount := 0
+	var errs errSummary
 	for _, src := range srcs {
 		nav.moveCountChan <- 1
 
 		srcStat, err := os.Stat(src)
 		if err != nil {
-			errCount++
-			echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+			echo.args[0] = errs.add(err)
 			ui.exprChan <- echo
 			continue
 		}
@@ -813,17 +2090,28 @@ func (nav *nav) moveAsync(ui *ui, srcs []string, dstDir string) {
 
 		dstStat, err := os.Stat(dst)
 		if os.SameFile(srcStat, dstStat) {
-			errCount++
-			echo.args[0] = fmt.Sprintf("[%d] rename %s %s: source and destination are the same file", errCount, src, dst)
+			echo.args[0] = errs.add(&os.LinkError{Op: "rename", Old: src, New: dst, Err: errors.New("source and destination are the same file")})
 			ui.exprChan <- echo
 			continue
 		} else if !os.IsNotExist(err) {
-			var newPath string
-			for i := 1; !os.IsNotExist(err); i++ {
-				newPath = fmt.Sprintf("%s.~%d~", dst, i)
-				_, err = os.Lstat(newPath)
+			switch {
+			case policy == conflictRename:
+				dst = conflictName(dst)
+			case srcStat.IsDir() && dstStat.IsDir():
+				for _, err := range moveMerge(src, dst, src, policy) {
+					echo.args[0] = errs.add(err)
+					ui.exprChan <- echo
+				}
+				continue
+			case policy == conflictSkip:
+				continue
+			default:
+				if err := os.RemoveAll(dst); err != nil {
+					echo.args[0] = errs.add(err)
+					ui.exprChan <- echo
+					continue
+				}
 			}
-			dst = newPath
 		}
 
 		if err := os.Rename(src, dst); err != nil {
@@ -837,36 +2125,38 @@ func (nav *nav) moveAsync(ui *ui, srcs []string, dstDir string) {
 
 				nav.copyTotalChan <- total
 
-				nums, errs := copyAll([]string{src}, dstDir)
+				nums, errChan := copyAll([]string{src}, dstDir, policy)
 
-				oldCount := errCount
+				oldCount := errs.count
 			loop:
 				for {
 					select {
 					case n := <-nums:
 						nav.copyBytesChan <- n
-					case err, ok := <-errs:
+					case err, ok := <-errChan:
 						if !ok {
 							break loop
 						}
-						errCount++
-						echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+						echo.args[0] = errs.add(err)
 						ui.exprChan <- echo
 					}
 				}
 
 				nav.copyTotalChan <- -total
 
-				if errCount == oldCount {
+				if errs.count == oldCount {
 					if err := os.RemoveAll(src); err != nil {
-						errCount++
-						echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+						echo.args[0] = errs.add(err)
 						ui.exprChan <- echo
 					}
 				}
 			} else {
-				errCount++
-				echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+				echo.args[0] = errs.add(err)
+				ui.exprChan <- echo
+			}
+		} else if gOpts.rewritelinks {
+			if err := relinkAll(src, dst, src); err != nil {
+				echo.args[0] = errs.add(err)
 				ui.exprChan <- echo
 			}
 		}
@@ -875,17 +2165,56 @@ func (nav *nav) moveAsync(ui *ui, srcs []string, dstDir string) {
 	nav.moveTotalChan <- -len(srcs)
 
 	if err := remote("send load"); err != nil {
-		errCount++
-		echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+		echo.args[0] = errs.add(err)
 		ui.exprChan <- echo
 	}
 
-	if errCount == 0 {
+	if errs.count == 0 {
 		ui.exprChan <- &callExpr{"echo", []string{"\033[0;32mMoved successfully\033[0m"}, 1}
+		ui.notify(start, "Moved successfully")
+	} else {
+		ui.notify(start, fmt.Sprintf("Move finished with %d error(s)", errs.count))
 	}
 }
 
-func (nav *nav) paste(ui *ui) error {
+// This function extracts the given files in archives to the given directory in
+// the background with the progress shown as in copying. Errors are reported
+// for each file and the remaining files are still extracted.
+func (nav *nav) extractAsync(ui *ui, srcs []string, dstDir string) {
+	start := time.Now()
+
+	total := archiveSize(srcs)
+
+	nav.copyTotalChan <- total
+
+	var errs errSummary
+	n := 0
+	for _, src := range srcs {
+		if _, err := extractArchiveFile(src, dstDir, nav.copyBytesChan); err != nil {
+			msg := errs.add(fmt.Errorf("%s: %s", baseName(src), err))
+			ui.exprChan <- &callExpr{"echoerr", []string{"archive-extract: " + msg}, 1}
+			continue
+		}
+		n++
+	}
+
+	nav.copyTotalChan <- -total
+
+	if err := remote("send load"); err != nil {
+		ui.exprChan <- &callExpr{"echoerr", []string{"archive-extract: " + errs.add(err)}, 1}
+	}
+
+	if errs.count == 0 {
+		ui.exprChan <- &callExpr{"echo", []string{fmt.Sprintf("archive-extract: %d files extracted to %s", n, dstDir)}, 1}
+		ui.notify(start, "Extracted successfully")
+	} else {
+		ui.notify(start, fmt.Sprintf("Extraction finished with %d error(s)", errs.count))
+	}
+}
+
+// This function copies or moves files in the copy/cut buffer to the current
+// directory with the given policy for conflicting files.
+func (nav *nav) paste(ui *ui, policy conflictPolicy) error {
 	srcs, cp, err := loadFiles()
 	if err != nil {
 		return err
@@ -897,10 +2226,12 @@ func (nav *nav) paste(ui *ui) error {
 
 	dstDir := nav.currDir().path
 
+	nav.pastePaths = pastePaths(srcs, dstDir, cp, policy)
+
 	if cp {
-		go nav.copyAsync(ui, srcs, dstDir)
+		go nav.copyAsync(ui, srcs, dstDir, policy)
 	} else {
-		go nav.moveAsync(ui, srcs, dstDir)
+		go nav.moveAsync(ui, srcs, dstDir, policy)
 	}
 
 	if err := saveFiles(nil, false); err != nil {
@@ -914,6 +2245,75 @@ func (nav *nav) paste(ui *ui) error {
 	return nil
 }
 
+// This function returns the paths that the given files end up after they are
+// pasted to the given directory. When conflicting files are renamed, a suffix
+// (e.g. 'foo.~1~') is added to the new name. When they are skipped, files that
+// are not pasted are left out, except directories merged into existing ones. A
+// file moved to the directory it is already in is kept as it is.
+func pastePaths(srcs []string, dstDir string, cp bool, policy conflictPolicy) []string {
+	taken := make(map[string]bool)
+
+	var dsts []string
+	for _, src := range srcs {
+		dst := filepath.Join(dstDir, filepath.Base(src))
+
+		_, err := os.Lstat(dst)
+		if !cp && err == nil {
+			srcStat, serr := os.Stat(src)
+			dstStat, derr := os.Stat(dst)
+			if serr == nil && derr == nil && os.SameFile(srcStat, dstStat) {
+				dsts = append(dsts, dst)
+				continue
+			}
+		}
+
+		if policy != conflictRename {
+			if taken[dst] {
+				continue
+			}
+			if err == nil && policy == conflictSkip {
+				srcStat, serr := os.Stat(src)
+				dstStat, derr := os.Stat(dst)
+				if serr != nil || derr != nil || !srcStat.IsDir() || !dstStat.IsDir() {
+					continue
+				}
+			}
+		} else if !os.IsNotExist(err) || taken[dst] {
+			newPath := dst
+			for i := 1; !os.IsNotExist(err) || taken[newPath]; i++ {
+				newPath = fmt.Sprintf("%s.~%d~", dst, i)
+				_, err = os.Lstat(newPath)
+			}
+			dst = newPath
+		}
+
+		taken[dst] = true
+		dsts = append(dsts, dst)
+	}
+
+	return dsts
+}
+
+// This function returns the files from the last paste that still exist.
+func (nav *nav) followPaths() ([]string, error) {
+	if len(nav.pastePaths) == 0 {
+		return nil, errors.New("no pasted files")
+	}
+
+	var paths []string
+	for _, path := range nav.pastePaths {
+		if _, err := os.Lstat(path); err == nil {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, errors.New("pasted files no longer exist")
+	}
+
+	return paths, nil
+}
+
 func (nav *nav) del(ui *ui) error {
 	list, err := nav.currFileOrSelections()
 	if err != nil {
@@ -922,7 +2322,7 @@ func (nav *nav) del(ui *ui) error {
 
 	go func() {
 		echo := &callExpr{"echoerr", []string{""}, 1}
-		errCount := 0
+		var errs errSummary
 
 		nav.deleteTotalChan <- len(list)
 
@@ -930,8 +2330,7 @@ func (nav *nav) del(ui *ui) error {
 			nav.deleteCountChan <- 1
 
 			if err := os.RemoveAll(path); err != nil {
-				errCount++
-				echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+				echo.args[0] = errs.add(err)
 				ui.exprChan <- echo
 			}
 		}
@@ -939,8 +2338,7 @@ func (nav *nav) del(ui *ui) error {
 		nav.deleteTotalChan <- -len(list)
 
 		if err := remote("send load"); err != nil {
-			errCount++
-			echo.args[0] = fmt.Sprintf("[%d] %s", errCount, err)
+			echo.args[0] = errs.add(err)
 			ui.exprChan <- echo
 		}
 	}()
@@ -972,6 +2370,263 @@ func (nav *nav) rename() error {
 	return nil
 }
 
+// This function returns the path of a new file with the given name, which is
+// relative to the current directory unless it is absolute. An error is
+// returned when there is already a file with the path.
+func (nav *nav) createPath(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("empty name")
+	}
+
+	path := replaceTilde(name)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(nav.currDir().path, path)
+	}
+	path = filepath.Clean(path)
+
+	if _, err := os.Lstat(path); err == nil {
+		return "", fmt.Errorf("file exists: %s", path)
+	}
+
+	return path, nil
+}
+
+// This function creates a file or a directory with the given name relative to
+// the current directory and returns its path. A trailing path separator in the
+// name means a directory, otherwise an empty regular file is created.
+// Intermediate directories are created as needed. An error is returned when
+// the path already exists.
+func (nav *nav) create(name string) (string, error) {
+	path, err := nav.createPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	isDir := os.IsPathSeparator(name[len(name)-1])
+
+	if isDir {
+		if err := os.MkdirAll(path, os.ModePerm); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return "", err
+	}
+
+	return path, f.Close()
+}
+
+// This function creates a file with the given name relative to the current
+// directory from the given template in the template directory and returns
+// its path. Placeholders in the template are replaced when 'templatesubst'
+// option is enabled.
+func (nav *nav) createFromTemplate(tmpl, name string) (string, error) {
+	src, err := templatePath(templateDir(), tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := nav.createPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if err := copyTemplate(src, path, gOpts.templatesubst, time.Now()); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// This function returns the list of old and new paths for renaming the given
+// files to the given names in the same directories, which is used for bulk
+// renaming commands such as 'rename-case' and 'pipe-rename'. Files whose names
+// do not change are skipped. An error is returned when a new path collides
+// with an existing file or with the new path of another file in the list.
+func bulkRenames(paths, names []string) ([][2]string, error) {
+	if len(paths) != len(names) {
+		return nil, fmt.Errorf("number of names mismatch: expected %d but got %d", len(paths), len(names))
+	}
+
+	var list [][2]string
+	targets := make(map[string]bool)
+	sources := make(map[string]bool)
+
+	for i, oldPath := range paths {
+		if filepath.Join(filepath.Dir(oldPath), names[i]) != oldPath {
+			sources[oldPath] = true
+		}
+	}
+
+	for i, oldPath := range paths {
+		name := names[i]
+		if name == "" || name == "." || name == ".." || strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+			return nil, fmt.Errorf("invalid name: '%s'", name)
+		}
+
+		newPath := filepath.Join(filepath.Dir(oldPath), name)
+		if newPath == oldPath {
+			continue
+		}
+
+		if targets[newPath] {
+			return nil, fmt.Errorf("multiple files renamed to: %s", newPath)
+		}
+		targets[newPath] = true
+
+		// allow case only changes in case insensitive filesystems and files
+		// renamed to the old names of other renamed files (e.g. swaps)
+		if newStat, err := os.Lstat(newPath); err == nil && !sources[newPath] {
+			oldStat, err := os.Lstat(oldPath)
+			if err != nil || !os.SameFile(oldStat, newStat) {
+				return nil, fmt.Errorf("file exists: %s", newPath)
+			}
+		}
+
+		list = append(list, [2]string{oldPath, newPath})
+	}
+
+	return list, nil
+}
+
+// This function returns the list of old and new paths for renaming the given
+// files with 'rename-case' command.
+func caseRenames(paths []string, method string) ([][2]string, error) {
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		name, err := changeCase(filepath.Base(path), method)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return bulkRenames(paths, names)
+}
+
+// This function returns the list of old and new paths for changing the
+// extensions of the given files with 'rename-ext' command. Compound extensions
+// are replaced as a whole when 'compoundext' option is enabled.
+func extRenames(paths []string, ext string) ([][2]string, error) {
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, changeExt(filepath.Base(path), ext, gOpts.compoundext))
+	}
+
+	return bulkRenames(paths, names)
+}
+
+// This function returns the list of old and new paths for renaming the given
+// files with sequential names made from the template in 'rename-seq' command.
+// Extensions of the files are kept as they are.
+func seqRenames(paths []string, template string) ([][2]string, error) {
+	names := make([]string, 0, len(paths))
+	for i, path := range paths {
+		name, err := seqName(template, i+1, len(paths))
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name+nameExt(filepath.Base(path)))
+	}
+
+	return bulkRenames(paths, names)
+}
+
+// This function sorts the given paths in the order they are shown in their
+// directories. Directories are kept in the order of their first appearance and
+// files in directories that are not loaded are kept at the end as they are.
+func (nav *nav) sortPaths(paths []string) []string {
+	dirInds := make(map[string]int)
+	fileInds := make(map[string]int)
+
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if _, ok := dirInds[dir]; ok {
+			continue
+		}
+		dirInds[dir] = len(dirInds)
+		if d, ok := nav.dirCache[dir]; ok {
+			for i, f := range d.files {
+				fileInds[filepath.Join(dir, f.Name())] = i
+			}
+		}
+	}
+
+	sorted := append([]string{}, paths...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		di, dj := dirInds[filepath.Dir(sorted[i])], dirInds[filepath.Dir(sorted[j])]
+		if di != dj {
+			return di < dj
+		}
+		fi, iok := fileInds[sorted[i]]
+		fj, jok := fileInds[sorted[j]]
+		if iok != jok {
+			return iok
+		}
+		return iok && fi < fj
+	})
+
+	return sorted
+}
+
+// This function performs the renames prepared by bulk rename commands. Files are
+// first moved to temporary names when a file is renamed to the old name of
+// another file so that renames do not overwrite each other.
+func (nav *nav) renameBulk() error {
+	list := nav.renameList
+	nav.renameList = nil
+
+	sources := make(map[string]bool)
+	for _, pair := range list {
+		sources[pair[0]] = true
+	}
+
+	chained := false
+	for _, pair := range list {
+		if sources[pair[1]] {
+			chained = true
+			break
+		}
+	}
+
+	if chained {
+		temps := make([][2]string, 0, len(list))
+		for i, pair := range list {
+			tmp := filepath.Join(filepath.Dir(pair[0]), fmt.Sprintf(".lf-rename-%d-%d", os.Getpid(), i))
+			if err := os.Rename(pair[0], tmp); err != nil {
+				// move back files that are already moved to temporary names
+				for j, t := range temps {
+					if err := os.Rename(t[0], list[j][0]); err != nil {
+						log.Printf("renaming back: %s", err)
+					}
+				}
+				return err
+			}
+			temps = append(temps, [2]string{tmp, pair[1]})
+		}
+		list = temps
+	}
+
+	for _, pair := range list {
+		if err := os.Rename(pair[0], pair[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (nav *nav) sync() error {
 	list, cp, err := loadFiles()
 	if err != nil {
@@ -983,10 +2638,27 @@ func (nav *nav) sync() error {
 		nav.saves[f] = cp
 	}
 
-	return nav.readMarks()
+	if err := nav.readMarks(); err != nil {
+		return err
+	}
+
+	nav.bookmarks, err = readBookmarks(gBookmarksPath)
+	return err
 }
 
 func (nav *nav) cd(wd string) error {
+	if archive, _, ok := splitArchivePath(wd); ok {
+		if _, err := os.Stat(archive); err != nil {
+			return fmt.Errorf("cd: %s", err)
+		}
+		if err := os.Chdir(filepath.Dir(archive)); err != nil {
+			return fmt.Errorf("cd: %s", err)
+		}
+		nav.getDirs(wd)
+		return nil
+	}
+
+	wd = replaceUserDir(wd)
 	wd = replaceTilde(wd)
 	wd = filepath.Clean(wd)
 
@@ -1031,18 +2703,64 @@ func (nav *nav) sel(path string) error {
 	return nil
 }
 
-func (nav *nav) globSel(pattern string, invert bool) error {
+// This function moves the cursor to the file with the given name in the current
+// directory without changing the directory. Paths are matched by their base
+// names, but their parent directories should be the current directory.
+func (nav *nav) reveal(name string) error {
+	dir := nav.currDir()
+
+	// names without separators are matched directly since paths of virtual
+	// directories in archives can not be joined or cleaned as real paths
+	base := name
+	switch {
+	case isArchivePath(name):
+		if parentDir(name) != dir.path {
+			return fmt.Errorf("not in current directory: %s", name)
+		}
+		base = baseName(name)
+	case strings.ContainsAny(name, "/"+string(filepath.Separator)) || name == "~" || name == "." || name == "..":
+		path := replaceTilde(name)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir.path, path)
+		}
+		path = filepath.Clean(path)
+
+		if filepath.Dir(path) != dir.path {
+			return fmt.Errorf("not in current directory: %s", name)
+		}
+
+		base = filepath.Base(path)
+	}
+
+	// the cursor is only clamped to the files when there is no such file
+	dir.sel(base, nav.height)
+	if dir.name() != base {
+		return fmt.Errorf("no such file: %s", name)
+	}
+
+	return nil
+}
+
+// This function toggles the selection of files in the current directory whose
+// names match the given pattern. Hidden files are also matched when 'hidden'
+// is set even if they are not shown.
+func (nav *nav) globSel(pattern string, invert, hidden bool) error {
 	dir := nav.currDir()
 	anyMatched := false
 
-	for i := 0; i < len(dir.files); i++ {
-		matched, err := filepath.Match(pattern, dir.files[i].Name())
+	files := dir.files
+	if hidden {
+		files = dir.allFiles
+	}
+
+	for i := 0; i < len(files); i++ {
+		matched, err := filepath.Match(pattern, files[i].Name())
 		if err != nil {
 			return fmt.Errorf("glob-select: %s", err)
 		}
 		if matched {
 			anyMatched = true
-			fpath := filepath.Join(dir.path, dir.files[i].Name())
+			fpath := filepath.Join(dir.path, files[i].Name())
 			if _, ok := nav.selections[fpath]; ok == invert {
 				nav.toggleSelection(fpath)
 			}
@@ -1056,12 +2774,184 @@ func (nav *nav) globSel(pattern string, invert bool) error {
 	return nil
 }
 
+// This is the maximum number of files selected by 'glob-select-recursive'
+// command to avoid filling the selection when the pattern is too broad.
+const gGlobSelectLimit = 10000
+
+var errWalkCancelled = errors.New("walk cancelled")
+
+// This type keeps the state of a running walk of recursive selection commands
+// so that invoking one of them again cancels the walk.
+type walkScan struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newWalkScan() *walkScan {
+	return &walkScan{make(chan struct{}), make(chan struct{})}
+}
+
+func (s *walkScan) running() bool {
+	select {
+	case <-s.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// This function walks the directory tree under the given root and returns the
+// paths of files for which the given function returns true. Hidden files and
+// directories matching the given hidden patterns are skipped unless hidden is
+// true. At most 'limit' paths are returned and 'capped' is set when there are
+// more matches. The walk stops early when the stop channel is closed.
+func walkMatches(root string, match func(path string, info os.FileInfo) bool, limit int, hidden bool, hiddenfiles []string, stop <-chan struct{}) (matches []string, capped bool, err error) {
+	errLimit := errors.New("limit reached")
+
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		select {
+		case <-stop:
+			return errWalkCancelled
+		default:
+		}
+
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+
+		if path == root {
+			return nil
+		}
+
+		if !hidden && isHidden(info, filepath.Dir(path), hiddenfiles) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if match(path, info) {
+			if len(matches) == limit {
+				return errLimit
+			}
+			matches = append(matches, path)
+		}
+
+		return nil
+	})
+
+	if err == errLimit {
+		return matches, true, nil
+	}
+
+	return matches, false, err
+}
+
+// This function returns the paths of files under the given root whose names
+// match the given glob as in 'walkMatches'.
+func globWalk(root, pattern string, limit int, hidden bool, hiddenfiles []string, stop <-chan struct{}) (matches []string, capped bool, err error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, false, err
+	}
+
+	return walkMatches(root, func(path string, info os.FileInfo) bool {
+		matched, _ := filepath.Match(pattern, info.Name())
+		return matched
+	}, limit, hidden, hiddenfiles, stop)
+}
+
+// This function returns the paths of files under the given root matching the
+// given regular expression as in 'walkMatches'. The expression is matched
+// against the names of files, or the paths relative to the root with slashes
+// as separators when byPath is true. Expressions are not anchored and can
+// match anywhere unless they start with '^' or end with '$'.
+func regexWalk(root string, re *regexp.Regexp, byPath bool, limit int, hidden bool, hiddenfiles []string, stop <-chan struct{}) (matches []string, capped bool, err error) {
+	return walkMatches(root, func(path string, info os.FileInfo) bool {
+		if !byPath {
+			return re.MatchString(info.Name())
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(filepath.ToSlash(rel))
+	}, limit, hidden, hiddenfiles, stop)
+}
+
+// This function runs the given walk of a recursive selection command with the
+// given name and sends the matches to the ui to be selected in the main loop.
+// The walk stops early when the stop channel of the scan is closed.
+func walkSelAsync(ui *ui, name, pattern string, scan *walkScan, walk func(stop <-chan struct{}) ([]string, bool, error)) {
+	defer close(scan.done)
+
+	matches, capped, err := walk(scan.stop)
+	if err != nil {
+		ui.exprChan <- &callExpr{"echoerr", []string{fmt.Sprintf("%s: %s", name, err)}, 1}
+		return
+	}
+
+	if len(matches) == 0 {
+		ui.exprChan <- &callExpr{"echoerr", []string{fmt.Sprintf("%s: pattern not found: %s", name, pattern)}, 1}
+		return
+	}
+
+	ui.exprChan <- &walkResult{name, matches, capped}
+}
+
+// This type is sent from the walk to the ui so that the matches are selected
+// in the main loop.
+type walkResult struct {
+	name    string
+	matches []string
+	capped  bool
+}
+
+func (e *walkResult) String() string { return e.name }
+
+func (e *walkResult) eval(app *app, args []string) {
+	count := 0
+	for _, path := range e.matches {
+		if _, ok := app.nav.selections[path]; !ok {
+			app.nav.toggleSelection(path)
+			count++
+		}
+	}
+
+	if e.capped {
+		app.ui.echof("%s: %d files selected (limited to %d matches)", e.name, count, gGlobSelectLimit)
+	} else {
+		app.ui.echof("%s: %d files selected", e.name, count)
+	}
+}
+
+// This function selects the files in the current directory for which the
+// given function returns true and returns the number of newly selected files.
+func (nav *nav) selectMatching(match func(f *file) bool) int {
+	dir := nav.currDir()
+
+	count := 0
+	for _, f := range dir.files {
+		if !match(f) {
+			continue
+		}
+		if _, ok := nav.selections[f.path]; !ok {
+			nav.toggleSelection(f.path)
+			count++
+		}
+	}
+
+	return count
+}
+
 func findMatch(name, pattern string) bool {
 	if gOpts.ignorecase {
 		lpattern := strings.ToLower(pattern)
 		if !gOpts.smartcase || lpattern == pattern {
-			pattern = lpattern
-			name = strings.ToLower(name)
+			pattern = foldCase(pattern)
+			name = foldCase(name)
 		}
 	}
 	if gOpts.ignoredia {
@@ -1142,8 +3032,8 @@ func searchMatch(name, pattern string) (matched bool, err error) {
 	if gOpts.ignorecase {
 		lpattern := strings.ToLower(pattern)
 		if !gOpts.smartcase || lpattern == pattern {
-			pattern = lpattern
-			name = strings.ToLower(name)
+			pattern = foldCase(pattern)
+			name = foldCase(name)
 		}
 	}
 	if gOpts.ignoredia {
@@ -1159,6 +3049,108 @@ func searchMatch(name, pattern string) (matched bool, err error) {
 	return strings.Contains(name, pattern), nil
 }
 
+// This function returns the function to normalize characters before matching
+// them with the given search pattern one by one, which ignores case and
+// diacritics according to 'ignorecase', 'smartcase', 'ignoredia', and
+// 'smartdia' options as in 'searchMatch' function.
+func searchNorm(pattern string) func(r rune) rune {
+	fold := false
+	if gOpts.ignorecase {
+		lpattern := strings.ToLower(pattern)
+		fold = !gOpts.smartcase || lpattern == pattern
+	}
+	dia := false
+	if gOpts.ignoredia {
+		lpattern := removeDiacritics(pattern)
+		dia = !gOpts.smartdia || lpattern == pattern
+	}
+
+	return func(r rune) rune {
+		if fold {
+			r = foldRune(r)
+		}
+		if dia {
+			if s := []rune(removeDiacritics(string(r))); len(s) == 1 {
+				r = s[0]
+			}
+		}
+		return r
+	}
+}
+
+// This function returns the beginning and end indices of the non-overlapping
+// matches of the search pattern in the runes of the given name, which are used
+// to highlight matches in the listing. Characters are compared one by one so
+// that indices are not shifted when case conversion changes the length of the
+// name. Glob patterns are not supported and never return any matches.
+func searchSpans(name, pattern string) [][2]int {
+	if pattern == "" || gOpts.globsearch {
+		return nil
+	}
+
+	norm := searchNorm(pattern)
+
+	var pat []rune
+	for _, r := range pattern {
+		pat = append(pat, norm(r))
+	}
+
+	var runes []rune
+	for _, r := range name {
+		runes = append(runes, norm(r))
+	}
+
+	var spans [][2]int
+outer:
+	for i := 0; i+len(pat) <= len(runes); {
+		for j, r := range pat {
+			if runes[i+j] != r {
+				i++
+				continue outer
+			}
+		}
+		spans = append(spans, [2]int{i, i + len(pat)})
+		i += len(pat)
+	}
+
+	return spans
+}
+
+// This function keeps a separate search pattern for each directory when
+// 'dirsearch' option is enabled. The pattern of the previous directory is
+// saved and the pattern of the current directory is restored, which is empty
+// for directories without a saved pattern. The pattern at the time the option
+// is enabled belongs to the directory that is current at the time.
+func (nav *nav) syncSearch() {
+	if !gOpts.dirsearch {
+		nav.searchDir = ""
+		return
+	}
+
+	curr := nav.currDir().path
+	if curr == nav.searchDir {
+		return
+	}
+
+	if nav.searchDir != "" {
+		if nav.search == "" {
+			delete(nav.searches, nav.searchDir)
+		} else {
+			nav.searches[nav.searchDir] = nav.search
+		}
+		nav.search = nav.searches[curr]
+	}
+
+	nav.searchDir = curr
+}
+
+// This function clears the search pattern along with the pattern saved for
+// the current directory.
+func (nav *nav) clearSearch() {
+	nav.search = ""
+	delete(nav.searches, nav.currDir().path)
+}
+
 func (nav *nav) searchNext() error {
 	dir := nav.currDir()
 	for i := dir.ind + 1; i < len(dir.files); i++ {
@@ -0,0 +1,2275 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPruneSelections(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	foo := filepath.Join(dir, "foo")
+	bar := filepath.Join(dir, "bar")
+	baz := filepath.Join(dir, "baz")
+
+	if err := ioutil.WriteFile(foo, nil, 0644); err != nil {
+		t.Fatalf("creating temporary file: %s", err)
+	}
+
+	nav := &nav{selections: make(map[string]int)}
+
+	nav.toggleSelection(foo)
+	nav.toggleSelection(bar)
+	nav.toggleSelection(baz)
+
+	if got := nav.pruneSelections(); got != 2 {
+		t.Errorf("expected '2' stale entries but got '%d'", got)
+	}
+
+	if _, ok := nav.selections[foo]; !ok || len(nav.selections) != 1 {
+		t.Errorf("expected only '%s' to remain selected but got '%v'", foo, nav.selections)
+	}
+
+	if err := os.Remove(foo); err != nil {
+		t.Fatalf("removing temporary file: %s", err)
+	}
+
+	if got := nav.pruneSelections(); got != 1 {
+		t.Errorf("expected '1' stale entry but got '%d'", got)
+	}
+
+	if len(nav.selections) != 0 || nav.selectionInd != 0 {
+		t.Errorf("expected empty selection but got '%v' with index '%d'", nav.selections, nav.selectionInd)
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		s      string
+		length int
+		exp    int
+	}{
+		{"1", 100, 0},
+		{"42", 100, 41},
+		{"100", 100, 99},
+		{"1000", 100, 99},
+		{"0", 100, 0},
+		{"0%", 100, 0},
+		{"50%", 100, 49},
+		{"50%", 7, 3},
+		{"100%", 7, 6},
+		{"200%", 7, 6},
+		{"1", 0, 0},
+		{"50%", 0, 0},
+	}
+
+	for _, test := range tests {
+		got, err := parseLine(test.s, test.length)
+		if err != nil {
+			t.Errorf("at input '%s' with length '%d' unexpected error: %s", test.s, test.length, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' with length '%d' expected '%d' but got '%d'", test.s, test.length, test.exp, got)
+		}
+	}
+
+	for _, s := range []string{"", "foo", "%", "-1", "-5%", "5%%"} {
+		if _, err := parseLine(s, 100); err == nil {
+			t.Errorf("at input '%s' expected an error", s)
+		}
+	}
+}
+
+func TestGotoLine(t *testing.T) {
+	defer func(scrolloff int) { gOpts.scrolloff = scrolloff }(gOpts.scrolloff)
+	gOpts.scrolloff = 2
+
+	d := &dir{files: make([]*file, 100)}
+	nav := &nav{dirs: []*dir{d}, height: 10}
+
+	tests := []struct {
+		ind int
+		exp int
+		pos int
+	}{
+		{50, 50, 7},
+		{99, 99, 9},
+		{120, 99, 9},
+		{90, 90, 2},
+		{0, 0, 0},
+		{-5, 0, 0},
+	}
+
+	for _, test := range tests {
+		nav.gotoLine(test.ind)
+		if d.ind != test.exp || d.pos != test.pos {
+			t.Errorf("at input '%d' expected index '%d' and position '%d' but got '%d' and '%d'", test.ind, test.exp, test.pos, d.ind, d.pos)
+		}
+	}
+
+	empty := &dir{}
+	nav.dirs = []*dir{empty}
+	nav.gotoLine(5)
+	if empty.ind != 0 || empty.pos != 0 {
+		t.Errorf("at empty directory expected index '0' and position '0' but got '%d' and '%d'", empty.ind, empty.pos)
+	}
+}
+
+func TestSelectionsAcrossDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	foo := filepath.Join(dir, "foo")
+	bar := filepath.Join(dir, "bar")
+	if err := os.Mkdir(foo, 0755); err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	if err := os.Mkdir(bar, 0755); err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+
+	paths := []string{
+		filepath.Join(foo, "a"),
+		filepath.Join(bar, "b"),
+		filepath.Join(foo, "c"),
+		filepath.Join(dir, "d"),
+	}
+	for _, path := range paths {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	nav := &nav{selections: make(map[string]int)}
+
+	for _, path := range paths {
+		nav.toggleSelection(path)
+	}
+
+	if got := nav.currSelections(); !reflect.DeepEqual(got, paths) {
+		t.Errorf("expected '%v' but got '%v'", paths, got)
+	}
+
+	if got := nav.pruneSelections(); got != 0 {
+		t.Errorf("expected '0' stale entries but got '%d'", got)
+	}
+
+	nav.toggleSelection(paths[1])
+
+	exp := []string{paths[0], paths[2], paths[3]}
+	if got := nav.currSelections(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestCaseRenames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"Foo Bar.TXT", "baz.txt", "Qux.md", "qux.md.bak", "QUX.MD.bak"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	paths := []string{
+		filepath.Join(dir, "Foo Bar.TXT"),
+		filepath.Join(dir, "baz.txt"),
+	}
+
+	got, err := caseRenames(paths, "snake")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	exp := [][2]string{{paths[0], filepath.Join(dir, "foo_bar.TXT")}}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+
+	nav := &nav{renameList: got}
+	if err := nav.renameBulk(); err != nil {
+		t.Fatalf("renaming files: %s", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "foo_bar.TXT")); err != nil {
+		t.Errorf("renamed file not found: %s", err)
+	}
+
+	if _, err := caseRenames([]string{filepath.Join(dir, "baz.txt")}, "camel"); err == nil {
+		t.Errorf("at method 'camel' expected an error")
+	}
+
+	// collision with another file in the list
+	if _, err := caseRenames([]string{filepath.Join(dir, "qux.md.bak"), filepath.Join(dir, "QUX.MD.bak")}, "upper"); err == nil {
+		t.Errorf("expected an error for files renamed to the same name")
+	}
+
+	// collision with an existing file, unless it is the same file in
+	// case insensitive filesystems
+	qux := filepath.Join(dir, "Qux.md")
+	insensitive := false
+	if _, err := os.Lstat(filepath.Join(dir, "qux.md")); err == nil {
+		insensitive = true
+	}
+	if _, err := caseRenames([]string{filepath.Join(dir, "qux.md.bak")}, "upper"); err == nil && !insensitive {
+		t.Errorf("expected an error for existing file 'QUX.MD.bak'")
+	}
+	if _, err := caseRenames([]string{qux}, "lower"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestExtRenames(t *testing.T) {
+	defer func(compoundext bool) { gOpts.compoundext = compoundext }(gOpts.compoundext)
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a.jpeg", "b.jpeg", "b.jpg", "c", "d.tar.gz", "e.txt", "e.md"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		names       []string
+		ext         string
+		compoundext bool
+		exp         [][2]string
+		err         bool
+	}{
+		{[]string{"a.jpeg"}, "jpg", false, [][2]string{{path("a.jpeg"), path("a.jpg")}}, false},
+		{[]string{"c"}, "txt", false, [][2]string{{path("c"), path("c.txt")}}, false},
+		{[]string{"a.jpeg", "c"}, "", false, [][2]string{{path("a.jpeg"), path("a")}}, false},
+		{[]string{"d.tar.gz"}, "zst", false, [][2]string{{path("d.tar.gz"), path("d.tar.zst")}}, false},
+		{[]string{"d.tar.gz"}, "tgz", true, [][2]string{{path("d.tar.gz"), path("d.tgz")}}, false},
+		{[]string{"a.jpeg"}, "jpeg", false, nil, false},
+		{[]string{"b.jpeg"}, "jpg", false, nil, true},
+		{[]string{"e.txt", "e.md"}, "rst", false, nil, true},
+		{[]string{"a.jpeg"}, "x/y", false, nil, true},
+	}
+
+	for _, test := range tests {
+		gOpts.compoundext = test.compoundext
+
+		var paths []string
+		for _, name := range test.names {
+			paths = append(paths, path(name))
+		}
+
+		got, err := extRenames(paths, test.ext)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%v' with extension '%s' expected error '%t' but got '%v'", test.names, test.ext, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' with extension '%s' expected '%v' but got '%v'", test.names, test.ext, test.exp, got)
+		}
+	}
+}
+
+func TestGlobWalk(t *testing.T) {
+	hiddenfiles := []string{".*"}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{"foo", "foo/bar", "foo/bar/baz", ".hidden"} {
+		if err := os.MkdirAll(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+	}
+
+	files := []string{
+		"a.txt",
+		"b.go",
+		"foo/c.txt",
+		"foo/bar/d.txt",
+		"foo/bar/baz/e.txt",
+		"foo/bar/baz/f.md",
+		"foo/.g.txt",
+		".hidden/h.txt",
+	}
+	for _, path := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, path), nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	join := func(paths ...string) []string {
+		var list []string
+		for _, path := range paths {
+			list = append(list, filepath.Join(dir, path))
+		}
+		return list
+	}
+
+	matches, capped, err := globWalk(dir, "*.txt", 100, false, hiddenfiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	exp := join("a.txt", "foo/bar/baz/e.txt", "foo/bar/d.txt", "foo/c.txt")
+	if !reflect.DeepEqual(matches, exp) || capped {
+		t.Errorf("expected '%v' but got '%v' (capped: %t)", exp, matches, capped)
+	}
+
+	matches, capped, err = globWalk(dir, "*.txt", 100, true, hiddenfiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	exp = join(".hidden/h.txt", "a.txt", "foo/.g.txt", "foo/bar/baz/e.txt", "foo/bar/d.txt", "foo/c.txt")
+	if !reflect.DeepEqual(matches, exp) || capped {
+		t.Errorf("expected '%v' but got '%v' (capped: %t)", exp, matches, capped)
+	}
+
+	matches, capped, err = globWalk(dir, "*.txt", 3, false, hiddenfiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(matches) != 3 || !capped {
+		t.Errorf("expected '3' capped matches but got '%v' (capped: %t)", matches, capped)
+	}
+
+	matches, capped, err = globWalk(dir, "*.md", 1, false, hiddenfiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if exp := join("foo/bar/baz/f.md"); !reflect.DeepEqual(matches, exp) || capped {
+		t.Errorf("expected '%v' but got '%v' (capped: %t)", exp, matches, capped)
+	}
+
+	if _, _, err := globWalk(dir, "[", 100, false, hiddenfiles, nil); err == nil {
+		t.Errorf("at pattern '[' expected an error")
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if _, _, err := globWalk(dir, "*.txt", 100, false, hiddenfiles, stop); err != errWalkCancelled {
+		t.Errorf("with a closed stop channel expected '%s' but got '%v'", errWalkCancelled, err)
+	}
+
+	defer func(selectioninfo bool) { gOpts.selectioninfo = selectioninfo }(gOpts.selectioninfo)
+	gOpts.selectioninfo = false
+
+	app := &app{
+		ui:  &ui{exprChan: make(chan expr, 1)},
+		nav: &nav{selections: map[string]int{filepath.Join(dir, "a.txt"): 0}, selectionInd: 1},
+	}
+
+	scan := newWalkScan()
+	walkSelAsync(app.ui, "glob-select-recursive", "*.txt", scan, func(stop <-chan struct{}) ([]string, bool, error) {
+		return globWalk(dir, "*.txt", 100, false, hiddenfiles, stop)
+	})
+	if scan.running() {
+		t.Errorf("expected the walk to be finished")
+	}
+
+	e := <-app.ui.exprChan
+	if _, ok := e.(*walkResult); !ok {
+		t.Fatalf("expected the matches to be sent but got '%v'", e)
+	}
+	e.eval(app, nil)
+	if len(app.nav.selections) != 4 {
+		t.Errorf("expected '4' files to be selected but got '%v'", app.nav.selections)
+	}
+	if msg := app.ui.msg; msg != "glob-select-recursive: 3 files selected" {
+		t.Errorf("expected newly selected files to be reported but got '%s'", msg)
+	}
+}
+
+func TestRegexWalk(t *testing.T) {
+	hiddenfiles := []string{".*"}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{"src/pkg", "lib/src", ".git"} {
+		if err := os.MkdirAll(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+	}
+
+	for _, path := range []string{
+		"main_test.go",
+		"src/a.go",
+		"src/a_test.go",
+		"src/pkg/b_test.go",
+		"lib/src/c_test.go",
+		".git/d_test.go",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, path), nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	join := func(paths ...string) []string {
+		var list []string
+		for _, path := range paths {
+			list = append(list, filepath.Join(dir, path))
+		}
+		return list
+	}
+
+	tests := []struct {
+		pattern string
+		byPath  bool
+		hidden  bool
+		exp     []string
+	}{
+		{`_test\.go$`, false, false, join("lib/src/c_test.go", "main_test.go", "src/a_test.go", "src/pkg/b_test.go")},
+		{`^a`, false, false, join("src/a.go", "src/a_test.go")},
+		{`^src$`, false, false, join("lib/src", "src")},
+		{`src/.*_test\.go$`, false, false, nil},
+		{`src/.*_test\.go$`, true, false, join("lib/src/c_test.go", "src/a_test.go", "src/pkg/b_test.go")},
+		{`^src/.*_test\.go$`, true, false, join("src/a_test.go", "src/pkg/b_test.go")},
+		{`^src/[^/]*\.go$`, true, false, join("src/a.go", "src/a_test.go")},
+		{`^[^/]*_test\.go$`, true, false, join("main_test.go")},
+		{`^\.git/`, true, false, nil},
+		{`^\.git/`, true, true, join(".git/d_test.go")},
+	}
+
+	for _, test := range tests {
+		matches, capped, err := regexWalk(dir, regexp.MustCompile(test.pattern), test.byPath, 100, test.hidden, hiddenfiles, nil)
+		if err != nil {
+			t.Fatalf("at pattern '%s' unexpected error: %s", test.pattern, err)
+		}
+		if !reflect.DeepEqual(matches, test.exp) || capped {
+			t.Errorf("at pattern '%s' with path '%t' expected '%v' but got '%v' (capped: %t)", test.pattern, test.byPath, test.exp, matches, capped)
+		}
+	}
+
+	matches, capped, err := regexWalk(dir, regexp.MustCompile(`_test`), false, 2, false, hiddenfiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(matches) != 2 || !capped {
+		t.Errorf("expected '2' capped matches but got '%v' (capped: %t)", matches, capped)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if _, _, err := regexWalk(dir, regexp.MustCompile(`_test`), false, 100, false, hiddenfiles, stop); err != errWalkCancelled {
+		t.Errorf("with a closed stop channel expected '%s' but got '%v'", errWalkCancelled, err)
+	}
+
+	ui := &ui{exprChan: make(chan expr, 1)}
+	walkSelAsync(ui, "select-regex-recursive", `^x`, newWalkScan(), func(stop <-chan struct{}) ([]string, bool, error) {
+		return regexWalk(dir, regexp.MustCompile(`^x`), false, 100, false, hiddenfiles, stop)
+	})
+	if e, ok := (<-ui.exprChan).(*callExpr); !ok || e.name != "echoerr" {
+		t.Errorf("without matches expected an error to be sent but got '%v'", e)
+	}
+}
+
+func TestSearchSpans(t *testing.T) {
+	defer func(ignorecase, smartcase, ignoredia, smartdia, globsearch bool) {
+		gOpts.ignorecase = ignorecase
+		gOpts.smartcase = smartcase
+		gOpts.ignoredia = ignoredia
+		gOpts.smartdia = smartdia
+		gOpts.globsearch = globsearch
+	}(gOpts.ignorecase, gOpts.smartcase, gOpts.ignoredia, gOpts.smartdia, gOpts.globsearch)
+	gOpts.ignoredia = false
+	gOpts.smartdia = false
+	gOpts.globsearch = false
+
+	tests := []struct {
+		name       string
+		pattern    string
+		ignorecase bool
+		smartcase  bool
+		exp        [][2]int
+	}{
+		{"foo.txt", "", false, false, nil},
+		{"foo.txt", "bar", false, false, nil},
+		{"foo.txt", "o", false, false, [][2]int{{1, 2}, {2, 3}}},
+		{"foo.txt", "txt", false, false, [][2]int{{4, 7}}},
+		{"aaaa", "aa", false, false, [][2]int{{0, 2}, {2, 4}}},
+		{"aaa", "aa", false, false, [][2]int{{0, 2}}},
+		{"Foo.TXT", "txt", false, false, nil},
+		{"Foo.TXT", "txt", true, false, [][2]int{{4, 7}}},
+		{"Foo.TXT", "foo", true, true, [][2]int{{0, 3}}},
+		{"Foo.TXT", "FOO", true, true, nil},
+		{"Foo.TXT", "Foo", true, true, [][2]int{{0, 3}}},
+		{"ÇİLEK.md", "çilek", true, false, [][2]int{{0, 5}}},
+		{"日本語ファイル.txt", "ファイル", false, false, [][2]int{{3, 7}}},
+		{"日本語ファイル日本語", "日本", false, false, [][2]int{{0, 2}, {7, 9}}},
+		{"ＡＢＣ", "ｂ", true, false, [][2]int{{1, 2}}},
+		{"STRAẞE", "straße", true, false, [][2]int{{0, 6}}},
+		{"ſtraße", "STRASSE", true, false, nil},
+		{"Kelvin", "kelvin", true, true, [][2]int{{0, 6}}},
+		{"ΟΔΟΣ.txt", "οδος", true, false, [][2]int{{0, 4}}},
+		{"İzmir", "izmir", true, false, [][2]int{{0, 5}}},
+	}
+
+	for _, test := range tests {
+		gOpts.ignorecase = test.ignorecase
+		gOpts.smartcase = test.smartcase
+		if got := searchSpans(test.name, test.pattern); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' with pattern '%s' expected '%v' but got '%v'", test.name, test.pattern, test.exp, got)
+		}
+	}
+
+	gOpts.globsearch = true
+	if got := searchSpans("foo.txt", "foo"); got != nil {
+		t.Errorf("expected no spans with globsearch but got '%v'", got)
+	}
+}
+
+func TestPreviewMatch(t *testing.T) {
+	defer func(ignorecase, smartcase, ignoredia, smartdia, globsearch bool) {
+		gOpts.ignorecase = ignorecase
+		gOpts.smartcase = smartcase
+		gOpts.ignoredia = ignoredia
+		gOpts.smartdia = smartdia
+		gOpts.globsearch = globsearch
+	}(gOpts.ignorecase, gOpts.smartcase, gOpts.ignoredia, gOpts.smartdia, gOpts.globsearch)
+	gOpts.ignorecase = true
+	gOpts.smartcase = true
+	gOpts.ignoredia = true
+	gOpts.smartdia = false
+	gOpts.globsearch = false
+
+	tests := []struct {
+		line    string
+		pattern string
+		beg     int
+		end     int
+	}{
+		{"foo bar baz", "bar", 4, 7},
+		{"foo bar baz", "ba", 4, 6},
+		{"foo bar baz", "qux", -1, -1},
+		{"foo bar baz", "", -1, -1},
+		{"foo BAR baz", "bar", 4, 7},
+		{"foo BAR baz", "Bar", -1, -1},
+		{"foo Bar baz", "Bar", 4, 7},
+		{"foo çelik baz", "celik", 4, 10},
+		{"foo \033[31mbar\033[0m baz", "bar", 9, 12},
+		{"foo \033[31mba\033[0mr baz", "bar", 9, 16},
+		{"\033[1mbar", "bar", 4, 7},
+		{"ağaç bar", "bar", 7, 10},
+	}
+
+	for _, test := range tests {
+		if beg, end := previewMatch(test.line, test.pattern); beg != test.beg || end != test.end {
+			t.Errorf("at input '%q' with pattern '%s' expected '%d:%d' but got '%d:%d'", test.line, test.pattern, test.beg, test.end, beg, end)
+		}
+	}
+
+	gOpts.globsearch = true
+	if beg, _ := previewMatch("foo bar baz", "bar"); beg != -1 {
+		t.Errorf("expected no match with globsearch but got '%d'", beg)
+	}
+}
+
+func TestPreviewOffset(t *testing.T) {
+	defer func(scrolloff int) { gOpts.scrolloff = scrolloff }(gOpts.scrolloff)
+
+	tests := []struct {
+		scrolloff int
+		match     int
+		total     int
+		height    int
+		exp       int
+	}{
+		{0, -1, 100, 10, 0},
+		{0, 0, 100, 10, 0},
+		{0, 5, 100, 10, 5},
+		{0, 50, 100, 10, 50},
+		{0, 95, 100, 10, 90},
+		{0, 99, 100, 10, 90},
+		{0, 3, 5, 10, 0},
+		{2, 50, 100, 10, 48},
+		{2, 1, 100, 10, 0},
+		{2, 98, 100, 10, 90},
+		{10, 50, 100, 10, 46},
+		{10, 50, 100, 1, 50},
+	}
+
+	for _, test := range tests {
+		gOpts.scrolloff = test.scrolloff
+		if got := previewOffset(test.match, test.total, test.height); got != test.exp {
+			t.Errorf("at input '%d' with total '%d', height '%d', and scrolloff '%d' expected '%d' but got '%d'", test.match, test.total, test.height, test.scrolloff, test.exp, got)
+		}
+	}
+}
+
+func TestPreviewLines(t *testing.T) {
+	defer func(previewbytes, previewlines int) {
+		gOpts.previewbytes = previewbytes
+		gOpts.previewlines = previewlines
+	}(gOpts.previewbytes, gOpts.previewlines)
+
+	tests := []struct {
+		s     string
+		bytes int
+		lines int
+		h     int
+		exp   []string
+	}{
+		{"a\nb\nc\nd\n", 0, 0, 10, []string{"a", "b", "c", "d"}},
+		{"a\nb\nc\nd\n", 0, 0, 2, []string{"a", "b"}},
+		{"a\nb\nc\nd\n", 0, 3, 10, []string{"a", "b", "c", gPreviewTruncated}},
+		{"a\nb\nc\nd\n", 0, 4, 10, []string{"a", "b", "c", "d"}},
+		{"a\nb\nc\nd\n", 0, 5, 10, []string{"a", "b", "c", "d"}},
+		{"a\nb\nc\nd\n", 0, 1, 2, []string{"a", gPreviewTruncated}},
+		{"a\nb\nc\nd\n", 0, 3, 2, []string{"a", "b"}},
+		{"ab\ncd\nef\n", 4, 0, 10, []string{"ab", "c", gPreviewTruncated}},
+		{"ab\ncd\nef\n", 5, 0, 10, []string{"ab", "cd", gPreviewTruncated}},
+		{"ab\ncd\nef\n", 9, 0, 10, []string{"ab", "cd", "ef"}},
+		{"ab\ncd\nef\n", 20, 0, 10, []string{"ab", "cd", "ef"}},
+		{"ab\ncd\nef\n", 5, 1, 10, []string{"ab", gPreviewTruncated}},
+	}
+
+	for _, test := range tests {
+		gOpts.previewbytes = test.bytes
+		gOpts.previewlines = test.lines
+		if got, _, _ := previewLines(strings.NewReader(test.s), test.h, 0, ""); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' with previewbytes '%d', previewlines '%d', and height '%d' expected '%q' but got '%q'",
+				test.s, test.bytes, test.lines, test.h, test.exp, got)
+		}
+	}
+}
+
+// This type is an endless reader repeating the given string and counting the
+// number of bytes read from it.
+type endlessReader struct {
+	s string
+	n int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.s[(r.n+i)%len(r.s)]
+	}
+	r.n += len(p)
+	return len(p), nil
+}
+
+func TestPreviewLinesStop(t *testing.T) {
+	defer func(previewbytes, previewlines int) {
+		gOpts.previewbytes = previewbytes
+		gOpts.previewlines = previewlines
+	}(gOpts.previewbytes, gOpts.previewlines)
+
+	tests := []struct {
+		s      string
+		bytes  int
+		lines  int
+		search string
+		max    int
+		exp    []string
+	}{
+		{"x", 10, 0, "", 11, []string{"xxxxxxxxxx", gPreviewTruncated}},
+		{"ab\n", 7, 0, "", 8, []string{"ab", "ab", "a", gPreviewTruncated}},
+		{"ab\n", 0, 2, "", 64 << 10, []string{"ab", "ab", gPreviewTruncated}},
+		{"ab\n", 0, 2, "z", 64 << 10, []string{"ab", "ab", gPreviewTruncated}},
+		{"ab\n", 100, 0, "z", 101, []string{"ab", "ab", "ab", "ab", "ab"}},
+	}
+
+	for _, test := range tests {
+		gOpts.previewbytes = test.bytes
+		gOpts.previewlines = test.lines
+		r := &endlessReader{s: test.s}
+		got, _, _ := previewLines(r, 5, 0, test.search)
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' with previewbytes '%d' and previewlines '%d' expected '%q' but got '%q'",
+				test.s, test.bytes, test.lines, test.exp, got)
+		}
+		if r.n > test.max {
+			t.Errorf("at input '%q' with previewbytes '%d' and previewlines '%d' expected at most '%d' bytes read but got '%d'",
+				test.s, test.bytes, test.lines, test.max, r.n)
+		}
+	}
+}
+
+func TestPreviewScroll(t *testing.T) {
+	defer func(previewbytes, previewlines, scrolloff int) {
+		gOpts.previewbytes = previewbytes
+		gOpts.previewlines = previewlines
+		gOpts.scrolloff = scrolloff
+	}(gOpts.previewbytes, gOpts.previewlines, gOpts.scrolloff)
+	gOpts.previewbytes = 0
+	gOpts.scrolloff = 0
+
+	tests := []struct {
+		s      string
+		lines  int
+		h      int
+		scroll int
+		search string
+		exp    []string
+		off    int
+		last   bool
+	}{
+		{"a\nb\nc\nd\ne\n", 0, 2, 0, "", []string{"a", "b"}, 0, false},
+		{"a\nb\nc\nd\ne\n", 0, 2, 1, "", []string{"b", "c"}, 1, false},
+		{"a\nb\nc\nd\ne\n", 0, 2, 3, "", []string{"d", "e"}, 3, true},
+		{"a\nb\nc\nd\ne\n", 0, 2, 4, "", []string{"d", "e"}, 3, true},
+		{"a\nb\nc\nd\ne\n", 0, 2, 100, "", []string{"d", "e"}, 3, true},
+		{"a\nb\n", 0, 5, 0, "", []string{"a", "b"}, 0, true},
+		{"a\nb\n", 0, 5, 3, "", []string{"a", "b"}, 0, true},
+		{"a\nb\nc\nd\ne\n", 3, 2, 5, "", []string{"c", gPreviewTruncated}, 2, true},
+		{"a\nb\nc\nd\ne\nf\n", 0, 2, 0, "c", []string{"\033[7mc\033[27m", "d"}, 0, false},
+		{"a\nb\nc\nd\ne\nf\n", 0, 2, 2, "c", []string{"e", "f"}, 2, true},
+		{"a\nb\nc\nd\ne\nf\n", 0, 2, 5, "c", []string{"e", "f"}, 2, true},
+	}
+
+	for _, test := range tests {
+		gOpts.previewlines = test.lines
+		got, off, last := previewLines(strings.NewReader(test.s), test.h, test.scroll, test.search)
+		if !reflect.DeepEqual(got, test.exp) || off != test.off || last != test.last {
+			t.Errorf("at input '%q' with height '%d' and scroll '%d' expected '%q', '%d', and '%t' but got '%q', '%d', and '%t'",
+				test.s, test.h, test.scroll, test.exp, test.off, test.last, got, off, last)
+		}
+	}
+}
+
+func TestScrollPreview(t *testing.T) {
+	nav := &nav{}
+
+	nav.syncPreviewScroll("/foo")
+	if nav.scrollPreview(-1, nil) || nav.previewScroll != 0 {
+		t.Errorf("expected scrolling up at the top to be ignored but got '%d'", nav.previewScroll)
+	}
+
+	if !nav.scrollPreview(3, nil) || nav.previewScroll != 3 {
+		t.Errorf("expected offset '3' but got '%d'", nav.previewScroll)
+	}
+	if !nav.scrollPreview(-5, nil) || nav.previewScroll != 0 {
+		t.Errorf("expected offset to be clamped to '0' but got '%d'", nav.previewScroll)
+	}
+	if !nav.scrollPreview(2*gPreviewScrollLimit, nil) || nav.previewScroll != gPreviewScrollLimit {
+		t.Errorf("expected offset to be clamped to '%d' but got '%d'", gPreviewScrollLimit, nav.previewScroll)
+	}
+	nav.previewScroll = 4
+
+	if nav.scrollPreview(1, &reg{path: "/foo", scroll: 4, last: true}) || nav.previewScroll != 4 {
+		t.Errorf("expected scrolling down at the end to be ignored but got '%d'", nav.previewScroll)
+	}
+	if !nav.scrollPreview(1, &reg{path: "/foo", scroll: 4, last: false}) || nav.previewScroll != 5 {
+		t.Errorf("expected offset '5' but got '%d'", nav.previewScroll)
+	}
+	if !nav.scrollPreview(1, &reg{path: "/foo", loading: true, last: true}) || nav.previewScroll != 6 {
+		t.Errorf("expected offset '6' while loading but got '%d'", nav.previewScroll)
+	}
+	if !nav.scrollPreview(1, &reg{path: "/bar", last: true}) || nav.previewScroll != 7 {
+		t.Errorf("expected offset '7' with the preview of another file but got '%d'", nav.previewScroll)
+	}
+	if !nav.scrollPreview(-1, &reg{path: "/foo", scroll: 7, last: true}) || nav.previewScroll != 6 {
+		t.Errorf("expected scrolling up at the end to be allowed but got '%d'", nav.previewScroll)
+	}
+
+	nav.clampPreviewScroll(&reg{path: "/foo", scroll: 2, last: false})
+	if nav.previewScroll != 6 {
+		t.Errorf("expected offset to be kept before the end but got '%d'", nav.previewScroll)
+	}
+	nav.clampPreviewScroll(&reg{path: "/bar", scroll: 2, last: true})
+	if nav.previewScroll != 6 {
+		t.Errorf("expected offset to be kept for the preview of another file but got '%d'", nav.previewScroll)
+	}
+	nav.clampPreviewScroll(&reg{path: "/foo", scroll: 2, last: true})
+	if nav.previewScroll != 2 {
+		t.Errorf("expected offset to be clamped to the end at '2' but got '%d'", nav.previewScroll)
+	}
+
+	nav.syncPreviewScroll("/foo")
+	if nav.previewScroll != 2 {
+		t.Errorf("expected offset to be kept for the same file but got '%d'", nav.previewScroll)
+	}
+	nav.syncPreviewScroll("/bar")
+	if nav.previewScroll != 0 || nav.previewPath != "/bar" {
+		t.Errorf("expected offset to be reset for another file but got '%d' for '%s'", nav.previewScroll, nav.previewPath)
+	}
+}
+
+func TestSelTally(t *testing.T) {
+	var tally selTally
+
+	tests := []struct {
+		op      string
+		path    string
+		size    int64
+		total   int64
+		unknown int
+		exp     string
+	}{
+		{"add", "/a", 900, 900, 0, "1 selected, 900B"},
+		{"add", "/b", 2100, 3000, 0, "2 selected, 3.0K"},
+		{"add", "/dir", -1, 3000, 1, "3 selected, 3.0K+?"},
+		{"add", "/b", 24, 924, 1, "3 selected, 924B+?"},
+		{"remove", "/a", 0, 24, 1, "2 selected, 24B+?"},
+		{"remove", "/a", 0, 24, 1, "2 selected, 24B+?"},
+		{"remove", "/b", 0, 0, 1, "1 selected, ?"},
+		{"add", "/empty", 0, 0, 1, "2 selected, 0B+?"},
+		{"remove", "/dir", 0, 0, 0, "1 selected, 0B"},
+		{"remove", "/empty", 0, 0, 0, "0 selected, ?"},
+	}
+
+	for _, test := range tests {
+		switch test.op {
+		case "add":
+			tally.add(test.path, test.size)
+		case "remove":
+			tally.remove(test.path)
+		}
+		if tally.total != test.total || tally.unknown != test.unknown {
+			t.Errorf("after %s '%s' expected '%d' and '%d' unknown but got '%d' and '%d' unknown",
+				test.op, test.path, test.total, test.unknown, tally.total, tally.unknown)
+		}
+		if got := tally.String(); got != test.exp {
+			t.Errorf("after %s '%s' expected '%s' but got '%s'", test.op, test.path, test.exp, got)
+		}
+	}
+
+	tally.add("/a", 10)
+	tally.reset()
+	if tally.total != 0 || tally.unknown != 0 || len(tally.sizes) != 0 {
+		t.Errorf("expected empty tally after reset but got '%v'", tally)
+	}
+}
+
+func TestSelectionSizes(t *testing.T) {
+	defer func(selectioninfo bool) {
+		gOpts.selectioninfo = selectioninfo
+	}(gOpts.selectioninfo)
+	gOpts.selectioninfo = true
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	foo := filepath.Join(dir, "foo")
+	bar := filepath.Join(dir, "bar")
+	sub := filepath.Join(dir, "sub")
+	if err := ioutil.WriteFile(foo, make([]byte, 100), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+	if err := ioutil.WriteFile(bar, make([]byte, 20), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("creating directory: %s", err)
+	}
+
+	nav := &nav{selections: make(map[string]int)}
+
+	check := func(total int64, unknown int) {
+		t.Helper()
+		if nav.selSizes.total != total || nav.selSizes.unknown != unknown {
+			t.Errorf("expected '%d' and '%d' unknown but got '%d' and '%d' unknown", total, unknown, nav.selSizes.total, nav.selSizes.unknown)
+		}
+	}
+
+	nav.toggleSelection(foo)
+	nav.toggleSelection(bar)
+	nav.toggleSelection(sub)
+	check(120, 1)
+
+	nav.toggleSelection(foo)
+	check(20, 1)
+
+	nav.unselect()
+	check(0, 0)
+
+	if err := nav.reselect(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	check(20, 1)
+
+	if err := os.Remove(bar); err != nil {
+		t.Fatalf("removing file: %s", err)
+	}
+	nav.pruneSelections()
+	check(0, 1)
+
+	gOpts.selectioninfo = false
+	nav.countSelections()
+	nav.toggleSelection(foo)
+	check(0, 0)
+}
+
+func TestReselect(t *testing.T) {
+	nav := &nav{selections: make(map[string]int)}
+
+	if err := nav.reselect(); err == nil {
+		t.Errorf("expected an error without a previous selection")
+	}
+
+	paths := []string{"/foo/a", "/bar/b", "/foo/c"}
+	for _, path := range paths {
+		nav.toggleSelection(path)
+	}
+
+	nav.unselect()
+	if len(nav.selections) != 0 {
+		t.Errorf("expected empty selection but got '%v'", nav.currSelections())
+	}
+
+	nav.unselect()
+
+	if err := nav.reselect(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := nav.currSelections(); !reflect.DeepEqual(got, paths) {
+		t.Errorf("expected '%v' but got '%v'", paths, got)
+	}
+
+	nav.toggleSelection("/baz/d")
+	if got, exp := nav.currSelections(), append(paths, "/baz/d"); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+
+	if err := nav.reselect(); err == nil {
+		t.Errorf("expected an error after restoring the previous selection")
+	}
+
+	nav.unselect()
+	nav.toggleSelection("/qux/e")
+
+	if err := nav.reselect(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, exp := nav.currSelections(), append(paths, "/baz/d"); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+
+	if err := nav.reselect(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got, exp := nav.currSelections(), []string{"/qux/e"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestGridMove(t *testing.T) {
+	defer func(scrolloff int) { gOpts.scrolloff = scrolloff }(gOpts.scrolloff)
+	gOpts.scrolloff = 0
+
+	d := &dir{files: make([]*file, 23)}
+	nav := &nav{dirs: []*dir{d}, height: 3}
+
+	d.setCols(5, nav.height)
+
+	tests := []struct {
+		move func()
+		ind  int
+		pos  int
+	}{
+		{func() { nav.right(1) }, 1, 0},
+		{func() { nav.right(10) }, 4, 0},
+		{func() { nav.down(5) }, 9, 1},
+		{func() { nav.down(5) }, 14, 2},
+		{func() { nav.down(5) }, 19, 2},
+		{func() { nav.down(5) }, 22, 2},
+		{func() { nav.right(1) }, 22, 2},
+		{func() { nav.left(1) }, 21, 2},
+		{func() { nav.left(10) }, 20, 2},
+		{func() { nav.up(5) }, 15, 1},
+		{func() { nav.up(10) }, 5, 0},
+		{func() { nav.up(5) }, 0, 0},
+		{func() { nav.bottom() }, 22, 2},
+		{func() { nav.top() }, 0, 0},
+	}
+
+	for i, test := range tests {
+		test.move()
+		if d.ind != test.ind || d.pos != test.pos {
+			t.Errorf("at move '%d' expected index '%d' and position '%d' but got '%d' and '%d'", i, test.ind, test.pos, d.ind, d.pos)
+		}
+	}
+
+	d.ind, d.pos = 17, 1
+	d.setCols(1, nav.height)
+	if d.ind != 17 || d.pos != 2 {
+		t.Errorf("expected index '17' and position '2' after changing to list but got '%d' and '%d'", d.ind, d.pos)
+	}
+}
+
+func TestPastePaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	src1 := filepath.Join(dir, "src1")
+	src2 := filepath.Join(dir, "src2")
+	dst := filepath.Join(dir, "dst")
+	for _, d := range []string{src1, src2, dst} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(src1, "a"),
+		filepath.Join(src1, "b"),
+		filepath.Join(src2, "b"),
+		filepath.Join(dst, "a"),
+		filepath.Join(dst, "c"),
+		filepath.Join(dst, "c.~1~"),
+	} {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	tests := []struct {
+		srcs []string
+		cp   bool
+		exp  []string
+	}{
+		{
+			[]string{filepath.Join(src1, "b")},
+			false,
+			[]string{filepath.Join(dst, "b")},
+		},
+		{
+			[]string{filepath.Join(src1, "a"), filepath.Join(src1, "b")},
+			true,
+			[]string{filepath.Join(dst, "a.~1~"), filepath.Join(dst, "b")},
+		},
+		{
+			[]string{filepath.Join(src1, "b"), filepath.Join(src2, "b")},
+			false,
+			[]string{filepath.Join(dst, "b"), filepath.Join(dst, "b.~1~")},
+		},
+		{
+			[]string{filepath.Join(dst, "c")},
+			true,
+			[]string{filepath.Join(dst, "c.~2~")},
+		},
+		{
+			[]string{filepath.Join(dst, "c")},
+			false,
+			[]string{filepath.Join(dst, "c")},
+		},
+	}
+
+	for _, test := range tests {
+		if got := pastePaths(test.srcs, dst, test.cp, conflictRename); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.srcs, test.exp, got)
+		}
+	}
+
+	policies := []struct {
+		policy conflictPolicy
+		exp    []string
+	}{
+		{conflictOverwrite, []string{filepath.Join(dst, "a"), filepath.Join(dst, "b")}},
+		{conflictSkip, []string{filepath.Join(dst, "b")}},
+	}
+
+	srcs := []string{filepath.Join(src1, "a"), filepath.Join(src1, "b"), filepath.Join(src2, "b")}
+	for _, test := range policies {
+		if got := pastePaths(srcs, dst, true, test.policy); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at policy '%d' expected '%v' but got '%v'", test.policy, test.exp, got)
+		}
+	}
+
+	nav := &nav{}
+
+	if _, err := nav.followPaths(); err == nil {
+		t.Errorf("expected an error without a paste")
+	}
+
+	nav.pastePaths = pastePaths([]string{filepath.Join(src1, "a"), filepath.Join(src1, "b")}, dst, false, conflictRename)
+	if err := os.Rename(filepath.Join(src1, "a"), nav.pastePaths[0]); err != nil {
+		t.Fatalf("moving file: %s", err)
+	}
+
+	exp := []string{filepath.Join(dst, "a.~1~")}
+	if got, err := nav.followPaths(); err != nil || !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v' with error '%v'", exp, got, err)
+	}
+
+	if err := os.Rename(filepath.Join(src1, "b"), nav.pastePaths[1]); err != nil {
+		t.Fatalf("moving file: %s", err)
+	}
+
+	exp = []string{filepath.Join(dst, "a.~1~"), filepath.Join(dst, "b")}
+	if got, err := nav.followPaths(); err != nil || !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v' with error '%v'", exp, got, err)
+	}
+
+	for _, path := range exp {
+		if err := os.Remove(path); err != nil {
+			t.Fatalf("removing file: %s", err)
+		}
+	}
+
+	if _, err := nav.followPaths(); err == nil {
+		t.Errorf("expected an error after pasted files are removed")
+	}
+}
+
+type fakeFileInfo string
+
+func (f fakeFileInfo) Name() string       { return string(f) }
+func (f fakeFileInfo) Size() int64        { return 0 }
+func (f fakeFileInfo) Mode() os.FileMode  { return 0 }
+func (f fakeFileInfo) ModTime() time.Time { return time.Time{} }
+func (f fakeFileInfo) IsDir() bool        { return false }
+func (f fakeFileInfo) Sys() interface{}   { return nil }
+
+// This function receives the directories loading in the background until none
+// of the cached directories is loading, so that loading goroutines do not read
+// options while the following tests change them. Tests should also call this
+// before changing loaded directories on disk, since a directory changed while
+// it is loading is loaded twice and only the first one is waited for here.
+func waitDirs(nav *nav) {
+	for {
+		loading := false
+		for _, d := range nav.dirCache {
+			loading = loading || d.loading
+		}
+		if !loading {
+			return
+		}
+		d := <-nav.dirChan
+		nav.dirCache[d.path] = d
+		for i := range nav.dirs {
+			if nav.dirs[i].path == d.path {
+				nav.dirs[i] = d
+			}
+		}
+	}
+}
+
+func TestPreviewRequest(t *testing.T) {
+	defer func(previewsearch bool, previewer string) {
+		gOpts.previewsearch = previewsearch
+		gOpts.previewer = previewer
+	}(gOpts.previewsearch, gOpts.previewer)
+	gOpts.previewsearch = true
+	gOpts.previewer = ""
+
+	f, err := ioutil.TempFile("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("foo\nbar\n" + strings.Repeat("baz\n", 10)); err != nil {
+		t.Fatalf("writing temporary file: %s", err)
+	}
+	f.Close()
+
+	nav := &nav{regChan: make(chan *reg, 2), search: "foo"}
+	nav.preview(previewReq{path: f.Name(), search: "bar", scroll: 1}, newWin(20, 5, 0, 0))
+
+	select {
+	case r := <-nav.regChan:
+		if r.path != f.Name() || r.search != "bar" {
+			t.Errorf("expected preview of '%s' with search 'bar' but got '%s' with search '%s'", f.Name(), r.path, r.search)
+		}
+		if r.scroll != 1 || len(r.lines) == 0 || r.lines[0] != "baz" {
+			t.Errorf("expected preview scrolled by '1' line past 'bar' but got '%d' lines '%q'", r.scroll, r.lines)
+		}
+	default:
+		t.Fatalf("expected a preview to be sent")
+	}
+
+	select {
+	case r := <-nav.regChan:
+		t.Errorf("expected a single preview but got another one for '%s'", r.path)
+	default:
+	}
+}
+
+func TestReveal(t *testing.T) {
+	root := os.TempDir()
+
+	var files []*file
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		files = append(files, &file{FileInfo: fakeFileInfo(name)})
+	}
+	d := &dir{path: filepath.Clean(root), files: files}
+	nav := &nav{dirs: []*dir{d}, height: 3}
+
+	tests := []struct {
+		name string
+		ind  int
+		err  bool
+	}{
+		{"g", 6, false},
+		{"a", 0, false},
+		{filepath.Join(root, "e"), 4, false},
+		{filepath.Join("..", filepath.Base(root), "c"), 2, false},
+		{"missing", 2, true},
+		{filepath.Join("sub", "a"), 2, true},
+		{"h", 7, false},
+	}
+
+	for _, test := range tests {
+		err := nav.reveal(test.name)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' expected error '%t' but got '%v'", test.name, test.err, err)
+		}
+		if d.ind != test.ind {
+			t.Errorf("at input '%s' expected index '%d' but got '%d'", test.name, test.ind, d.ind)
+		}
+		if d.pos < 0 || d.pos >= nav.height || d.pos > d.ind {
+			t.Errorf("at input '%s' expected the cursor to be visible but got position '%d'", test.name, d.pos)
+		}
+	}
+
+	// virtual directories of archives are separated with a double slash
+	archive := filepath.Join(root, "foo.zip")
+	ad := &dir{path: archivePath(archive, "sub"), files: files}
+	nav.dirs = []*dir{ad}
+
+	archiveTests := []struct {
+		name string
+		ind  int
+		err  bool
+	}{
+		{"c", 2, false},
+		{archivePath(archive, "sub/f"), 5, false},
+		{"missing", 5, true},
+		{archivePath(archive, "b"), 5, true},
+		{filepath.Join(root, "a"), 5, true},
+	}
+
+	for _, test := range archiveTests {
+		err := nav.reveal(test.name)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' expected error '%t' but got '%v'", test.name, test.err, err)
+		}
+		if ad.ind != test.ind {
+			t.Errorf("at input '%s' expected index '%d' but got '%d'", test.name, test.ind, ad.ind)
+		}
+	}
+}
+
+func TestMoveName(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+
+	tests := []struct {
+		name string
+		to   int
+		exp  []string
+	}{
+		{"a", 0, []string{"a", "b", "c", "d"}},
+		{"c", 0, []string{"c", "a", "b", "d"}},
+		{"a", 3, []string{"b", "c", "d", "a"}},
+		{"b", 2, []string{"a", "c", "b", "d"}},
+		{"c", 1, []string{"a", "c", "b", "d"}},
+		{"a", -1, []string{"a", "b", "c", "d"}},
+		{"d", 4, []string{"a", "b", "c", "d"}},
+		{"b", 10, []string{"a", "c", "d", "b"}},
+	}
+
+	for _, test := range tests {
+		if got := moveName(names, test.name, test.to); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' to '%d' expected '%v' but got '%v'", test.name, test.to, test.exp, got)
+		}
+	}
+	if exp := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected input to be unchanged but got '%v'", names)
+	}
+}
+
+func TestManualSort(t *testing.T) {
+	defer func(sortType sortType, grouporder []string, orders map[string][]string) {
+		gOpts.sortType = sortType
+		gOpts.grouporder = grouporder
+		gManualOrders = orders
+	}(gOpts.sortType, gOpts.grouporder, gManualOrders)
+	gOpts.sortType = sortType{manualSort, hiddenSort}
+	gOpts.grouporder = nil
+	gManualOrders = make(map[string][]string)
+
+	loadDir := func(names ...string) *dir {
+		var files []*file
+		for _, name := range names {
+			files = append(files, &file{FileInfo: fakeFileInfo(name)})
+		}
+		d := &dir{path: "/dir", allFiles: files}
+		d.sort()
+		return d
+	}
+
+	names := func(d *dir) []string {
+		var list []string
+		for _, f := range d.files {
+			list = append(list, f.Name())
+		}
+		return list
+	}
+
+	d := loadDir("c", "a", "b", "d")
+	if exp := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(names(d), exp) {
+		t.Errorf("expected natural order '%v' without a manual order but got '%v'", exp, names(d))
+	}
+
+	nav := &nav{dirs: []*dir{d}, height: 10}
+
+	tests := []struct {
+		ind int
+		to  int
+		exp []string
+	}{
+		{2, 0, []string{"c", "a", "b", "d"}},
+		{1, 2, []string{"c", "b", "a", "d"}},
+		{3, 2, []string{"c", "b", "d", "a"}},
+		{0, 3, []string{"b", "d", "a", "c"}},
+		{0, -1, []string{"b", "d", "a", "c"}},
+	}
+
+	for _, test := range tests {
+		d.ind = test.ind
+		name := d.files[test.ind].Name()
+		if err := nav.moveManual(test.to); err != nil {
+			t.Fatalf("at index '%d' to '%d' unexpected error: %s", test.ind, test.to, err)
+		}
+		if got := names(d); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at index '%d' to '%d' expected '%v' but got '%v'", test.ind, test.to, test.exp, got)
+		}
+		if d.name() != name {
+			t.Errorf("at index '%d' to '%d' expected cursor on '%s' but got '%s'", test.ind, test.to, name, d.name())
+		}
+	}
+
+	// the order is kept when the directory is loaded again and new files are
+	// appended at the end in natural order
+	d = loadDir("f", "a", "b", "c", "d", "e")
+	if exp := []string{"b", "d", "a", "c", "e", "f"}; !reflect.DeepEqual(names(d), exp) {
+		t.Errorf("after reload expected '%v' but got '%v'", exp, names(d))
+	}
+
+	// moving files in reverse order moves them in the order shown
+	gOpts.sortType.option |= reverseSort
+	d = loadDir("a", "b", "c", "d")
+	if exp := []string{"c", "a", "d", "b"}; !reflect.DeepEqual(names(d), exp) {
+		t.Errorf("in reverse expected '%v' but got '%v'", exp, names(d))
+	}
+	nav.dirs = []*dir{d}
+	d.ind = 3
+	if err := nav.moveManual(0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if exp := []string{"b", "c", "a", "d"}; !reflect.DeepEqual(names(d), exp) {
+		t.Errorf("in reverse expected '%v' but got '%v'", exp, names(d))
+	}
+	if exp := []string{"d", "a", "c", "b"}; !reflect.DeepEqual(gManualOrders["/dir"], exp) {
+		t.Errorf("in reverse expected saved order '%v' but got '%v'", exp, gManualOrders["/dir"])
+	}
+
+	gOpts.sortType = sortType{naturalSort, 0}
+	d = loadDir("a", "b")
+	nav.dirs = []*dir{d}
+	if err := nav.moveManual(1); err == nil {
+		t.Errorf("expected an error when sort type is not 'manual'")
+	}
+}
+
+func TestSortPaths(t *testing.T) {
+	nav := &nav{dirCache: make(map[string]*dir)}
+	for path, names := range map[string][]string{
+		"/foo": {"c", "a", "b"},
+		"/bar": {"y", "x"},
+	} {
+		d := &dir{path: path}
+		for _, name := range names {
+			d.files = append(d.files, &file{FileInfo: fakeFileInfo(name)})
+		}
+		nav.dirCache[path] = d
+	}
+
+	tests := []struct {
+		paths []string
+		exp   []string
+	}{
+		{
+			[]string{"/foo/a", "/foo/b", "/foo/c"},
+			[]string{"/foo/c", "/foo/a", "/foo/b"},
+		},
+		{
+			[]string{"/bar/x", "/foo/b", "/bar/y", "/foo/c"},
+			[]string{"/bar/y", "/bar/x", "/foo/c", "/foo/b"},
+		},
+		{
+			[]string{"/baz/2", "/foo/b", "/baz/1", "/foo/z", "/foo/a"},
+			[]string{"/baz/2", "/baz/1", "/foo/a", "/foo/b", "/foo/z"},
+		},
+	}
+
+	for _, test := range tests {
+		if got := nav.sortPaths(test.paths); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.paths, test.exp, got)
+		}
+	}
+}
+
+func TestSeqRenames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"b.jpg", "a.jpg", "c.png", "photo_1.jpg", "other"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	paths := []string{
+		filepath.Join(dir, "b.jpg"),
+		filepath.Join(dir, "photo_1.jpg"),
+		filepath.Join(dir, "c.png"),
+	}
+
+	renames, err := seqRenames(paths, "photo_{n}")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	exp := [][2]string{
+		{filepath.Join(dir, "b.jpg"), filepath.Join(dir, "photo_1.jpg")},
+		{filepath.Join(dir, "photo_1.jpg"), filepath.Join(dir, "photo_2.jpg")},
+		{filepath.Join(dir, "c.png"), filepath.Join(dir, "photo_3.png")},
+	}
+	if !reflect.DeepEqual(renames, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, renames)
+	}
+
+	nav := &nav{renameList: renames}
+	if err := nav.renameBulk(); err != nil {
+		t.Fatalf("renaming files: %s", err)
+	}
+
+	for name, content := range map[string]string{
+		"photo_1.jpg": "b.jpg",
+		"photo_2.jpg": "photo_1.jpg",
+		"photo_3.png": "c.png",
+		"a.jpg":       "a.jpg",
+		"other":       "other",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("reading renamed file: %s", err)
+			continue
+		}
+		if string(b) != content {
+			t.Errorf("at file '%s' expected content '%s' but got '%s'", name, content, b)
+		}
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("reading directory: %s", err)
+	}
+	if len(files) != 5 {
+		t.Errorf("expected '5' files after renaming but got '%d'", len(files))
+	}
+
+	if _, err := seqRenames([]string{filepath.Join(dir, "a.jpg")}, "photo_{n}"); err == nil {
+		t.Errorf("expected an error for renaming to an existing file")
+	}
+
+	if _, err := seqRenames([]string{filepath.Join(dir, "a.jpg")}, "photo"); err == nil {
+		t.Errorf("expected an error for template without a placeholder")
+	}
+}
+
+func TestJumpExt(t *testing.T) {
+	defer func(wrapscroll bool) { gOpts.wrapscroll = wrapscroll }(gOpts.wrapscroll)
+
+	var files []*file
+	for _, name := range []string{"a.jpg", "b.txt", "c", "d.JPG", ".bashrc", "e.jpg", "f", "g.txt"} {
+		files = append(files, &file{FileInfo: fakeFileInfo(name)})
+	}
+
+	d := &dir{files: files}
+	nav := &nav{dirs: []*dir{d}, height: 10}
+
+	tests := []struct {
+		wrapscroll bool
+		ind        int
+		back       bool
+		exp        int
+		ok         bool
+	}{
+		{false, 0, false, 3, true},
+		{false, 3, false, 5, true},
+		{false, 5, false, 5, false},
+		{true, 5, false, 0, true},
+		{false, 5, true, 3, true},
+		{false, 0, true, 0, false},
+		{true, 0, true, 5, true},
+		{false, 1, false, 7, true},
+		{true, 7, false, 1, true},
+		{false, 2, false, 4, true},
+		{false, 4, false, 6, true},
+		{false, 6, true, 4, true},
+	}
+
+	for _, test := range tests {
+		gOpts.wrapscroll = test.wrapscroll
+		d.ind, d.pos = test.ind, test.ind
+		if ok := nav.jumpExt(test.back); ok != test.ok || d.ind != test.exp {
+			t.Errorf("at index '%d' with back '%t' and wrapscroll '%t' expected '%d' and '%t' but got '%d' and '%t'", test.ind, test.back, test.wrapscroll, test.exp, test.ok, d.ind, ok)
+		}
+	}
+
+	single := &dir{files: files[:1]}
+	nav.dirs = []*dir{single}
+	gOpts.wrapscroll = true
+	if nav.jumpExt(false) {
+		t.Errorf("expected no jump in a directory with a single file")
+	}
+}
+
+func TestGlobSelHidden(t *testing.T) {
+	defer func(option sortOption, hiddenfiles []string) {
+		gOpts.sortType.option = option
+		gOpts.hiddenfiles = hiddenfiles
+	}(gOpts.sortType.option, gOpts.hiddenfiles)
+	gOpts.sortType.option &= ^hiddenSort
+	gOpts.hiddenfiles = []string{".*"}
+
+	var files []*file
+	for _, name := range []string{"a.txt", ".b.txt", "c.go", ".d"} {
+		files = append(files, &file{FileInfo: fakeFileInfo(name)})
+	}
+
+	d := &dir{path: "/dir", allFiles: files}
+	d.sort()
+	nav := &nav{dirs: []*dir{d}, selections: make(map[string]int)}
+
+	if err := nav.globSel("*.txt", false, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if exp := []string{"/dir/a.txt"}; !reflect.DeepEqual(nav.currSelections(), exp) {
+		t.Errorf("without hidden flag expected '%v' but got '%v'", exp, nav.currSelections())
+	}
+
+	if err := nav.globSel(".*", false, false); err == nil {
+		t.Errorf("without hidden flag expected hidden files to be not found")
+	}
+
+	if err := nav.globSel("*.txt", false, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if exp := []string{"/dir/a.txt", "/dir/.b.txt"}; !reflect.DeepEqual(nav.currSelections(), exp) {
+		t.Errorf("with hidden flag expected '%v' but got '%v'", exp, nav.currSelections())
+	}
+
+	var names []string
+	for _, f := range d.files {
+		names = append(names, f.Name())
+	}
+	if exp := []string{"a.txt", "c.go"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected hidden files to remain hidden '%v' but got '%v'", exp, names)
+	}
+
+	if err := nav.globSel(".*", true, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if exp := []string{"/dir/a.txt"}; !reflect.DeepEqual(nav.currSelections(), exp) {
+		t.Errorf("with hidden flag expected '%v' after unselecting but got '%v'", exp, nav.currSelections())
+	}
+}
+
+func TestDiskString(t *testing.T) {
+	tests := []struct {
+		d   *disk
+		exp string
+	}{
+		{nil, ""},
+		{&disk{free: 0}, "0B free"},
+		{&disk{free: 999}, "999B free"},
+		{&disk{free: 1500}, "1.5K free"},
+		{&disk{free: 12345678901}, "12G free"},
+		{&disk{free: 12345678901, err: errors.New("failed")}, ""},
+	}
+
+	for _, test := range tests {
+		if got := test.d.String(); got != test.exp {
+			t.Errorf("at input '%v' expected '%s' but got '%s'", test.d, test.exp, got)
+		}
+	}
+}
+
+func TestDiskFree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	nav := &nav{
+		diskChan:  make(chan *disk, 1),
+		diskPaths: make(map[string]string),
+		diskCache: make(map[string]*disk),
+	}
+
+	if d := nav.diskFree(dir); d != nil {
+		t.Errorf("expected unknown free space before loading but got '%v'", d)
+	}
+	nav.checkDisk(<-nav.diskChan)
+
+	if _, err := diskFree(dir); err == nil {
+		d := nav.diskFree(dir)
+		if d == nil || d.String() == "" {
+			t.Errorf("expected free space after loading but got '%v'", d)
+		}
+	}
+
+	missing := filepath.Join(dir, "missing")
+	if d := nav.diskFree(missing); d != nil {
+		t.Errorf("expected unknown free space before loading but got '%v'", d)
+	}
+	d := <-nav.diskChan
+	if d.err == nil {
+		t.Errorf("at missing directory expected an error")
+	}
+	nav.checkDisk(d)
+	if d := nav.diskFree(missing); d.String() != "" {
+		t.Errorf("at missing directory expected no free space but got '%s'", d)
+	}
+}
+
+func TestToggleExecFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("execute bits are not supported on windows")
+	}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := filepath.Join(dir, "script")
+	if err := ioutil.WriteFile(script, nil, 0644); err != nil {
+		t.Fatalf("creating temporary file: %s", err)
+	}
+	if err := os.Chmod(script, 0644); err != nil {
+		t.Fatalf("changing mode: %s", err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	if err := os.Chmod(sub, 0755); err != nil {
+		t.Fatalf("changing mode: %s", err)
+	}
+
+	nav := &nav{}
+
+	changed, skipped, err := nav.toggleExec([]string{script, sub}, false)
+	if err != nil || changed != 1 || skipped != 1 {
+		t.Errorf("expected '1' changed and '1' skipped but got '%d' and '%d' (error: %v)", changed, skipped, err)
+	}
+
+	for _, test := range []struct {
+		path string
+		exp  os.FileMode
+	}{
+		{script, 0744},
+		{sub, 0755 | os.ModeDir},
+	} {
+		if info, err := os.Stat(test.path); err != nil || info.Mode() != test.exp {
+			t.Errorf("at file '%s' expected mode '%v' but got '%v' (error: %v)", test.path, test.exp, info.Mode(), err)
+		}
+	}
+
+	if _, _, err := nav.toggleExec([]string{script}, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if info, _ := os.Stat(script); info.Mode() != 0644 {
+		t.Errorf("expected mode '%v' but got '%v'", os.FileMode(0644), info.Mode())
+	}
+
+	if _, _, err := nav.toggleExec([]string{filepath.Join(dir, "missing")}, false); err == nil {
+		t.Errorf("at missing file expected an error")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	nav := &nav{dirs: []*dir{{path: root}}}
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+	}{
+		{"foo", "foo", false},
+		{"bar/", "bar", true},
+		{"baz/qux/quux.txt", filepath.Join("baz", "qux", "quux.txt"), false},
+		{"a/b/c/", filepath.Join("a", "b", "c"), true},
+		{"bar/foo", filepath.Join("bar", "foo"), false},
+		{filepath.Join(root, "abs") + "/", "abs", true},
+	}
+
+	for _, test := range tests {
+		path, err := nav.create(test.name)
+		if err != nil {
+			t.Errorf("at input '%s' unexpected error: %s", test.name, err)
+			continue
+		}
+		if exp := filepath.Join(root, test.path); path != exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.name, exp, path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Errorf("at input '%s' expected the entry to be created: %s", test.name, err)
+			continue
+		}
+		if info.IsDir() != test.isDir {
+			t.Errorf("at input '%s' expected directory '%t' but got '%t'", test.name, test.isDir, info.IsDir())
+		}
+		if !test.isDir && info.Size() != 0 {
+			t.Errorf("at input '%s' expected an empty file", test.name)
+		}
+	}
+
+	for _, name := range []string{"", "foo", "foo/", "bar/", "baz/qux"} {
+		if _, err := nav.create(name); err == nil {
+			t.Errorf("at input '%s' expected an error", name)
+		}
+	}
+}
+
+func TestSelectTarget(t *testing.T) {
+	tests := []struct {
+		paths   []string
+		expDir  string
+		expName string
+	}{
+		{[]string{"/a/b/c"}, "/a/b", "c"},
+		{[]string{"/a/b/c", "/a/b/d"}, "/a/b", "c"},
+		{[]string{"/a/b/c", "/a/e/f"}, "/a", "b"},
+		{[]string{"/a/b/c/d", "/a/b/e"}, "/a/b", "c"},
+		{[]string{"/a/b/e", "/a/b/c/d"}, "/a/b", "e"},
+		{[]string{"/a/b/c", "/x/y"}, "/", "a"},
+		{[]string{"/a/b", "/a/b/c"}, "/a", "b"},
+		{[]string{"/a"}, "/", "a"},
+	}
+
+	for _, test := range tests {
+		var paths []string
+		for _, path := range test.paths {
+			paths = append(paths, filepath.FromSlash(path))
+		}
+		dir, name := selectTarget(paths)
+		if dir != filepath.FromSlash(test.expDir) || name != test.expName {
+			t.Errorf("at input '%v' expected '%s' and '%s' but got '%s' and '%s'", test.paths, test.expDir, test.expName, dir, name)
+		}
+	}
+}
+
+func TestSelectPaths(t *testing.T) {
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	foo := filepath.Join(root, "foo")
+	bar := filepath.Join(root, "sub", "bar")
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	for _, path := range []string{foo, bar} {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	nav := &nav{selections: map[string]int{"/old": 0}, selectionInd: 1}
+
+	list := nav.selectPaths([]string{foo, filepath.Join(root, "missing"), bar + string(filepath.Separator), foo})
+	if exp := []string{foo, bar, foo}; !reflect.DeepEqual(list, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, list)
+	}
+	if exp := map[string]int{foo: 0, bar: 1}; !reflect.DeepEqual(nav.selections, exp) {
+		t.Errorf("expected exactly the existing files to be selected but got '%v'", nav.selections)
+	}
+	if _, ok := nav.prevSelections["/old"]; !ok {
+		t.Errorf("expected the previous selection to be kept")
+	}
+
+	if list := nav.selectPaths([]string{filepath.Join(root, "missing")}); list != nil {
+		t.Errorf("expected no files but got '%v'", list)
+	}
+	if len(nav.selections) != 2 {
+		t.Errorf("expected the selection to be kept when no file exists but got '%v'", nav.selections)
+	}
+}
+
+func TestErrSummary(t *testing.T) {
+	defer func(errorsummary bool) { gOpts.errorsummary = errorsummary }(gOpts.errorsummary)
+
+	denied := func(path string) error {
+		return &os.PathError{Op: "unlinkat", Path: path, Err: errors.New("permission denied")}
+	}
+	busy := &os.PathError{Op: "unlinkat", Path: "/c", Err: errors.New("device or resource busy")}
+
+	gOpts.errorsummary = false
+	var errs errSummary
+	msgs := []string{errs.add(denied("/a")), errs.add(denied("/b"))}
+	if exp := []string{"[1] unlinkat /a: permission denied", "[2] unlinkat /b: permission denied"}; !reflect.DeepEqual(msgs, exp) {
+		t.Errorf("without errorsummary expected '%v' but got '%v'", exp, msgs)
+	}
+
+	gOpts.errorsummary = true
+	errs = errSummary{}
+	tests := []struct {
+		err error
+		exp string
+	}{
+		{denied("/a"), "[1] unlinkat /a: permission denied"},
+		{denied("/b"), "[2] permission denied (2 files)"},
+		{busy, "[3] permission denied (2 files); unlinkat /c: device or resource busy"},
+		{denied("/d"), "[4] permission denied (3 files); unlinkat /c: device or resource busy"},
+		{errors.New("copy: open /e: no space left on device"), "[5] permission denied (3 files); unlinkat /c: device or resource busy; copy: open /e: no space left on device"},
+		{errors.New("copy: open /f: no space left on device"), "[6] permission denied (3 files); unlinkat /c: device or resource busy; no space left on device (2 files)"},
+		{errors.New("remote failed"), "[7] permission denied (3 files); unlinkat /c: device or resource busy; no space left on device (2 files); remote failed"},
+	}
+
+	for i, test := range tests {
+		if got := errs.add(test.err); got != test.exp {
+			t.Errorf("at error %d '%s' expected '%s' but got '%s'", i+1, test.err, test.exp, got)
+		}
+	}
+
+	if errs.count != len(tests) {
+		t.Errorf("expected '%d' errors but got '%d'", len(tests), errs.count)
+	}
+}
+
+func TestSyncSearch(t *testing.T) {
+	defer func(dirsearch bool) { gOpts.dirsearch = dirsearch }(gOpts.dirsearch)
+
+	a := &dir{path: "/a"}
+	b := &dir{path: "/b"}
+	nav := &nav{dirs: []*dir{a}, searches: make(map[string]string), search: "foo"}
+
+	gOpts.dirsearch = false
+	nav.syncSearch()
+	nav.dirs = []*dir{b}
+	nav.syncSearch()
+	if nav.search != "foo" {
+		t.Errorf("expected the pattern to be kept when the option is disabled but got '%s'", nav.search)
+	}
+
+	gOpts.dirsearch = true
+	nav.syncSearch()
+	if nav.search != "foo" {
+		t.Errorf("expected the pattern to be kept when the option is enabled but got '%s'", nav.search)
+	}
+
+	tests := []struct {
+		dir    *dir
+		search string
+		exp    string
+	}{
+		{a, "bar", ""},
+		{b, "baz", "foo"},
+		{a, "", "bar"},
+		{a, "", "bar"},
+		{b, "", "baz"},
+	}
+
+	for _, test := range tests {
+		nav.dirs = []*dir{test.dir}
+		nav.syncSearch()
+		if nav.search != test.exp {
+			t.Errorf("at directory '%s' expected pattern '%s' but got '%s'", test.dir.path, test.exp, nav.search)
+		}
+		if test.search != "" {
+			nav.search = test.search
+		}
+	}
+
+	nav.clearSearch()
+	nav.dirs = []*dir{a}
+	nav.syncSearch()
+	if nav.search != "bar" {
+		t.Errorf("expected the pattern of other directories to be kept but got '%s'", nav.search)
+	}
+	nav.dirs = []*dir{b}
+	nav.syncSearch()
+	if nav.search != "" {
+		t.Errorf("expected the pattern to be cleared but got '%s'", nav.search)
+	}
+}
+
+func TestGroupOrder(t *testing.T) {
+	defer func(sortType sortType, grouporder, hiddenfiles []string) {
+		gOpts.sortType = sortType
+		gOpts.grouporder = grouporder
+		gOpts.hiddenfiles = hiddenfiles
+	}(gOpts.sortType, gOpts.grouporder, gOpts.hiddenfiles)
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{"b-dir", ".h-dir"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+	}
+	for _, name := range []string{"a-file", ".h-file"} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+	if err := os.Symlink("a-file", filepath.Join(root, "c-link")); err != nil {
+		t.Skipf("creating symbolic link: %s", err)
+	}
+	if err := os.Symlink("b-dir", filepath.Join(root, "d-dlink")); err != nil {
+		t.Fatalf("creating symbolic link: %s", err)
+	}
+
+	gOpts.sortType = sortType{nameSort, dirfirstSort | hiddenSort}
+	gOpts.hiddenfiles = []string{".*"}
+
+	tests := []struct {
+		order []string
+		exp   []string
+	}{
+		{nil, []string{".h-dir", "b-dir", "d-dlink", ".h-file", "a-file", "c-link"}},
+		{[]string{"dir"}, []string{".h-dir", "b-dir", "d-dlink", ".h-file", "a-file", "c-link"}},
+		{[]string{"file"}, []string{".h-file", "a-file", "c-link", ".h-dir", "b-dir", "d-dlink"}},
+		{[]string{"dir", "link", "file"}, []string{".h-dir", "b-dir", "d-dlink", "c-link", ".h-file", "a-file"}},
+		{[]string{"link", "dir", "file"}, []string{"c-link", "d-dlink", ".h-dir", "b-dir", ".h-file", "a-file"}},
+		{[]string{"hidden", "dir", "file"}, []string{".h-dir", ".h-file", "b-dir", "d-dlink", "a-file", "c-link"}},
+		{[]string{"file", "hidden", "dir"}, []string{".h-file", "a-file", "c-link", ".h-dir", "b-dir", "d-dlink"}},
+		{[]string{"link"}, []string{"c-link", "d-dlink", ".h-dir", ".h-file", "a-file", "b-dir"}},
+	}
+
+	for _, test := range tests {
+		gOpts.grouporder = test.order
+		d := newDir(root)
+		d.sort()
+
+		var names []string
+		for _, f := range d.files {
+			names = append(names, f.Name())
+		}
+		if !reflect.DeepEqual(names, test.exp) {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.order, test.exp, names)
+		}
+	}
+}
+
+func TestClearCaches(t *testing.T) {
+	defer func(childCounts map[string]childCount, sortCmds map[string]sortCmdEntry, archives map[string]archiveCacheEntry) {
+		gChildCounts = childCounts
+		gSortCmdCache = sortCmds
+		gArchiveCache = archives
+	}(gChildCounts, gSortCmdCache, gArchiveCache)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting current directory: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := ioutil.WriteFile(filepath.Join(root, "foo"), nil, 0644); err != nil {
+		t.Fatalf("creating temporary file: %s", err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("changing directory: %s", err)
+	}
+
+	nav := newNav(10)
+	defer waitDirs(nav)
+	waitDirs(nav)
+
+	nav.regCache["/foo"] = &reg{path: "/foo"}
+	nav.diskPaths[root] = "key"
+	nav.diskCache["key"] = &disk{path: root, key: "key"}
+	nav.diskPath = root
+	gChildCounts = map[string]childCount{root: {time.Unix(0, 0), 1}}
+	gSortCmdCache = map[string]sortCmdEntry{root: {"sort", time.Unix(0, 0), nil}}
+	gArchiveCache = map[string]archiveCacheEntry{root: {}}
+
+	if err := nav.clearCaches([]string{"previews", "unknown"}); err == nil {
+		t.Errorf("expected an error for an unknown cache")
+	}
+	if len(nav.regCache) != 1 || len(nav.diskCache) != 1 || len(gChildCounts) != 1 || len(gSortCmdCache) != 1 || len(gArchiveCache) != 1 {
+		t.Errorf("expected no cache to be cleared with an unknown cache")
+	}
+
+	if err := nav.clearCaches([]string{"previews", "diskfree"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(nav.regCache) != 0 || len(nav.diskCache) != 0 || len(nav.diskPaths) != 0 || nav.diskPath != "" {
+		t.Errorf("expected previews and free spaces to be cleared")
+	}
+
+	old := nav.dirCache[root]
+	if err := nav.clearCaches(nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d := nav.dirCache[root]; d == nil || d == old || nav.currDir() != d {
+		t.Errorf("expected the current directory to be loaded again")
+	}
+	if len(gChildCounts) != 0 || len(gSortCmdCache) != 0 || len(gArchiveCache) != 0 {
+		t.Errorf("expected the counts, orders, and archive entries to be cleared")
+	}
+}
+
+func TestGitRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{
+		"repo/.git",
+		"repo/src/pkg",
+		"repo/sub/.git",
+		"plain/a/b",
+	} {
+		if err := os.MkdirAll(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatalf("creating directory: %s", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "repo", "worktree", "src"), 0755); err != nil {
+		t.Fatalf("creating directory: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "repo", "worktree", ".git"), []byte("gitdir: ../.git/worktrees/wt\n"), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	tests := []struct {
+		path string
+		exp  string
+	}{
+		{"repo", "repo"},
+		{"repo/src/pkg", "repo"},
+		{"repo/src/pkg/", "repo"},
+		{"repo/sub", "repo/sub"},
+		{"repo/worktree/src", "repo/worktree"},
+	}
+
+	for _, test := range tests {
+		path := filepath.Join(dir, filepath.FromSlash(test.path))
+		exp := filepath.Join(dir, filepath.FromSlash(test.exp))
+		if got, ok := gitRoot(path); !ok || got != exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", path, exp, got)
+		}
+	}
+
+	if root, ok := gitRoot(dir); ok {
+		t.Skipf("temp dir is in a git repository at '%s'", root)
+	}
+	if got, ok := gitRoot(filepath.Join(dir, "plain", "a", "b")); ok {
+		t.Errorf("expected no git root but got '%s'", got)
+	}
+}
+
+func TestFastForward(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links are tested on unix")
+	}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{
+		"chain/proj/src/main.go",
+		"file/only.txt",
+		"hidden/sub/foo",
+		"hidden/.git/HEAD",
+		"mixed/sub/foo",
+		"mixed/bar",
+		"target/src/foo",
+		"target/bar",
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+
+	for _, path := range []string{"empty", "loop", "x", "z", "viaLink"} {
+		if err := os.Mkdir(filepath.Join(dir, path), 0755); err != nil {
+			t.Fatalf("creating directory: %s", err)
+		}
+	}
+
+	for link, target := range map[string]string{
+		"loop/self":     ".",
+		"x/y":           "../z",
+		"z/w":           "../x",
+		"viaLink/inner": "../target",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatalf("creating link: %s", err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		exp  string
+	}{
+		{"chain", "chain/proj/src"},
+		{"chain/proj", "chain/proj/src"},
+		{"chain/proj/src", "chain/proj/src"},
+		{"file", "file"},
+		{"hidden", "hidden"},
+		{"mixed", "mixed"},
+		{"empty", "empty"},
+		{"loop", "loop"},
+		{"x", "x/y"},
+		{"viaLink", "viaLink/inner"},
+		{"missing", "missing"},
+	}
+
+	for _, test := range tests {
+		if got := fastForward(filepath.Join(dir, test.path)); got != filepath.Join(dir, test.exp) {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.path, test.exp, strings.TrimPrefix(got, dir+"/"))
+		}
+	}
+}
+
+func TestDirChanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, s string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(s), 0644); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+
+	tests := []struct {
+		change func()
+		exp    map[string]bool
+	}{
+		{func() {}, map[string]bool{"mtime": false, "count": false, "files": false}},
+		{func() { write("foo", "foo bar") }, map[string]bool{"mtime": false, "count": false, "files": true}},
+		{func() { write("baz", "") }, map[string]bool{"mtime": false, "count": true, "files": true}},
+		{func() { os.Remove(filepath.Join(dir, "foo")) }, map[string]bool{"mtime": false, "count": true, "files": true}},
+	}
+
+	for i, test := range tests {
+		write("foo", "foo")
+		os.Remove(filepath.Join(dir, "baz"))
+
+		d := newDir(dir)
+
+		test.change()
+
+		for check, exp := range test.exp {
+			if got := entriesChanged(dir, d.allFiles, check); got != exp {
+				t.Errorf("at change '%d' with '%s' expected '%t' but got '%t'", i, check, exp, got)
+			}
+		}
+	}
+}
+
+func TestCheckEntries(t *testing.T) {
+	defer func(periodcheck string) { gOpts.periodcheck = periodcheck }(gOpts.periodcheck)
+	gOpts.periodcheck = "count"
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	nav := &nav{dirChan: make(chan *dir, 1)}
+
+	// the load time is moved forward so that only entries are compared
+	d := newDir(root)
+	d.sortType = gOpts.sortType
+	d.hiddenfiles = gOpts.hiddenfiles
+	d.grouporder = gOpts.grouporder
+	d.ignorecase = gOpts.ignorecase
+	d.ignoredia = gOpts.ignoredia
+	d.loadTime = time.Now().Add(time.Hour)
+
+	if err := ioutil.WriteFile(filepath.Join(root, "foo"), nil, 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	gDirCheckMutex.Lock()
+	gDirChecks[root] = true
+	gDirCheckMutex.Unlock()
+
+	nav.checkDir(d)
+	select {
+	case <-nav.dirChan:
+		t.Errorf("expected directory not to be checked again while it is checked")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	gDirCheckMutex.Lock()
+	delete(gDirChecks, root)
+	gDirCheckMutex.Unlock()
+
+	nav.checkDir(d)
+	select {
+	case nd := <-nav.dirChan:
+		if len(nd.allFiles) != 1 {
+			t.Errorf("expected the directory to be loaded again with '1' file but got '%d'", len(nd.allFiles))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the directory to be loaded again")
+	}
+}
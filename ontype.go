@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type typeBranch struct {
+	pattern string
+	cmd     string
+}
+
+// This function parses the branches of 'on-type' command given in the syntax
+// 'pattern=command' where the command is split at the first equal sign.
+func parseTypeBranches(args []string) ([]typeBranch, error) {
+	if len(args) == 0 {
+		return nil, errors.New("requires at least one branch")
+	}
+
+	branches := make([]typeBranch, 0, len(args))
+	for _, arg := range args {
+		ind := strings.Index(arg, "=")
+		if ind <= 0 || ind == len(arg)-1 {
+			return nil, fmt.Errorf("invalid branch: %s", arg)
+		}
+		pattern := arg[:ind]
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %s", pattern)
+		}
+		branches = append(branches, typeBranch{pattern, arg[ind+1:]})
+	}
+
+	return branches, nil
+}
+
+// These are the keys of file types used in 'LS_COLORS' and 'LF_ICONS', which
+// can also be used as patterns of branches to classify files in the same way
+// as colors and icons.
+var gTypeKeys = map[string]bool{
+	"ln": true,
+	"or": true,
+	"tw": true,
+	"ow": true,
+	"st": true,
+	"di": true,
+	"pi": true,
+	"so": true,
+	"bd": true,
+	"cd": true,
+	"su": true,
+	"sg": true,
+	"ex": true,
+}
+
+// This function reports whether the given file matches the given pattern of a
+// branch. Patterns are either one of the type names 'dir', 'file', 'link',
+// 'exec', and 'archive', one of the keys of file types used for colors and
+// icons (e.g. 'ln' or 'ex'), or glob patterns matched with the name of the
+// file. Symbolic links to directories and files are also classified as
+// directories and files since the information of their targets is used.
+func matchType(f *file, pattern string) bool {
+	if gTypeKeys[pattern] {
+		return fileTypeKey(f, true) == pattern
+	}
+
+	switch pattern {
+	case "dir":
+		return f.IsDir()
+	case "file":
+		return f.Mode().IsRegular()
+	case "link":
+		return f.linkState != notLink
+	case "exec":
+		return f.Mode().IsRegular() && f.Mode()&0111 != 0
+	case "archive":
+		_, ok := archiveExt(f.Name())
+		return !f.IsDir() && ok
+	}
+
+	matched, _ := filepath.Match(pattern, f.Name())
+	return matched
+}
+
+// This function returns the command of the first branch matching the given
+// file, or false if there is no matching branch.
+func typeCommand(f *file, branches []typeBranch) (string, bool) {
+	for _, b := range branches {
+		if matchType(f, b.pattern) {
+			return b.cmd, true
+		}
+	}
+	return "", false
+}
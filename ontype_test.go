@@ -0,0 +1,155 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseTypeBranches(t *testing.T) {
+	tests := []struct {
+		args []string
+		exp  []typeBranch
+		err  bool
+	}{
+		{[]string{"dir=open"}, []typeBranch{{"dir", "open"}}, false},
+		{[]string{"dir=open", "*=open"}, []typeBranch{{"dir", "open"}, {"*", "open"}}, false},
+		{[]string{"*.pdf=$zathura \"$f\""}, []typeBranch{{"*.pdf", "$zathura \"$f\""}}, false},
+		{[]string{"archive=set x=y"}, []typeBranch{{"archive", "set x=y"}}, false},
+		{nil, nil, true},
+		{[]string{"open"}, nil, true},
+		{[]string{"=open"}, nil, true},
+		{[]string{"dir="}, nil, true},
+		{[]string{"[=open"}, nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseTypeBranches(test.args)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%v' expected error '%t' but got '%v'", test.args, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.args, test.exp, got)
+		}
+	}
+
+	// branches are kept as single arguments when the map is parsed
+	p := newParser(strings.NewReader(`map <enter> on-type dir=open archive=extract '*.pdf=$zathura "$f"' *=open`))
+	if !p.parse() {
+		t.Fatalf("parsing map: %v", p.err)
+	}
+	call, ok := p.expr.(*mapExpr).expr.(*callExpr)
+	if !ok {
+		t.Fatalf("expected a command but got '%v'", p.expr)
+	}
+	if exp := []string{"dir=open", "archive=extract", `*.pdf=$zathura "$f"`, "*=open"}; !reflect.DeepEqual(call.args, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, call.args)
+	}
+}
+
+func TestTypeCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file types are tested on unix")
+	}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, mode := range map[string]os.FileMode{
+		"notes.txt":   0644,
+		"doc.pdf":     0644,
+		"src.tar.gz":  0644,
+		"BACKUP.ZIP":  0644,
+		"run.sh":      0755,
+		"archive.zip": 0755,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.zip"), 0755); err != nil {
+		t.Fatalf("creating directory: %s", err)
+	}
+	for link, target := range map[string]string{"dlink": "sub.zip", "flink": "notes.txt", "broken": "missing"} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatalf("creating link: %s", err)
+		}
+	}
+
+	files, err := readdir(dir)
+	if err != nil {
+		t.Fatalf("reading directory: %s", err)
+	}
+	byName := make(map[string]*file)
+	for _, f := range files {
+		byName[f.Name()] = f
+	}
+
+	branches := []typeBranch{
+		{"dir", "open"},
+		{"archive", "extract"},
+		{"*.pdf", "$zathura $f"},
+		{"exec", "$$f"},
+		{"link", "follow"},
+		{"*", "$$EDITOR $f"},
+	}
+
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{"sub.zip", "open"},
+		{"dlink", "open"},
+		{"src.tar.gz", "extract"},
+		{"BACKUP.ZIP", "extract"},
+		{"archive.zip", "extract"},
+		{"doc.pdf", "$zathura $f"},
+		{"run.sh", "$$f"},
+		{"flink", "follow"},
+		{"broken", "follow"},
+		{"notes.txt", "$$EDITOR $f"},
+	}
+
+	for _, test := range tests {
+		if got, ok := typeCommand(byName[test.name], branches); !ok || got != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.name, test.exp, got)
+		}
+	}
+
+	// keys of file types are matched in the same way as colors and icons
+	keys := []struct {
+		name string
+		key  string
+	}{
+		{"sub.zip", "di"},
+		{"dlink", "ln"},
+		{"broken", "or"},
+		{"run.sh", "ex"},
+		{"notes.txt", ""},
+	}
+
+	for _, test := range keys {
+		for key := range gTypeKeys {
+			if got := matchType(byName[test.name], key); got != (key == test.key) {
+				t.Errorf("at input '%s' with key '%s' expected '%t' but got '%t'", test.name, key, key == test.key, got)
+			}
+		}
+	}
+
+	// branches are tried in the given order
+	if got, _ := typeCommand(byName["flink"], []typeBranch{{"file", "edit"}, {"link", "follow"}}); got != "edit" {
+		t.Errorf("expected first matching branch 'edit' but got '%s'", got)
+	}
+
+	if got, ok := typeCommand(byName["notes.txt"], []typeBranch{{"dir", "open"}, {"*.pdf", "view"}}); ok {
+		t.Errorf("expected no matching branch but got '%s'", got)
+	}
+}
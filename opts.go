@@ -12,8 +12,14 @@ const (
 	atimeSort
 	ctimeSort
 	extSort
+	manualSort
+	commandSort
 )
 
+// These are the names of sort methods in the order of their values, which is
+// also the order used by 'sort-next' command.
+var gSortMethods = []string{"natural", "name", "size", "time", "atime", "ctime", "ext", "manual", "command"}
+
 type sortOption byte
 
 const (
@@ -27,36 +33,103 @@ type sortType struct {
 	option sortOption
 }
 
+// This function returns the name of the sort method followed by an arrow
+// showing the direction of sorting (e.g. 'name↓' or 'size↑' when reversed).
+func (t sortType) indicator() string {
+	if t.option&reverseSort != 0 {
+		return gSortMethods[t.method] + "↑"
+	}
+	return gSortMethods[t.method] + "↓"
+}
+
 var gOpts struct {
+	altscreen      bool
 	anchorfind     bool
+	archivebrowse  bool
+	breadcrumbs    bool
+	compoundext    bool
 	dircounts      bool
+	diropener      bool
+	dirsearch      bool
+	diskfree       bool
 	drawbox        bool
+	errorsummary   bool
+	fastforward    bool
 	globsearch     bool
+	grid           bool
+	iconalign      bool
 	icons          bool
 	ignorecase     bool
 	ignoredia      bool
 	incsearch      bool
+	infocursor     bool
+	minimal        bool
 	number         bool
 	preview        bool
+	previewsearch  bool
+	previewwrap    bool
+	quitiflast     bool
+	readonly       bool
 	relativenumber bool
+	relativetime   bool
+	rewritelinks   bool
+	scrollbar      bool
+	selectioninfo  bool
 	smartcase      bool
 	smartdia       bool
+	sortindicator  bool
+	templatesubst  bool
+	whichkey       bool
 	wrapscan       bool
 	wrapscroll     bool
+	cmdtimeout     int
 	findlen        int
+	notifytime     int
+	parentwidth    int
 	period         int
+	previewbytes   int
+	previewlines   int
+	previewwidth   int
+	idleperiod     int
+	incsearchdelay int
 	scrolloff      int
 	tabstop        int
+	waittimeout    int
+	whichkeydelay  int
+	boxchars       string
+	boxstyle       string
 	errorfmt       string
+	pasteconflict  string
+	filemanager    string
+	heatmap        string
+	heatmapcolors  string
+	heatmapwindow  string
 	filesep        string
 	ifs            string
+	notify         string
+	periodcheck    string
+	permfmt        string
+	previewansi    string
 	previewer      string
 	cleaner        string
+	cursorline     string
+	cursorinactive string
+	cursorselected string
+	selectedline   string
+	searchmatch    string
+	difftool       string
+	dirlinkicon    string
+	iconset        string
 	promptfmt      string
 	shell          string
+	sortcmd        string
+	templatedir    string
+	terminal       string
 	timefmt        string
 	truncatechar   string
 	ratios         []int
+	badges         []string
+	grouporder     []string
 	hiddenfiles    []string
 	info           []string
 	shellopts      []string
@@ -66,36 +139,145 @@ var gOpts struct {
 	sortType       sortType
 }
 
+// This type keeps the rendering options replaced with 'minimal' option so that
+// they can be restored when it is disabled. Options changed while 'minimal'
+// option is enabled are marked so that they are not restored.
+type minimalOpts struct {
+	icons   bool
+	drawbox bool
+	info    []string
+	changed map[string]bool
+}
+
+var gMinimalSaved minimalOpts
+
+// This function enables or disables 'minimal' option. Icons, file information,
+// and boxes are turned off when it is enabled and their previous values are
+// restored when it is disabled unless they are changed in the meantime.
+// Setting the current value again does nothing so that saved values are not
+// lost.
+func setMinimal(on bool) {
+	if on == gOpts.minimal {
+		return
+	}
+
+	if on {
+		gMinimalSaved = minimalOpts{gOpts.icons, gOpts.drawbox, gOpts.info, make(map[string]bool)}
+		gOpts.icons = false
+		gOpts.drawbox = false
+		gOpts.info = nil
+	} else {
+		if !gMinimalSaved.changed["icons"] {
+			gOpts.icons = gMinimalSaved.icons
+		}
+		if !gMinimalSaved.changed["drawbox"] {
+			gOpts.drawbox = gMinimalSaved.drawbox
+		}
+		if !gMinimalSaved.changed["info"] {
+			gOpts.info = gMinimalSaved.info
+		}
+		gMinimalSaved = minimalOpts{}
+	}
+
+	gOpts.minimal = on
+}
+
+// This function marks the given option replaced with 'minimal' option as
+// changed by the user so that it is kept when 'minimal' option is disabled.
+func minimalChanged(opt string) {
+	if gOpts.minimal {
+		gMinimalSaved.changed[opt] = true
+	}
+}
+
 func init() {
+	gOpts.altscreen = true
 	gOpts.anchorfind = true
+	gOpts.archivebrowse = false
+	gOpts.breadcrumbs = false
+	gOpts.compoundext = false
 	gOpts.dircounts = false
+	gOpts.diropener = false
+	gOpts.dirsearch = false
+	gOpts.diskfree = false
 	gOpts.drawbox = false
+	gOpts.errorsummary = false
+	gOpts.fastforward = false
 	gOpts.globsearch = false
+	gOpts.grid = false
+	gOpts.iconalign = false
 	gOpts.icons = false
 	gOpts.ignorecase = true
 	gOpts.ignoredia = true
 	gOpts.incsearch = false
+	gOpts.infocursor = false
+	gOpts.minimal = false
 	gOpts.number = false
 	gOpts.preview = true
+	gOpts.previewsearch = false
+	gOpts.previewwrap = false
+	gOpts.quitiflast = false
+	gOpts.readonly = false
 	gOpts.relativenumber = false
+	gOpts.relativetime = false
+	gOpts.rewritelinks = false
+	gOpts.scrollbar = false
+	gOpts.selectioninfo = false
 	gOpts.smartcase = true
 	gOpts.smartdia = false
+	gOpts.sortindicator = false
+	gOpts.templatesubst = false
+	gOpts.whichkey = true
 	gOpts.wrapscan = true
 	gOpts.wrapscroll = false
+	gOpts.cmdtimeout = 0
 	gOpts.findlen = 1
+	gOpts.notifytime = 10
+	gOpts.parentwidth = 0
 	gOpts.period = 0
+	gOpts.previewbytes = 0
+	gOpts.previewlines = 0
+	gOpts.previewwidth = 0
+	gOpts.idleperiod = 0
+	gOpts.incsearchdelay = 0
 	gOpts.scrolloff = 0
 	gOpts.tabstop = 8
+	gOpts.waittimeout = 0
+	gOpts.whichkeydelay = 0
+	gOpts.boxchars = "single"
+	gOpts.boxstyle = ""
 	gOpts.errorfmt = "\033[7;31;47m%s\033[0m"
+	gOpts.pasteconflict = "rename"
+	gOpts.filemanager = ""
+	gOpts.heatmap = ""
+	gOpts.heatmapcolors = "#ffd75f:#585858"
+	gOpts.heatmapwindow = "7d"
 	gOpts.filesep = "\n"
 	gOpts.ifs = ""
+	gOpts.notify = ""
+	gOpts.periodcheck = "mtime"
+	gOpts.permfmt = "symbolic"
+	gOpts.previewansi = "render"
 	gOpts.previewer = ""
 	gOpts.cleaner = ""
+	gOpts.cursorline = ""
+	gOpts.cursorinactive = ""
+	gOpts.cursorselected = ""
+	gOpts.selectedline = ""
+	gOpts.searchmatch = "1;4"
+	gOpts.difftool = ""
+	gOpts.dirlinkicon = "link"
+	gOpts.iconset = "basic"
 	gOpts.promptfmt = "\033[32;1m%u@%h\033[0m:\033[34;1m%d\033[0m\033[1m%f\033[0m"
 	gOpts.shell = gDefaultShell
+	gOpts.sortcmd = ""
+	gOpts.templatedir = ""
+	gOpts.terminal = ""
 	gOpts.timefmt = time.ANSIC
 	gOpts.truncatechar = "~"
 	gOpts.ratios = []int{1, 2, 3}
+	gOpts.badges = nil
+	gOpts.grouporder = nil
 	gOpts.hiddenfiles = []string{".*"}
 	gOpts.info = nil
 	gOpts.shellopts = nil
@@ -113,10 +295,14 @@ func init() {
 	gOpts.keys["<c-d>"] = &callExpr{"half-down", nil, 1}
 	gOpts.keys["<c-f>"] = &callExpr{"page-down", nil, 1}
 	gOpts.keys["<pgdn>"] = &callExpr{"page-down", nil, 1}
+	gOpts.keys["<a-k>"] = &callExpr{"preview-scroll-up", nil, 1}
+	gOpts.keys["<a-j>"] = &callExpr{"preview-scroll-down", nil, 1}
+	gOpts.keys["<a-u>"] = &callExpr{"preview-page-up", nil, 1}
+	gOpts.keys["<a-d>"] = &callExpr{"preview-page-down", nil, 1}
 	gOpts.keys["h"] = &callExpr{"updir", nil, 1}
-	gOpts.keys["<left>"] = &callExpr{"updir", nil, 1}
+	gOpts.keys["<left>"] = &callExpr{"left", nil, 1}
 	gOpts.keys["l"] = &callExpr{"open", nil, 1}
-	gOpts.keys["<right>"] = &callExpr{"open", nil, 1}
+	gOpts.keys["<right>"] = &callExpr{"right", nil, 1}
 	gOpts.keys["q"] = &callExpr{"quit", nil, 1}
 	gOpts.keys["gg"] = &callExpr{"top", nil, 1}
 	gOpts.keys["<home>"] = &callExpr{"top", nil, 1}
@@ -148,6 +334,7 @@ func init() {
 	gOpts.keys["'"] = &callExpr{"mark-load", nil, 1}
 	gOpts.keys[`"`] = &callExpr{"mark-remove", nil, 1}
 	gOpts.keys[`r`] = &callExpr{"rename", nil, 1}
+	gOpts.keys["."] = &callExpr{"repeat", nil, 1}
 	gOpts.keys["<c-n>"] = &callExpr{"cmd-history-next", nil, 1}
 	gOpts.keys["<c-p>"] = &callExpr{"cmd-history-prev", nil, 1}
 
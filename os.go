@@ -12,6 +12,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 )
 
 var (
@@ -28,10 +29,14 @@ var (
 )
 
 var (
-	gUser        *user.User
-	gConfigPaths []string
-	gMarksPath   string
-	gHistoryPath string
+	gUser          *user.User
+	gConfigPaths   []string
+	gMarksPath     string
+	gBookmarksPath string
+	gFrecencyPath  string
+	gHistoryPath   string
+	gTrashPath     string
+	gTemplateDir   string
 )
 
 func init() {
@@ -82,8 +87,13 @@ func init() {
 		data = filepath.Join(gUser.HomeDir, ".local", "share")
 	}
 
+	gTemplateDir = filepath.Join(config, "lf", "templates")
+
 	gMarksPath = filepath.Join(data, "lf", "marks")
+	gBookmarksPath = filepath.Join(data, "lf", "bookmarks")
+	gFrecencyPath = filepath.Join(data, "lf", "frecency")
 	gHistoryPath = filepath.Join(data, "lf", "history")
+	gTrashPath = filepath.Join(data, "Trash")
 
 	gDefaultSocketPath = filepath.Join(os.TempDir(), fmt.Sprintf("lf.%s.sock", gUser.Username))
 }
@@ -106,6 +116,15 @@ func pauseCommand() *exec.Cmd {
 	return exec.Command(gOpts.shell, "-c", cmd)
 }
 
+func notifyCommand(title, msg string) *exec.Cmd {
+	if runtime.GOOS == "darwin" {
+		script := fmt.Sprintf("display notification %q with title %q", msg, title)
+		return exec.Command("osascript", "-e", script)
+	}
+
+	return exec.Command("notify-send", title, msg)
+}
+
 func shellCommand(s string, args []string) *exec.Cmd {
 	if len(gOpts.ifs) != 0 {
 		s = fmt.Sprintf("IFS='%s'; %s", gOpts.ifs, s)
@@ -118,6 +137,96 @@ func shellCommand(s string, args []string) *exec.Cmd {
 	return exec.Command(gOpts.shell, args...)
 }
 
+// This function returns the command line running the given diff tool with the
+// two files passed as positional arguments and the output piped into the
+// pager.
+func diffShell(tool string, paths []string) (prefix, s string, args []string) {
+	return "$", tool + ` "$1" "$2" | $PAGER`, paths
+}
+
+// This function returns the shell command to open the given files together in
+// the editor. Paths are passed as separate arguments so they are not
+// interpreted by the shell.
+func editShell(paths []string) (prefix, s string, args []string) {
+	return "$", `$EDITOR "$@"`, paths
+}
+
+// This function returns the command to set the clipboard to its standard input
+// with the given MIME type. 'wl-copy' is used on wayland and 'xclip' is used on
+// X11 since both of them support custom MIME types.
+func clipboardCommand(mime string) (*exec.Cmd, error) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("wl-copy"); err == nil {
+			return exec.Command("wl-copy", "--type", mime), nil
+		}
+	}
+
+	if os.Getenv("DISPLAY") != "" {
+		if _, err := exec.LookPath("xclip"); err == nil {
+			return exec.Command("xclip", "-selection", "clipboard", "-t", mime, "-i"), nil
+		}
+	}
+
+	return nil, fmt.Errorf("wl-copy or xclip is required to set the clipboard")
+}
+
+// This function runs the given command in its own process group and kills the
+// process group when the command does not finish within the given timeout so
+// that child processes started by the shell are also killed. The process group
+// is moved to the foreground of the terminal while it is running so that
+// interactive commands can still read from the terminal.
+func runTimeout(cmd *exec.Cmd, timeout time.Duration) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pgid := cmd.Process.Pid
+
+	defer takeForeground(pgid)()
+
+	// the command may be stopped when it reads the terminal before it is moved
+	// to the foreground so it is continued afterwards
+	syscall.Kill(-pgid, syscall.SIGCONT)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
+			log.Printf("killing process group: %s", err)
+		}
+		<-done
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// This function turns the focus reporting mode (private mode 1004) of the
+// terminal on or off by writing to the controlling terminal directly.
+func setFocusReporting(enable bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		log.Printf("opening terminal: %s", err)
+		return
+	}
+	defer tty.Close()
+
+	seq := "\033[?1004l"
+	if enable {
+		seq = "\033[?1004h"
+	}
+
+	if _, err := tty.WriteString(seq); err != nil {
+		log.Printf("setting focus reporting: %s", err)
+	}
+}
+
 func setDefaults() {
 	gOpts.cmds["open"] = &execExpr{"&", `$OPENER "$f"`}
 	gOpts.keys["e"] = &execExpr{"$", `$EDITOR "$f"`}
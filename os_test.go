@@ -0,0 +1,108 @@
+// +build !windows
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffShell(t *testing.T) {
+	prefix, s, args := diffShell("diff -u", []string{"/foo bar", "/baz"})
+
+	if prefix != "$" {
+		t.Errorf("expected prefix '$' but got '%s'", prefix)
+	}
+
+	if exp := `diff -u "$1" "$2" | $PAGER`; s != exp {
+		t.Errorf("expected '%s' but got '%s'", exp, s)
+	}
+
+	if exp := []string{"/foo bar", "/baz"}; !reflect.DeepEqual(args, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, args)
+	}
+}
+
+func TestEditShell(t *testing.T) {
+	defer func(shell string, shellopts []string, ifs string) {
+		gOpts.shell = shell
+		gOpts.shellopts = shellopts
+		gOpts.ifs = ifs
+	}(gOpts.shell, gOpts.shellopts, gOpts.ifs)
+	gOpts.shell = "sh"
+	gOpts.shellopts = nil
+	gOpts.ifs = ""
+
+	defer os.Setenv("EDITOR", os.Getenv("EDITOR"))
+	os.Setenv("EDITOR", `printf %s\000`)
+
+	paths := []string{
+		"/foo bar",
+		"/baz",
+		`/it's "quoted"`,
+		"/$HOME `pwd` \\n",
+		"/semi;colon&|pipe",
+		"/-dash",
+	}
+
+	prefix, s, args := editShell(paths)
+
+	if prefix != "$" {
+		t.Errorf("expected prefix '$' but got '%s'", prefix)
+	}
+
+	out, err := shellCommand(s, args).Output()
+	if err != nil {
+		t.Fatalf("running shell: %s", err)
+	}
+
+	got := strings.Split(strings.TrimSuffix(string(out), "\000"), "\000")
+	if !reflect.DeepEqual(got, paths) {
+		t.Errorf("expected '%q' but got '%q'", paths, got)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	if err := runTimeout(exec.Command("sh", "-c", "true"), time.Second); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := runTimeout(exec.Command("sh", "-c", "exit 3"), time.Second); err == nil || strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected the exit error but got '%v'", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("creating pipe: %s", err)
+	}
+	defer r.Close()
+
+	// the pipe is closed only when both the shell and its child are killed
+	cmd := exec.Command("sh", "-c", "sleep 10 & sleep 10")
+	cmd.Stdout = w
+	start := time.Now()
+	err = runTimeout(cmd, 100*time.Millisecond)
+	w.Close()
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout error but got '%v'", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected the command to be killed after the timeout but it took '%s'", d)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ioutil.ReadAll(r)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected child processes to be killed")
+	}
+}
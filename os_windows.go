@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 )
 
 var (
@@ -27,10 +28,14 @@ var (
 )
 
 var (
-	gUser        *user.User
-	gConfigPaths []string
-	gMarksPath   string
-	gHistoryPath string
+	gUser          *user.User
+	gConfigPaths   []string
+	gMarksPath     string
+	gBookmarksPath string
+	gFrecencyPath  string
+	gHistoryPath   string
+	gTrashPath     string
+	gTemplateDir   string
 )
 
 func init() {
@@ -67,7 +72,10 @@ func init() {
 	}
 
 	gMarksPath = filepath.Join(data, "lf", "marks")
+	gBookmarksPath = filepath.Join(data, "lf", "bookmarks")
+	gFrecencyPath = filepath.Join(data, "lf", "frecency")
 	gHistoryPath = filepath.Join(data, "lf", "history")
+	gTemplateDir = filepath.Join(data, "lf", "templates")
 }
 
 func detachedCommand(name string, arg ...string) *exec.Cmd {
@@ -80,6 +88,20 @@ func pauseCommand() *exec.Cmd {
 	return exec.Command("cmd", "/c", "pause")
 }
 
+func notifyCommand(title, msg string) *exec.Cmd {
+	quote := func(s string) string { return "'" + strings.Replace(s, "'", "''", -1) + "'" }
+
+	script := fmt.Sprintf(`Add-Type -AssemblyName System.Windows.Forms
+	        $n = New-Object System.Windows.Forms.NotifyIcon
+	        $n.Icon = [System.Drawing.SystemIcons]::Information
+	        $n.Visible = $true
+	        $n.ShowBalloonTip(5000, %s, %s, 'Info')
+	        Start-Sleep -Seconds 5
+	        $n.Dispose()`, quote(title), quote(msg))
+
+	return exec.Command("powershell", "-NoProfile", "-Command", script)
+}
+
 func shellCommand(s string, args []string) *exec.Cmd {
 	args = append([]string{"/c", s}, args...)
 
@@ -88,6 +110,59 @@ func shellCommand(s string, args []string) *exec.Cmd {
 	return exec.Command(gOpts.shell, args...)
 }
 
+// This function returns the command line running the given diff tool on the
+// two files with the output shown in the console while waiting for a key
+// press, since there is no pager to pipe the output into by default.
+func diffShell(tool string, paths []string) (prefix, s string, args []string) {
+	return "!", fmt.Sprintf(`%s "%s" "%s"`, tool, paths[0], paths[1]), nil
+}
+
+// This function returns the shell command to open the given files together in
+// the editor. Arguments are quoted in the command since they can not be
+// referred in the command line with 'cmd'.
+func editShell(paths []string) (prefix, s string, args []string) {
+	s = "%EDITOR%"
+	for _, path := range paths {
+		s += fmt.Sprintf(` "%s"`, path)
+	}
+	return "$", s, nil
+}
+
+// This function runs the given command and kills it when it does not finish
+// within the given timeout. Processes started by the command are not killed
+// since process groups are not available on windows.
+func runTimeout(cmd *exec.Cmd, timeout time.Duration) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		if err := cmd.Process.Kill(); err != nil {
+			log.Printf("killing process: %s", err)
+		}
+		<-done
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// This function is not supported since windows clipboard does not use MIME
+// types for file payloads.
+func clipboardCommand(mime string) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("setting the clipboard with MIME types is not supported on windows")
+}
+
+// This function does nothing since windows console has no focus reporting
+// mode to turn on.
+func setFocusReporting(enable bool) {}
+
 func setDefaults() {
 	gOpts.cmds["open"] = &execExpr{"&", "%OPENER% %f%"}
 	gOpts.keys["e"] = &execExpr{"$", "%EDITOR% %f%"}
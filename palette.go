@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// These are the builtin commands that do nothing useful without arguments, so
+// choosing them in the command palette opens the command line with the name
+// of the command typed instead of running it.
+var gCmdArgs = map[string]bool{
+	"batch":                  true,
+	"bookmark-jump":          true,
+	"bookmark-set":           true,
+	"cd":                     true,
+	"cmd-help":               true,
+	"cmd-timeout":            true,
+	"create":                 true,
+	"echo":                   true,
+	"echoerr":                true,
+	"echomsg":                true,
+	"glob-select":            true,
+	"glob-select-recursive":  true,
+	"glob-unselect":          true,
+	"goto-line":              true,
+	"on-type":                true,
+	"pipe-rename":            true,
+	"print":                  true,
+	"print-reply":            true,
+	"prompt":                 true,
+	"push":                   true,
+	"rename-case":            true,
+	"rename-seq":             true,
+	"reveal":                 true,
+	"select":                 true,
+	"select-age":             true,
+	"select-paths":           true,
+	"select-regex-recursive": true,
+	"select-size":            true,
+	"shell-pick":             true,
+	"source":                 true,
+	"which-key":              true,
+	"workspace":              true,
+	"yank-relative":          true,
+	"z":                      true,
+}
+
+type paletteItem struct {
+	name string
+	desc string
+}
+
+// These are the keywords of expressions other than commands (e.g. 'set'),
+// which can not be run as commands from the command palette.
+var gPaletteKeywords = map[string]bool{
+	"set":  true,
+	"map":  true,
+	"cmap": true,
+	"cmd":  true,
+}
+
+// This function returns the commands listed in the command palette sorted by
+// name. Command line commands are left out since they only work while typing
+// in the command line, except for the ones that are also useful otherwise.
+// User defined commands are shown with their definitions and they replace the
+// builtin commands with the same name.
+func paletteItems() []paletteItem {
+	descs := make(map[string]string)
+
+	for name, desc := range gCmdDescs {
+		if name == "command-palette" || gPaletteKeywords[name] || strings.HasPrefix(name, "cmd-") && !gCmdArgs[name] {
+			continue
+		}
+		descs[name] = desc
+	}
+
+	for name := range gOpts.cmds {
+		s, err := cmdHelp(name)
+		if err != nil {
+			continue
+		}
+		descs[name] = "user: " + strings.TrimPrefix(s, "cmd "+name+" ")
+	}
+
+	items := make([]paletteItem, 0, len(descs))
+	for name, desc := range descs {
+		items = append(items, paletteItem{name, desc})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].name < items[j].name
+	})
+
+	return items
+}
+
+func isWordStart(rs []rune, i int) bool {
+	return i == 0 || rs[i-1] == '-' || rs[i-1] == '_' || unicode.IsSpace(rs[i-1])
+}
+
+// This function reports whether the characters of the given pattern appear in
+// the given string in order and returns a score of the match. Matches at the
+// start of words and consecutive matches are scored higher, and characters
+// skipped between matches lower the score. Case and diacritics are ignored
+// with respect to 'ignorecase', 'smartcase', 'ignoredia', and 'smartdia'
+// options as in 'search' command.
+func fuzzyScore(pattern, s string) (int, bool) {
+	norm := searchNorm(pattern)
+
+	ps := []rune(pattern)
+	rs := []rune(s)
+
+	score := 0
+	prev := -1
+	j := 0
+	for i := 0; i < len(rs) && j < len(ps); i++ {
+		if norm(rs[i]) != norm(ps[j]) {
+			continue
+		}
+
+		score++
+		if isWordStart(rs, i) {
+			score += 8
+		}
+		if prev >= 0 && prev == i-1 {
+			score += 5
+		} else if prev >= 0 {
+			score -= min(i-prev-1, 3)
+		}
+
+		prev = i
+		j++
+	}
+
+	if j < len(ps) {
+		return 0, false
+	}
+
+	return score, true
+}
+
+// This function returns the items matching the given pattern with better
+// matches first. Items are matched by their names and the ones only matching
+// by their descriptions are shown after them. Items are kept in their order
+// when the pattern is empty.
+func filterPalette(items []paletteItem, pattern string) []paletteItem {
+	if pattern == "" {
+		return items
+	}
+
+	type match struct {
+		item   paletteItem
+		byName bool
+		score  int
+	}
+
+	var matches []match
+	for _, item := range items {
+		if score, ok := fuzzyScore(pattern, item.name); ok {
+			matches = append(matches, match{item, true, score})
+		} else if score, ok := fuzzyScore(pattern, item.desc); ok {
+			matches = append(matches, match{item, false, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].byName != matches[j].byName {
+			return matches[i].byName
+		}
+		return matches[i].score > matches[j].score
+	})
+
+	res := make([]paletteItem, len(matches))
+	for i, m := range matches {
+		res[i] = m.item
+	}
+
+	return res
+}
+
+func listPalette(items []paletteItem) *bytes.Buffer {
+	t := new(tabwriter.Writer)
+	b := new(bytes.Buffer)
+
+	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
+	fmt.Fprintln(t, "index\tcommand\tdescription")
+	for i, item := range items {
+		fmt.Fprintf(t, "%d\t%s\t%s\n", i+1, item.name, item.desc)
+	}
+	t.Flush()
+
+	return b
+}
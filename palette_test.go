@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	defer func(ignorecase, smartcase, ignoredia, smartdia bool) {
+		gOpts.ignorecase = ignorecase
+		gOpts.smartcase = smartcase
+		gOpts.ignoredia = ignoredia
+		gOpts.smartdia = smartdia
+	}(gOpts.ignorecase, gOpts.smartcase, gOpts.ignoredia, gOpts.smartdia)
+	gOpts.ignorecase = true
+	gOpts.smartcase = true
+	gOpts.ignoredia = true
+	gOpts.smartdia = false
+
+	tests := []struct {
+		pattern string
+		s       string
+		ok      bool
+	}{
+		{"", "quit", true},
+		{"q", "quit", true},
+		{"slsz", "select-size", true},
+		{"ssz", "select-size", true},
+		{"sz", "select-size", true},
+		{"zs", "select-size", false},
+		{"SEL", "select", false},
+		{"SEL", "SELECT", true},
+		{"sel", "Select", true},
+		{"sel", "sélect", true},
+		{"quitt", "quit", false},
+	}
+
+	for _, test := range tests {
+		if _, ok := fuzzyScore(test.pattern, test.s); ok != test.ok {
+			t.Errorf("at input '%s' and '%s' expected '%t' but got '%t'", test.pattern, test.s, test.ok, ok)
+		}
+	}
+
+	better := []struct {
+		pattern string
+		s1      string
+		s2      string
+	}{
+		{"sz", "size", "select-size"},
+		{"sel", "select", "unselect"},
+		{"gl", "glob-select", "toggle"},
+		{"rn", "rename", "return"},
+	}
+
+	for _, test := range better {
+		s1, ok1 := fuzzyScore(test.pattern, test.s1)
+		s2, ok2 := fuzzyScore(test.pattern, test.s2)
+		if !ok1 || !ok2 || s1 <= s2 {
+			t.Errorf("at input '%s' expected '%s' (%d) to score higher than '%s' (%d)", test.pattern, test.s1, s1, test.s2, s2)
+		}
+	}
+}
+
+func TestPaletteItems(t *testing.T) {
+	defer func(cmds map[string]expr) { gOpts.cmds = cmds }(gOpts.cmds)
+	gOpts.cmds = map[string]expr{
+		"trash": &execExpr{"%", "mv \"$fx\" ~/.trash"},
+		"quit":  &callExpr{"echo", []string{"bye"}, 1},
+	}
+
+	items := paletteItems()
+
+	byName := make(map[string]string)
+	for i, item := range items {
+		if i > 0 && items[i-1].name >= item.name {
+			t.Errorf("expected items to be sorted but got '%s' before '%s'", items[i-1].name, item.name)
+		}
+		byName[item.name] = item.desc
+	}
+
+	tests := []struct {
+		name string
+		exp  string
+		ok   bool
+	}{
+		{"up", gCmdDescs["up"], true},
+		{"cd", gCmdDescs["cd"], true},
+		{"trash", "user: %{{ mv \"$fx\" ~/.trash }}", true},
+		{"quit", "user: " + gOpts.cmds["quit"].String(), true},
+		{"cmd-help", gCmdDescs["cmd-help"], true},
+		{"cmd-enter", "", false},
+		{"command-palette", "", false},
+		{"set", "", false},
+		{"map", "", false},
+		{"cmap", "", false},
+		{"cmd", "", false},
+	}
+
+	for _, test := range tests {
+		desc, ok := byName[test.name]
+		if ok != test.ok {
+			t.Errorf("at input '%s' expected listed '%t' but got '%t'", test.name, test.ok, ok)
+			continue
+		}
+		if desc != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.name, test.exp, desc)
+		}
+	}
+}
+
+func TestFilterPalette(t *testing.T) {
+	defer func(ignorecase, smartcase bool) {
+		gOpts.ignorecase = ignorecase
+		gOpts.smartcase = smartcase
+	}(gOpts.ignorecase, gOpts.smartcase)
+	gOpts.ignorecase = true
+	gOpts.smartcase = true
+
+	items := []paletteItem{
+		{"delete", "remove the current file or selected files"},
+		{"mark-save", "save the current directory as a bookmark"},
+		{"quit", "quit lf and return to the shell"},
+		{"select-age", "select files modified before or after the given threshold"},
+		{"select-size", "select files with sizes above or below the given threshold"},
+		{"unselect", "clear the selection"},
+	}
+
+	tests := []struct {
+		pattern string
+		exp     []string
+	}{
+		{"", []string{"delete", "mark-save", "quit", "select-age", "select-size", "unselect"}},
+		{"sel", []string{"select-age", "select-size", "unselect", "delete", "quit"}},
+		{"ssz", []string{"select-size"}},
+		{"SEL", nil},
+		{"bookmark", []string{"mark-save"}},
+		{"xyz", nil},
+	}
+
+	for _, test := range tests {
+		var got []string
+		for _, item := range filterPalette(items, test.pattern) {
+			got = append(got, item.name)
+		}
+		if strings.Join(got, " ") != strings.Join(test.exp, " ") {
+			t.Errorf("at input '%s' expected '%v' but got '%v'", test.pattern, test.exp, got)
+		}
+	}
+}
@@ -10,7 +10,12 @@ package main
 //          | ExecExpr
 //          | ListExpr
 //
-// SetExpr  = 'set' <opt> <val> ';'
+// SetExpr  = 'set' SetOpt SetRest ';'
+//
+// SetOpt   = <opt> | <opt> <val>
+//
+// SetRest  = Nil
+//          | SetOpt SetRest
 //
 // MapExpr  = 'map' <keys> Expr
 //
@@ -132,6 +137,21 @@ func (e *listExpr) String() string {
 	return buf.String()
 }
 
+// This function checks whether the given option is a boolean option, which
+// does not take a value so that multiple options can be set in a single 'set'
+// command (e.g. 'set hidden preview reverse').
+func isBoolOpt(opt string) bool {
+	if strings.HasSuffix(opt, "!") {
+		return true
+	}
+	for _, word := range gOptWords {
+		if word == opt+"!" || strings.HasPrefix(opt, "no") && word == opt[2:]+"!" {
+			return true
+		}
+	}
+	return false
+}
+
 type parser struct {
 	scanner *scanner
 	expr    expr
@@ -159,23 +179,42 @@ func (p *parser) parseExpr() expr {
 	case tokenIdent:
 		switch s.tok {
 		case "set":
-			var val string
+			var exprs []expr
 
 			s.scan()
-			if s.typ != tokenIdent {
-				p.err = fmt.Errorf("expected identifier: %s", s.tok)
-			}
-			opt := s.tok
+			for {
+				if s.typ != tokenIdent {
+					if len(exprs) == 0 {
+						p.err = fmt.Errorf("expected identifier: %s", s.tok)
+					} else {
+						p.err = fmt.Errorf("expected identifier after '%s': %s", exprs[len(exprs)-1].(*setExpr).opt, s.tok)
+					}
+					break
+				}
+				opt := s.tok
+
+				var val string
 
-			s.scan()
-			if s.typ != tokenSemicolon {
-				val = s.tok
 				s.scan()
+				if s.typ != tokenSemicolon && !isBoolOpt(opt) {
+					val = s.tok
+					s.scan()
+				}
+
+				exprs = append(exprs, &setExpr{opt, val})
+
+				if s.typ == tokenSemicolon || s.typ == tokenEOF {
+					break
+				}
 			}
 
 			s.scan()
 
-			result = &setExpr{opt, val}
+			if len(exprs) == 1 {
+				result = exprs[0]
+			} else {
+				result = &listExpr{exprs, 1}
+			}
 		case "map":
 			var expr expr
 
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This function creates a private temporary directory for the result file and
+// returns the path of the result file in it. The directory is only accessible
+// by the user so that the result file can not be created by others in advance,
+// which would be possible for a predictable path in the shared temporary
+// directory.
+func newResultPath() (string, error) {
+	dir, err := ioutil.TempDir("", fmt.Sprintf("lf.%s.%d.", gUser.Username, gClientID))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "result"), nil
+}
+
+// This function removes the directory created for the given result file.
+func removeResultPath(path string) {
+	if path != "" {
+		os.RemoveAll(filepath.Dir(path))
+	}
+}
+
+// This function reads the paths written by a shell command to the result file
+// exported as 'LF_RESULT' environment variable and removes the file so that
+// the result is only used once. Paths are read one per line and relative paths
+// are resolved with respect to the given directory since the command is run
+// in the current directory.
+func readResult(path, wd string) ([]string, error) {
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no result")
+	}
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+
+	var paths []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		line = replaceTilde(line)
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(wd, line)
+		}
+		paths = append(paths, filepath.Clean(line))
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no result")
+	}
+
+	return paths, nil
+}
+
+// This function returns the expression to apply the given result paths. A
+// single directory is changed into as in 'cd' command, otherwise paths are
+// selected as in 'select-paths' command.
+func resultExpr(paths []string) expr {
+	if len(paths) == 1 {
+		if info, err := os.Stat(paths[0]); err == nil && info.IsDir() {
+			return &callExpr{"cd", paths, 1}
+		}
+	}
+	return &callExpr{"select-paths", paths, 1}
+}
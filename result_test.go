@@ -0,0 +1,164 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestReadResult(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "result")
+
+	tests := []struct {
+		s   string
+		exp []string
+		err bool
+	}{
+		{"/foo\n", []string{"/foo"}, false},
+		{"/foo/../bar\n/baz/\n", []string{"/bar", "/baz"}, false},
+		{"foo\r\n\nbar/baz", []string{filepath.Join(dir, "foo"), filepath.Join(dir, "bar", "baz")}, false},
+		{"~/foo\n", []string{filepath.Join(gUser.HomeDir, "foo")}, false},
+		{"", nil, true},
+		{"\n\n", nil, true},
+	}
+
+	for _, test := range tests {
+		if err := ioutil.WriteFile(path, []byte(test.s), 0644); err != nil {
+			t.Fatalf("writing result: %s", err)
+		}
+
+		got, err := readResult(path, dir)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%q' expected error '%t' but got '%v'", test.s, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' expected '%v' but got '%v'", test.s, test.exp, got)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("at input '%q' expected the result file to be removed", test.s)
+		}
+	}
+
+	if _, err := readResult(path, dir); err == nil {
+		t.Errorf("expected an error for a missing result file")
+	}
+}
+
+func TestResultExpr(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	tests := []struct {
+		paths []string
+		exp   expr
+	}{
+		{[]string{dir}, &callExpr{"cd", []string{dir}, 1}},
+		{[]string{file}, &callExpr{"select-paths", []string{file}, 1}},
+		{[]string{dir, file}, &callExpr{"select-paths", []string{dir, file}, 1}},
+		{[]string{filepath.Join(dir, "missing")}, &callExpr{"select-paths", []string{filepath.Join(dir, "missing")}, 1}},
+	}
+
+	for _, test := range tests {
+		if got := resultExpr(test.paths); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' expected '%v' but got '%v'", test.paths, test.exp, got)
+		}
+	}
+}
+
+func TestResultEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell commands are tested on unix")
+	}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(path string) { gResultPath = path }(gResultPath)
+	gResultPath = filepath.Join(dir, "result")
+
+	defer func(shell string, shellopts []string, ifs string) {
+		gOpts.shell = shell
+		gOpts.shellopts = shellopts
+		gOpts.ifs = ifs
+	}(gOpts.shell, gOpts.shellopts, gOpts.ifs)
+	gOpts.shell = "sh"
+	gOpts.shellopts = nil
+	gOpts.ifs = ""
+
+	defer os.Setenv("LF_RESULT", os.Getenv("LF_RESULT"))
+	exportEnvVars()
+
+	if got := os.Getenv("LF_RESULT"); got != gResultPath {
+		t.Fatalf("expected 'LF_RESULT' to be '%s' but got '%s'", gResultPath, got)
+	}
+
+	cmd := shellCommand(`printf '%s\n' foo "$1" > "$LF_RESULT"`, []string{"/bar baz"})
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("running shell: %s", err)
+	}
+
+	got, err := readResult(gResultPath, dir)
+	if err != nil {
+		t.Fatalf("reading result: %s", err)
+	}
+	if exp := []string{filepath.Join(dir, "foo"), "/bar baz"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+}
+
+func TestNewResultPath(t *testing.T) {
+	path, err := newResultPath()
+	if err != nil {
+		t.Fatalf("creating result directory: %s", err)
+	}
+	defer removeResultPath(path)
+
+	if filepath.Base(path) != "result" {
+		t.Errorf("expected result file in a new directory but got '%s'", path)
+	}
+
+	dir := filepath.Dir(path)
+	s, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("getting result directory info: %s", err)
+	}
+	if runtime.GOOS != "windows" && s.Mode().Perm() != 0700 {
+		t.Errorf("expected result directory to be private but got '%s'", s.Mode().Perm())
+	}
+
+	if other, err := newResultPath(); err != nil || other == path {
+		t.Errorf("expected a different result path than '%s' but got '%s' (error: %v)", path, other, err)
+	} else {
+		removeResultPath(other)
+	}
+
+	removeResultPath(path)
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected result directory to be removed but got error: %v", err)
+	}
+
+	removeResultPath("")
+}
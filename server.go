@@ -7,6 +7,9 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 var (
@@ -17,6 +20,61 @@ var (
 	gListener net.Listener
 )
 
+// These are the pending 'print' requests waiting for replies from clients.
+var (
+	gReplyID    int
+	gReplyList  = make(map[int]chan string)
+	gReplyMutex sync.Mutex
+)
+
+const gReplyTimeout = 5 * time.Second
+
+// This function sends the given 'print' request to the client with the given
+// id and returns the reply of the client.
+func printRequest(id int, s string) (string, error) {
+	c, ok := gConnList[id]
+	if !ok {
+		return "", fmt.Errorf("no such client: %d", id)
+	}
+
+	ch := make(chan string, 1)
+
+	gReplyMutex.Lock()
+	gReplyID++
+	rid := gReplyID
+	gReplyList[rid] = ch
+	gReplyMutex.Unlock()
+
+	defer func() {
+		gReplyMutex.Lock()
+		delete(gReplyList, rid)
+		gReplyMutex.Unlock()
+	}()
+
+	fmt.Fprintf(c, "print-reply %d %s\n", rid, s)
+
+	select {
+	case reply := <-ch:
+		return reply, nil
+	case <-time.After(gReplyTimeout):
+		return "", fmt.Errorf("timed out waiting for client: %d", id)
+	}
+}
+
+// This function returns the command sent to clients for 'batch' remote command
+// to evaluate the given commands together with 'batch' builtin command.
+func batchCommand(cmds []string, errexit bool) string {
+	var b strings.Builder
+	b.WriteString("batch")
+	if errexit {
+		b.WriteString(" -e")
+	}
+	for _, cmd := range cmds {
+		b.WriteString(" " + quoteArg(cmd))
+	}
+	return b.String()
+}
+
 func serve() {
 	f, err := os.Create(gServerLogPath)
 	if err != nil {
@@ -114,6 +172,68 @@ Loop:
 					}
 				}
 			}
+		case "batch":
+			word2, rest2 := splitWord(rest)
+			errexit := word2 == "-e"
+			if errexit {
+				word2, _ = splitWord(rest2)
+			}
+			var cmds []string
+			for s.Scan() && s.Text() != "" {
+				cmds = append(cmds, s.Text())
+			}
+			if len(cmds) == 0 {
+				log.Print("listen: batch: requires at least one command")
+				break
+			}
+			cmd := batchCommand(cmds, errexit)
+			if word2 == "" {
+				for _, c := range gConnList {
+					fmt.Fprintln(c, cmd)
+				}
+				break
+			}
+			id, err := strconv.Atoi(word2)
+			if err != nil {
+				log.Print("listen: batch: client id should be a number")
+				break
+			}
+			if c, ok := gConnList[id]; ok {
+				fmt.Fprintln(c, cmd)
+			}
+		case "print":
+			word2, rest2 := splitWord(rest)
+			id, err := strconv.Atoi(word2)
+			if err != nil {
+				fmt.Fprintln(c, "print: client id should be a number")
+				break
+			}
+			reply, err := printRequest(id, rest2)
+			if err != nil {
+				fmt.Fprintf(c, "print: %s\n", err)
+				break
+			}
+			fmt.Fprintln(c, reply)
+		case "reply":
+			word2, rest2 := splitWord(rest)
+			rid, err := strconv.Atoi(word2)
+			if err != nil {
+				log.Print("listen: reply: reply id should be a number")
+				break
+			}
+			reply, err := strconv.Unquote(rest2)
+			if err != nil {
+				log.Printf("listen: reply: %s", err)
+				break
+			}
+			gReplyMutex.Lock()
+			if ch, ok := gReplyList[rid]; ok {
+				select {
+				case ch <- reply:
+				default:
+				}
+			}
+			gReplyMutex.Unlock()
 		case "quit":
 			gQuitChan <- struct{}{}
 			for _, c := range gConnList {
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBatchCommand(t *testing.T) {
+	tests := []struct {
+		cmds    []string
+		errexit bool
+		exp     string
+	}{
+		{[]string{"set hidden"}, false, `batch "set hidden"`},
+		{[]string{"set hidden", "cd /tmp"}, true, `batch -e "set hidden" "cd /tmp"`},
+		{[]string{`echo "foo"; echo bar`}, false, `batch "echo \"foo\"; echo bar"`},
+	}
+
+	for _, test := range tests {
+		got := batchCommand(test.cmds, test.errexit)
+		if got != test.exp {
+			t.Errorf("at input '%q' expected '%s' but got '%s'", test.cmds, test.exp, got)
+			continue
+		}
+
+		p := newParser(strings.NewReader(got))
+		if !p.parse() {
+			t.Errorf("at input '%q' unable to parse '%s': %v", test.cmds, got, p.err)
+			continue
+		}
+		args := test.cmds
+		if test.errexit {
+			args = append([]string{"-e"}, args...)
+		}
+		e, ok := p.expr.(*callExpr)
+		if !ok || e.name != "batch" || !reflect.DeepEqual(e.args, args) {
+			t.Errorf("at input '%q' expected 'batch' with '%q' but got '%v'", test.cmds, args, p.expr)
+		}
+	}
+}
+
+func TestHandleConnBatch(t *testing.T) {
+	defer func(connList map[int]net.Conn) { gConnList = connList }(gConnList)
+
+	client, clientServer := net.Pipe()
+	defer client.Close()
+	gConnList = map[int]net.Conn{1000: clientServer}
+
+	remote, remoteServer := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		handleConn(remoteServer)
+		clientServer.Close()
+		close(done)
+	}()
+
+	go func() {
+		fmt.Fprint(remote, "batch -e 1000\nset hidden\ncd /tmp\n\nbatch 1000\n\nsend 1000 redraw\n")
+		remote.Close()
+	}()
+
+	var lines []string
+	s := bufio.NewScanner(client)
+	for s.Scan() {
+		lines = append(lines, s.Text())
+	}
+	<-done
+
+	// commands of a batch are sent to the client in a single message so that
+	// they are evaluated with a single redraw, and empty batches are ignored
+	if exp := []string{`batch -e "set hidden" "cd /tmp"`, "redraw"}; !reflect.DeepEqual(lines, exp) {
+		t.Errorf("expected '%q' but got '%q'", exp, lines)
+	}
+}
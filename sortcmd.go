@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type sortCmdEntry struct {
+	cmd     string
+	modTime time.Time
+	order   []string
+}
+
+// These are the orders of files returned by 'sortcmd' option for each
+// directory. Orders are reused as long as the command and the modification
+// time of the directory are the same since the command can be expensive.
+var (
+	gSortCmdCache = make(map[string]sortCmdEntry)
+	gSortCmdMutex sync.Mutex
+)
+
+// This is the time given to 'sortcmd' option to print the order of files. The
+// command is killed when it takes longer and files are kept in natural order
+// instead.
+var gSortCmdTimeout = 2 * time.Second
+
+// This function parses the output of 'sortcmd' option with a file on each
+// line. Empty lines are skipped and only the last element of paths is used so
+// that commands printing paths instead of names also work.
+func parseSortOutput(s string) []string {
+	var names []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		names = append(names, filepath.Base(line))
+	}
+	return names
+}
+
+// This function runs the given command in the given directory with the given
+// names of files on its standard input, one on each line, and returns the
+// names in the order printed by the command. The command is killed when it
+// does not finish within the given timeout.
+func runSortCmd(s, dir string, names []string, timeout time.Duration) ([]string, error) {
+	cmd := shellCommand(s, nil)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(strings.Join(names, "\n") + "\n")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%s: %s", err, msg)
+			}
+			return nil, err
+		}
+	case <-time.After(timeout):
+		if err := cmd.Process.Kill(); err != nil {
+			log.Printf("killing sort command: %s", err)
+		}
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+
+	return parseSortOutput(stdout.String()), nil
+}
+
+// This function returns the order of files in the given directory from the
+// last run of 'sortcmd' option and reports whether the order is loaded for
+// the current command and modification time of the directory. Nil is returned
+// as the order when the command is empty, failed, or timed out so that files
+// are kept in natural order. The command is not run here since sorting is
+// also done on the main goroutine.
+func sortCmdOrder(path string) ([]string, bool) {
+	if gOpts.sortcmd == "" {
+		return nil, true
+	}
+
+	s, err := os.Stat(path)
+	if err != nil {
+		return nil, true
+	}
+
+	gSortCmdMutex.Lock()
+	entry, ok := gSortCmdCache[path]
+	gSortCmdMutex.Unlock()
+
+	if ok && entry.cmd == gOpts.sortcmd && entry.modTime.Equal(s.ModTime()) {
+		return entry.order, true
+	}
+
+	return nil, false
+}
+
+// This function runs 'sortcmd' option for the given directory with the names
+// of the given files in natural order unless the order is already loaded. It
+// is called when directories are loaded in the background. Failures are also
+// cached so that the command is not run again until the command or the
+// directory is changed.
+func loadSortCmd(path string, files []*file) {
+	if _, ok := sortCmdOrder(path); ok {
+		return
+	}
+
+	s, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Name()
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		s1, s2 := normalize(names[i], names[j], gOpts.ignorecase, gOpts.ignoredia)
+		return naturalLess(s1, s2)
+	})
+
+	cmd := gOpts.sortcmd
+	order, err := runSortCmd(cmd, path, names, gSortCmdTimeout)
+	if err != nil {
+		log.Printf("sorting with command: %s", err)
+	}
+
+	gSortCmdMutex.Lock()
+	gSortCmdCache[path] = sortCmdEntry{cmd, s.ModTime(), order}
+	gSortCmdMutex.Unlock()
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseSortOutput(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp []string
+	}{
+		{"", nil},
+		{"b\na\n", []string{"b", "a"}},
+		{"b\r\n\r\na\r\n", []string{"b", "a"}},
+		{"./b\n/dir/a\n", []string{"b", "a"}},
+		{"foo bar\n\n\n", []string{"foo bar"}},
+	}
+
+	for _, test := range tests {
+		if got := parseSortOutput(test.s); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' expected '%v' but got '%v'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestOrderFiles(t *testing.T) {
+	tests := []struct {
+		names []string
+		order []string
+		exp   []string
+	}{
+		{[]string{"a", "b", "c"}, []string{"c", "a", "b"}, []string{"c", "a", "b"}},
+		{[]string{"a", "b", "c"}, []string{"c"}, []string{"c", "a", "b"}},
+		{[]string{"a", "b", "c"}, []string{"b", "x", "c", "a", "y"}, []string{"b", "c", "a"}},
+		{[]string{"a", "b", "c"}, []string{"b", "c", "b", "a"}, []string{"b", "c", "a"}},
+		{[]string{"a", "b", "c"}, []string{}, []string{"a", "b", "c"}},
+		{[]string{"a", "b", "c"}, nil, []string{"a", "b", "c"}},
+		{nil, []string{"a"}, nil},
+	}
+
+	for _, test := range tests {
+		var files []*file
+		for _, name := range test.names {
+			files = append(files, &file{FileInfo: fakeFileInfo(name)})
+		}
+
+		orderFiles(files, test.order)
+
+		var got []string
+		for _, f := range files {
+			got = append(got, f.Name())
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%v' and '%v' expected '%v' but got '%v'", test.names, test.order, test.exp, got)
+		}
+	}
+}
+
+func TestCommandSort(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sort commands are tested with posix shells")
+	}
+
+	defer func(sortType sortType, grouporder []string, sortcmd, shell string, shellopts []string, ifs string, cache map[string]sortCmdEntry, timeout time.Duration) {
+		gOpts.sortType = sortType
+		gOpts.grouporder = grouporder
+		gOpts.sortcmd = sortcmd
+		gOpts.shell = shell
+		gOpts.shellopts = shellopts
+		gOpts.ifs = ifs
+		gSortCmdCache = cache
+		gSortCmdTimeout = timeout
+	}(gOpts.sortType, gOpts.grouporder, gOpts.sortcmd, gOpts.shell, gOpts.shellopts, gOpts.ifs, gSortCmdCache, gSortCmdTimeout)
+	gOpts.sortType = sortType{commandSort, hiddenSort}
+	gOpts.grouporder = nil
+	gOpts.shell = "sh"
+	gOpts.shellopts = nil
+	gOpts.ifs = ""
+	gSortCmdCache = make(map[string]sortCmdEntry)
+	gSortCmdTimeout = 100 * time.Millisecond
+
+	tmp, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	runs := filepath.Join(tmp, "runs")
+	if err := os.Mkdir(filepath.Join(tmp, "sub"), 0755); err != nil {
+		t.Fatalf("creating dir: %s", err)
+	}
+
+	loadDir := func(names ...string) []string {
+		var files []*file
+		for _, name := range names {
+			files = append(files, &file{FileInfo: fakeFileInfo(name)})
+		}
+		d := &dir{path: filepath.Join(tmp, "sub"), allFiles: files}
+
+		// commands are not run while sorting on the main goroutine
+		before, _ := ioutil.ReadFile(runs)
+		d.sort()
+		if after, _ := ioutil.ReadFile(runs); len(after) != len(before) {
+			t.Errorf("at input '%s' expected the command not to be run while sorting", gOpts.sortcmd)
+		}
+
+		loadSortCmd(d.path, files)
+		d.sort()
+		if d.sortcmd != gOpts.sortcmd {
+			t.Errorf("at input '%s' expected the order to be loaded", gOpts.sortcmd)
+		}
+
+		var list []string
+		for _, f := range d.files {
+			list = append(list, f.Name())
+		}
+		return list
+	}
+
+	tests := []struct {
+		cmd   string
+		names []string
+		exp   []string
+		runs  int
+	}{
+		{"sort -r", []string{"a", "c", "b"}, []string{"c", "b", "a"}, 1},
+		{"sort -r", []string{"a", "c", "b"}, []string{"c", "b", "a"}, 1},
+		{"grep b", []string{"a", "c", "b"}, []string{"b", "a", "c"}, 2},
+		{"echo x; echo c; echo c", []string{"a", "c", "b"}, []string{"c", "a", "b"}, 3},
+		{"exit 1", []string{"a", "c", "b"}, []string{"a", "b", "c"}, 4},
+		{"sleep 1; echo c", []string{"a", "c", "b"}, []string{"a", "b", "c"}, 5},
+		{"", []string{"a", "c", "b"}, []string{"a", "b", "c"}, 5},
+	}
+
+	for _, test := range tests {
+		if test.cmd != "" {
+			gOpts.sortcmd = "echo >> '" + runs + "'; " + test.cmd
+		} else {
+			gOpts.sortcmd = ""
+		}
+
+		if got := loadDir(test.names...); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' expected '%v' but got '%v'", test.cmd, test.exp, got)
+		}
+
+		buf, _ := ioutil.ReadFile(runs)
+		if n := len(buf); n != test.runs {
+			t.Errorf("at input '%s' expected command to be run '%d' times but got '%d'", test.cmd, test.runs, n)
+		}
+	}
+}
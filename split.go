@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// This function opens the other pane of split mode in the given directory with
+// an empty selection. The current pane keeps the focus.
+func (nav *nav) openSplit(path string) error {
+	if nav.split != nil {
+		return errors.New("already split")
+	}
+
+	if !isArchivePath(path) {
+		s, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !s.IsDir() {
+			return fmt.Errorf("not a directory: %s", path)
+		}
+	}
+
+	nav.split = &workspace{
+		dir:        path,
+		selections: make(map[string]int),
+	}
+	nav.splitRight = false
+	nav.loadSplit()
+
+	return nil
+}
+
+// This function loads the directory of the other pane of split mode. This is
+// done when the pane is opened or its directory is changed rather than when
+// the pane is drawn, since loading also starts checks for changes. The cursor
+// is placed on the saved file of the pane when the loaded directory is
+// received in the main loop.
+func (nav *nav) loadSplit() {
+	if nav.split == nil {
+		return
+	}
+
+	if _, ok := nav.dirCache[nav.split.dir]; ok {
+		return
+	}
+
+	nav.loadDir(nav.split.dir)
+}
+
+// This function closes the other pane of split mode and keeps the current
+// pane.
+func (nav *nav) closeSplit() error {
+	if nav.split == nil {
+		return errors.New("no split pane")
+	}
+	nav.split = nil
+	return nil
+}
+
+// This function moves the focus to the other pane by swapping the state of
+// the current pane with the saved state of the other pane as in workspaces.
+// The panes stay on their sides of the screen so that only the focus moves.
+// The current pane is kept when the other pane can not be restored.
+func (nav *nav) focusSplit() error {
+	if nav.split == nil {
+		return errors.New("no split pane")
+	}
+
+	other := nav.split
+	curr := nav.saveWorkspace()
+
+	if err := nav.restoreWorkspace(other); err != nil {
+		nav.restoreWorkspace(curr)
+		return err
+	}
+
+	nav.split = curr
+	nav.splitRight = !nav.splitRight
+	nav.loadSplit()
+
+	return nil
+}
+
+// This function returns the directory of the other pane as the destination of
+// copying and moving files between the panes.
+func (nav *nav) splitDest() (string, error) {
+	if nav.split == nil {
+		return "", errors.New("no split pane")
+	}
+	if isArchivePath(nav.split.dir) {
+		return "", errors.New("archives are read-only")
+	}
+	if nav.split.dir == nav.currDir().path {
+		return "", errors.New("both panes are in the same directory")
+	}
+	return nav.split.dir, nil
+}
+
+// This function copies or moves the current file or selected files to the
+// directory of the other pane in the background as in 'paste' function.
+func (nav *nav) splitPaste(ui *ui, cp bool) error {
+	dstDir, err := nav.splitDest()
+	if err != nil {
+		return err
+	}
+
+	srcs, err := nav.currFileOrSelections()
+	if err != nil {
+		return err
+	}
+
+	if cp {
+		go nav.copyAsync(ui, srcs, dstDir, currConflictPolicy())
+	} else {
+		go nav.moveAsync(ui, srcs, dstDir, currConflictPolicy())
+	}
+
+	return nil
+}
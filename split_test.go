@@ -0,0 +1,193 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitFocus(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getting current directory: %s", err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	foo := filepath.Join(root, "foo")
+	bar := filepath.Join(root, "bar")
+	for _, dir := range []string{foo, bar} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+		for _, name := range []string{"a", "b"} {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+				t.Fatalf("creating temporary file: %s", err)
+			}
+		}
+	}
+
+	if err := os.Chdir(foo); err != nil {
+		t.Fatalf("changing directory: %s", err)
+	}
+
+	nav := newNav(10)
+	defer waitDirs(nav)
+	if err := nav.sel(filepath.Join(foo, "b")); err != nil {
+		t.Fatalf("selecting file: %s", err)
+	}
+	nav.toggleSelection(filepath.Join(foo, "a"))
+
+	if err := nav.focusSplit(); err == nil {
+		t.Errorf("expected an error for switching focus without a split pane")
+	}
+	if err := nav.openSplit(filepath.Join(foo, "a")); err == nil {
+		t.Errorf("expected an error for opening a split pane in a file")
+	}
+	if err := nav.openSplit(filepath.Join(root, "missing")); err == nil {
+		t.Errorf("expected an error for opening a split pane in a missing directory")
+	}
+
+	if err := nav.openSplit(bar); err != nil {
+		t.Fatalf("opening split pane: %s", err)
+	}
+	if err := nav.openSplit(bar); err == nil {
+		t.Errorf("expected an error for opening a second split pane")
+	}
+	if nav.currDir().path != foo {
+		t.Errorf("expected the current pane to keep the focus in '%s' but got '%s'", foo, nav.currDir().path)
+	}
+	if _, ok := nav.dirCache[bar]; !ok || nav.splitRight {
+		t.Errorf("expected the other pane to be loaded at the right side")
+	}
+
+	if err := nav.focusSplit(); err != nil {
+		t.Fatalf("switching focus: %s", err)
+	}
+	if nav.currDir().path != bar || nav.split.dir != foo {
+		t.Errorf("expected the focus in '%s' and the other pane in '%s' but got '%s' and '%s'", bar, foo, nav.currDir().path, nav.split.dir)
+	}
+	if !nav.splitRight {
+		t.Errorf("expected the focus to be moved to the right side")
+	}
+	if len(nav.selections) != 0 {
+		t.Errorf("expected the other pane to start with an empty selection but got '%v'", nav.selections)
+	}
+	if cwd, err := os.Getwd(); err != nil || cwd != bar {
+		t.Errorf("expected the working directory '%s' but got '%s'", bar, cwd)
+	}
+
+	nav.toggleSelection(filepath.Join(bar, "b"))
+
+	if err := nav.focusSplit(); err != nil {
+		t.Fatalf("switching focus: %s", err)
+	}
+	if nav.currDir().path != foo || nav.split.dir != bar {
+		t.Errorf("expected the focus in '%s' and the other pane in '%s' but got '%s' and '%s'", foo, bar, nav.currDir().path, nav.split.dir)
+	}
+	if nav.splitRight {
+		t.Errorf("expected the focus to be moved back to the left side")
+	}
+	if curr, err := nav.currFile(); err != nil || curr.Name() != "b" {
+		t.Errorf("expected the current file 'b' to be restored but got '%v' (error: %v)", curr, err)
+	}
+	if exp := map[string]int{filepath.Join(foo, "a"): 0}; !reflect.DeepEqual(nav.selections, exp) {
+		t.Errorf("expected the selection '%v' to be restored but got '%v'", exp, nav.selections)
+	}
+	if exp := map[string]int{filepath.Join(bar, "b"): 0}; !reflect.DeepEqual(nav.split.selections, exp) {
+		t.Errorf("expected the selection '%v' to be kept in the other pane but got '%v'", exp, nav.split.selections)
+	}
+
+	waitDirs(nav)
+	if err := os.RemoveAll(bar); err != nil {
+		t.Fatalf("removing directory: %s", err)
+	}
+	if err := nav.focusSplit(); err == nil {
+		t.Errorf("expected an error for switching focus to a removed directory")
+	}
+	if nav.currDir().path != foo || nav.split.dir != bar {
+		t.Errorf("expected the focus to be kept in '%s' but got '%s'", foo, nav.currDir().path)
+	}
+	if exp := map[string]int{filepath.Join(foo, "a"): 0}; !reflect.DeepEqual(nav.selections, exp) {
+		t.Errorf("expected the selection '%v' to be kept but got '%v'", exp, nav.selections)
+	}
+
+	if nav.splitRight {
+		t.Errorf("expected the focus to be kept at the left side")
+	}
+
+	if err := nav.reloadDirs(); err != nil {
+		t.Fatalf("reloading directories: %s", err)
+	}
+	if _, ok := nav.dirCache[bar]; !ok {
+		t.Errorf("expected the other pane to be loaded again after reloading directories")
+	}
+	waitDirs(nav)
+
+	if err := nav.closeSplit(); err != nil || nav.split != nil {
+		t.Errorf("expected the split pane to be closed but got '%v' (error: %v)", nav.split, err)
+	}
+	if err := nav.closeSplit(); err == nil {
+		t.Errorf("expected an error for closing without a split pane")
+	}
+}
+
+func TestSplitWidths(t *testing.T) {
+	defer func(drawbox bool) { gOpts.drawbox = drawbox }(gOpts.drawbox)
+
+	tests := []struct {
+		wtot    int
+		drawbox bool
+		exp     []int
+	}{
+		{80, false, []int{40, 40}},
+		{81, false, []int{40, 41}},
+		{80, true, []int{40, 39}},
+	}
+
+	for _, test := range tests {
+		gOpts.drawbox = test.drawbox
+		if got := splitWidths(test.wtot); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%d' with drawbox '%t' expected '%v' but got '%v'", test.wtot, test.drawbox, test.exp, got)
+		}
+	}
+}
+
+func TestSplitDest(t *testing.T) {
+	nav := &nav{dirs: []*dir{{path: "/foo"}}}
+
+	if _, err := nav.splitDest(); err == nil {
+		t.Errorf("expected an error without a split pane")
+	}
+
+	tests := []struct {
+		dir string
+		exp string
+		err bool
+	}{
+		{"/bar", "/bar", false},
+		{"/foo/bar", "/foo/bar", false},
+		{"/foo", "", true},
+		{archivePath("/bar.zip", ""), "", true},
+		{archivePath("/bar.zip", "baz"), "", true},
+	}
+
+	for _, test := range tests {
+		nav.split = &workspace{dir: test.dir}
+		got, err := nav.splitDest()
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' expected error '%t' but got '%v'", test.dir, test.err, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.dir, test.exp, got)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"gopkg.in/djherbis/times.v1"
+)
+
+// This is the layout of times shown by 'stat' command, which is given with
+// full precision unlike 'timefmt' option since it is meant for inspection.
+const gStatTimeFmt = "2006-01-02 15:04:05.000000000 -0700"
+
+type statField struct {
+	name  string
+	value string
+}
+
+// This function returns the type of the file of the given mode as shown by
+// 'stat' command.
+func fileTypeName(m os.FileMode) string {
+	switch {
+	case m&os.ModeDir != 0:
+		return "directory"
+	case m&os.ModeSymlink != 0:
+		return "symbolic link"
+	case m&os.ModeNamedPipe != 0:
+		return "fifo"
+	case m&os.ModeSocket != 0:
+		return "socket"
+	case m&os.ModeCharDevice != 0:
+		return "character device"
+	case m&os.ModeDevice != 0:
+		return "block device"
+	}
+	return "regular file"
+}
+
+// This function returns the permissions of the given mode in both symbolic and
+// octal forms (e.g. '-rwsr-xr-x (4755)').
+func statMode(m os.FileMode) string {
+	return fmt.Sprintf("%s (%s)", permSymbolic(m), permOctal(m))
+}
+
+// This function returns the given size in bytes together with its humanized
+// form when it is different (e.g. '2048 (2.0K)').
+func statSize(size int64) string {
+	if size < 1000 {
+		return fmt.Sprintf("%d", size)
+	}
+	return fmt.Sprintf("%d (%s)", size, humanize(size))
+}
+
+// This function returns the metadata of the given file shown by 'stat'
+// command. Symbolic links are not followed and their targets are shown
+// instead. Platform specific fields (e.g. inode or owner) are only included
+// when they are available.
+func statFields(path string) ([]statField, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := []statField{
+		{"path", path},
+		{"type", fileTypeName(info.Mode())},
+		{"size", statSize(info.Size())},
+	}
+
+	fields = append(fields, statSys(info)...)
+
+	fields = append(fields, statField{"mode", statMode(info.Mode())})
+
+	ts := times.Get(info)
+	fields = append(fields, statField{"access", ts.AccessTime().Format(gStatTimeFmt)})
+	fields = append(fields, statField{"modify", ts.ModTime().Format(gStatTimeFmt)})
+	if ts.HasChangeTime() {
+		fields = append(fields, statField{"change", ts.ChangeTime().Format(gStatTimeFmt)})
+	}
+	if ts.HasBirthTime() {
+		fields = append(fields, statField{"birth", ts.BirthTime().Format(gStatTimeFmt)})
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			target += " (broken)"
+		}
+		fields = append(fields, statField{"target", target})
+	}
+
+	return fields, nil
+}
+
+func listStat(fields []statField) *bytes.Buffer {
+	t := new(tabwriter.Writer)
+	b := new(bytes.Buffer)
+
+	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
+	fmt.Fprintln(t, "field\tvalue")
+	for _, f := range fields {
+		fmt.Fprintf(t, "%s\t%s\n", f.name, f.value)
+	}
+	t.Flush()
+
+	return b
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestStatMode(t *testing.T) {
+	tests := []struct {
+		m   os.FileMode
+		exp string
+	}{
+		{0644, "-rw-r--r-- (0644)"},
+		{os.ModeDir | 0755, "drwxr-xr-x (0755)"},
+		{os.ModeSetuid | 0755, "-rwsr-xr-x (4755)"},
+		{os.ModeSetuid | 0644, "-rwSr--r-- (4644)"},
+		{os.ModeSetgid | 0750, "-rwxr-s--- (2750)"},
+		{os.ModeSetgid | 0740, "-rwxr-S--- (2740)"},
+		{os.ModeDir | os.ModeSticky | 0777, "drwxrwxrwt (1777)"},
+		{os.ModeDir | os.ModeSticky | 0776, "drwxrwxrwT (1776)"},
+		{os.ModeSetuid | os.ModeSetgid | os.ModeSticky | 0777, "-rwsrwsrwt (7777)"},
+		{os.ModeSymlink | 0777, "lrwxrwxrwx (0777)"},
+		{os.ModeNamedPipe | 0600, "prw------- (0600)"},
+	}
+
+	for _, test := range tests {
+		if got := statMode(test.m); got != test.exp {
+			t.Errorf("at input '%v' expected '%s' but got '%s'", test.m, test.exp, got)
+		}
+	}
+}
+
+func TestStatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		exp  string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1000 (1.0K)"},
+		{2500000, "2500000 (2.5M)"},
+	}
+
+	for _, test := range tests {
+		if got := statSize(test.size); got != test.exp {
+			t.Errorf("at input '%d' expected '%s' but got '%s'", test.size, test.exp, got)
+		}
+	}
+}
+
+func TestStatFields(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links and special bits are tested on unix")
+	}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(file, []byte("hello"), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+	if err := os.Chmod(file, os.ModeSetuid|0755); err != nil {
+		t.Fatalf("changing mode: %s", err)
+	}
+
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink("foo", link); err != nil {
+		t.Fatalf("creating link: %s", err)
+	}
+	broken := filepath.Join(dir, "broken")
+	if err := os.Symlink("missing", broken); err != nil {
+		t.Fatalf("creating link: %s", err)
+	}
+
+	tests := []struct {
+		path string
+		exp  map[string]string
+	}{
+		{file, map[string]string{
+			"path":  file,
+			"type":  "regular file",
+			"size":  "5",
+			"mode":  "-rwsr-xr-x (4755)",
+			"links": "1",
+		}},
+		{dir, map[string]string{
+			"type": "directory",
+		}},
+		{link, map[string]string{
+			"type":   "symbolic link",
+			"target": "foo",
+		}},
+		{broken, map[string]string{
+			"target": "missing (broken)",
+		}},
+	}
+
+	for _, test := range tests {
+		fields, err := statFields(test.path)
+		if err != nil {
+			t.Fatalf("at input '%s' unexpected error: %s", test.path, err)
+		}
+
+		got := make(map[string]string)
+		for _, f := range fields {
+			got[f.name] = f.value
+		}
+
+		for name, exp := range test.exp {
+			if got[name] != exp {
+				t.Errorf("at input '%s' expected '%s' to be '%s' but got '%s'", test.path, name, exp, got[name])
+			}
+		}
+
+		for _, name := range []string{"inode", "owner", "group", "access", "modify"} {
+			if got[name] == "" {
+				t.Errorf("at input '%s' expected '%s' to be shown", test.path, name)
+			}
+		}
+	}
+
+	if _, err := statFields(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+
+	fields, _ := statFields(file)
+	lines := strings.Split(strings.TrimSpace(listStat(fields).String()), "\n")
+	if len(lines) != len(fields)+1 {
+		t.Fatalf("expected '%d' lines but got '%d'", len(fields)+1, len(lines))
+	}
+
+	// values are aligned with tabs which are expanded when the table is drawn
+	column := func(prefix string) int {
+		col := 0
+		for _, r := range prefix {
+			if r == '\t' {
+				col += gOpts.tabstop - col%gOpts.tabstop
+			} else {
+				col++
+			}
+		}
+		return col
+	}
+
+	exp := column(strings.TrimSuffix(lines[1], fields[0].value))
+	for i, f := range fields {
+		if got := column(strings.TrimSuffix(lines[i+1], f.value)); got != exp {
+			t.Errorf("expected value of '%s' to be aligned at column '%d' but got '%d'", f.name, exp, got)
+		}
+	}
+}
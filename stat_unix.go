@@ -0,0 +1,41 @@
+// +build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// This function returns the platform specific metadata of the given file for
+// 'stat' command, which are the number of allocated blocks, inode number,
+// number of hard links, and owner user and group.
+func statSys(info os.FileInfo) []statField {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	owner := uid
+	if u, err := user.LookupId(uid); err == nil {
+		owner = fmt.Sprintf("%s (%s)", u.Username, uid)
+	}
+
+	gid := strconv.FormatUint(uint64(st.Gid), 10)
+	group := gid
+	if g, err := user.LookupGroupId(gid); err == nil {
+		group = fmt.Sprintf("%s (%s)", g.Name, gid)
+	}
+
+	return []statField{
+		{"blocks", strconv.FormatInt(int64(st.Blocks), 10)},
+		{"inode", strconv.FormatUint(uint64(st.Ino), 10)},
+		{"links", strconv.FormatUint(uint64(st.Nlink), 10)},
+		{"owner", owner},
+		{"group", group},
+	}
+}
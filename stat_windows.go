@@ -0,0 +1,8 @@
+package main
+
+import "os"
+
+// Inodes, links, and owners are not available on windows.
+func statSys(info os.FileInfo) []statField {
+	return nil
+}
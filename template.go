@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// This function returns the directory of templates for 'new-from-template'
+// command, which is 'templatedir' option when it is set.
+func templateDir() string {
+	if gOpts.templatedir != "" {
+		return replaceTilde(gOpts.templatedir)
+	}
+	return gTemplateDir
+}
+
+// This function returns the path of the template with the given name in the
+// given directory. Names leading outside of the directory after cleaning (e.g.
+// '../foo') are rejected since templates are only read from the directory.
+func templatePath(dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	if path == filepath.Clean(dir) || !isSubdir(dir, path) {
+		return "", fmt.Errorf("invalid template name: %s", name)
+	}
+	return path, nil
+}
+
+// This function returns the names of templates in the given directory sorted
+// by name. Templates are regular files and symbolic links to regular files,
+// and hidden files are skipped so that editor backups and similar files are
+// not listed.
+func listTemplates(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(filepath.Join(dir, info.Name())); err != nil {
+				continue
+			}
+		}
+		if info.Mode().IsRegular() {
+			names = append(names, info.Name())
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// This function replaces the placeholders in the contents of a template for
+// the file with the given name created at the given time. Placeholders are
+// '{{name}}' for the name of the file, '{{stem}}' for the name without the
+// extension, '{{date}}' for the date as 'YYYY-MM-DD', and '{{year}}' for the
+// year. Other text in braces is kept as it is.
+func expandTemplate(s, name string, now time.Time) string {
+	return strings.NewReplacer(
+		"{{name}}", name,
+		"{{stem}}", strings.TrimSuffix(name, filepath.Ext(name)),
+		"{{date}}", now.Format("2006-01-02"),
+		"{{year}}", now.Format("2006"),
+	).Replace(s)
+}
+
+// This function creates the file with the given path from the given template
+// with the same permissions. Placeholders in the contents are replaced when
+// subst is true. Existing files are not overwritten.
+func copyTemplate(src, dst string, subst bool, now time.Time) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	buf, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if subst {
+		buf = []byte(expandTemplate(string(buf), filepath.Base(dst), now))
+	}
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(dst)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return nil
+}
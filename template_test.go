@@ -0,0 +1,157 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestListTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"script.sh", "README.md", ".hidden", "note.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatalf("creating dir: %s", err)
+	}
+
+	exp := []string{"README.md", "note.txt", "script.sh"}
+
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("note.txt", filepath.Join(dir, "link")); err != nil {
+			t.Fatalf("creating link: %s", err)
+		}
+		if err := os.Symlink("missing", filepath.Join(dir, "broken")); err != nil {
+			t.Fatalf("creating link: %s", err)
+		}
+		exp = []string{"README.md", "link", "note.txt", "script.sh"}
+	}
+
+	got, err := listTemplates(dir)
+	if err != nil {
+		t.Fatalf("listing templates: %s", err)
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+
+	if _, err := listTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error for a missing directory")
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local)
+
+	tests := []struct {
+		s    string
+		name string
+		exp  string
+	}{
+		{"", "foo.go", ""},
+		{"no placeholders", "foo.go", "no placeholders"},
+		{"// {{name}}", "foo.go", "// foo.go"},
+		{"package {{stem}}", "foo.go", "package foo"},
+		{"{{stem}}", "archive.tar.gz", "archive.tar"},
+		{"{{stem}}", "Makefile", "Makefile"},
+		{"# {{stem}} ({{date}})", "notes.md", "# notes (2021-03-04)"},
+		{"Copyright {{year}}", "LICENSE", "Copyright 2021"},
+		{"{{name}} {{name}}", "a", "a a"},
+		{"{{unknown}} {name} {{ name }}", "a", "{{unknown}} {name} {{ name }}"},
+	}
+
+	for _, test := range tests {
+		if got := expandTemplate(test.s, test.name, now); got != test.exp {
+			t.Errorf("at input '%s' with name '%s' expected '%s' but got '%s'", test.s, test.name, test.exp, got)
+		}
+	}
+}
+
+func TestTemplatePath(t *testing.T) {
+	dir := filepath.Join("/", "templates")
+
+	tests := []struct {
+		name string
+		exp  string
+		err  bool
+	}{
+		{"script.sh", filepath.Join(dir, "script.sh"), false},
+		{"sub/../script.sh", filepath.Join(dir, "script.sh"), false},
+		{"/script.sh", filepath.Join(dir, "script.sh"), false},
+		{"../script.sh", "", true},
+		{"sub/../../script.sh", "", true},
+		{"..", "", true},
+		{".", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		got, err := templatePath(dir, test.name)
+		if (err != nil) != test.err || got != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s' (error: %v)", test.name, test.exp, got, err)
+		}
+	}
+}
+
+func TestCopyTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local)
+
+	src := filepath.Join(dir, "template.sh")
+	if err := ioutil.WriteFile(src, []byte("#!/bin/sh\n# {{name}}\n"), 0755); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	tests := []struct {
+		name  string
+		subst bool
+		exp   string
+	}{
+		{"plain.sh", false, "#!/bin/sh\n# {{name}}\n"},
+		{"subst.sh", true, "#!/bin/sh\n# subst.sh\n"},
+	}
+
+	for _, test := range tests {
+		dst := filepath.Join(dir, test.name)
+		if err := copyTemplate(src, dst, test.subst, now); err != nil {
+			t.Errorf("at input '%s' unexpected error: %s", test.name, err)
+			continue
+		}
+		buf, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("reading file: %s", err)
+		}
+		if string(buf) != test.exp {
+			t.Errorf("at input '%s' expected '%q' but got '%q'", test.name, test.exp, buf)
+		}
+		if runtime.GOOS != "windows" {
+			if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != 0755 {
+				t.Errorf("at input '%s' expected permissions to be kept", test.name)
+			}
+		}
+	}
+
+	dst := filepath.Join(dir, "plain.sh")
+	if err := copyTemplate(src, dst, true, now); err == nil {
+		t.Errorf("expected an error when creating an existing file")
+	}
+	if buf, _ := ioutil.ReadFile(dst); string(buf) != tests[0].exp {
+		t.Errorf("expected existing file not to be overwritten but got '%q'", buf)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// This is the format of deletion dates in trash info files which are in local
+// time without a time zone.
+const gTrashDateFmt = "2006-01-02T15:04:05"
+
+// This type is a trash directory in the freedesktop.org trash specification
+// with 'files' and 'info' subdirectories. Relative paths in trash info files
+// are relative to the top directory of the filesystem the trash belongs to.
+type trashDir struct {
+	path string
+	top  string
+}
+
+type trashItem struct {
+	dir  trashDir
+	name string
+	path string
+	date time.Time
+}
+
+func (item trashItem) filePath() string { return filepath.Join(item.dir.path, "files", item.name) }
+
+func (item trashItem) infoPath() string {
+	return filepath.Join(item.dir.path, "info", item.name+".trashinfo")
+}
+
+// This function parses the contents of a trash info file and returns the
+// original path and the deletion date of the trashed file. Paths are percent
+// encoded in these files and relative paths are resolved against the given
+// top directory. Relative paths leading outside of the top directory (e.g.
+// '../foo') are rejected so that restoring can not write elsewhere.
+func parseTrashInfo(s, top string) (string, time.Time, error) {
+	var path, date string
+	header := false
+
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "["):
+			header = line == "[Trash Info]"
+		case header && strings.HasPrefix(line, "Path="):
+			path = strings.TrimPrefix(line, "Path=")
+		case header && strings.HasPrefix(line, "DeletionDate="):
+			date = strings.TrimPrefix(line, "DeletionDate=")
+		}
+	}
+
+	if path == "" {
+		return "", time.Time{}, errors.New("missing path")
+	}
+
+	path, err := url.PathUnescape(path)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid path: %s", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(top, path)
+		if !isSubdir(top, path) {
+			return "", time.Time{}, fmt.Errorf("path outside of top directory: %s", path)
+		}
+	}
+
+	t, err := time.ParseInLocation(gTrashDateFmt, date, time.Local)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid deletion date: %s", date)
+	}
+
+	return filepath.Clean(path), t, nil
+}
+
+// This function returns the trash directories that exist among the trash in
+// the home directory and the trash directories in the top directories of the
+// given mounted filesystems for the given user id. Both the shared '.Trash'
+// directory, which is only used when it has the sticky bit and is not a
+// symbolic link, and the '.Trash-uid' directory of the user are considered.
+func trashDirs(home string, tops []string, uid int) []trashDir {
+	var dirs []trashDir
+	seen := make(map[string]bool)
+
+	add := func(path, top string) {
+		if seen[path] {
+			return
+		}
+		if info, err := os.Stat(filepath.Join(path, "info")); err != nil || !info.IsDir() {
+			return
+		}
+		seen[path] = true
+		dirs = append(dirs, trashDir{path, top})
+	}
+
+	if home != "" {
+		add(home, "/")
+	}
+
+	for _, top := range tops {
+		shared := filepath.Join(top, ".Trash")
+		if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+			add(filepath.Join(shared, strconv.Itoa(uid)), top)
+		}
+		add(filepath.Join(top, fmt.Sprintf(".Trash-%d", uid)), top)
+	}
+
+	return dirs
+}
+
+// This function returns the items in the given trash directory. Info files
+// that cannot be parsed or without a trashed file are skipped.
+func readTrash(dir trashDir) ([]trashItem, error) {
+	infos, err := ioutil.ReadDir(filepath.Join(dir.path, "info"))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []trashItem
+	for _, info := range infos {
+		name := strings.TrimSuffix(info.Name(), ".trashinfo")
+		if name == info.Name() {
+			continue
+		}
+
+		item := trashItem{dir: dir, name: name}
+
+		buf, err := ioutil.ReadFile(item.infoPath())
+		if err != nil {
+			continue
+		}
+		item.path, item.date, err = parseTrashInfo(string(buf), dir.top)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Lstat(item.filePath()); err != nil {
+			continue
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// This function returns the items in all trash directories with the most
+// recently deleted items first. Filesystems mounted from block devices are
+// searched for trash directories when they can be listed on the platform.
+func listTrash() []trashItem {
+	var tops []string
+	if mounts, err := listMounts(); err == nil {
+		for _, m := range mounts {
+			if m.dir != "" {
+				tops = append(tops, m.dir)
+			}
+		}
+	}
+
+	var items []trashItem
+	for _, dir := range trashDirs(gTrashPath, tops, os.Getuid()) {
+		list, err := readTrash(dir)
+		if err != nil {
+			continue
+		}
+		items = append(items, list...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].date.After(items[j].date)
+	})
+
+	return items
+}
+
+// This function returns the items deleted before the given threshold of age
+// at the given time (e.g. '+30d' for items deleted more than 30 days ago).
+func expiredTrash(items []trashItem, t threshold, now time.Time) []trashItem {
+	var expired []trashItem
+	for _, item := range items {
+		if t.match(int64(now.Sub(item.date) / time.Second)) {
+			expired = append(expired, item)
+		}
+	}
+	return expired
+}
+
+// This function moves the trashed item back to its original path. Missing
+// parent directories are created and existing files are not overwritten.
+func restoreTrash(item trashItem) error {
+	if _, err := os.Lstat(item.path); err == nil {
+		return fmt.Errorf("file exists: %s", item.path)
+	}
+	if err := os.MkdirAll(filepath.Dir(item.path), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(item.filePath(), item.path); err != nil {
+		return err
+	}
+	return os.Remove(item.infoPath())
+}
+
+// This function removes the trashed item permanently. The info file is removed
+// last so that the item is still listed when the file cannot be removed.
+func removeTrash(item trashItem) error {
+	if err := os.RemoveAll(item.filePath()); err != nil {
+		return err
+	}
+	return os.Remove(item.infoPath())
+}
+
+// This function parses the numbers of items chosen in the menu separated with
+// spaces and returns their indices.
+func parseChoices(s string, n int) ([]int, error) {
+	var inds []int
+	for _, tok := range strings.Fields(s) {
+		i, err := strconv.Atoi(tok)
+		if err != nil || i < 1 || i > n {
+			return nil, fmt.Errorf("invalid choice: %s", tok)
+		}
+		inds = append(inds, i-1)
+	}
+	if len(inds) == 0 {
+		return nil, fmt.Errorf("invalid choice: %s", s)
+	}
+	return inds, nil
+}
+
+func listTrashItems(items []trashItem) *bytes.Buffer {
+	t := new(tabwriter.Writer)
+	b := new(bytes.Buffer)
+
+	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
+	fmt.Fprintln(t, "index\tdeleted\tpath")
+	for i, item := range items {
+		fmt.Fprintf(t, "%d\t%s\t%s\n", i+1, item.date.Format("2006-01-02 15:04"), item.path)
+	}
+	t.Flush()
+
+	return b
+}
+
+func emptyTrashAsync(ui *ui, items []trashItem) {
+	removed := 0
+	for _, item := range items {
+		if err := removeTrash(item); err != nil {
+			ui.exprChan <- &callExpr{"echoerr", []string{fmt.Sprintf("trash-empty: %s", err)}, 1}
+			continue
+		}
+		removed++
+	}
+	ui.exprChan <- &callExpr{"echo", []string{fmt.Sprintf("trash-empty: %d items removed", removed)}, 1}
+}
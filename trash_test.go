@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestParseTrashInfo(t *testing.T) {
+	date := time.Date(2004, 8, 31, 22, 32, 8, 0, time.Local)
+
+	tests := []struct {
+		s    string
+		top  string
+		path string
+		date time.Time
+		err  bool
+	}{
+		{"[Trash Info]\nPath=/home/user/foo\nDeletionDate=2004-08-31T22:32:08\n", "/", "/home/user/foo", date, false},
+		{"[Trash Info]\r\nPath=/foo%20bar/%C3%A7\r\nDeletionDate=2004-08-31T22:32:08\r\n", "/", "/foo bar/ç", date, false},
+		{"[Trash Info]\nPath=photos/a.jpg\nDeletionDate=2004-08-31T22:32:08\n", "/media/usb", "/media/usb/photos/a.jpg", date, false},
+		{"# comment\n\n[Trash Info]\nDeletionDate=2004-08-31T22:32:08\nPath=/foo/../bar\n", "/", "/bar", date, false},
+		{"[Other]\nPath=/foo\n[Trash Info]\nPath=/bar\nDeletionDate=2004-08-31T22:32:08\n", "/", "/bar", date, false},
+		{"[Other]\nPath=/foo\nDeletionDate=2004-08-31T22:32:08\n", "/", "", time.Time{}, true},
+		{"[Trash Info]\nDeletionDate=2004-08-31T22:32:08\n", "/", "", time.Time{}, true},
+		{"[Trash Info]\nPath=/foo\n", "/", "", time.Time{}, true},
+		{"[Trash Info]\nPath=/foo\nDeletionDate=yesterday\n", "/", "", time.Time{}, true},
+		{"[Trash Info]\nPath=/foo%zz\nDeletionDate=2004-08-31T22:32:08\n", "/", "", time.Time{}, true},
+		{"[Trash Info]\nPath=photos/../a.jpg\nDeletionDate=2004-08-31T22:32:08\n", "/media/usb", "/media/usb/a.jpg", date, false},
+		{"[Trash Info]\nPath=../../etc/passwd\nDeletionDate=2004-08-31T22:32:08\n", "/media/usb", "", time.Time{}, true},
+		{"[Trash Info]\nPath=photos/%2E%2E/%2E%2E/etc\nDeletionDate=2004-08-31T22:32:08\n", "/media/usb", "", time.Time{}, true},
+		{"[Trash Info]\nPath=..\nDeletionDate=2004-08-31T22:32:08\n", "/media/usb", "", time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		path, date, err := parseTrashInfo(test.s, test.top)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%q' expected error '%t' but got '%v'", test.s, test.err, err)
+			continue
+		}
+		if path != test.path || !date.Equal(test.date) {
+			t.Errorf("at input '%q' expected '%s' and '%v' but got '%s' and '%v'", test.s, test.path, test.date, path, date)
+		}
+	}
+}
+
+func TestExpiredTrash(t *testing.T) {
+	now := time.Date(2020, 1, 31, 12, 0, 0, 0, time.Local)
+
+	var items []trashItem
+	for _, days := range []int{0, 1, 7, 30, 31, 365} {
+		items = append(items, trashItem{
+			name: fmt.Sprintf("%dd", days),
+			date: now.Add(-time.Duration(days) * 24 * time.Hour),
+		})
+	}
+
+	tests := []struct {
+		s   string
+		exp []string
+	}{
+		{"+30d", []string{"31d", "365d"}},
+		{"+30", []string{"31d", "365d"}},
+		{"+1w", []string{"30d", "31d", "365d"}},
+		{"+0", []string{"1d", "7d", "30d", "31d", "365d"}},
+		{"-2d", []string{"0d", "1d"}},
+		{"+400d", nil},
+	}
+
+	for _, test := range tests {
+		th, err := parseAgeThreshold(test.s)
+		if err != nil {
+			t.Fatalf("at input '%s' unexpected error: %s", test.s, err)
+		}
+
+		var got []string
+		for _, item := range expiredTrash(items, th, now) {
+			got = append(got, item.name)
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' expected '%v' but got '%v'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestParseChoices(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp []int
+		err bool
+	}{
+		{"1", []int{0}, false},
+		{"3 1", []int{2, 0}, false},
+		{"  2  ", []int{1}, false},
+		{"", nil, true},
+		{"0", nil, true},
+		{"4", nil, true},
+		{"1 foo", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := parseChoices(test.s, 3)
+		if (err != nil) != test.err {
+			t.Errorf("at input '%s' expected error '%t' but got '%v'", test.s, test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' expected '%v' but got '%v'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestTrash(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("trash directories are tested on unix")
+	}
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	home := filepath.Join(dir, "home", "Trash")
+	mount := filepath.Join(dir, "mnt")
+	shared := filepath.Join(dir, "shared")
+
+	for _, path := range []string{
+		filepath.Join(home, "files"),
+		filepath.Join(home, "info"),
+		filepath.Join(mount, ".Trash-1000", "files"),
+		filepath.Join(mount, ".Trash-1000", "info"),
+		filepath.Join(shared, ".Trash", "1000", "info"),
+	} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("creating dir: %s", err)
+		}
+	}
+
+	// shared trash directories are only used with the sticky bit
+	dirs := trashDirs(home, []string{mount, shared, filepath.Join(dir, "missing")}, 1000)
+	exp := []trashDir{{home, "/"}, {filepath.Join(mount, ".Trash-1000"), mount}}
+	if !reflect.DeepEqual(dirs, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, dirs)
+	}
+
+	if err := os.Chmod(filepath.Join(shared, ".Trash"), os.ModeSticky|0777); err != nil {
+		t.Fatalf("changing mode: %s", err)
+	}
+	dirs = trashDirs(home, []string{mount, shared}, 1000)
+	if len(dirs) != 3 || dirs[2].path != filepath.Join(shared, ".Trash", "1000") {
+		t.Errorf("expected shared trash directory to be used but got '%v'", dirs)
+	}
+
+	write := func(path, s string) {
+		if err := ioutil.WriteFile(path, []byte(s), 0644); err != nil {
+			t.Fatalf("writing file: %s", err)
+		}
+	}
+
+	orig := filepath.Join(dir, "restored", "foo")
+	write(filepath.Join(home, "files", "foo"), "foo")
+	write(filepath.Join(home, "info", "foo.trashinfo"), "[Trash Info]\nPath="+orig+"\nDeletionDate=2020-01-02T03:04:05\n")
+	write(filepath.Join(home, "info", "orphan.trashinfo"), "[Trash Info]\nPath=/orphan\nDeletionDate=2020-01-02T03:04:05\n")
+	write(filepath.Join(home, "info", "README"), "not an info file")
+	write(filepath.Join(mount, ".Trash-1000", "files", "bar"), "bar")
+	write(filepath.Join(mount, ".Trash-1000", "info", "bar.trashinfo"), "[Trash Info]\nPath=bar\nDeletionDate=2020-01-02T03:04:05\n")
+
+	items, err := readTrash(dirs[0])
+	if err != nil {
+		t.Fatalf("reading trash: %s", err)
+	}
+	if len(items) != 1 || items[0].name != "foo" || items[0].path != orig {
+		t.Fatalf("expected only 'foo' to be listed but got '%v'", items)
+	}
+
+	if err := restoreTrash(items[0]); err != nil {
+		t.Fatalf("restoring item: %s", err)
+	}
+	if buf, err := ioutil.ReadFile(orig); err != nil || string(buf) != "foo" {
+		t.Errorf("expected file to be restored but got '%s' (%v)", buf, err)
+	}
+	if _, err := os.Lstat(items[0].infoPath()); !os.IsNotExist(err) {
+		t.Errorf("expected info file to be removed")
+	}
+
+	items, err = readTrash(dirs[1])
+	if err != nil {
+		t.Fatalf("reading trash: %s", err)
+	}
+	if len(items) != 1 || items[0].path != filepath.Join(mount, "bar") {
+		t.Fatalf("expected 'bar' to be listed relative to the mount but got '%v'", items)
+	}
+
+	write(filepath.Join(mount, "bar"), "existing")
+	if err := restoreTrash(items[0]); err == nil {
+		t.Errorf("expected an error when restoring over an existing file")
+	}
+
+	if err := removeTrash(items[0]); err != nil {
+		t.Fatalf("removing item: %s", err)
+	}
+	for _, path := range []string{items[0].filePath(), items[0].infoPath()} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("expected '%s' to be removed", path)
+		}
+	}
+}
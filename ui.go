@@ -151,6 +151,134 @@ func init() {
 	}
 }
 
+// Modifier prefixes of key names in the order they are shown.
+var gKeyMods = []struct {
+	prefix string
+	mod    tcell.ModMask
+}{
+	{"a-", tcell.ModAlt},
+	{"c-", tcell.ModCtrl},
+	{"s-", tcell.ModShift},
+}
+
+// This function returns whether the given key is a control character or
+// backtab, which already imply the control or shift modifier so only the alt
+// modifier is shown in their names.
+func implicitMods(k tcell.Key) bool {
+	return k <= tcell.KeyUS || k == tcell.KeyDEL || k == tcell.KeyBacktab
+}
+
+// This function returns the name of the given key as used in mappings (e.g.
+// '<a-x>', '<c-up>', or '<s-f-5>'). Modifiers are shown in the order 'a-',
+// 'c-', and 's-'. The name is empty for unknown keys.
+func keyName(k tcell.Key, ch rune, mod tcell.ModMask) string {
+	var val string
+	if k == tcell.KeyRune {
+		switch ch {
+		case '<':
+			val = "lt"
+		case '>':
+			val = "gt"
+		case ' ':
+			val = "space"
+		default:
+			val = string(ch)
+		}
+		if mod&tcell.ModAlt == 0 {
+			if len(val) > 1 {
+				return "<" + val + ">"
+			}
+			return val
+		}
+		return "<a-" + val + ">"
+	}
+
+	val, ok := gKeyVal[k]
+	if !ok {
+		return ""
+	}
+
+	if implicitMods(k) {
+		mod &= tcell.ModAlt
+	}
+
+	var prefix string
+	for _, m := range gKeyMods {
+		if mod&m.mod != 0 {
+			prefix += m.prefix
+		}
+	}
+
+	return "<" + prefix + val[1:]
+}
+
+// This function parses the given key name and returns the key, the character
+// for regular keys, and the modifiers. Modifiers can be given in any order
+// (e.g. '<c-s-up>' or '<s-c-up>'), function keys can also be given without a
+// dash (e.g. '<f5>'), and only the alt modifier is accepted for regular keys
+// and control characters.
+func parseKeyName(s string) (tcell.Key, rune, tcell.ModMask, error) {
+	if utf8.RuneCountInString(s) == 1 {
+		ch, _ := utf8.DecodeRuneInString(s)
+		return tcell.KeyRune, ch, tcell.ModNone, nil
+	}
+
+	switch s {
+	case "<lt>":
+		return tcell.KeyRune, '<', tcell.ModNone, nil
+	case "<gt>":
+		return tcell.KeyRune, '>', tcell.ModNone, nil
+	case "<space>":
+		return tcell.KeyRune, ' ', tcell.ModNone, nil
+	}
+
+	if k, ok := gValKey[s]; ok {
+		return k, 0, tcell.ModNone, nil
+	}
+
+	if m := reFuncKey.FindStringSubmatch(s); m != nil {
+		if k, ok := gValKey["<f-"+m[1]+">"]; ok {
+			return k, 0, tcell.ModNone, nil
+		}
+	}
+
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		for _, m := range gKeyMods {
+			if !strings.HasPrefix(s[1:], m.prefix) || len(s) <= len(m.prefix)+2 {
+				continue
+			}
+			rest := s[1+len(m.prefix) : len(s)-1]
+			if utf8.RuneCountInString(rest) != 1 {
+				rest = "<" + rest + ">"
+			}
+			k, ch, mod, err := parseKeyName(rest)
+			if err != nil || mod&m.mod != 0 {
+				break
+			}
+			if m.mod != tcell.ModAlt && (k == tcell.KeyRune || implicitMods(k)) {
+				break
+			}
+			return k, ch, mod | m.mod, nil
+		}
+	}
+
+	return tcell.KeyESC, 0, tcell.ModNone, fmt.Errorf("unknown key: %s", s)
+}
+
+// This function replaces key names in the given key sequence with their
+// canonical names so that equivalent names (e.g. '<c-s-up>' and '<s-c-up>')
+// are bound to the same mapping. Unknown key names are kept as they are.
+func normalizeKeys(s string) string {
+	var b strings.Builder
+	for _, key := range splitKeys(s) {
+		if k, ch, mod, err := parseKeyName(key); err == nil && len(key) > 1 {
+			key = keyName(k, ch, mod)
+		}
+		b.WriteString(key)
+	}
+	return b.String()
+}
+
 type win struct {
 	w, h, x, y int
 }
@@ -163,19 +291,67 @@ func (win *win) renew(w, h, x, y int) {
 	win.w, win.h, win.x, win.y = w, h, x, y
 }
 
+// This function reads the escape sequence at the beginning of the given string
+// and returns its length. Parameters are also returned for SGR sequences (i.e.
+// CSI sequences ending with 'm') which are used to set colors and attributes.
+// Other CSI sequences such as cursor movements are not supported so they
+// should simply be skipped.
+func readEscape(s string) (n int, params string, sgr bool) {
+	if len(s) < 2 {
+		return len(s), "", false
+	}
+
+	if s[1] != '[' {
+		return 2, "", false
+	}
+
+	j := 2
+	for j < len(s) && s[j] >= 0x30 && s[j] <= 0x3f {
+		j++
+	}
+	params = s[2:j]
+	for j < len(s) && s[j] >= 0x20 && s[j] <= 0x2f {
+		j++
+	}
+
+	if j == len(s) || s[j] < 0x40 || s[j] > 0x7e {
+		return j, "", false
+	}
+
+	if s[j] != 'm' {
+		return j + 1, "", false
+	}
+
+	return j + 1, params, true
+}
+
+// This function removes escape sequences from the given string.
+func stripEscapes(s string) string {
+	if strings.IndexByte(s, gEscapeCode) == -1 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == gEscapeCode {
+			n, _, _ := readEscape(s[i:])
+			i += n - 1
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
 func printLength(s string) int {
 	ind := 0
 	off := 0
 	for i := 0; i < len(s); i++ {
 		r, w := utf8.DecodeRuneInString(s[i:])
 
-		if r == gEscapeCode && i+1 < len(s) && s[i+1] == '[' {
-			j := strings.IndexByte(s[i:min(len(s), i+32)], 'm')
-			if j == -1 {
-				continue
-			}
-
-			i += j
+		if r == gEscapeCode {
+			n, _, _ := readEscape(s[i:])
+			i += n - 1
 			continue
 		}
 
@@ -194,22 +370,24 @@ func printLength(s string) int {
 func (win *win) print(screen tcell.Screen, x, y int, st tcell.Style, s string) tcell.Style {
 	off := x
 	for i := 0; i < len(s); i++ {
-		r, w := utf8.DecodeRuneInString(s[i:])
-
-		if r == gEscapeCode && i+1 < len(s) && s[i+1] == '[' {
-			j := strings.IndexByte(s[i:min(len(s), i+32)], 'm')
-			if j == -1 {
-				continue
+		if s[i] == gEscapeCode {
+			// colors are not drawn at all in 'minimal' mode, including the
+			// ones in previews and messages
+			n, params, sgr := readEscape(s[i:])
+			if sgr && !gOpts.minimal {
+				st = applyAnsiCodes(params, st)
 			}
-
-			st = applyAnsiCodes(s[i+2:i+j], st)
-
-			i += j
+			i += n - 1
 			continue
 		}
 
+		// Grapheme clusters are drawn in a single cell with combining
+		// characters so that they are not overwritten by each other.
+		g, w := nextGrapheme(s[i:])
+		r := g[0]
+
 		if x < win.w {
-			screen.SetContent(win.x+x, win.y+y, r, nil, st)
+			screen.SetContent(win.x+x, win.y+y, r, g[1:], st)
 		}
 
 		i += w - 1
@@ -221,7 +399,7 @@ func (win *win) print(screen tcell.Screen, x, y int, st tcell.Style, s string) t
 			}
 			x += s
 		} else {
-			x += runewidth.RuneWidth(r)
+			x += graphemeWidth(g)
 		}
 	}
 
@@ -253,24 +431,184 @@ func (win *win) printReg(screen tcell.Screen, reg *reg) {
 		return
 	}
 
-	for i, l := range reg.lines {
+	i := 0
+	for _, l := range reg.lines {
 		if i > win.h-1 {
 			break
 		}
 
-		st = win.print(screen, 2, i, st, l)
+		if !gOpts.previewwrap {
+			st = win.print(screen, 2, i, st, l)
+			i++
+			continue
+		}
+
+		beg := 0
+		for _, end := range append(wrapPoints(l, win.w-2), len(l)) {
+			if i > win.h-1 {
+				break
+			}
+			st = win.print(screen, 2, i, st, l[beg:end])
+			beg = end
+			i++
+		}
+	}
+}
+
+// This function returns the byte offsets in the given preview line where the
+// line is wrapped to fit the given width for 'previewwrap' option. Escape
+// sequences take no space, tabs are expanded as in 'print' for each wrapped
+// part, and grapheme clusters are never split. A cluster wider than the width
+// is kept in its own part.
+func wrapPoints(s string, width int) []int {
+	var points []int
+	x := 0
+	for i := 0; i < len(s); {
+		if s[i] == gEscapeCode {
+			n, _, _ := readEscape(s[i:])
+			i += n
+			continue
+		}
+
+		g, n := nextGrapheme(s[i:])
+
+		w := graphemeWidth(g)
+		if g[0] == '\t' {
+			w = gOpts.tabstop - x%gOpts.tabstop
+		}
+
+		if x > 0 && x+w > width {
+			points = append(points, i)
+			x = 0
+			if g[0] == '\t' {
+				w = gOpts.tabstop
+			}
+		}
+
+		x += w
+		i += n
 	}
+	return points
 }
 
 var gThisYear = time.Now().Year()
 
+// This function formats the given time relative to the given current time
+// for 'relativetime' option (e.g. '3 min ago', 'yesterday', or '2 mo ago').
+// It returns false for times more than a minute in the future or more than a
+// year in the past, which should be shown in absolute form instead.
+func relTime(t, now time.Time) (string, bool) {
+	d := now.Sub(t)
+	switch {
+	case d < -time.Minute:
+		return "", false
+	case d < time.Minute:
+		return "just now", true
+	case d < time.Hour:
+		return fmt.Sprintf("%d min ago", d/time.Minute), true
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", d/time.Hour), true
+	case d < 48*time.Hour:
+		return "yesterday", true
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%d days ago", d/(24*time.Hour)), true
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%d mo ago", d/(30*24*time.Hour)), true
+	}
+	return "", false
+}
+
 func infotimefmt(t time.Time) string {
+	if gOpts.relativetime {
+		if s, ok := relTime(t, time.Now()); ok {
+			return s
+		}
+	}
 	if t.Year() == gThisYear {
 		return t.Format("Jan _2 15:04")
 	}
 	return t.Format("Jan _2  2006")
 }
 
+// This function formats the permissions of the given mode either in symbolic
+// form (e.g. 'drwxr-xr-x') or in octal form (e.g. '0755') according to
+// 'permfmt' option. Setuid, setgid, and sticky bits are shown in the execute
+// fields as in 'ls' (e.g. 'rws', 'rwT') or in the leading octal digit.
+func permString(m os.FileMode) string {
+	if gOpts.permfmt == "octal" {
+		return permOctal(m)
+	}
+	return permSymbolic(m)
+}
+
+func permOctal(m os.FileMode) string {
+	perm := uint32(m.Perm())
+	if m&os.ModeSetuid != 0 {
+		perm |= 04000
+	}
+	if m&os.ModeSetgid != 0 {
+		perm |= 02000
+	}
+	if m&os.ModeSticky != 0 {
+		perm |= 01000
+	}
+	return fmt.Sprintf("%04o", perm)
+}
+
+func permSymbolic(m os.FileMode) string {
+	buf := []byte("----------")
+
+	switch {
+	case m&os.ModeDir != 0:
+		buf[0] = 'd'
+	case m&os.ModeSymlink != 0:
+		buf[0] = 'l'
+	case m&os.ModeNamedPipe != 0:
+		buf[0] = 'p'
+	case m&os.ModeSocket != 0:
+		buf[0] = 's'
+	case m&os.ModeCharDevice != 0:
+		buf[0] = 'c'
+	case m&os.ModeDevice != 0:
+		buf[0] = 'b'
+	}
+
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if m&(1<<uint(8-i)) != 0 {
+			buf[i+1] = rwx[i]
+		}
+	}
+
+	special := func(i int, set bool, c byte) {
+		if !set {
+			return
+		}
+		if buf[i] == 'x' {
+			buf[i] = c
+		} else {
+			buf[i] = c - 'a' + 'A'
+		}
+	}
+
+	special(3, m&os.ModeSetuid != 0, 's')
+	special(6, m&os.ModeSetgid != 0, 's')
+	special(9, m&os.ModeSticky != 0, 't')
+
+	return string(buf)
+}
+
+// This function reports whether the given information type is shown with
+// 'info' option.
+func hasInfo(name string) bool {
+	for _, s := range gOpts.info {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
 func fileInfo(f *file, d *dir) string {
 	var info string
 
@@ -308,12 +646,37 @@ func fileInfo(f *file, d *dir) string {
 			default:
 				info = fmt.Sprintf("%s 999+", info)
 			}
+		case "childcount":
+			switch {
+			case !f.IsDir():
+				info = fmt.Sprintf("%s %4s", info, "")
+			case f.childCount < 0:
+				info = fmt.Sprintf("%s    ?", info)
+			default:
+				info = fmt.Sprintf("%s %4d", info, f.childCount)
+			}
 		case "time":
 			info = fmt.Sprintf("%s %12s", info, infotimefmt(f.ModTime()))
 		case "atime":
 			info = fmt.Sprintf("%s %12s", info, infotimefmt(f.accessTime))
 		case "ctime":
 			info = fmt.Sprintf("%s %12s", info, infotimefmt(f.changeTime))
+		case "perm":
+			info = fmt.Sprintf("%s %s", info, permString(f.Mode()))
+		case "xattr":
+			if f.xattrCount == -1 {
+				names, err := listXattrs(path)
+				if err != nil {
+					log.Printf("listing extended attributes: %s", err)
+				}
+				f.xattrCount = len(names)
+			}
+
+			if f.xattrCount > 0 {
+				info = fmt.Sprintf("%s @", info)
+			} else {
+				info = fmt.Sprintf("%s  ", info)
+			}
 		default:
 			log.Printf("unknown info type: %s", s)
 		}
@@ -322,11 +685,25 @@ func fileInfo(f *file, d *dir) string {
 	return info
 }
 
-func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]int, saves map[string]bool, colors styleMap, icons iconMap) {
+// This function returns the information of the given file shown on the line
+// of the file. Information is replaced with spaces of the same width on lines
+// other than the current line when 'infocursor' option is enabled so that the
+// names are truncated the same way as the cursor moves.
+func rowInfo(f *file, d *dir, current bool) string {
+	info := fileInfo(f, d)
+	if gOpts.infocursor && !current {
+		return strings.Repeat(" ", runeSliceWidth([]rune(info)))
+	}
+	return info
+}
+
+func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]int, saves map[string]bool, colors styleMap, icons iconMap, cursor, search string) {
 	if win.w < 5 || dir == nil {
 		return
 	}
 
+	dir.setCols(1, win.h)
+
 	if dir.noPerm {
 		win.print(screen, 2, 0, tcell.StyleDefault.Reverse(true), "permission denied")
 		return
@@ -349,6 +726,24 @@ func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]in
 		return
 	}
 
+	if gOpts.minimal {
+		for i, f := range dir.files[beg:end] {
+			path := filepath.Join(dir.path, f.Name())
+			st := tcell.StyleDefault
+			if i == dir.pos {
+				_, selected := selections[path]
+				st = cursorStyle(st, cursorLineCodes(cursor, selected))
+			}
+			win.print(screen, 0, i, st, minimalLine(fileMark(path, selections, saves), f.Name(), win.w))
+		}
+		return
+	}
+
+	var iconw int
+	if gOpts.icons && gOpts.iconalign {
+		iconw = icons.width()
+	}
+
 	var lnwidth int
 	var lnformat string
 
@@ -363,8 +758,28 @@ func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]in
 		lnformat = fmt.Sprintf("%%%d.d ", lnwidth)
 	}
 
+	if gOpts.scrollbar && len(dir.files) > win.h {
+		win.printScrollbar(screen, beg, len(dir.files))
+		win = newWin(win.w-1, win.h, win.x, win.y)
+	}
+
+	heat := newHeatmap(time.Now())
+
 	for i, f := range dir.files[beg:end] {
-		st := colors.get(f)
+		st := heat.style(colors.get(f), f.ModTime())
+
+		lnst := tcell.StyleDefault.Foreground(tcell.ColorOlive)
+
+		path := filepath.Join(dir.path, f.Name())
+
+		_, selected := selections[path]
+		codes := cursorLineCodes(cursor, selected)
+
+		if i == dir.pos && codes != "" {
+			bg := applyAnsiCodes(codes, tcell.StyleDefault)
+			win.print(screen, 0, i, bg, strings.Repeat(" ", win.w))
+			lnst = cursorStyle(lnst, codes)
+		}
 
 		if lnwidth > 0 {
 			var ln string
@@ -384,12 +799,10 @@ func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]in
 				}
 			}
 
-			win.print(screen, 0, i, tcell.StyleDefault.Foreground(tcell.ColorOlive), ln)
+			win.print(screen, 0, i, lnst, ln)
 		}
 
-		path := filepath.Join(dir.path, f.Name())
-
-		if _, ok := selections[path]; ok {
+		if selected {
 			win.print(screen, lnwidth, i, st.Background(tcell.ColorPurple), " ")
 		} else if cp, ok := saves[path]; ok {
 			if cp {
@@ -399,9 +812,7 @@ func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]in
 			}
 		}
 
-		if i == dir.pos {
-			st = st.Reverse(true)
-		}
+		st = lineStyle(st, cursor, i == dir.pos, selected)
 
 		var s []rune
 
@@ -409,12 +820,28 @@ func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]in
 
 		var iwidth int
 
+		var badges []string
+		if len(gOpts.badges) != 0 {
+			badges = fileBadges(f, dir.hiddenfiles, gOpts.badges)
+			for _, b := range badges {
+				iwidth += printLength(b)
+			}
+			iwidth++
+			s = append(s, []rune(strings.Repeat(" ", iwidth))...)
+		}
+
 		if gOpts.icons {
-			s = append(s, []rune(icons.get(f))...)
+			icon := icons.get(f)
+			if iconw > 0 {
+				icon = padIcon(icon, iconw)
+			}
+			s = append(s, []rune(icon)...)
 			s = append(s, ' ')
-			iwidth = 2
+			iwidth += iconWidth(icon) + 1
 		}
 
+		off := len(s)
+
 		for _, r := range f.Name() {
 			s = append(s, r)
 		}
@@ -422,22 +849,21 @@ func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]in
 		w := runeSliceWidth(s)
 
 		if w > win.w-3 {
-			s = runeSliceWidthRange(s, 0, win.w-4)
-			s = append(s, []rune(gOpts.truncatechar)...)
+			s = truncateRunes(s, win.w-3, gOpts.truncatechar)
 		} else {
 			for i := 0; i < win.w-3-w; i++ {
 				s = append(s, ' ')
 			}
 		}
 
-		info := fileInfo(f, dir)
+		info := rowInfo(f, dir, i == dir.pos)
+		infow := runeSliceWidth([]rune(info))
 
-		if len(info) > 0 && win.w-lnwidth-iwidth-2 > 2*len(info) {
-			if win.w-2 > w+len(info) {
-				s = runeSliceWidthRange(s, 0, win.w-3-len(info)-lnwidth)
+		if infow > 0 && win.w-lnwidth-iwidth-2 > 2*infow {
+			if win.w-2 > w+infow {
+				s = runeSliceWidthRange(s, 0, win.w-3-infow-lnwidth)
 			} else {
-				s = runeSliceWidthRange(s, 0, win.w-4-len(info)-lnwidth)
-				s = append(s, []rune(gOpts.truncatechar)...)
+				s = truncateRunes(s, win.w-3-infow-lnwidth, gOpts.truncatechar)
 			}
 			for _, r := range info {
 				s = append(s, r)
@@ -447,6 +873,349 @@ func (win *win) printDir(screen tcell.Screen, dir *dir, selections map[string]in
 		s = append(s, ' ')
 
 		win.print(screen, lnwidth+1, i, st, string(s))
+		win.printMatches(screen, lnwidth+1, i, st, s, off, f.Name(), search)
+
+		x := lnwidth + 2
+		for _, b := range badges {
+			win.print(screen, x, i, st, b)
+			x += printLength(b)
+		}
+	}
+}
+
+// This function returns the character shown before the name of the file with
+// the given path in 'minimal' mode, which is '*' for selected files, '+' for
+// copied files, '-' for cut files, and a space otherwise.
+func fileMark(path string, selections map[string]int, saves map[string]bool) rune {
+	if _, ok := selections[path]; ok {
+		return '*'
+	}
+	if cp, ok := saves[path]; ok {
+		if cp {
+			return '+'
+		}
+		return '-'
+	}
+	return ' '
+}
+
+// This function returns the line of a file drawn in 'minimal' mode with the
+// given mark and name fitted to the given width of the pane.
+func minimalLine(mark rune, name string, width int) string {
+	s := []rune{' '}
+	s = append(s, []rune(name)...)
+
+	if w := runeSliceWidth(s); w > width-3 {
+		s = truncateRunes(s, width-3, gOpts.truncatechar)
+	} else {
+		s = append(s, []rune(strings.Repeat(" ", width-3-w))...)
+	}
+
+	return string(mark) + string(s) + " "
+}
+
+type matchCell struct {
+	x int
+	s string
+}
+
+// This function returns the column offsets and the texts of the parts of the
+// given line to highlight for the given spans of matches in the name of the
+// file, which starts at the given index of the line. Only the part of the name
+// that is still shown after truncation is highlighted and columns are counted
+// with the widths of the characters before each part.
+func matchCells(s []rune, off int, name []rune, spans [][2]int) []matchCell {
+	n := 0
+	for n < len(name) && off+n < len(s) && s[off+n] == name[n] {
+		n++
+	}
+
+	var cells []matchCell
+	for _, sp := range spans {
+		beg, end := sp[0], min(sp[1], n)
+		if beg >= end {
+			continue
+		}
+		cells = append(cells, matchCell{runeSliceWidth(s[:off+beg]), string(s[off+beg : off+end])})
+	}
+
+	return cells
+}
+
+// This function draws the matches of the search pattern in the name of the
+// file over the line drawn at the given position with the style of the line
+// and the codes of 'searchmatch' option applied on top of it.
+func (win *win) printMatches(screen tcell.Screen, x, y int, st tcell.Style, s []rune, off int, name, search string) {
+	if search == "" || gOpts.searchmatch == "" {
+		return
+	}
+
+	st = applyAnsiCodes(gOpts.searchmatch, st)
+
+	for _, c := range matchCells(s, off, []rune(name), searchSpans(name, search)) {
+		win.print(screen, x+c.x, y, st, c.s)
+	}
+}
+
+// This function returns the style of the current line from the given style of
+// the file. The style is reversed by default, otherwise the given codes are
+// applied on top of the style of the file. Foreground color is reset when it
+// becomes the same as the background color to keep the name readable.
+func cursorStyle(st tcell.Style, cursor string) tcell.Style {
+	if cursor == "" {
+		return st.Reverse(true)
+	}
+
+	st = applyAnsiCodes(cursor, st)
+
+	if fg, bg, _ := st.Decompose(); fg == bg && fg != tcell.ColorDefault {
+		st = st.Foreground(tcell.ColorDefault)
+	}
+
+	return st
+}
+
+// This function returns the codes for the style of the current line when the
+// current file is selected or not. The codes of 'cursorselected' take
+// precedence over the given codes of the cursor for selected files when set.
+func cursorLineCodes(cursor string, selected bool) string {
+	if selected && gOpts.cursorselected != "" {
+		return gOpts.cursorselected
+	}
+	return cursor
+}
+
+// This function returns the style of a line in the list of files from the
+// given style of the file depending on whether the file is the current file
+// and whether it is selected. The codes of 'selectedline' are applied to the
+// selected files and the style of the cursor is applied on top of them for
+// the current file, unless 'cursorselected' is set to be used instead.
+func lineStyle(st tcell.Style, cursor string, current, selected bool) tcell.Style {
+	if !current {
+		if selected && gOpts.selectedline != "" {
+			st = applyAnsiCodes(gOpts.selectedline, st)
+		}
+		return st
+	}
+
+	if selected && gOpts.selectedline != "" && gOpts.cursorselected == "" {
+		st = applyAnsiCodes(gOpts.selectedline, st)
+	}
+
+	return cursorStyle(st, cursorLineCodes(cursor, selected))
+}
+
+// This function returns the codes for the style of the current line, which is
+// 'cursorinactive' when it is set and the terminal is not focused, otherwise
+// 'cursorline'.
+func cursorCodes(focused bool) string {
+	if !focused && gOpts.cursorinactive != "" {
+		return gOpts.cursorinactive
+	}
+	return gOpts.cursorline
+}
+
+func (ui *ui) cursorline() string {
+	return cursorCodes(ui.focused)
+}
+
+// This function returns the widths of the columns for the given widths of
+// entries placed in rows with the given number of columns.
+func gridWidths(widths []int, cols int) []int {
+	colws := make([]int, cols)
+	for i, w := range widths {
+		colws[i%cols] = max(colws[i%cols], w)
+	}
+	return colws
+}
+
+// This function returns the largest number of columns that can fit the given
+// widths of entries placed in rows to the given width of the pane, similar to
+// 'ls -x'. It returns one when entries do not fit in multiple columns.
+func gridColumns(widths []int, width int) int {
+	if len(widths) == 0 {
+		return 1
+	}
+
+	minw := widths[0]
+	for _, w := range widths {
+		minw = min(minw, w)
+	}
+
+	for cols := min(len(widths), width/max(minw, 1)); cols > 1; cols-- {
+		total := 0
+		for _, w := range gridWidths(widths, cols) {
+			total += w
+		}
+		if total <= width {
+			return cols
+		}
+	}
+
+	return 1
+}
+
+// This function draws the directory as a grid of multiple columns when the
+// names of the files are short enough and falls back to a list otherwise.
+// Line numbers and file information are not shown in grid layout.
+func (win *win) printGrid(screen tcell.Screen, dir *dir, selections map[string]int, saves map[string]bool, colors styleMap, icons iconMap, cursor, search string) {
+	if win.w < 5 || dir == nil || dir.noPerm || len(dir.files) == 0 {
+		win.printDir(screen, dir, selections, saves, colors, icons, cursor, search)
+		return
+	}
+
+	width := win.w
+	if gOpts.scrollbar {
+		width--
+	}
+
+	var iconw int
+	if gOpts.icons && gOpts.iconalign {
+		iconw = icons.width()
+	}
+
+	// badges are kept to be drawn after the names in the column before them
+	widths := make([]int, len(dir.files))
+	badges := make([][]string, len(dir.files))
+	for i, f := range dir.files {
+		widths[i] = runeSliceWidth([]rune(f.Name())) + 3
+		if len(gOpts.badges) != 0 {
+			badges[i] = fileBadges(f, dir.hiddenfiles, gOpts.badges)
+			for _, b := range badges[i] {
+				widths[i] += printLength(b)
+			}
+			widths[i]++
+		}
+		if gOpts.icons {
+			widths[i] += max(iconw, iconWidth(icons.get(f))) + 1
+		}
+	}
+
+	cols := gridColumns(widths, width)
+	if cols == 1 {
+		win.printDir(screen, dir, selections, saves, colors, icons, cursor, search)
+		return
+	}
+
+	dir.setCols(cols, win.h)
+
+	colws := gridWidths(widths, cols)
+	rows := (len(dir.files) + cols - 1) / cols
+
+	beg := max(dir.ind/cols-dir.pos, 0)
+	end := min(beg+win.h, rows)
+
+	if gOpts.scrollbar && rows > win.h {
+		win.printScrollbar(screen, beg, rows)
+	}
+
+	heat := newHeatmap(time.Now())
+
+	for r := beg; r < end; r++ {
+		x := 0
+		for c := 0; c < cols && r*cols+c < len(dir.files); c++ {
+			ind := r*cols + c
+			f := dir.files[ind]
+
+			st := heat.style(colors.get(f), f.ModTime())
+
+			path := filepath.Join(dir.path, f.Name())
+
+			_, selected := selections[path]
+
+			// the background of the current line spans the whole cell
+			if codes := cursorLineCodes(cursor, selected); ind == dir.ind && codes != "" {
+				bg := applyAnsiCodes(codes, tcell.StyleDefault)
+				win.print(screen, x, r-beg, bg, strings.Repeat(" ", colws[c]))
+			}
+
+			if selected {
+				win.print(screen, x, r-beg, st.Background(tcell.ColorPurple), " ")
+			} else if cp, ok := saves[path]; ok {
+				if cp {
+					win.print(screen, x, r-beg, st.Background(tcell.ColorOlive), " ")
+				} else {
+					win.print(screen, x, r-beg, st.Background(tcell.ColorMaroon), " ")
+				}
+			}
+
+			st = lineStyle(st, cursor, ind == dir.ind, selected)
+
+			var s []rune
+
+			s = append(s, ' ')
+
+			if len(gOpts.badges) != 0 {
+				bwidth := 1
+				for _, b := range badges[ind] {
+					bwidth += printLength(b)
+				}
+				s = append(s, []rune(strings.Repeat(" ", bwidth))...)
+			}
+
+			if gOpts.icons {
+				icon := icons.get(f)
+				if iconw > 0 {
+					icon = padIcon(icon, iconw)
+				}
+				s = append(s, []rune(icon)...)
+				s = append(s, ' ')
+			}
+
+			off := len(s)
+
+			for _, r := range f.Name() {
+				s = append(s, r)
+			}
+
+			for w := runeSliceWidth(s); w < colws[c]-1; w++ {
+				s = append(s, ' ')
+			}
+
+			win.print(screen, x+1, r-beg, st, string(s))
+			win.printMatches(screen, x+1, r-beg, st, s, off, f.Name(), search)
+
+			bx := x + 2
+			for _, b := range badges[ind] {
+				win.print(screen, bx, r-beg, st, b)
+				bx += printLength(b)
+			}
+
+			x += colws[c]
+		}
+	}
+}
+
+// This function returns the position and the size of the scrollbar thumb for
+// a pane with the given height showing the list starting from the given index.
+// The thumb is at least one line long and it is at the bottom when the end of
+// the list is visible.
+func scrollbarThumb(height, beg, total int) (pos, size int) {
+	if height <= 0 || total <= height {
+		return 0, max(height, 0)
+	}
+
+	size = max(1, (height*height+total/2)/total)
+
+	maxbeg := total - height
+	beg = max(0, min(beg, maxbeg))
+
+	pos = (beg*(height-size) + maxbeg/2) / maxbeg
+
+	return pos, size
+}
+
+func (win *win) printScrollbar(screen tcell.Screen, beg, total int) {
+	pos, size := scrollbarThumb(win.h, beg, total)
+
+	st := tcell.StyleDefault.Dim(true)
+
+	for i := 0; i < win.h; i++ {
+		r := '░'
+		if i >= pos && i < pos+size {
+			r = '█'
+		}
+		screen.SetContent(win.x+win.w-1, win.y+i, r, nil, st)
 	}
 }
 
@@ -454,15 +1223,19 @@ type ui struct {
 	screen       tcell.Screen
 	wins         []*win
 	promptWin    *win
+	crumbWin     *win
 	msgWin       *win
 	menuWin      *win
 	msg          string
+	lastErr      string
 	regPrev      *reg
 	dirPrev      *dir
 	exprChan     chan expr
 	keyChan      chan string
 	tevChan      chan tcell.Event
 	evChan       chan tcell.Event
+	focusChan    chan bool
+	focused      bool
 	menuBuf      *bytes.Buffer
 	menuSelected int
 	cmdPrefix    string
@@ -476,18 +1249,53 @@ type ui struct {
 	icons        iconMap
 }
 
+// This function returns the ratios of the panes shown for the given width of
+// the terminal and whether the preview pane is shown. The preview pane is
+// hidden when the width is less than 'previewwidth' and parent panes are
+// hidden when the width is less than 'parentwidth'.
+func paneLayout(wtot int) (ratios []int, preview bool) {
+	ratios, preview = gOpts.ratios, gOpts.preview
+
+	if preview && gOpts.previewwidth != 0 && wtot < gOpts.previewwidth {
+		ratios, preview = ratios[:len(ratios)-1], false
+	}
+
+	if gOpts.parentwidth != 0 && wtot < gOpts.parentwidth {
+		n := 1
+		if preview {
+			n = 2
+		}
+		if len(ratios) > n {
+			ratios = ratios[len(ratios)-n:]
+		}
+	}
+
+	return ratios, preview
+}
+
+// This function returns the number of rows used by the header lines between
+// the prompt line and the panes.
+func headerHeight() int {
+	if gOpts.breadcrumbs {
+		return 1
+	}
+	return 0
+}
+
 func getWidths(wtot int) []int {
+	ratios, _ := paneLayout(wtot)
+
 	rsum := 0
-	for _, r := range gOpts.ratios {
+	for _, r := range ratios {
 		rsum += r
 	}
 
-	wlen := len(gOpts.ratios)
+	wlen := len(ratios)
 	widths := make([]int, wlen)
 
 	wsum := 0
 	for i := 0; i < wlen-1; i++ {
-		widths[i] = gOpts.ratios[i] * (wtot / rsum)
+		widths[i] = ratios[i] * (wtot / rsum)
 		wsum += widths[i]
 	}
 	widths[wlen-1] = wtot - wsum
@@ -499,20 +1307,44 @@ func getWidths(wtot int) []int {
 	return widths
 }
 
+// This function returns the widths of the two panes of split mode, which
+// divide the screen into halves.
+func splitWidths(wtot int) []int {
+	widths := []int{wtot / 2, wtot - wtot/2}
+
+	if gOpts.drawbox {
+		widths[1]--
+	}
+
+	return widths
+}
+
 func getWins(screen tcell.Screen) []*win {
 	wtot, htot := screen.Size()
+	return layoutWins(getWidths(wtot), htot)
+}
+
+// This function returns the windows of the two panes of split mode placed as
+// the windows of directories.
+func getSplitWins(screen tcell.Screen) []*win {
+	wtot, htot := screen.Size()
+	return layoutWins(splitWidths(wtot), htot)
+}
 
+// This function returns the windows with the given widths placed side by side
+// between the header lines and the status line.
+func layoutWins(widths []int, htot int) []*win {
 	var wins []*win
 
-	widths := getWidths(wtot)
+	top := headerHeight()
 
 	wacc := 0
 	wlen := len(widths)
 	for i := 0; i < wlen; i++ {
 		if gOpts.drawbox {
-			wins = append(wins, newWin(widths[i], htot-4, wacc+1, 2))
+			wins = append(wins, newWin(widths[i], htot-4-top, wacc+1, 2+top))
 		} else {
-			wins = append(wins, newWin(widths[i], htot-2, wacc, 1))
+			wins = append(wins, newWin(widths[i], htot-2-top, wacc, 1+top))
 		}
 		wacc += widths[i]
 	}
@@ -527,12 +1359,15 @@ func newUI(screen tcell.Screen) *ui {
 		screen:       screen,
 		wins:         getWins(screen),
 		promptWin:    newWin(wtot, 1, 0, 0),
+		crumbWin:     newWin(wtot, 1, 0, 1),
 		msgWin:       newWin(wtot, 1, 0, htot-1),
 		menuWin:      newWin(wtot, 1, 0, htot-2),
 		exprChan:     make(chan expr, 1000),
 		keyChan:      make(chan string, 1000),
 		tevChan:      make(chan tcell.Event, 1000),
 		evChan:       make(chan tcell.Event, 1000),
+		focusChan:    make(chan bool, 1000),
+		focused:      true,
 		styles:       parseStyles(),
 		icons:        parseIcons(),
 		menuSelected: -2,
@@ -550,27 +1385,73 @@ func (ui *ui) pollEvents() {
 		if ev == nil {
 			return
 		}
+
+		// focus changes ('\033[I' and '\033[O') are not recognized by tcell
+		// and they are received as 'alt-[' followed by 'I' or 'O'
+		if focusReporting() && isFocusPrefix(ev) {
+			next := ui.screen.PollEvent()
+			if next == nil {
+				ui.tevChan <- ev
+				return
+			}
+			if focused, ok := focusEvent(next); ok {
+				ui.focusChan <- focused
+				continue
+			}
+			ui.tevChan <- ev
+			ev = next
+		}
+
 		ui.tevChan <- ev
 	}
 }
 
+// This function checks whether the given event is 'alt-[' which is the
+// beginning of a focus change sequence.
+func isFocusPrefix(ev tcell.Event) bool {
+	tev, ok := ev.(*tcell.EventKey)
+	return ok && tev.Key() == tcell.KeyRune && tev.Rune() == '[' && tev.Modifiers() == tcell.ModAlt
+}
+
+// This function checks whether the given event following 'alt-[' completes a
+// focus change sequence and returns whether the terminal is focused.
+func focusEvent(ev tcell.Event) (focused, ok bool) {
+	tev, ok := ev.(*tcell.EventKey)
+	if !ok || tev.Key() != tcell.KeyRune || tev.Modifiers() != tcell.ModNone {
+		return false, false
+	}
+	switch tev.Rune() {
+	case 'I':
+		return true, true
+	case 'O':
+		return false, true
+	}
+	return false, false
+}
+
 func (ui *ui) renew() {
 	wtot, htot := ui.screen.Size()
 
 	widths := getWidths(wtot)
+	top := headerHeight()
+
+	if len(widths) != len(ui.wins) {
+		ui.wins = getWins(ui.screen)
+	}
 
 	wacc := 0
 	wlen := len(widths)
 	for i := 0; i < wlen; i++ {
 		if gOpts.drawbox {
-			ui.wins[i].renew(widths[i], htot-4, wacc+1, 2)
+			ui.wins[i].renew(widths[i], htot-4-top, wacc+1, 2+top)
 		} else {
-			ui.wins[i].renew(widths[i], htot-2, wacc, 1)
+			ui.wins[i].renew(widths[i], htot-2-top, wacc, 1+top)
 		}
 		wacc += widths[i]
 	}
 
 	ui.promptWin.renew(wtot, 1, 0, 0)
+	ui.crumbWin.renew(wtot, 1, 0, 1)
 	ui.msgWin.renew(wtot, 1, 0, htot-1)
 	ui.menuWin.renew(wtot, 1, 0, htot-2)
 }
@@ -603,6 +1484,7 @@ func (ui *ui) echomsgf(format string, a ...interface{}) {
 
 func (ui *ui) echoerr(msg string) {
 	ui.msg = fmt.Sprintf(gOpts.errorfmt, msg)
+	ui.lastErr = msg
 	log.Printf("error: %s", msg)
 }
 
@@ -610,11 +1492,55 @@ func (ui *ui) echoerrf(format string, a ...interface{}) {
 	ui.echoerr(fmt.Sprintf(format, a...))
 }
 
+// This function decides whether an operation taking the given duration is long
+// enough to notify the user about its completion.
+func notifiable(d time.Duration) bool {
+	return gOpts.notify != "" && d >= time.Duration(gOpts.notifytime)*time.Second
+}
+
+// This type is sent to the main loop to ring the terminal bell since the
+// screen should not be used from other goroutines.
+type bellExpr struct{}
+
+func (e *bellExpr) String() string { return "bell" }
+
+func (e *bellExpr) eval(app *app, args []string) {
+	if err := app.ui.screen.Beep(); err != nil {
+		log.Printf("ringing bell: %s", err)
+	}
+}
+
+// This function is used to notify the user about the completion of an
+// operation started at the given time using either the terminal bell or the
+// desktop notifier of the system depending on the value of 'notify' option.
+// It is called from the goroutines of operations, so the bell is rung in the
+// main loop instead.
+func (ui *ui) notify(start time.Time, msg string) {
+	if !notifiable(time.Since(start)) {
+		return
+	}
+
+	switch gOpts.notify {
+	case "bell":
+		ui.exprChan <- &bellExpr{}
+	case "desktop":
+		cmd := notifyCommand("lf", msg)
+		if err := cmd.Start(); err != nil {
+			log.Printf("sending notification: %s", err)
+			return
+		}
+		go cmd.Wait()
+	}
+}
+
 type reg struct {
 	loading  bool
 	volatile bool
 	loadTime time.Time
 	path     string
+	search   string
+	scroll   int
+	last     bool
 	lines    []string
 }
 
@@ -624,12 +1550,14 @@ func (ui *ui) loadFile(nav *nav, volatile bool) {
 		return
 	}
 
+	nav.syncPreviewScroll(curr.path)
+
 	if !gOpts.preview {
 		return
 	}
 
 	if volatile {
-		nav.previewChan <- ""
+		nav.previewChan <- previewReq{}
 	}
 
 	if curr.IsDir() {
@@ -649,7 +1577,7 @@ func (ui *ui) loadFileInfo(nav *nav) {
 	if curr.linkTarget != "" {
 		linkTarget = " -> " + curr.linkTarget
 	}
-	ui.echof("%v %4s %v%s", curr.Mode(), humanize(curr.Size()), curr.ModTime().Format(gOpts.timefmt), linkTarget)
+	ui.echof("%s %4s %v%s", permString(curr.Mode()), humanize(curr.Size()), curr.ModTime().Format(gOpts.timefmt), linkTarget)
 }
 
 func (ui *ui) drawPromptLine(nav *nav) {
@@ -699,6 +1627,56 @@ func (ui *ui) drawPromptLine(nav *nav) {
 	ui.promptWin.print(ui.screen, 0, 0, st, prompt)
 }
 
+// This is the separator used between path components in the breadcrumbs line.
+const gCrumbSep = " › "
+
+// This function returns the components of the given path starting with the
+// root directory (e.g. '/', 'home', 'user' for '/home/user').
+func pathComponents(path string) []string {
+	var comps []string
+	for ; !isRoot(path); path = filepath.Dir(path) {
+		comps = append([]string{filepath.Base(path)}, comps...)
+	}
+	return append([]string{path}, comps...)
+}
+
+// This function returns the breadcrumbs of the given path that fits in the
+// given width. Components are dropped from the left when the path is too long
+// so that the tail of the path is kept visible. The last component is also
+// truncated from the left when it does not fit by itself.
+func breadcrumbs(path string, width int) string {
+	comps := pathComponents(path)
+
+	s := strings.Join(comps, gCrumbSep)
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+
+	for i := 1; i < len(comps); i++ {
+		s = "…" + gCrumbSep + strings.Join(comps[i:], gCrumbSep)
+		if runewidth.StringWidth(s) <= width {
+			return s
+		}
+	}
+
+	if width < 1 {
+		return ""
+	}
+
+	runes := []rune(comps[len(comps)-1])
+	for len(runes) > 0 && runewidth.StringWidth(string(runes))+1 > width {
+		runes = runes[1:]
+	}
+
+	return "…" + string(runes)
+}
+
+func (ui *ui) drawBreadcrumbs(nav *nav) {
+	st := tcell.StyleDefault
+
+	ui.crumbWin.print(ui.screen, 0, 0, st, breadcrumbs(nav.currDir().path, ui.crumbWin.w))
+}
+
 func (ui *ui) drawStatLine(nav *nav) {
 	st := tcell.StyleDefault
 
@@ -725,39 +1703,110 @@ func (ui *ui) drawStatLine(nav *nav) {
 		progress += fmt.Sprintf("  [%d/%d]", nav.deleteCount, nav.deleteTotal)
 	}
 
-	ruler := fmt.Sprintf("%s%s  %d/%d", acc, progress, ind, tot)
+	var free string
+
+	if gOpts.diskfree {
+		if s := nav.diskFree(dir.path).String(); s != "" {
+			free = "  " + s
+		}
+	}
+
+	var sorting string
+
+	if gOpts.sortindicator {
+		sorting = "  " + gOpts.sortType.indicator()
+	}
+
+	var selection string
+
+	if gOpts.selectioninfo && len(nav.selections) > 0 {
+		selection = "  " + nav.selSizes.String()
+	}
+
+	ruler := fmt.Sprintf("%s%s%s%s%s  %d/%d", acc, progress, free, sorting, selection, ind, tot)
 
 	ui.msgWin.printRight(ui.screen, 0, st, ruler)
 }
 
-func (ui *ui) drawBox() {
-	st := tcell.StyleDefault
+// This type is the set of characters used to draw boxes around panes for
+// 'drawbox' option, which are the horizontal and vertical lines, the upper
+// left, upper right, lower left, and lower right corners, and the top and
+// bottom joints of the separators between panes.
+type boxChars struct {
+	h, v           rune
+	ul, ur, ll, lr rune
+	top, bottom    rune
+}
+
+// These are the sets of box drawing characters for 'boxchars' option.
+var gBoxChars = map[string]boxChars{
+	"ascii":   {'-', '|', '+', '+', '+', '+', '+', '+'},
+	"single":  {'─', '│', '┌', '┐', '└', '┘', '┬', '┴'},
+	"rounded": {'─', '│', '╭', '╮', '╰', '╯', '┬', '┴'},
+	"double":  {'═', '║', '╔', '╗', '╚', '╝', '╦', '╩'},
+}
+
+// This function returns the set of box drawing characters chosen with
+// 'boxchars' option. Single lines are used for unknown sets.
+func currBoxChars() boxChars {
+	if bc, ok := gBoxChars[gOpts.boxchars]; ok {
+		return bc
+	}
+	return gBoxChars["single"]
+}
+
+func (ui *ui) drawBox(wins []*win) {
+	st := applyAnsiCodes(gOpts.boxstyle, tcell.StyleDefault)
+	bc := currBoxChars()
 
 	w, h := ui.screen.Size()
 
+	top := 1 + headerHeight()
+
 	for i := 1; i < w-1; i++ {
-		ui.screen.SetContent(i, 1, '─', nil, st)
-		ui.screen.SetContent(i, h-2, '─', nil, st)
+		ui.screen.SetContent(i, top, bc.h, nil, st)
+		ui.screen.SetContent(i, h-2, bc.h, nil, st)
 	}
 
-	for i := 2; i < h-2; i++ {
-		ui.screen.SetContent(0, i, '│', nil, st)
-		ui.screen.SetContent(w-1, i, '│', nil, st)
+	for i := top + 1; i < h-2; i++ {
+		ui.screen.SetContent(0, i, bc.v, nil, st)
+		ui.screen.SetContent(w-1, i, bc.v, nil, st)
 	}
 
-	ui.screen.SetContent(0, 1, '┌', nil, st)
-	ui.screen.SetContent(w-1, 1, '┐', nil, st)
-	ui.screen.SetContent(0, h-2, '└', nil, st)
-	ui.screen.SetContent(w-1, h-2, '┘', nil, st)
+	ui.screen.SetContent(0, top, bc.ul, nil, st)
+	ui.screen.SetContent(w-1, top, bc.ur, nil, st)
+	ui.screen.SetContent(0, h-2, bc.ll, nil, st)
+	ui.screen.SetContent(w-1, h-2, bc.lr, nil, st)
 
 	wacc := 0
-	for wind := 0; wind < len(ui.wins)-1; wind++ {
-		wacc += ui.wins[wind].w
-		ui.screen.SetContent(wacc, 1, '┬', nil, st)
-		for i := 2; i < h-2; i++ {
-			ui.screen.SetContent(wacc, i, '│', nil, st)
+	for wind := 0; wind < len(wins)-1; wind++ {
+		wacc += wins[wind].w
+		ui.screen.SetContent(wacc, top, bc.top, nil, st)
+		for i := top + 1; i < h-2; i++ {
+			ui.screen.SetContent(wacc, i, bc.v, nil, st)
 		}
-		ui.screen.SetContent(wacc, h-2, '┴', nil, st)
+		ui.screen.SetContent(wacc, h-2, bc.bottom, nil, st)
+	}
+}
+
+// This function draws the current pane and the other pane of split mode in the
+// given windows. Each pane stays on its side of the screen when the focus is
+// moved, and the cursor of the other pane is drawn as when the terminal is not
+// focused. The other pane is only drawn after its directory is loaded.
+func (ui *ui) drawSplit(nav *nav, wins []*win) {
+	curr, other := wins[0], wins[1]
+	if nav.splitRight {
+		curr, other = other, curr
+	}
+
+	if gOpts.grid && !gOpts.minimal {
+		curr.printGrid(ui.screen, nav.currDir(), nav.selections, nav.saves, ui.styles, ui.icons, ui.cursorline(), nav.search)
+	} else {
+		curr.printDir(ui.screen, nav.currDir(), nav.selections, nav.saves, ui.styles, ui.icons, ui.cursorline(), nav.search)
+	}
+
+	if d, ok := nav.dirCache[nav.split.dir]; ok {
+		other.printDir(ui.screen, d, nav.split.selections, nav.saves, ui.styles, ui.icons, cursorCodes(false), "")
 	}
 }
 
@@ -773,17 +1822,39 @@ func (ui *ui) draw(nav *nav) {
 
 	ui.drawPromptLine(nav)
 
-	length := min(len(ui.wins), len(nav.dirs))
-	woff := len(ui.wins) - length
-
-	if gOpts.preview {
-		length = min(len(ui.wins)-1, len(nav.dirs))
-		woff = len(ui.wins) - 1 - length
+	if gOpts.breadcrumbs {
+		ui.drawBreadcrumbs(nav)
 	}
 
-	doff := len(nav.dirs) - length
-	for i := 0; i < length; i++ {
-		ui.wins[woff+i].printDir(ui.screen, nav.dirs[doff+i], nav.selections, nav.saves, ui.styles, ui.icons)
+	_, preview := paneLayout(wtot)
+
+	// the two panes of split mode are shown in halves of the screen instead
+	// of the parent directories and the preview
+	wins := ui.wins
+	if nav.split != nil {
+		wins = getSplitWins(ui.screen)
+		ui.drawSplit(nav, wins)
+	} else {
+		length := min(len(ui.wins), len(nav.dirs))
+		woff := len(ui.wins) - length
+
+		if preview {
+			length = min(len(ui.wins)-1, len(nav.dirs))
+			woff = len(ui.wins) - 1 - length
+		}
+
+		doff := len(nav.dirs) - length
+		for i := 0; i < length; i++ {
+			var search string
+			if i == length-1 {
+				search = nav.search
+			}
+			if gOpts.grid && !gOpts.minimal && i == length-1 {
+				ui.wins[woff+i].printGrid(ui.screen, nav.dirs[doff+i], nav.selections, nav.saves, ui.styles, ui.icons, ui.cursorline(), search)
+				continue
+			}
+			ui.wins[woff+i].printDir(ui.screen, nav.dirs[doff+i], nav.selections, nav.saves, ui.styles, ui.icons, ui.cursorline(), search)
+		}
 	}
 
 	switch ui.cmdPrefix {
@@ -803,21 +1874,21 @@ func (ui *ui) draw(nav *nav) {
 		ui.screen.ShowCursor(ui.msgWin.x+len(ui.cmdPrefix)+runeSliceWidth(ui.cmdAccLeft), ui.msgWin.y)
 	}
 
-	if gOpts.preview {
+	if nav.split == nil && preview {
 		curr, err := nav.currFile()
 		if err == nil {
-			preview := ui.wins[len(ui.wins)-1]
+			win := ui.wins[len(ui.wins)-1]
 
 			if curr.IsDir() {
-				preview.printDir(ui.screen, ui.dirPrev, nav.selections, nav.saves, ui.styles, ui.icons)
+				win.printDir(ui.screen, ui.dirPrev, nav.selections, nav.saves, ui.styles, ui.icons, ui.cursorline(), "")
 			} else if curr.Mode().IsRegular() {
-				preview.printReg(ui.screen, ui.regPrev)
+				win.printReg(ui.screen, ui.regPrev)
 			}
 		}
 	}
 
 	if gOpts.drawbox {
-		ui.drawBox()
+		ui.drawBox(wins)
 	}
 
 	if ui.menuBuf != nil {
@@ -832,6 +1903,8 @@ func (ui *ui) draw(nav *nav) {
 			ui.menuWin.y += 2
 		}
 
+		ui.menuWin.y += headerHeight()
+
 		ui.menuWin.printLine(ui.screen, 0, 0, st.Bold(true), lines[0])
 
 		for i, line := range lines[1:] {
@@ -857,7 +1930,28 @@ func findBinds(keys map[string]expr, prefix string) (binds map[string]expr, ok b
 	return
 }
 
-func listBinds(binds map[string]expr) *bytes.Buffer {
+// This function returns the keys that can be pressed after the given prefix
+// together with their commands. Only the next key is shown for mappings with
+// longer keys and their commands are shown as '...' (e.g. 'ga' for 'gab' and
+// 'gac' mappings) unless the shorter key is also mapped to a command.
+func continuations(keys map[string]expr, prefix string) map[string]string {
+	conts := make(map[string]string)
+	for key, expr := range keys {
+		if !strings.HasPrefix(key, prefix) || key == prefix {
+			continue
+		}
+		rest := splitKeys(key[len(prefix):])
+		next := prefix + rest[0]
+		if len(rest) == 1 {
+			conts[next] = expr.String()
+		} else if _, ok := conts[next]; !ok {
+			conts[next] = "..."
+		}
+	}
+	return conts
+}
+
+func listBinds(binds map[string]string) *bytes.Buffer {
 	t := new(tabwriter.Writer)
 	b := new(bytes.Buffer)
 
@@ -870,7 +1964,7 @@ func listBinds(binds map[string]expr) *bytes.Buffer {
 	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
 	fmt.Fprintln(t, "keys\tcommand")
 	for _, k := range keys {
-		fmt.Fprintf(t, "%s\t%v\n", k, binds[k])
+		fmt.Fprintf(t, "%s\t%s\n", k, binds[k])
 	}
 	t.Flush()
 
@@ -897,36 +1991,40 @@ func listMarks(marks map[string]string) *bytes.Buffer {
 	return b
 }
 
+// This function splits the output of a command into menu items. Each line is
+// considered as a separate item and empty lines are ignored.
+func parseMenuItems(s string) []string {
+	var items []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		items = append(items, line)
+	}
+	return items
+}
+
+func listItems(items []string) *bytes.Buffer {
+	t := new(tabwriter.Writer)
+	b := new(bytes.Buffer)
+
+	t.Init(b, 0, gOpts.tabstop, 2, '\t', 0)
+	fmt.Fprintln(t, "index\titem")
+	for i, item := range items {
+		fmt.Fprintf(t, "%d\t%s\n", i+1, item)
+	}
+	t.Flush()
+
+	return b
+}
+
 func (ui *ui) pollEvent() tcell.Event {
 	select {
 	case val := <-ui.keyChan:
-		var ch rune
-		var mod tcell.ModMask
-
-		k := tcell.KeyRune
-
-		if utf8.RuneCountInString(val) == 1 {
-			ch, _ = utf8.DecodeRuneInString(val)
-		} else {
-			switch {
-			case val == "<lt>":
-				ch = '<'
-			case val == "<gt>":
-				ch = '>'
-			case val == "<space>":
-				ch = ' '
-			case reAltKey.MatchString(val):
-				match := reAltKey.FindStringSubmatch(val)[1]
-				ch, _ = utf8.DecodeRuneInString(match)
-				mod = tcell.ModMask(tcell.ModAlt)
-			default:
-				if key, ok := gValKey[val]; ok {
-					k = key
-				} else {
-					k = tcell.KeyESC
-					ui.echoerrf("unknown key: %s", val)
-				}
-			}
+		k, ch, mod, err := parseKeyName(val)
+		if err != nil {
+			ui.echoerr(err.Error())
 		}
 
 		return tcell.NewEventKey(k, ch, mod)
@@ -961,7 +2059,7 @@ func (ui *ui) readNormalEvent(ev tcell.Event) expr {
 				ui.keyAcc = append(ui.keyAcc, tev.Rune())
 			}
 		} else {
-			val := gKeyVal[tev.Key()]
+			val := keyName(tev.Key(), tev.Rune(), tev.Modifiers())
 			if val == "<esc>" && string(ui.keyAcc) != "" {
 				ui.keyAcc = nil
 				ui.keyCount = nil
@@ -1004,7 +2102,18 @@ func (ui *ui) readNormalEvent(ev tcell.Event) expr {
 				ui.menuBuf = nil
 				return expr
 			}
-			ui.menuBuf = listBinds(binds)
+			ui.menuBuf = nil
+			if !gOpts.whichkey {
+				return draw
+			}
+			acc := string(ui.keyAcc)
+			if gOpts.whichkeydelay == 0 {
+				ui.menuBuf = listBinds(continuations(gOpts.keys, acc))
+				return draw
+			}
+			time.AfterFunc(time.Duration(gOpts.whichkeydelay)*time.Millisecond, func() {
+				ui.exprChan <- &callExpr{"which-key", []string{acc}, 1}
+			})
 			return draw
 		}
 	case *tcell.EventResize:
@@ -1032,7 +2141,7 @@ func readCmdEvent(ev tcell.Event) expr {
 				return &callExpr{"cmd-insert", []string{string(tev.Rune())}, 1}
 			}
 		} else {
-			val := gKeyVal[tev.Key()]
+			val := keyName(tev.Key(), tev.Rune(), tev.Modifiers())
 			if expr, ok := gOpts.cmdkeys[val]; ok {
 				return expr
 			}
@@ -1061,7 +2170,20 @@ func (ui *ui) readExpr() {
 	}()
 }
 
+// This function returns whether focus reporting of the terminal is needed by any
+// of the options depending on focus changes.
+func focusReporting() bool {
+	return gOpts.idleperiod != 0 || gOpts.cursorinactive != ""
+}
+
 func (ui *ui) pause() {
+	if focusReporting() {
+		setFocusReporting(false)
+	}
+	_, h := ui.screen.Size()
+	if err := setAltScreen(gOpts.altscreen, h); err != nil {
+		log.Printf("setting altscreen: %s", err)
+	}
 	ui.screen.Fini()
 }
 
@@ -1076,6 +2198,10 @@ func (ui *ui) resume() {
 
 	ui.screen = screen
 
+	if focusReporting() {
+		setFocusReporting(true)
+	}
+
 	go ui.pollEvents()
 
 	ui.renew()
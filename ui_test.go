@@ -0,0 +1,1110 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestNotifiable(t *testing.T) {
+	defer func(notify string, notifytime int) {
+		gOpts.notify = notify
+		gOpts.notifytime = notifytime
+	}(gOpts.notify, gOpts.notifytime)
+
+	tests := []struct {
+		notify     string
+		notifytime int
+		d          time.Duration
+		exp        bool
+	}{
+		{"", 0, 0, false},
+		{"", 10, time.Minute, false},
+		{"bell", 0, 0, true},
+		{"bell", 10, 9 * time.Second, false},
+		{"bell", 10, 10 * time.Second, true},
+		{"desktop", 10, time.Minute, true},
+		{"desktop", 60, time.Second, false},
+	}
+
+	for _, test := range tests {
+		gOpts.notify = test.notify
+		gOpts.notifytime = test.notifytime
+		if got := notifiable(test.d); got != test.exp {
+			t.Errorf("at input '%s' with notify '%s' and notifytime '%d' expected '%t' but got '%t'", test.d, test.notify, test.notifytime, test.exp, got)
+		}
+	}
+}
+
+func TestNotifyBell(t *testing.T) {
+	defer func(notify string, notifytime int) {
+		gOpts.notify = notify
+		gOpts.notifytime = notifytime
+	}(gOpts.notify, gOpts.notifytime)
+	gOpts.notify = "bell"
+	gOpts.notifytime = 0
+
+	ui := &ui{exprChan: make(chan expr, 1)}
+	ui.notify(time.Now(), "done")
+
+	select {
+	case e := <-ui.exprChan:
+		if _, ok := e.(*bellExpr); !ok {
+			t.Errorf("expected the bell to be sent to the main loop but got '%s'", e)
+		}
+	default:
+		t.Errorf("expected the bell to be sent to the main loop")
+	}
+}
+
+func TestParseMenuItems(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp []string
+	}{
+		{"", nil},
+		{"\n", nil},
+		{"foo", []string{"foo"}},
+		{"foo\n", []string{"foo"}},
+		{"foo\nbar\n", []string{"foo", "bar"}},
+		{"foo\r\nbar\r\n", []string{"foo", "bar"}},
+		{"foo\n\n  \nbar", []string{"foo", "bar"}},
+		{"  foo bar  \n", []string{"  foo bar  "}},
+	}
+
+	for _, test := range tests {
+		if got := parseMenuItems(test.s); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' expected '%q' but got '%q'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestScrollbarThumb(t *testing.T) {
+	tests := []struct {
+		height int
+		beg    int
+		total  int
+		pos    int
+		size   int
+	}{
+		{10, 0, 5, 0, 10},
+		{10, 0, 10, 0, 10},
+		{10, 0, 20, 0, 5},
+		{10, 10, 20, 5, 5},
+		{10, 5, 20, 3, 5},
+		{10, 0, 1000, 0, 1},
+		{10, 990, 1000, 9, 1},
+		{10, 495, 1000, 5, 1},
+		{10, 2000, 1000, 9, 1},
+		{10, -5, 1000, 0, 1},
+		{10, 1, 11, 1, 9},
+		{0, 0, 10, 0, 0},
+	}
+
+	for _, test := range tests {
+		pos, size := scrollbarThumb(test.height, test.beg, test.total)
+		if pos != test.pos || size != test.size {
+			t.Errorf("at input '%d', '%d', '%d' expected '%d', '%d' but got '%d', '%d'", test.height, test.beg, test.total, test.pos, test.size, pos, size)
+		}
+	}
+}
+
+func TestReadEscape(t *testing.T) {
+	tests := []struct {
+		s      string
+		n      int
+		params string
+		sgr    bool
+	}{
+		{"\033", 1, "", false},
+		{"\033[m", 3, "", true},
+		{"\033[0mfoo", 4, "0", true},
+		{"\033[1;31mfoo", 7, "1;31", true},
+		{"\033[38;5;208mfoo", 11, "38;5;208", true},
+		{"\033[2Kfoo", 4, "", false},
+		{"\033[10;20Hfoo", 8, "", false},
+		{"\033[?25lfoo", 6, "", false},
+		{"\033[1;31", 6, "", false},
+		{"\033(B", 2, "", false},
+	}
+
+	for _, test := range tests {
+		n, params, sgr := readEscape(test.s)
+		if n != test.n || params != test.params || sgr != test.sgr {
+			t.Errorf("at input '%q' expected '%d', '%s', '%t' but got '%d', '%s', '%t'", test.s, test.n, test.params, test.sgr, n, params, sgr)
+		}
+	}
+}
+
+func TestPreviewLine(t *testing.T) {
+	defer func(previewansi string) { gOpts.previewansi = previewansi }(gOpts.previewansi)
+
+	tests := []struct {
+		method string
+		s      string
+		exp    string
+	}{
+		{"render", "\033[1;31mfoo\033[0m", "\033[1;31mfoo\033[0m"},
+		{"strip", "\033[1;31mfoo\033[0m bar", "foo bar"},
+		{"strip", "\033[2Kfoo\033[10;20Hbar", "foobar"},
+		{"strip", "foo bar", "foo bar"},
+		{"raw", "\033[1;31mfoo\033[0m", "^[[1;31mfoo^[[0m"},
+	}
+
+	for _, test := range tests {
+		gOpts.previewansi = test.method
+		if got := previewLine(test.s); got != test.exp {
+			t.Errorf("at input '%q' with method '%s' expected '%q' but got '%q'", test.s, test.method, test.exp, got)
+		}
+	}
+}
+
+func TestPermString(t *testing.T) {
+	defer func(permfmt string) { gOpts.permfmt = permfmt }(gOpts.permfmt)
+
+	tests := []struct {
+		mode     os.FileMode
+		symbolic string
+		octal    string
+	}{
+		{0644, "-rw-r--r--", "0644"},
+		{0755 | os.ModeDir, "drwxr-xr-x", "0755"},
+		{0777 | os.ModeSymlink, "lrwxrwxrwx", "0777"},
+		{0755 | os.ModeSetuid, "-rwsr-xr-x", "4755"},
+		{0644 | os.ModeSetuid, "-rwSr--r--", "4644"},
+		{0755 | os.ModeSetgid, "-rwxr-sr-x", "2755"},
+		{0745 | os.ModeSetgid, "-rwxr-Sr-x", "2745"},
+		{0777 | os.ModeDir | os.ModeSticky, "drwxrwxrwt", "1777"},
+		{0776 | os.ModeDir | os.ModeSticky, "drwxrwxrwT", "1776"},
+		{0755 | os.ModeSetuid | os.ModeSetgid | os.ModeSticky, "-rwsr-sr-t", "7755"},
+		{0600 | os.ModeNamedPipe, "prw-------", "0600"},
+		{0600 | os.ModeSocket, "srw-------", "0600"},
+		{0660 | os.ModeDevice | os.ModeCharDevice, "crw-rw----", "0660"},
+		{0660 | os.ModeDevice, "brw-rw----", "0660"},
+		{0, "----------", "0000"},
+	}
+
+	for _, test := range tests {
+		gOpts.permfmt = "symbolic"
+		if got := permString(test.mode); got != test.symbolic {
+			t.Errorf("at input '%v' expected '%s' but got '%s'", test.mode, test.symbolic, got)
+		}
+		gOpts.permfmt = "octal"
+		if got := permString(test.mode); got != test.octal {
+			t.Errorf("at input '%v' expected '%s' but got '%s'", test.mode, test.octal, got)
+		}
+	}
+}
+
+func TestContinuations(t *testing.T) {
+	keys := map[string]expr{
+		"gg":       &callExpr{"top", nil, 1},
+		"gh":       &callExpr{"cd", []string{"~"}, 1},
+		"gab":      &callExpr{"cd", []string{"/a/b"}, 1},
+		"gac":      &callExpr{"cd", []string{"/a/c"}, 1},
+		"gx":       &callExpr{"cd", []string{"/x"}, 1},
+		"gxy":      &callExpr{"cd", []string{"/x/y"}, 1},
+		"g<c-x>":   &callExpr{"quit", nil, 1},
+		"g<c-x>z":  &callExpr{"quit", nil, 1},
+		"g<lt>":    &callExpr{"up", nil, 1},
+		"j":        &callExpr{"down", nil, 1},
+		"<space>j": &callExpr{"down", nil, 1},
+	}
+
+	tests := []struct {
+		prefix string
+		exp    map[string]string
+	}{
+		{"g", map[string]string{
+			"gg":     "top -- []",
+			"gh":     "cd -- [~]",
+			"ga":     "...",
+			"gx":     "cd -- [/x]",
+			"g<c-x>": "quit -- []",
+			"g<lt>":  "up -- []",
+		}},
+		{"ga", map[string]string{
+			"gab": "cd -- [/a/b]",
+			"gac": "cd -- [/a/c]",
+		}},
+		{"g<c-x>", map[string]string{
+			"g<c-x>z": "quit -- []",
+		}},
+		{"<space>", map[string]string{
+			"<space>j": "down -- []",
+		}},
+		{"gg", map[string]string{}},
+		{"z", map[string]string{}},
+	}
+
+	for _, test := range tests {
+		if got := continuations(keys, test.prefix); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' expected '%v' but got '%v'", test.prefix, test.exp, got)
+		}
+	}
+}
+
+func TestGridColumns(t *testing.T) {
+	tests := []struct {
+		widths []int
+		width  int
+		exp    int
+	}{
+		{nil, 80, 1},
+		{[]int{10}, 80, 1},
+		{[]int{10, 10}, 80, 2},
+		{[]int{10, 10}, 19, 1},
+		{[]int{10, 10}, 20, 2},
+		{[]int{10, 10, 10, 10, 10}, 40, 4},
+		{[]int{10, 10, 10, 10, 10}, 49, 4},
+		{[]int{10, 10, 10, 10, 10}, 50, 5},
+		{[]int{10, 10, 10, 10, 10}, 80, 5},
+		{[]int{30, 5, 5, 5, 5, 5}, 39, 2},
+		{[]int{30, 5, 5, 5, 5, 5}, 40, 3},
+		{[]int{30, 5, 5, 5, 5, 5}, 45, 4},
+		{[]int{5, 5, 5, 30, 5, 5}, 40, 3},
+		{[]int{5, 5, 5, 5, 5, 5, 5, 30}, 39, 2},
+		{[]int{5, 5, 5, 5, 5, 5, 5, 30}, 40, 3},
+		{[]int{5, 5, 5, 5, 5, 5, 5, 30}, 50, 5},
+		{[]int{50, 5}, 40, 1},
+	}
+
+	for _, test := range tests {
+		if got := gridColumns(test.widths, test.width); got != test.exp {
+			t.Errorf("at input '%v' with width '%d' expected '%d' but got '%d'", test.widths, test.width, test.exp, got)
+		}
+	}
+}
+
+func TestFocusEvent(t *testing.T) {
+	tests := []struct {
+		ev      tcell.Event
+		prefix  bool
+		focused bool
+		ok      bool
+	}{
+		{tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModAlt), true, false, false},
+		{tcell.NewEventKey(tcell.KeyRune, '[', tcell.ModNone), false, false, false},
+		{tcell.NewEventKey(tcell.KeyRune, 'I', tcell.ModNone), false, true, true},
+		{tcell.NewEventKey(tcell.KeyRune, 'O', tcell.ModNone), false, false, true},
+		{tcell.NewEventKey(tcell.KeyRune, 'I', tcell.ModAlt), false, false, false},
+		{tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone), false, false, false},
+		{tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), false, false, false},
+		{tcell.NewEventResize(80, 24), false, false, false},
+	}
+
+	for _, test := range tests {
+		if prefix := isFocusPrefix(test.ev); prefix != test.prefix {
+			t.Errorf("at input '%v' expected prefix '%t' but got '%t'", test.ev, test.prefix, prefix)
+		}
+		if focused, ok := focusEvent(test.ev); focused != test.focused || ok != test.ok {
+			t.Errorf("at input '%v' expected '%t' and '%t' but got '%t' and '%t'", test.ev, test.focused, test.ok, focused, ok)
+		}
+	}
+}
+
+func TestCursorStyle(t *testing.T) {
+	tests := []struct {
+		cursorline string
+		st         tcell.Style
+		exp        tcell.Style
+	}{
+		{"", tcell.StyleDefault, tcell.StyleDefault.Reverse(true)},
+		{"", tcell.StyleDefault.Foreground(tcell.ColorMaroon), tcell.StyleDefault.Foreground(tcell.ColorMaroon).Reverse(true)},
+		{"44", tcell.StyleDefault, tcell.StyleDefault.Background(tcell.ColorNavy)},
+		{"44", tcell.StyleDefault.Foreground(tcell.ColorMaroon), tcell.StyleDefault.Foreground(tcell.ColorMaroon).Background(tcell.ColorNavy)},
+		{"44", tcell.StyleDefault.Foreground(tcell.ColorNavy), tcell.StyleDefault.Background(tcell.ColorNavy)},
+		{"48;5;236", tcell.StyleDefault.Foreground(tcell.ColorTeal).Bold(true), tcell.StyleDefault.Foreground(tcell.ColorTeal).Background(tcell.Color236).Bold(true)},
+		{"1;44", tcell.StyleDefault.Foreground(tcell.ColorMaroon), tcell.StyleDefault.Foreground(tcell.ColorMaroon).Background(tcell.ColorNavy).Bold(true)},
+		{"30;47", tcell.StyleDefault.Foreground(tcell.ColorMaroon), tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorSilver)},
+		{"1", tcell.StyleDefault.Foreground(tcell.ColorMaroon), tcell.StyleDefault.Foreground(tcell.ColorMaroon).Bold(true)},
+		{"4", tcell.StyleDefault.Foreground(tcell.ColorMaroon), tcell.StyleDefault.Foreground(tcell.ColorMaroon).Underline(true)},
+		{"7", tcell.StyleDefault.Foreground(tcell.ColorMaroon), tcell.StyleDefault.Foreground(tcell.ColorMaroon).Reverse(true)},
+		{"1;4;44", tcell.StyleDefault, tcell.StyleDefault.Background(tcell.ColorNavy).Bold(true).Underline(true)},
+	}
+
+	for _, test := range tests {
+		if got := cursorStyle(test.st, test.cursorline); got != test.exp {
+			t.Errorf("at input '%s' with style '%v' expected '%v' but got '%v'", test.cursorline, test.st, test.exp, got)
+		}
+	}
+}
+
+func TestCursorCodes(t *testing.T) {
+	defer func(cursorline, cursorinactive string) {
+		gOpts.cursorline = cursorline
+		gOpts.cursorinactive = cursorinactive
+	}(gOpts.cursorline, gOpts.cursorinactive)
+
+	tests := []struct {
+		cursorline     string
+		cursorinactive string
+		focused        bool
+		exp            string
+	}{
+		{"", "", true, ""},
+		{"", "", false, ""},
+		{"44", "", true, "44"},
+		{"44", "", false, "44"},
+		{"44", "4", true, "44"},
+		{"44", "4", false, "4"},
+		{"", "4", true, ""},
+		{"", "4", false, "4"},
+	}
+
+	for _, test := range tests {
+		gOpts.cursorline = test.cursorline
+		gOpts.cursorinactive = test.cursorinactive
+		if got := cursorCodes(test.focused); got != test.exp {
+			t.Errorf("at cursorline '%s', cursorinactive '%s', and focused '%t' expected '%s' but got '%s'", test.cursorline, test.cursorinactive, test.focused, test.exp, got)
+		}
+	}
+}
+
+func TestLineStyle(t *testing.T) {
+	defer func(selectedline, cursorselected string) {
+		gOpts.selectedline = selectedline
+		gOpts.cursorselected = cursorselected
+	}(gOpts.selectedline, gOpts.cursorselected)
+
+	st := tcell.StyleDefault.Foreground(tcell.ColorMaroon)
+
+	tests := []struct {
+		selectedline   string
+		cursorselected string
+		cursor         string
+		current        bool
+		selected       bool
+		exp            tcell.Style
+	}{
+		{"", "", "", false, false, st},
+		{"", "", "", false, true, st},
+		{"", "", "", true, false, st.Reverse(true)},
+		{"", "", "", true, true, st.Reverse(true)},
+		{"35", "", "", false, false, st},
+		{"35", "", "", false, true, st.Foreground(tcell.ColorPurple)},
+		{"35", "", "", true, false, st.Reverse(true)},
+		{"35", "", "", true, true, st.Foreground(tcell.ColorPurple).Reverse(true)},
+		{"35", "", "44", true, true, st.Foreground(tcell.ColorPurple).Background(tcell.ColorNavy)},
+		{"35", "4;45", "44", false, true, st.Foreground(tcell.ColorPurple)},
+		{"35", "4;45", "44", true, false, st.Background(tcell.ColorNavy)},
+		{"35", "4;45", "44", true, true, st.Background(tcell.ColorPurple).Underline(true)},
+		{"", "4", "", true, true, st.Underline(true)},
+		{"", "4", "", true, false, st.Reverse(true)},
+	}
+
+	for _, test := range tests {
+		gOpts.selectedline = test.selectedline
+		gOpts.cursorselected = test.cursorselected
+		if got := lineStyle(st, test.cursor, test.current, test.selected); got != test.exp {
+			t.Errorf("at selectedline '%s', cursorselected '%s', cursor '%s', current '%t', and selected '%t' expected '%v' but got '%v'",
+				test.selectedline, test.cursorselected, test.cursor, test.current, test.selected, test.exp, got)
+		}
+	}
+}
+
+func TestMatchCells(t *testing.T) {
+	tests := []struct {
+		s     string
+		off   int
+		name  string
+		spans [][2]int
+		exp   []matchCell
+	}{
+		{" foo.txt  ", 1, "foo.txt", nil, nil},
+		{" foo.txt  ", 1, "foo.txt", [][2]int{{4, 7}}, []matchCell{{5, "txt"}}},
+		{" foo.txt  ", 1, "foo.txt", [][2]int{{1, 2}, {2, 3}}, []matchCell{{2, "o"}, {3, "o"}}},
+		{" * foo.txt  ", 3, "foo.txt", [][2]int{{0, 3}}, []matchCell{{3, "foo"}}},
+		{" 日本語.txt ", 1, "日本語.txt", [][2]int{{1, 2}}, []matchCell{{3, "本"}}},
+		{" 日本語.txt ", 1, "日本語.txt", [][2]int{{4, 7}}, []matchCell{{8, "txt"}}},
+		{" 日本~", 1, "日本語.txt", [][2]int{{1, 3}}, []matchCell{{3, "本"}}},
+		{" foo.t~", 1, "foo.txt", [][2]int{{4, 7}}, []matchCell{{5, "t"}}},
+		{" foo.~", 1, "foo.txt", [][2]int{{4, 7}}, nil},
+	}
+
+	for _, test := range tests {
+		got := matchCells([]rune(test.s), test.off, []rune(test.name), test.spans)
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%s' with spans '%v' expected '%v' but got '%v'", test.s, test.spans, test.exp, got)
+		}
+	}
+}
+
+func TestSortIndicator(t *testing.T) {
+	tests := []struct {
+		t   sortType
+		exp string
+	}{
+		{sortType{naturalSort, 0}, "natural↓"},
+		{sortType{nameSort, dirfirstSort}, "name↓"},
+		{sortType{sizeSort, reverseSort}, "size↑"},
+		{sortType{timeSort, dirfirstSort | hiddenSort | reverseSort}, "time↑"},
+		{sortType{atimeSort, hiddenSort}, "atime↓"},
+		{sortType{ctimeSort, reverseSort}, "ctime↑"},
+		{sortType{extSort, 0}, "ext↓"},
+	}
+
+	for _, test := range tests {
+		if got := test.t.indicator(); got != test.exp {
+			t.Errorf("at input '%v' expected '%s' but got '%s'", test.t, test.exp, got)
+		}
+	}
+}
+
+func TestPaneLayout(t *testing.T) {
+	defer func(ratios []int, preview bool, parentwidth, previewwidth int) {
+		gOpts.ratios = ratios
+		gOpts.preview = preview
+		gOpts.parentwidth = parentwidth
+		gOpts.previewwidth = previewwidth
+	}(gOpts.ratios, gOpts.preview, gOpts.parentwidth, gOpts.previewwidth)
+
+	tests := []struct {
+		ratios       []int
+		preview      bool
+		parentwidth  int
+		previewwidth int
+		wtot         int
+		expRatios    []int
+		expPreview   bool
+	}{
+		{[]int{1, 2, 3}, true, 0, 0, 40, []int{1, 2, 3}, true},
+		{[]int{1, 2, 3}, true, 0, 80, 120, []int{1, 2, 3}, true},
+		{[]int{1, 2, 3}, true, 0, 80, 80, []int{1, 2, 3}, true},
+		{[]int{1, 2, 3}, true, 0, 80, 79, []int{1, 2}, false},
+		{[]int{1, 2, 3}, true, 100, 0, 120, []int{1, 2, 3}, true},
+		{[]int{1, 2, 3}, true, 100, 0, 99, []int{2, 3}, true},
+		{[]int{1, 2, 3}, true, 100, 60, 80, []int{2, 3}, true},
+		{[]int{1, 2, 3}, true, 100, 60, 40, []int{2}, false},
+		{[]int{1, 2, 3}, false, 0, 80, 40, []int{1, 2, 3}, false},
+		{[]int{1, 2, 3}, false, 100, 0, 40, []int{3}, false},
+		{[]int{1, 2}, true, 0, 80, 40, []int{1}, false},
+		{[]int{1, 2}, true, 100, 0, 40, []int{1, 2}, true},
+		{[]int{1}, false, 100, 0, 40, []int{1}, false},
+	}
+
+	for _, test := range tests {
+		gOpts.ratios = test.ratios
+		gOpts.preview = test.preview
+		gOpts.parentwidth = test.parentwidth
+		gOpts.previewwidth = test.previewwidth
+		ratios, preview := paneLayout(test.wtot)
+		if !reflect.DeepEqual(ratios, test.expRatios) || preview != test.expPreview {
+			t.Errorf("at ratios '%v', preview '%t', parentwidth '%d', previewwidth '%d', and width '%d' expected '%v' and '%t' but got '%v' and '%t'",
+				test.ratios, test.preview, test.parentwidth, test.previewwidth, test.wtot, test.expRatios, test.expPreview, ratios, preview)
+		}
+	}
+}
+
+func TestChildCount(t *testing.T) {
+	defer func(info []string) { gOpts.info = info }(gOpts.info)
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, name := range []string{"empty", "sub"} {
+		if err := os.Mkdir(filepath.Join(root, name), 0755); err != nil {
+			t.Fatalf("creating temporary directory: %s", err)
+		}
+	}
+	for _, name := range []string{"foo", filepath.Join("sub", "bar"), filepath.Join("sub", ".baz")} {
+		if err := ioutil.WriteFile(filepath.Join(root, name), nil, 0644); err != nil {
+			t.Fatalf("creating temporary file: %s", err)
+		}
+	}
+
+	// counts are only loaded when 'childcount' is shown at the time of loading
+	tests := []struct {
+		info []string
+		exp  map[string]string
+	}{
+		{[]string{"childcount"}, map[string]string{"empty": "    0", "sub": "    2", "foo": "     "}},
+		{[]string{"size"}, map[string]string{"empty": "    ?", "sub": "    ?", "foo": "     "}},
+	}
+
+	for _, test := range tests {
+		gOpts.info = test.info
+		d := newDir(root)
+		if d.childcount != hasInfo("childcount") {
+			t.Errorf("at info '%v' expected counting to be '%t' but got '%t'", test.info, hasInfo("childcount"), d.childcount)
+		}
+
+		gOpts.info = []string{"childcount"}
+		for _, f := range d.files {
+			if got := fileInfo(f, d); got != test.exp[f.Name()] {
+				t.Errorf("at info '%v' and file '%s' expected '%s' but got '%s'", test.info, f.Name(), test.exp[f.Name()], got)
+			}
+		}
+	}
+}
+
+func TestLoadChildCount(t *testing.T) {
+	defer func(childCounts map[string]childCount) { gChildCounts = childCounts }(gChildCounts)
+	gChildCounts = make(map[string]childCount)
+
+	root, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+
+	files, err := readdir(root)
+	if err != nil {
+		t.Fatalf("reading directory: %s", err)
+	}
+
+	countChildren(files)
+	if files[0].childCount != 0 {
+		t.Errorf("expected '0' entries but got '%d'", files[0].childCount)
+	}
+	if childrenChanged(files) {
+		t.Errorf("expected counts to be unchanged after loading")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(sub, "foo"), nil, 0644); err != nil {
+		t.Fatalf("creating temporary file: %s", err)
+	}
+
+	// the modification time is kept the same to show that cached values are
+	// used as long as the modification time of the directory is the same
+	if n := loadChildCount(sub, files[0].ModTime()); n != 0 {
+		t.Errorf("expected the cached count '0' but got '%d'", n)
+	}
+
+	modTime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(sub, modTime, modTime); err != nil {
+		t.Fatalf("changing times: %s", err)
+	}
+	if !childrenChanged(files) {
+		t.Errorf("expected counts to be changed after the directory is modified")
+	}
+
+	files, err = readdir(root)
+	if err != nil {
+		t.Fatalf("reading directory: %s", err)
+	}
+	countChildren(files)
+	if files[0].childCount != 1 {
+		t.Errorf("expected '1' entries after loading again but got '%d'", files[0].childCount)
+	}
+}
+
+func TestRowInfo(t *testing.T) {
+	defer func(info []string, infocursor bool) {
+		gOpts.info = info
+		gOpts.infocursor = infocursor
+	}(gOpts.info, gOpts.infocursor)
+	gOpts.info = []string{"size", "perm"}
+
+	f := &file{FileInfo: fakeFileInfo("foo")}
+	d := &dir{path: "/"}
+
+	info := fileInfo(f, d)
+	blank := "                "
+	if len(info) != len(blank) {
+		t.Fatalf("expected information '%s' with width '%d' but got '%d'", info, len(blank), len(info))
+	}
+
+	tests := []struct {
+		infocursor bool
+		current    bool
+		exp        string
+	}{
+		{false, true, info},
+		{false, false, info},
+		{true, true, info},
+		{true, false, blank},
+	}
+
+	for _, test := range tests {
+		gOpts.infocursor = test.infocursor
+		if got := rowInfo(f, d, test.current); got != test.exp {
+			t.Errorf("at input '%t' and '%t' expected '%s' but got '%s'", test.infocursor, test.current, test.exp, got)
+		}
+	}
+
+	gOpts.info = nil
+	gOpts.infocursor = true
+	if got := rowInfo(f, d, false); got != "" {
+		t.Errorf("expected no information without 'info' option but got '%s'", got)
+	}
+}
+
+func TestSetMinimal(t *testing.T) {
+	defer func(icons, drawbox, minimal bool, info []string) {
+		gOpts.icons = icons
+		gOpts.drawbox = drawbox
+		gOpts.minimal = minimal
+		gOpts.info = info
+		gMinimalSaved = minimalOpts{}
+	}(gOpts.icons, gOpts.drawbox, gOpts.minimal, gOpts.info)
+
+	tests := []struct {
+		icons   bool
+		drawbox bool
+		info    []string
+	}{
+		{true, true, []string{"size", "time"}},
+		{false, true, nil},
+		{true, false, []string{"perm"}},
+		{false, false, nil},
+	}
+
+	for _, test := range tests {
+		gOpts.minimal = false
+		gOpts.icons = test.icons
+		gOpts.drawbox = test.drawbox
+		gOpts.info = test.info
+
+		setMinimal(true)
+		if !gOpts.minimal || gOpts.icons || gOpts.drawbox || gOpts.info != nil {
+			t.Errorf("at input '%t', '%t', and '%v' expected rendering options to be disabled but got '%t', '%t', and '%v'", test.icons, test.drawbox, test.info, gOpts.icons, gOpts.drawbox, gOpts.info)
+		}
+
+		setMinimal(true)
+
+		setMinimal(false)
+		if gOpts.minimal || gOpts.icons != test.icons || gOpts.drawbox != test.drawbox || !reflect.DeepEqual(gOpts.info, test.info) {
+			t.Errorf("at input '%t', '%t', and '%v' expected rendering options to be restored but got '%t', '%t', and '%v'", test.icons, test.drawbox, test.info, gOpts.icons, gOpts.drawbox, gOpts.info)
+		}
+
+		setMinimal(false)
+		if gOpts.icons != test.icons || gOpts.drawbox != test.drawbox || !reflect.DeepEqual(gOpts.info, test.info) {
+			t.Errorf("at input '%t', '%t', and '%v' expected rendering options to be kept but got '%t', '%t', and '%v'", test.icons, test.drawbox, test.info, gOpts.icons, gOpts.drawbox, gOpts.info)
+		}
+	}
+
+	changes := []struct {
+		exprs   []*setExpr
+		icons   bool
+		drawbox bool
+		info    []string
+	}{
+		{nil, true, true, []string{"size"}},
+		{[]*setExpr{{"noicons", ""}}, false, true, []string{"size"}},
+		{[]*setExpr{{"icons", ""}, {"info", "time"}}, true, true, []string{"time"}},
+		{[]*setExpr{{"info", ""}}, true, true, nil},
+		{[]*setExpr{{"info", "bogus"}}, true, true, []string{"size"}},
+	}
+
+	for _, test := range changes {
+		gOpts.minimal = false
+		gOpts.icons = true
+		gOpts.drawbox = true
+		gOpts.info = []string{"size"}
+
+		setMinimal(true)
+		for _, e := range test.exprs {
+			e.eval(&app{ui: &ui{}, nav: &nav{dirs: []*dir{{}}}}, nil)
+		}
+		setMinimal(false)
+
+		if gOpts.icons != test.icons || gOpts.drawbox != test.drawbox || !reflect.DeepEqual(gOpts.info, test.info) {
+			t.Errorf("at input '%v' expected changed options to be kept as '%t', '%t', and '%v' but got '%t', '%t', and '%v'", test.exprs, test.icons, test.drawbox, test.info, gOpts.icons, gOpts.drawbox, gOpts.info)
+		}
+	}
+}
+
+func TestMinimalLine(t *testing.T) {
+	defer func(truncatechar string) {
+		gOpts.truncatechar = truncatechar
+	}(gOpts.truncatechar)
+	gOpts.truncatechar = "~"
+
+	selections := map[string]int{"/sel": 0}
+	saves := map[string]bool{"/cp": true, "/mv": false}
+
+	tests := []struct {
+		path  string
+		width int
+		exp   string
+	}{
+		{"/foo", 10, "  foo    "},
+		{"/sel", 10, "* sel    "},
+		{"/cp", 10, "+ cp     "},
+		{"/mv", 10, "- mv     "},
+		{"/foobarbaz", 10, "  fooba~ "},
+		{"/ユーザー", 12, "  ユーザー "},
+		{"/ユーザー", 11, "  ユーザ~ "},
+	}
+
+	for _, test := range tests {
+		mark := fileMark(test.path, selections, saves)
+		if got := minimalLine(mark, test.path[1:], test.width); got != test.exp {
+			t.Errorf("at input '%s' with width '%d' expected '%s' but got '%s'", test.path, test.width, test.exp, got)
+		}
+	}
+}
+
+func TestGridBadges(t *testing.T) {
+	defer func(badges []string, icons bool) {
+		gOpts.badges = badges
+		gOpts.icons = icons
+	}(gOpts.badges, gOpts.icons)
+	gOpts.badges = []string{"readonly"}
+	gOpts.icons = false
+
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("failed to initialize screen: %s", err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 5)
+
+	dir := &dir{path: "/", files: []*file{
+		{FileInfo: fakeFileInfo("a"), path: "/a"},
+		{FileInfo: fakeFileInfo("b"), path: "/b"},
+	}}
+
+	win := newWin(40, 5, 0, 0)
+	win.printGrid(screen, dir, nil, nil, nil, nil, "", "")
+
+	if dir.columns() != 2 {
+		t.Fatalf("expected files to be drawn in '2' columns but got '%d'", dir.columns())
+	}
+
+	// badges are drawn in a column before the names of files in each cell
+	for x, exp := range map[int]rune{2: 'R', 4: 'a', 8: 'R', 10: 'b'} {
+		if r, _, _, _ := screen.GetContent(x, 0); r != exp {
+			t.Errorf("at column '%d' expected '%c' but got '%c'", x, exp, r)
+		}
+	}
+}
+
+func TestGridCursorLine(t *testing.T) {
+	defer func(badges []string, icons bool) {
+		gOpts.badges = badges
+		gOpts.icons = icons
+	}(gOpts.badges, gOpts.icons)
+	gOpts.badges = nil
+	gOpts.icons = false
+
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("failed to initialize screen: %s", err)
+	}
+	defer screen.Fini()
+	screen.SetSize(40, 5)
+
+	dir := &dir{path: "/", files: []*file{
+		{FileInfo: fakeFileInfo("aaaa"), path: "/aaaa"},
+		{FileInfo: fakeFileInfo("b"), path: "/b"},
+		{FileInfo: fakeFileInfo("c"), path: "/c"},
+	}}
+
+	win := newWin(40, 5, 0, 0)
+	win.printGrid(screen, dir, nil, nil, nil, nil, "44", "")
+
+	bg := applyAnsiCodes("44", tcell.StyleDefault)
+
+	// the cell of the current file is as wide as its column
+	for x := 0; x < 7; x++ {
+		if _, _, st, _ := screen.GetContent(x, 0); st != bg {
+			t.Errorf("at column '%d' expected the background of the current line but got style '%v'", x, st)
+		}
+	}
+	if _, _, st, _ := screen.GetContent(7, 0); st == bg {
+		t.Errorf("expected the background of the current line to end at the cell")
+	}
+}
+
+func TestMinimalStyles(t *testing.T) {
+	defer func(minimal bool, cursorselected string) {
+		gOpts.minimal = minimal
+		gOpts.cursorselected = cursorselected
+	}(gOpts.minimal, gOpts.cursorselected)
+	gOpts.minimal = true
+	gOpts.cursorselected = "1"
+
+	screen := tcell.NewSimulationScreen("")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("failed to initialize screen: %s", err)
+	}
+	defer screen.Fini()
+	screen.SetSize(20, 5)
+
+	win := newWin(20, 5, 0, 0)
+
+	win.print(screen, 0, 0, tcell.StyleDefault, "\033[31;44mfoo\033[0m")
+	if _, _, st, _ := screen.GetContent(0, 0); st != tcell.StyleDefault {
+		t.Errorf("expected colors to be ignored but got style '%v'", st)
+	}
+
+	dir := &dir{path: "/", files: []*file{
+		{FileInfo: fakeFileInfo("a"), path: "/a"},
+		{FileInfo: fakeFileInfo("b"), path: "/b"},
+	}}
+
+	tests := []struct {
+		cursor     string
+		selections map[string]int
+		exp        tcell.Style
+	}{
+		{"", nil, tcell.StyleDefault.Reverse(true)},
+		{"4", nil, tcell.StyleDefault.Underline(true)},
+		{"4", map[string]int{"/a": 0}, tcell.StyleDefault.Bold(true)},
+	}
+
+	for _, test := range tests {
+		win.printDir(screen, dir, test.selections, nil, nil, nil, test.cursor, "")
+		if _, _, st, _ := screen.GetContent(2, 0); st != test.exp {
+			t.Errorf("at input '%q' with selections '%v' expected style '%v' for the current file but got '%v'", test.cursor, test.selections, test.exp, st)
+		}
+		if _, _, st, _ := screen.GetContent(2, 1); st != tcell.StyleDefault {
+			t.Errorf("at input '%q' with selections '%v' expected default style for other files but got '%v'", test.cursor, test.selections, st)
+		}
+	}
+}
+
+func TestBoxChars(t *testing.T) {
+	defer func(boxchars string) {
+		gOpts.boxchars = boxchars
+	}(gOpts.boxchars)
+
+	tests := []struct {
+		boxchars string
+		exp      boxChars
+	}{
+		{"ascii", boxChars{'-', '|', '+', '+', '+', '+', '+', '+'}},
+		{"single", boxChars{'─', '│', '┌', '┐', '└', '┘', '┬', '┴'}},
+		{"rounded", boxChars{'─', '│', '╭', '╮', '╰', '╯', '┬', '┴'}},
+		{"double", boxChars{'═', '║', '╔', '╗', '╚', '╝', '╦', '╩'}},
+		{"", boxChars{'─', '│', '┌', '┐', '└', '┘', '┬', '┴'}},
+		{"foo", boxChars{'─', '│', '┌', '┐', '└', '┘', '┬', '┴'}},
+	}
+
+	for _, test := range tests {
+		gOpts.boxchars = test.boxchars
+		if got := currBoxChars(); got != test.exp {
+			t.Errorf("at input '%s' expected '%q' but got '%q'", test.boxchars, test.exp, got)
+		}
+	}
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("breadcrumbs are tested with unix paths")
+	}
+
+	tests := []struct {
+		path  string
+		width int
+		exp   string
+	}{
+		{"/", 10, "/"},
+		{"/home", 10, "/ › home"},
+		{"/home/user/src", 80, "/ › home › user › src"},
+		{"/home/user/src", 21, "/ › home › user › src"},
+		{"/home/user/src", 20, "… › user › src"},
+		{"/home/user/src", 14, "… › user › src"},
+		{"/home/user/src", 13, "… › src"},
+		{"/home/user/src", 7, "… › src"},
+		{"/home/user/src", 6, "…src"},
+		{"/home/user/src", 3, "…rc"},
+		{"/home/user/src", 1, "…"},
+		{"/home/user/src", 0, ""},
+		{"/home/ユーザー", 12, "… › ユーザー"},
+		{"/home/ユーザー", 11, "…ユーザー"},
+		{"/home/ユーザー", 6, "…ザー"},
+	}
+
+	for _, test := range tests {
+		if got := breadcrumbs(test.path, test.width); got != test.exp {
+			t.Errorf("at input '%s' with width '%d' expected '%s' but got '%s'", test.path, test.width, test.exp, got)
+		}
+	}
+}
+
+func TestParseKeyName(t *testing.T) {
+	tests := []struct {
+		s   string
+		k   tcell.Key
+		ch  rune
+		mod tcell.ModMask
+		val string
+	}{
+		{"a", tcell.KeyRune, 'a', tcell.ModNone, "a"},
+		{"<lt>", tcell.KeyRune, '<', tcell.ModNone, "<lt>"},
+		{"<space>", tcell.KeyRune, ' ', tcell.ModNone, "<space>"},
+		{"<a-x>", tcell.KeyRune, 'x', tcell.ModAlt, "<a-x>"},
+		{"<a-gt>", tcell.KeyRune, '>', tcell.ModAlt, "<a-gt>"},
+		{"<up>", tcell.KeyUp, 0, tcell.ModNone, "<up>"},
+		{"<c-up>", tcell.KeyUp, 0, tcell.ModCtrl, "<c-up>"},
+		{"<s-up>", tcell.KeyUp, 0, tcell.ModShift, "<s-up>"},
+		{"<a-right>", tcell.KeyRight, 0, tcell.ModAlt, "<a-right>"},
+		{"<c-s-up>", tcell.KeyUp, 0, tcell.ModCtrl | tcell.ModShift, "<c-s-up>"},
+		{"<s-c-up>", tcell.KeyUp, 0, tcell.ModCtrl | tcell.ModShift, "<c-s-up>"},
+		{"<s-a-c-pgdn>", tcell.KeyPgDn, 0, tcell.ModAlt | tcell.ModCtrl | tcell.ModShift, "<a-c-s-pgdn>"},
+		{"<f-5>", tcell.KeyF5, 0, tcell.ModNone, "<f-5>"},
+		{"<f5>", tcell.KeyF5, 0, tcell.ModNone, "<f-5>"},
+		{"<s-f5>", tcell.KeyF5, 0, tcell.ModShift, "<s-f-5>"},
+		{"<c-f-12>", tcell.KeyF12, 0, tcell.ModCtrl, "<c-f-12>"},
+		{"<c-a>", tcell.KeyCtrlA, 0, tcell.ModNone, "<c-a>"},
+		{"<a-c-a>", tcell.KeyCtrlA, 0, tcell.ModAlt, "<a-c-a>"},
+		{"<a-enter>", tcell.KeyEnter, 0, tcell.ModAlt, "<a-enter>"},
+	}
+
+	for _, test := range tests {
+		k, ch, mod, err := parseKeyName(test.s)
+		if err != nil || k != test.k || ch != test.ch || mod != test.mod {
+			t.Errorf("at input '%s' expected '%v %q %v' but got '%v %q %v' (error: %v)", test.s, test.k, test.ch, test.mod, k, ch, mod, err)
+			continue
+		}
+		if val := keyName(k, ch, mod); val != test.val {
+			t.Errorf("at input '%s' expected name '%s' but got '%s'", test.s, test.val, val)
+		}
+	}
+
+	for _, s := range []string{"<foo>", "<c-x-up>", "<c-c-up>", "<c-b-a>", "<s-x>", "<s-c-a>", "<c-enter>", "<a->", "<f65>"} {
+		if _, _, _, err := parseKeyName(s); err == nil {
+			t.Errorf("at input '%s' expected an error", s)
+		}
+	}
+}
+
+func TestKeyName(t *testing.T) {
+	tests := []struct {
+		k   tcell.Key
+		ch  rune
+		mod tcell.ModMask
+		exp string
+	}{
+		{tcell.KeyRune, 'a', tcell.ModNone, "a"},
+		{tcell.KeyRune, 'a', tcell.ModAlt, "<a-a>"},
+		{tcell.KeyRune, '<', tcell.ModNone, "<lt>"},
+		{tcell.KeyUp, 0, tcell.ModNone, "<up>"},
+		{tcell.KeyUp, 0, tcell.ModShift | tcell.ModCtrl, "<c-s-up>"},
+		{tcell.KeyF5, 0, tcell.ModShift, "<s-f-5>"},
+		{tcell.KeyCtrlA, 0, tcell.ModCtrl, "<c-a>"},
+		{tcell.KeyCtrlA, 0, tcell.ModCtrl | tcell.ModAlt, "<a-c-a>"},
+		{tcell.KeyBacktab, 0, tcell.ModShift, "<backtab>"},
+		{tcell.Key(1000), 0, tcell.ModNone, ""},
+	}
+
+	for _, test := range tests {
+		if got := keyName(test.k, test.ch, test.mod); got != test.exp {
+			t.Errorf("at input '%v %q %v' expected '%s' but got '%s'", test.k, test.ch, test.mod, test.exp, got)
+		}
+	}
+}
+
+func TestNormalizeKeys(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+	}{
+		{"j", "j"},
+		{"gg", "gg"},
+		{"<s-c-up>", "<c-s-up>"},
+		{"g<s-f5>j", "g<s-f-5>j"},
+		{"<c-a><a-x>", "<c-a><a-x>"},
+		{"<foo>", "<foo>"},
+	}
+
+	for _, test := range tests {
+		if got := normalizeKeys(test.s); got != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestRelTime(t *testing.T) {
+	now := time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		d   time.Duration
+		exp string
+		ok  bool
+	}{
+		{-2 * time.Minute, "", false},
+		{-30 * time.Second, "just now", true},
+		{0, "just now", true},
+		{59 * time.Second, "just now", true},
+		{time.Minute, "1 min ago", true},
+		{59*time.Minute + 59*time.Second, "59 min ago", true},
+		{time.Hour, "1h ago", true},
+		{23 * time.Hour, "23h ago", true},
+		{24 * time.Hour, "yesterday", true},
+		{47 * time.Hour, "yesterday", true},
+		{48 * time.Hour, "2 days ago", true},
+		{29 * 24 * time.Hour, "29 days ago", true},
+		{30 * 24 * time.Hour, "1 mo ago", true},
+		{364 * 24 * time.Hour, "12 mo ago", true},
+		{365 * 24 * time.Hour, "", false},
+		{10 * 365 * 24 * time.Hour, "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := relTime(now.Add(-test.d), now)
+		if got != test.exp || ok != test.ok {
+			t.Errorf("at input '%s' expected '%s' (%t) but got '%s' (%t)", test.d, test.exp, test.ok, got, ok)
+		}
+	}
+}
+
+func TestInfoTimeFmt(t *testing.T) {
+	defer func(relativetime bool) { gOpts.relativetime = relativetime }(gOpts.relativetime)
+
+	recent := time.Now().Add(-5 * time.Minute)
+	old := time.Date(2000, time.January, 2, 3, 4, 5, 0, time.Local)
+
+	gOpts.relativetime = false
+	if got := infotimefmt(old); got != "Jan  2  2000" {
+		t.Errorf("expected absolute time 'Jan  2  2000' but got '%s'", got)
+	}
+
+	gOpts.relativetime = true
+	if got := infotimefmt(recent); got != "5 min ago" {
+		t.Errorf("expected relative time '5 min ago' but got '%s'", got)
+	}
+	if got := infotimefmt(old); got != "Jan  2  2000" {
+		t.Errorf("expected absolute time 'Jan  2  2000' for old times but got '%s'", got)
+	}
+}
+
+func TestWrapPoints(t *testing.T) {
+	defer func(tabstop int) { gOpts.tabstop = tabstop }(gOpts.tabstop)
+	gOpts.tabstop = 4
+
+	tests := []struct {
+		s     string
+		width int
+		exp   []int
+	}{
+		{"", 4, nil},
+		{"abcd", 4, nil},
+		{"abcde", 4, []int{4}},
+		{"abcdefghij", 4, []int{4, 8}},
+		{"世界世界", 4, []int{6}},
+		{"a世界", 4, []int{4}},
+		{"ab世界", 3, []int{2, 5}},
+		{"世界", 1, []int{3}},
+		{"ae\u0301bcd", 4, []int{6}},
+		{"abc\U0001F468\u200d\U0001F469\u200d\U0001F467", 4, []int{3}},
+		{"\033[31mabcd\033[0mef", 4, []int{13}},
+		{"ab\033[31mcdef", 4, []int{9}},
+		{"a\tb", 4, []int{2}},
+		{"a\tb", 5, nil},
+		{"abcd\tef", 6, []int{4}},
+	}
+
+	for _, test := range tests {
+		if got := wrapPoints(test.s, test.width); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' with width '%d' expected '%v' but got '%v'", test.s, test.width, test.exp, got)
+		}
+	}
+}
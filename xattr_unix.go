@@ -0,0 +1,42 @@
+// +build linux darwin
+
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// This function returns the names of extended attributes of the given file.
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+
+	size, err = unix.Listxattr(path, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return splitXattrs(buf[:size]), nil
+}
+
+// This function splits the list of extended attribute names returned by the
+// system which are separated by null characters.
+func splitXattrs(buf []byte) []string {
+	var names []string
+	for _, name := range strings.Split(string(buf), "\x00") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,68 @@
+// +build linux darwin
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSplitXattrs(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp []string
+	}{
+		{"", nil},
+		{"user.foo\x00", []string{"user.foo"}},
+		{"user.foo\x00user.bar\x00", []string{"user.foo", "user.bar"}},
+		{"com.apple.quarantine", []string{"com.apple.quarantine"}},
+	}
+
+	for _, test := range tests {
+		if got := splitXattrs([]byte(test.s)); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("at input '%q' expected '%v' but got '%v'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestListXattrs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "foo")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("creating temporary file: %s", err)
+	}
+
+	names, err := listXattrs(path)
+	if err != nil {
+		t.Skipf("listing extended attributes is not supported: %s", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("expected no extended attributes but got '%v'", names)
+	}
+
+	if err := unix.Setxattr(path, "user.lf-test", []byte("foo"), 0); err != nil {
+		t.Skipf("setting extended attributes is not supported: %s", err)
+	}
+
+	names, err = listXattrs(path)
+	if err != nil {
+		t.Fatalf("listing extended attributes: %s", err)
+	}
+	if exp := []string{"user.lf-test"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, names)
+	}
+
+	if _, err := listXattrs(filepath.Join(dir, "bar")); err == nil {
+		t.Errorf("expected an error for missing file")
+	}
+}
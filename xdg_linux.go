@@ -0,0 +1,121 @@
+// +build linux
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// These are the default user directories relative to the home directory used
+// by 'xdg-user-dirs' when they are not set in 'user-dirs.dirs' file.
+var gUserDirDefaults = map[string]string{
+	"DESKTOP":     "Desktop",
+	"DOCUMENTS":   "Documents",
+	"DOWNLOAD":    "Downloads",
+	"MUSIC":       "Music",
+	"PICTURES":    "Pictures",
+	"PUBLICSHARE": "Public",
+	"TEMPLATES":   "Templates",
+	"VIDEOS":      "Videos",
+}
+
+// This function parses the contents of 'user-dirs.dirs' file and returns the
+// user directories by their names (e.g. 'DOWNLOAD' for 'XDG_DOWNLOAD_DIR').
+// Values are either absolute paths or paths relative to '$HOME' in double
+// quotes as written by 'xdg-user-dirs-update'. Directories set to the home
+// directory itself are disabled and they are not returned.
+func parseUserDirs(s, home string) map[string]string {
+	dirs := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		ind := strings.IndexByte(line, '=')
+		if ind < 0 {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:ind])
+		if !strings.HasPrefix(key, "XDG_") || !strings.HasSuffix(key, "_DIR") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "XDG_"), "_DIR")
+
+		val := strings.TrimSpace(line[ind+1:])
+		if len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"' {
+			val = val[1 : len(val)-1]
+		}
+		val = strings.Replace(val, `\"`, `"`, -1)
+
+		switch {
+		case val == "$HOME" || val == "$HOME/":
+			continue
+		case strings.HasPrefix(val, "$HOME/"):
+			val = filepath.Join(home, val[len("$HOME/"):])
+		case !filepath.IsAbs(val):
+			continue
+		}
+
+		dirs[name] = filepath.Clean(val)
+	}
+	return dirs
+}
+
+// This function returns the user directories of the current user read from
+// 'user-dirs.dirs' file in the configuration directory. Defaults are used for
+// the directories that are not set or when there is no such file.
+func userDirs() map[string]string {
+	config := os.Getenv("XDG_CONFIG_HOME")
+	if config == "" {
+		config = filepath.Join(gUser.HomeDir, ".config")
+	}
+
+	dirs := make(map[string]string)
+	if buf, err := ioutil.ReadFile(filepath.Join(config, "user-dirs.dirs")); err == nil {
+		dirs = parseUserDirs(string(buf), gUser.HomeDir)
+	}
+
+	for name, dir := range gUserDirDefaults {
+		if _, ok := dirs[name]; !ok {
+			dirs[name] = filepath.Join(gUser.HomeDir, dir)
+		}
+	}
+
+	return dirs
+}
+
+// This function replaces a leading user directory token in the given path
+// (e.g. '$XDG_DOWNLOAD_DIR' or '$XDG_MUSIC_DIR/albums') with the directory it
+// refers to. The path is returned as it is when there is no such token or
+// the directory is unknown.
+func resolveUserDir(s string, dirs func() map[string]string) string {
+	if !strings.HasPrefix(s, "$XDG_") {
+		return s
+	}
+
+	token, rest := s[1:], ""
+	if ind := strings.IndexByte(token, '/'); ind >= 0 {
+		token, rest = token[:ind], token[ind:]
+	}
+
+	if !strings.HasSuffix(token, "_DIR") {
+		return s
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(token, "XDG_"), "_DIR")
+
+	dir, ok := dirs()[name]
+	if !ok {
+		return s
+	}
+
+	return dir + rest
+}
+
+func replaceUserDir(s string) string {
+	return resolveUserDir(s, userDirs)
+}
@@ -0,0 +1,103 @@
+// +build linux
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseUserDirs(t *testing.T) {
+	s := `# This file is written by xdg-user-dirs-update
+# If you want to change or add directories, just edit the line you're
+# interested in.
+XDG_DESKTOP_DIR="$HOME/Desktop"
+XDG_DOWNLOAD_DIR="$HOME/İndirilenler"
+XDG_TEMPLATES_DIR="$HOME/"
+XDG_PUBLICSHARE_DIR="$HOME"
+XDG_DOCUMENTS_DIR="/data/docs"
+XDG_MUSIC_DIR="$HOME/My \"Music\""
+  XDG_PICTURES_DIR = "$HOME/Pictures/../Photos"
+XDG_VIDEOS_DIR="Videos"
+FOO_DIR="$HOME/foo"
+invalid line
+`
+
+	exp := map[string]string{
+		"DESKTOP":   "/home/user/Desktop",
+		"DOWNLOAD":  "/home/user/İndirilenler",
+		"DOCUMENTS": "/data/docs",
+		"MUSIC":     `/home/user/My "Music"`,
+		"PICTURES":  "/home/user/Photos",
+	}
+
+	if got := parseUserDirs(s, "/home/user"); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected '%v' but got '%v'", exp, got)
+	}
+
+	if got := parseUserDirs("", "/home/user"); len(got) != 0 {
+		t.Errorf("at empty input expected no entries but got '%v'", got)
+	}
+}
+
+func TestResolveUserDir(t *testing.T) {
+	dirs := func() map[string]string {
+		return map[string]string{
+			"DOWNLOAD": "/home/user/Downloads",
+			"MUSIC":    "/data/music",
+		}
+	}
+
+	tests := []struct {
+		s   string
+		exp string
+	}{
+		{"$XDG_DOWNLOAD_DIR", "/home/user/Downloads"},
+		{"$XDG_DOWNLOAD_DIR/", "/home/user/Downloads/"},
+		{"$XDG_MUSIC_DIR/albums/foo", "/data/music/albums/foo"},
+		{"$XDG_VIDEOS_DIR", "$XDG_VIDEOS_DIR"},
+		{"$XDG_DOWNLOAD", "$XDG_DOWNLOAD"},
+		{"$XDG_DOWNLOAD_DIRS", "$XDG_DOWNLOAD_DIRS"},
+		{"$HOME/foo", "$HOME/foo"},
+		{"/tmp/$XDG_DOWNLOAD_DIR", "/tmp/$XDG_DOWNLOAD_DIR"},
+		{"~/foo", "~/foo"},
+		{"foo", "foo"},
+	}
+
+	for _, test := range tests {
+		if got := resolveUserDir(test.s, dirs); got != test.exp {
+			t.Errorf("at input '%s' expected '%s' but got '%s'", test.s, test.exp, got)
+		}
+	}
+}
+
+func TestUserDirsDefaults(t *testing.T) {
+	defer func(config string) { os.Setenv("XDG_CONFIG_HOME", config) }(os.Getenv("XDG_CONFIG_HOME"))
+
+	dir, err := ioutil.TempDir("", "lf-test-")
+	if err != nil {
+		t.Fatalf("creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("XDG_CONFIG_HOME", dir)
+
+	if got, exp := userDirs()["DOWNLOAD"], filepath.Join(gUser.HomeDir, "Downloads"); got != exp {
+		t.Errorf("without a file expected '%s' but got '%s'", exp, got)
+	}
+
+	s := "XDG_DOWNLOAD_DIR=\"/data/downloads\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "user-dirs.dirs"), []byte(s), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	dirs := userDirs()
+	if got, exp := dirs["DOWNLOAD"], "/data/downloads"; got != exp {
+		t.Errorf("with a file expected '%s' but got '%s'", exp, got)
+	}
+	if got, exp := dirs["MUSIC"], filepath.Join(gUser.HomeDir, "Music"); got != exp {
+		t.Errorf("with a file expected default '%s' but got '%s'", exp, got)
+	}
+}
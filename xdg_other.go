@@ -0,0 +1,8 @@
+// +build !linux
+
+package main
+
+// User directories are only supported on linux.
+func replaceUserDir(s string) string {
+	return s
+}